@@ -0,0 +1,11 @@
+package main
+
+import (
+	admissionwebhook "github.com/run-ai/fake-gpu-operator/internal/admission-webhook"
+	"github.com/run-ai/fake-gpu-operator/internal/common/app"
+)
+
+func main() {
+	appRunner := app.NewAppRunner(admissionwebhook.NewAdmissionWebhookApp())
+	appRunner.Run()
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
 	"github.com/run-ai/fake-gpu-operator/internal/deviceplugin"
 	"github.com/spf13/viper"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
@@ -23,17 +24,21 @@ var InClusterConfigFn = ctrl.GetConfigOrDie
 var KubeClientFn = func(c *rest.Config) kubernetes.Interface {
 	return kubernetes.NewForConfigOrDie(c)
 }
+var DynamicClientFn = func(c *rest.Config) dynamic.Interface {
+	return dynamic.NewForConfigOrDie(c)
+}
 
 func main() {
 	clusterConfig := InClusterConfigFn()
 	kubeClient := KubeClientFn(clusterConfig)
+	dynamicClient := DynamicClientFn(clusterConfig)
 
 	log.Println("Fake Device Plugin Running")
 	requiredEnvVars := []string{constants.EnvTopologyCmName, constants.EnvTopologyCmNamespace, constants.EnvNodeName}
 	config.ValidateConfig(requiredEnvVars)
 	viper.AutomaticEnv()
 
-	topology, err := topology.GetNodeTopologyFromCM(kubeClient, os.Getenv(constants.EnvNodeName))
+	topology, err := topology.GetNodeTopology(dynamicClient, os.Getenv(constants.EnvNodeName))
 	if err != nil {
 		log.Printf("Failed to get topology: %s\n", err)
 		os.Exit(1)
@@ -0,0 +1,35 @@
+// Command fgo holds offline tooling for fake-gpu-operator configuration.
+//
+//	fgo validate --topology topology.yaml -f deploy/fake-gpu-operator/templates/profiles/builtin.yaml
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `Usage: fgo <command> [flags]
+
+Commands:
+  validate    Validate a cluster topology and the GPU profiles it references,
+              and print the GPU spec each node pool resolves to
+
+Run "fgo <command> -h" for the flags of a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "validate":
+		os.Exit(runValidate(os.Args[2:]))
+	case "-h", "--help", "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "fgo: unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+}
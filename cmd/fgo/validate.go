@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/profile"
+	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// validateNamespace holds the profile ConfigMaps of the in-memory cluster
+// the topology is resolved against.
+const validateNamespace = "fgo-validate"
+
+// fileList is a flag that can be repeated.
+type fileList []string
+
+func (f *fileList) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *fileList) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// poolSpec is the GPU spec a node pool resolves to.
+type poolSpec struct {
+	Backend       string                   `yaml:"backend,omitempty"`
+	Profile       string                   `yaml:"profile,omitempty"`
+	GpuProduct    string                   `yaml:"gpuProduct"`
+	GpuMemory     int                      `yaml:"gpuMemory"`
+	GpuCount      int                      `yaml:"gpuCount"`
+	DriverVersion string                   `yaml:"driverVersion,omitempty"`
+	CudaVersion   string                   `yaml:"cudaVersion,omitempty"`
+	Gpus          []gpuSpec                `yaml:"gpus,omitempty"`
+	OtherDevices  []topology.GenericDevice `yaml:"otherDevices,omitempty"`
+}
+
+// gpuSpec is listed for pools with mixed cards only.
+type gpuSpec struct {
+	Product string `yaml:"product"`
+	Memory  int    `yaml:"memory"`
+}
+
+func runValidate(args []string) int {
+	var topologyFile string
+	var profiles, fragments, manifests fileList
+
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	flags.StringVar(&topologyFile, "topology", "", "cluster topology YAML, as held by the topology ConfigMap (the chart's topology or cluster value)")
+	flags.Var(&profiles, "profile", "GPU profile YAML, named after the file, e.g. a100-pcie.yaml (repeatable)")
+	flags.Var(&fragments, "fragment", "GPU profile fragment YAML, named after the file (repeatable)")
+	flags.Var(&manifests, "f", "Kubernetes manifests holding the topology, profile and fragment ConfigMaps, e.g. helm template output or the chart's builtin.yaml (repeatable)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: fgo validate [--topology FILE] [--profile FILE]... [--fragment FILE]... [-f FILE]...\n\n"+
+			"Validates the topology the way the status-updater reads it and prints the GPU spec\n"+
+			"each node pool resolves to. Problems are listed on stderr with the path of the\n"+
+			"offending field; the exit code is 1 when there are any.\n\n")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+
+	client := fake.NewSimpleClientset()
+	var topologyData []byte
+	load := func() error {
+		for _, file := range manifests {
+			data, err := loadManifests(client, file)
+			if err != nil {
+				return err
+			}
+			if data != nil {
+				topologyData = data
+			}
+		}
+		for _, file := range profiles {
+			if err := loadProfile(client, profile.CmNamePrefix, file); err != nil {
+				return err
+			}
+		}
+		for _, file := range fragments {
+			if err := loadProfile(client, profile.FragmentCmNamePrefix, file); err != nil {
+				return err
+			}
+		}
+		if topologyFile != "" {
+			data, err := os.ReadFile(topologyFile)
+			if err != nil {
+				return err
+			}
+			topologyData = data
+		}
+		if topologyData == nil {
+			return errors.New("no topology given, pass --topology or a manifest with the topology ConfigMap")
+		}
+		return nil
+	}
+	if err := load(); err != nil {
+		fmt.Fprintf(os.Stderr, "fgo validate: %v\n", err)
+		return 2
+	}
+
+	report := topology.ValidateTopology(client, validateNamespace, topologyData)
+	if report.Config != nil {
+		printPools(report)
+	}
+
+	if len(report.Errors) == 0 {
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "%d problem(s) found:\n", len(report.Errors))
+	for _, err := range report.Errors {
+		fmt.Fprintf(os.Stderr, "  %v\n", err)
+	}
+	return 1
+}
+
+func printPools(report *topology.ValidationReport) {
+	pools := make(map[string]poolSpec, len(report.Pools))
+	for name, resolved := range report.Pools {
+		pool := report.Config.NodePools[name]
+		spec := poolSpec{
+			Backend:       pool.Gpu.Backend,
+			Profile:       pool.Gpu.Profile,
+			GpuProduct:    resolved.GpuProduct,
+			GpuMemory:     resolved.GpuMemory,
+			GpuCount:      resolved.GpuCount,
+			DriverVersion: resolved.DriverVersion,
+			CudaVersion:   resolved.CudaVersion,
+			OtherDevices:  resolved.OtherDevices,
+		}
+		for _, device := range resolved.Devices {
+			if device.Product != resolved.GpuProduct || device.Memory != resolved.GpuMemory {
+				spec.Gpus = gpuSpecs(resolved.Devices)
+				break
+			}
+		}
+		pools[name] = spec
+	}
+
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(map[string]interface{}{"nodePools": pools}); err != nil {
+		fmt.Fprintf(os.Stderr, "fgo validate: failed to print node pools: %v\n", err)
+	}
+}
+
+func gpuSpecs(devices []profile.DeviceSpec) []gpuSpec {
+	gpus := make([]gpuSpec, len(devices))
+	for idx, device := range devices {
+		gpus[idx] = gpuSpec{Product: device.Product, Memory: device.Memory}
+	}
+	return gpus
+}
+
+// loadProfile adds a profile or fragment file as the ConfigMap the chart
+// would render for it.
+func loadProfile(client *fake.Clientset, cmNamePrefix, file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	return client.Tracker().Add(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: cmNamePrefix + name, Namespace: validateNamespace},
+		Data:       map[string]string{profile.CmProfileKey: string(data)},
+	})
+}
+
+// loadManifests adds the profile and fragment ConfigMaps of a manifest file
+// and returns the topology it holds, if any. Lines holding only a Helm
+// template directive, as around the chart's builtin.yaml, are skipped.
+func loadManifests(client *fake.Clientset, file string) ([]byte, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var stripped bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	scanner.Buffer(nil, len(raw)+1)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "{{") && strings.HasSuffix(line, "}}") {
+			continue
+		}
+		stripped.WriteString(scanner.Text() + "\n")
+	}
+
+	var topologyData []byte
+	decoder := utilyaml.NewYAMLOrJSONDecoder(&stripped, 4096)
+	for {
+		var cm corev1.ConfigMap
+		if err := decoder.Decode(&cm); err != nil {
+			if err == io.EOF {
+				return topologyData, nil
+			}
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		if cm.Kind != "ConfigMap" {
+			continue
+		}
+
+		if data, ok := cm.Data[topology.CmTopologyKey]; ok {
+			topologyData = []byte(data)
+			continue
+		}
+		if !strings.HasPrefix(cm.Name, profile.CmNamePrefix) && !strings.HasPrefix(cm.Name, profile.FragmentCmNamePrefix) {
+			continue
+		}
+		cm.Namespace = validateNamespace
+		if err := client.Tracker().Add(&cm); err != nil {
+			return nil, fmt.Errorf("failed to load %s from %s: %w", cm.Name, file, err)
+		}
+	}
+}
@@ -25,6 +25,8 @@ type nvidiaSmiArgs struct {
 	GpuTotalMem   int
 	GpuUtil       int
 	GpuIdx        int
+	PciBusID      string
+	MigMode       string
 	ProcessName   string
 }
 
@@ -33,8 +35,14 @@ type nvidiaSmiArgs struct {
 const (
 	defaultDriverVersion = "470.129.06"
 	defaultCudaVersion   = "11.4"
+	// defaultPciBusID is shown for GPUs whose profile sets no PCI bus ID.
+	defaultPciBusID = "00000001:00:00.0"
 )
 
+// podAnnotationsPath is where pods mount their annotations through a
+// downward API volume for the fake nvidia-smi.
+const podAnnotationsPath = "/etc/podinfo/annotations"
+
 type config struct {
 	Debug bool
 }
@@ -120,12 +128,12 @@ func getNvidiaSmiArgs() (args []nvidiaSmiArgs, summary string, errs []error) {
 			fmt.Printf("GPU portion from RUNAI_NUM_OF_GPUS: %f\n", gpuPortion)
 		}
 	}
-	gpuTotalMem := int(float64(nodeTopology.GpuMemory) * gpuPortion)
 
 	currentPodName := os.Getenv("HOSTNAME")
 	currentPodUuid := os.Getenv("POD_UUID")
+	currentContainerName := os.Getenv("CONTAINER_NAME")
 	if conf.Debug {
-		fmt.Printf("Current pod name: %s, UUID: %s\n", currentPodName, currentPodUuid)
+		fmt.Printf("Current pod name: %s, UUID: %s, container: %s\n", currentPodName, currentPodUuid, currentContainerName)
 	}
 
 	// A missing process name only blanks one table column — non-fatal.
@@ -134,11 +142,108 @@ func getNvidiaSmiArgs() (args []nvidiaSmiArgs, summary string, errs []error) {
 		errs = append(errs, err)
 	}
 
+	// DRA claims name their devices explicitly; prefer that over matching the
+	// pod by name or UID, which is unreliable for DRA pods.
+	visibleDevices, visibleErrs := topology.VisibleDevicesFromEnv(os.Environ())
+	errs = append(errs, visibleErrs...)
+	if len(visibleDevices) == 0 {
+		// Pods that mount their annotations through the downward API get the
+		// GPUs the status-updater assigned to them.
+		var assignedErr error
+		visibleDevices, assignedErr = assignedVisibleDevices(podAnnotationsPath, currentContainerName)
+		if assignedErr != nil {
+			errs = append(errs, assignedErr)
+		}
+	}
+
+	var allArgs []nvidiaSmiArgs
+	if len(visibleDevices) > 0 {
+		allArgs, errs = visibleDeviceArgs(&nodeTopology, visibleDevices, gpuPortion, processName, errs)
+	} else {
+		allArgs = podMatchedArgs(&nodeTopology, currentPodName, currentPodUuid, currentContainerName, gpuPortion, processName)
+	}
+
+	if len(allArgs) == 0 {
+		allArgs = append(allArgs, nvidiaSmiArgs{
+			GpuProduct:    nodeTopology.GpuProductOf(0),
+			DriverVersion: nodeTopology.DriverVersion,
+			CudaVersion:   nodeTopology.CudaVersion,
+			GpuTotalMem:   int(float64(nodeTopology.GpuMemoryOf(0)) * gpuPortion),
+			MigMode:       migMode(nodeTopology.MigStrategy),
+			ProcessName:   processName,
+		})
+	}
+
+	return allArgs, "", errs
+}
+
+// visibleDeviceArgs renders the devices a DRA claim injected into the
+// container, looked up in the node topology by UUID.
+func visibleDeviceArgs(nodeTopology *topology.NodeTopology, visibleDevices []topology.VisibleDevice, gpuPortion float64, processName string, errs []error) ([]nvidiaSmiArgs, []error) {
+	gpuIdxByUUID := make(map[string]int, len(nodeTopology.Gpus))
+	for idx, gpu := range nodeTopology.Gpus {
+		gpuIdxByUUID[strings.ToLower(gpu.ID)] = idx
+	}
+
+	var allArgs []nvidiaSmiArgs
+	for _, device := range visibleDevices {
+		gpuIdx, found := gpuIdxByUUID[strings.ToLower(device.GpuUUID())]
+		if !found {
+			errs = append(errs, fmt.Errorf("device %s is not in the node topology", device.GpuUUID()))
+			continue
+		}
+		if conf.Debug {
+			fmt.Printf("Found claimed GPU %d (%s)\n", device.Index, device.UUID)
+		}
+		args := gpuArgs(nodeTopology, gpuIdx, gpuPortion, processName)
+		args.GpuIdx = device.Index
+		if device.MigProfile != "" {
+			args.MigMode = "Enabled"
+		}
+		allArgs = append(allArgs, args)
+	}
+	return allArgs, errs
+}
+
+// assignedVisibleDevices returns the GPUs listed in the run.ai/simulated-gpus
+// annotation of a downward API annotations file, leaving out the GPUs
+// allocated to the pod's other containers. A missing file yields no devices.
+func assignedVisibleDevices(annotationsPath, currentContainerName string) ([]topology.VisibleDevice, error) {
+	file, err := os.Open(annotationsPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading pod annotations %s: %w", annotationsPath, err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	gpus, err := topology.AssignedGpusFromDownwardAPI(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading pod annotations %s: %w", annotationsPath, err)
+	}
+
+	var devices []topology.VisibleDevice
+	for _, gpu := range gpus {
+		if currentContainerName != "" && gpu.Container != "" && gpu.Container != currentContainerName {
+			continue
+		}
+		devices = append(devices, gpu.VisibleDevice())
+	}
+	return devices, nil
+}
+
+// podMatchedArgs renders the GPUs the node topology records as allocated to
+// the current pod, matched by pod name or UID. When the container name is
+// known, GPUs attributed to the pod's other containers are left out.
+func podMatchedArgs(nodeTopology *topology.NodeTopology, currentPodName, currentPodUuid, currentContainerName string, gpuPortion float64, processName string) []nvidiaSmiArgs {
 	var allArgs []nvidiaSmiArgs
 	for idx, gpu := range nodeTopology.Gpus {
 		matched := false
 		if gpu.Status.AllocatedBy.Pod == currentPodName {
-			matched = true
+			matched = currentContainerName == "" || gpu.Status.AllocatedBy.Container == currentContainerName
 		} else {
 			for podUuid := range gpu.Status.PodGpuUsageStatus {
 				if string(podUuid) == currentPodUuid {
@@ -153,29 +258,40 @@ func getNvidiaSmiArgs() (args []nvidiaSmiArgs, summary string, errs []error) {
 		if conf.Debug {
 			fmt.Printf("Found GPU %d allocated to pod %s\n", idx, currentPodName)
 		}
-		allArgs = append(allArgs, nvidiaSmiArgs{
-			GpuProduct:    nodeTopology.GpuProduct,
-			DriverVersion: nodeTopology.DriverVersion,
-			CudaVersion:   nodeTopology.CudaVersion,
-			GpuTotalMem:   gpuTotalMem,
-			GpuUsedMem:    float32(gpu.Status.PodGpuUsageStatus.FbUsed(nodeTopology.GpuMemory)) * float32(gpuPortion),
-			GpuUtil:       gpu.Status.PodGpuUsageStatus.Utilization(),
-			GpuIdx:        idx,
-			ProcessName:   processName,
-		})
+		allArgs = append(allArgs, gpuArgs(nodeTopology, idx, gpuPortion, processName))
 	}
 
-	if len(allArgs) == 0 {
-		allArgs = append(allArgs, nvidiaSmiArgs{
-			GpuProduct:    nodeTopology.GpuProduct,
-			DriverVersion: nodeTopology.DriverVersion,
-			CudaVersion:   nodeTopology.CudaVersion,
-			GpuTotalMem:   gpuTotalMem,
-			ProcessName:   processName,
-		})
+	return allArgs
+}
+
+// gpuArgs renders the GPU at idx of the node topology with its own product,
+// memory and bus ID, scaling its memory by the pod's GPU portion.
+func gpuArgs(nodeTopology *topology.NodeTopology, idx int, gpuPortion float64, processName string) nvidiaSmiArgs {
+	gpu := nodeTopology.Gpus[idx]
+	gpuMemory := nodeTopology.GpuMemoryOf(idx)
+	return nvidiaSmiArgs{
+		GpuProduct:    nodeTopology.GpuProductOf(idx),
+		DriverVersion: nodeTopology.DriverVersion,
+		CudaVersion:   nodeTopology.CudaVersion,
+		GpuTotalMem:   int(float64(gpuMemory) * gpuPortion),
+		GpuUsedMem:    float32(gpu.Status.PodGpuUsageStatus.FbUsed(gpuMemory)) * float32(gpuPortion),
+		GpuUtil:       gpu.Status.PodGpuUsageStatus.Utilization(),
+		GpuIdx:        idx,
+		PciBusID:      gpu.PciBusID,
+		MigMode:       migMode(nodeTopology.MigStrategy),
+		ProcessName:   processName,
 	}
+}
 
-	return allArgs, "", errs
+// migMode returns the MIG mode of a whole GPU on a node with the given MIG
+// strategy: the GPUs of nodes that expose MIG devices are MIG capable.
+func migMode(migStrategy string) string {
+	switch migStrategy {
+	case "single", "mixed":
+		return "Disabled"
+	default:
+		return "N/A"
+	}
 }
 
 func readProcessName() (string, error) {
@@ -221,9 +337,13 @@ func printArgs(allArgs []nvidiaSmiArgs) {
 	t.AppendRow(table.Row{"", "", "              MIG M."})
 	t.AppendSeparator()
 	for _, args := range allArgs {
-		t.AppendRow(table.Row{fmt.Sprintf("%s  %s%s", sizeString(strconv.Itoa(args.GpuIdx), 3, true), sizeString(args.GpuProduct, 12, false), sizeString("Off", 13, true)), fmt.Sprintf("%s %s", sizeString("00000001:00:00.0", 16, false), sizeString("Off", 3, true)), sizeString("Off", 20, true)})
+		busID := args.PciBusID
+		if busID == "" {
+			busID = defaultPciBusID
+		}
+		t.AppendRow(table.Row{fmt.Sprintf("%s  %s%s", sizeString(strconv.Itoa(args.GpuIdx), 3, true), sizeString(args.GpuProduct, 12, false), sizeString("Off", 13, true)), fmt.Sprintf("%s %s", sizeString(busID, 16, false), sizeString("Off", 3, true)), sizeString("Off", 20, true)})
 		t.AppendRow(table.Row{"N/A   33C    P8    11W /  70W", sizeString(fmt.Sprintf("%dMiB / %dMiB", int(args.GpuUsedMem), args.GpuTotalMem), 20, true), fmt.Sprintf("%s %s", sizeString(strconv.Itoa(args.GpuUtil)+"%", 8, true), sizeString("Default", 11, true))})
-		t.AppendRow(table.Row{"", "", sizeString("N/A", 20, true)})
+		t.AppendRow(table.Row{"", "", sizeString(args.MigMode, 20, true)})
 		t.AppendSeparator()
 	}
 	t.Render()
@@ -50,7 +50,7 @@ func main() {
 			return
 		}
 
-		nodeTopology, err := topology.GetNodeTopologyFromCM(kubeclient.ClientSet, nodeName)
+		nodeTopology, err := topology.GetNodeTopology(kubeclient.DynamicClient, nodeName)
 		if err != nil {
 			if errors.IsNotFound(err) {
 				w.WriteHeader(http.StatusNotFound)
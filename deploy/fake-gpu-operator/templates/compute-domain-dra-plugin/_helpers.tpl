@@ -15,6 +15,10 @@ matchLabels:
 {{- define "fake-gpu-operator.compute-domain-dra-plugin.common.podTemplate.metadata" }}
 annotations:
   openshift.io/scc: hostmount-anyuid
+  {{- if gt (int (.Values.computeDomainDraPlugin).metricsPort) 0 }}
+  prometheus.io/scrape: "true"
+  prometheus.io/port: {{ (.Values.computeDomainDraPlugin).metricsPort | quote }}
+  {{- end }}
 labels:
   app: compute-domain-dra-plugin
   component: compute-domain-dra-plugin
@@ -41,7 +45,22 @@ containers:
       - name: HEALTHCHECK_PORT
         value: {{ (.Values.computeDomainDraPlugin).healthcheckPort | quote }}
       {{- end }}
+      {{- if (.Values.computeDomainDraPlugin).gcInterval }}
+      - name: GC_INTERVAL
+        value: {{ (.Values.computeDomainDraPlugin).gcInterval | quote }}
+      {{- end }}
+      - name: METRICS_BIND_ADDRESS
+        {{- if gt (int (.Values.computeDomainDraPlugin).metricsPort) 0 }}
+        value: ":{{ (.Values.computeDomainDraPlugin).metricsPort }}"
+        {{- else }}
+        value: "0"
+        {{- end }}
     name: compute-domain-dra-plugin-ctr
+    {{- if gt (int (.Values.computeDomainDraPlugin).metricsPort) 0 }}
+    ports:
+      - name: metrics
+        containerPort: {{ (.Values.computeDomainDraPlugin).metricsPort }}
+    {{- end }}
     {{- if (gt (int (.Values.computeDomainDraPlugin).healthcheckPort) 0) }}
     livenessProbe:
       grpc:
@@ -0,0 +1,130 @@
+package admissionwebhook
+
+import (
+	"context"
+	"fmt"
+
+	computedomainv1beta1 "github.com/NVIDIA/k8s-dra-driver-gpu/api/nvidia.com/resource/v1beta1"
+	"go.uber.org/zap/zapcore"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/app"
+)
+
+var (
+	scheme = runtime.NewScheme()
+)
+
+func init() {
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = computedomainv1beta1.AddToScheme(scheme)
+}
+
+type Config struct {
+	Port                int    `mapstructure:"WEBHOOK_PORT"`
+	CertDir             string `mapstructure:"WEBHOOK_CERT_DIR"`
+	HealthProbeAddress  string `mapstructure:"HEALTH_PROBE_BIND_ADDRESS"`
+	TopologyCmName      string `mapstructure:"TOPOLOGY_CM_NAME"`
+	TopologyCmNamespace string `mapstructure:"TOPOLOGY_CM_NAMESPACE"`
+}
+
+type AdmissionWebhookApp struct {
+	config *Config
+	stop   chan struct{}
+}
+
+func NewAdmissionWebhookApp() *AdmissionWebhookApp {
+	return &AdmissionWebhookApp{}
+}
+
+func (app *AdmissionWebhookApp) Name() string {
+	return "AdmissionWebhook"
+}
+
+func (app *AdmissionWebhookApp) GetConfig() interface{} {
+	if app.config == nil {
+		app.config = &Config{
+			Port:               9443,
+			CertDir:            "/tmp/k8s-webhook-server/serving-certs",
+			HealthProbeAddress: ":8081",
+		}
+	}
+	return app.config
+}
+
+func (app *AdmissionWebhookApp) Init(stop chan struct{}) {
+	app.stop = stop
+}
+
+func (app *AdmissionWebhookApp) Run() {
+	opts := zap.Options{
+		Development: true,
+		TimeEncoder: zapcore.ISO8601TimeEncoder,
+	}
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		<-app.stop
+		cancel()
+	}()
+
+	if err := app.runServer(ctx); err != nil {
+		ctrl.Log.Error(err, "webhook server exited with error")
+	}
+}
+
+func (app *AdmissionWebhookApp) runServer(ctx context.Context) error {
+	server := webhook.NewServer(webhook.Options{
+		Port:    app.config.Port,
+		CertDir: app.config.CertDir,
+	})
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+		Scheme:                 scheme,
+		Metrics:                metricsserver.Options{BindAddress: "0"},
+		HealthProbeBindAddress: app.config.HealthProbeAddress,
+		WebhookServer:          server,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create manager: %w", err)
+	}
+
+	RegisterHandlers(server, app.config.TopologyCmName, app.config.TopologyCmNamespace)
+
+	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+		return fmt.Errorf("failed to add health check: %w", err)
+	}
+	if err := mgr.AddReadyzCheck("readyz", server.StartedChecker()); err != nil {
+		return fmt.Errorf("failed to add ready check: %w", err)
+	}
+
+	ctrl.Log.Info("starting webhook server", "port", app.config.Port)
+	return mgr.Start(ctx)
+}
+
+// RegisterHandlers serves the validating webhooks on server. Only the
+// ConfigMap named topologyCmName in topologyCmNamespace is validated as a
+// topology.
+func RegisterHandlers(server webhook.Server, topologyCmName, topologyCmNamespace string) {
+	decoder := admission.NewDecoder(scheme)
+	server.Register(ComputeDomainPath, &webhook.Admission{Handler: &computeDomainValidator{decoder: decoder}})
+	server.Register(PodPath, &webhook.Admission{Handler: &podValidator{decoder: decoder}})
+	server.Register(TopologyConfigMapPath, &webhook.Admission{Handler: &topologyConfigMapValidator{
+		decoder:   decoder,
+		name:      topologyCmName,
+		namespace: topologyCmNamespace,
+	}})
+}
+
+// Verify that AdmissionWebhookApp implements the App interface
+var _ app.App = &AdmissionWebhookApp{}
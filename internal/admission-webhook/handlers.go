@@ -0,0 +1,111 @@
+package admissionwebhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	computedomainv1beta1 "github.com/NVIDIA/k8s-dra-driver-gpu/api/nvidia.com/resource/v1beta1"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	ComputeDomainPath     = "/validate-computedomain"
+	PodPath               = "/validate-pod"
+	TopologyConfigMapPath = "/validate-topology-configmap"
+)
+
+// computeDomainValidator rejects ComputeDomains the controller would otherwise
+// reconcile with silently defaulted or broken settings.
+type computeDomainValidator struct {
+	decoder admission.Decoder
+}
+
+func (v *computeDomainValidator) Handle(_ context.Context, req admission.Request) admission.Response {
+	domain := &computedomainv1beta1.ComputeDomain{}
+	if err := v.decoder.Decode(req, domain); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	errs := validateComputeDomain(domain)
+	if len(errs) > 0 && req.Operation == admissionv1.Update {
+		oldDomain := &computedomainv1beta1.ComputeDomain{}
+		if err := v.decoder.DecodeRaw(req.OldObject, oldDomain); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		errs = introducedErrors(errs, validateComputeDomain(oldDomain))
+	}
+	return response("ComputeDomain", domain.Name, errs)
+}
+
+// podValidator rejects pods whose GPU annotations the status-updater could not
+// interpret.
+type podValidator struct {
+	decoder admission.Decoder
+}
+
+func (v *podValidator) Handle(_ context.Context, req admission.Request) admission.Response {
+	pod := &corev1.Pod{}
+	if err := v.decoder.Decode(req, pod); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	name := pod.Name
+	if name == "" {
+		name = pod.GenerateName
+	}
+	errs := validatePodAnnotations(pod)
+	if len(errs) > 0 && req.Operation == admissionv1.Update {
+		oldPod := &corev1.Pod{}
+		if err := v.decoder.DecodeRaw(req.OldObject, oldPod); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		errs = introducedErrors(errs, validatePodAnnotations(oldPod))
+	}
+	return response("Pod", name, errs)
+}
+
+// topologyConfigMapValidator rejects edits to the topology ConfigMap that do
+// not parse or validate as a ClusterConfig. Other ConfigMaps are allowed.
+type topologyConfigMapValidator struct {
+	decoder   admission.Decoder
+	name      string
+	namespace string
+}
+
+func (v *topologyConfigMapValidator) Handle(_ context.Context, req admission.Request) admission.Response {
+	if req.Name != v.name || req.Namespace != v.namespace {
+		return admission.Allowed("")
+	}
+	cm := &corev1.ConfigMap{}
+	if err := v.decoder.Decode(req, cm); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	return response("ConfigMap", cm.Name, validateTopologyConfigMap(cm))
+}
+
+// introducedErrors drops the errors an update leaves as they were, so that
+// objects admitted before the webhook can still be updated, e.g. to remove
+// their finalizers.
+func introducedErrors(errs, oldErrs field.ErrorList) field.ErrorList {
+	existing := make(map[string]bool, len(oldErrs))
+	for _, err := range oldErrs {
+		existing[err.Error()] = true
+	}
+
+	var introduced field.ErrorList
+	for _, err := range errs {
+		if !existing[err.Error()] {
+			introduced = append(introduced, err)
+		}
+	}
+	return introduced
+}
+
+func response(kind, name string, errs field.ErrorList) admission.Response {
+	if len(errs) == 0 {
+		return admission.Allowed("")
+	}
+	return admission.Denied(fmt.Sprintf("%s %q is invalid: %s", kind, name, errs.ToAggregate()))
+}
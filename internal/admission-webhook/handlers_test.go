@@ -0,0 +1,130 @@
+package admissionwebhook
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	computedomainv1beta1 "github.com/NVIDIA/k8s-dra-driver-gpu/api/nvidia.com/resource/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
+)
+
+func newRequest(t *testing.T, obj metav1.Object) admission.Request {
+	raw, err := json.Marshal(obj)
+	require.NoError(t, err)
+	return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Name:      obj.GetName(),
+		Namespace: obj.GetNamespace(),
+		Operation: admissionv1.Create,
+		Object:    runtime.RawExtension{Raw: raw},
+	}}
+}
+
+func newUpdateRequest(t *testing.T, oldObj, obj metav1.Object) admission.Request {
+	req := newRequest(t, obj)
+	raw, err := json.Marshal(oldObj)
+	require.NoError(t, err)
+	req.Operation = admissionv1.Update
+	req.OldObject = runtime.RawExtension{Raw: raw}
+	return req
+}
+
+func TestPodValidator_Handle(t *testing.T) {
+	validator := &podValidator{decoder: admission.NewDecoder(scheme)}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "train",
+			Namespace:   "default",
+			Annotations: map[string]string{constants.AnnotationGpuFraction: "1.7"},
+		},
+	}
+	resp := validator.Handle(context.Background(), newRequest(t, pod))
+	assert.False(t, resp.Allowed)
+	assert.Contains(t, resp.Result.Message, `Pod "train" is invalid`)
+	assert.Contains(t, resp.Result.Message, "gpu-fraction")
+
+	pod.Annotations[constants.AnnotationGpuFraction] = "0.5"
+	resp = validator.Handle(context.Background(), newRequest(t, pod))
+	assert.True(t, resp.Allowed)
+}
+
+func TestPodValidator_HandleUpdate(t *testing.T) {
+	validator := &podValidator{decoder: admission.NewDecoder(scheme)}
+
+	oldPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "train",
+			Namespace:   "default",
+			Annotations: map[string]string{constants.AnnotationGpuFraction: "0.5"},
+		},
+	}
+	pod := oldPod.DeepCopy()
+	pod.Annotations[constants.AnnotationGpuFraction] = "1.7"
+	resp := validator.Handle(context.Background(), newUpdateRequest(t, oldPod, pod))
+	assert.False(t, resp.Allowed)
+	assert.Contains(t, resp.Result.Message, "gpu-fraction")
+
+	// A pod admitted with the problem before can still be updated.
+	updated := pod.DeepCopy()
+	updated.Labels = map[string]string{"team": "a"}
+	resp = validator.Handle(context.Background(), newUpdateRequest(t, pod, updated))
+	assert.True(t, resp.Allowed)
+}
+
+func TestComputeDomainValidator_HandleUpdate(t *testing.T) {
+	validator := &computeDomainValidator{decoder: admission.NewDecoder(scheme)}
+
+	oldDomain := &computedomainv1beta1.ComputeDomain{
+		ObjectMeta: metav1.ObjectMeta{Name: "imex", Namespace: "default"},
+		Spec: computedomainv1beta1.ComputeDomainSpec{
+			NumNodes: 2,
+			Channel: &computedomainv1beta1.ComputeDomainChannelSpec{
+				ResourceClaimTemplate: computedomainv1beta1.ComputeDomainResourceClaimTemplate{Name: "imex-channel"},
+			},
+		},
+	}
+	domain := oldDomain.DeepCopy()
+	domain.Spec.Channel.AllocationMode = "Some"
+	resp := validator.Handle(context.Background(), newUpdateRequest(t, oldDomain, domain))
+	assert.False(t, resp.Allowed)
+	assert.Contains(t, resp.Result.Message, `ComputeDomain "imex" is invalid`)
+	assert.Contains(t, resp.Result.Message, "allocationMode")
+
+	domain.Spec.Channel.AllocationMode = computedomainv1beta1.ComputeDomainChannelAllocationModeSingle
+	resp = validator.Handle(context.Background(), newUpdateRequest(t, oldDomain, domain))
+	assert.True(t, resp.Allowed)
+}
+
+func TestTopologyConfigMapValidator_Handle(t *testing.T) {
+	validator := &topologyConfigMapValidator{
+		decoder:   admission.NewDecoder(scheme),
+		name:      "topology",
+		namespace: "gpu-operator",
+	}
+	invalidData := map[string]string{topology.CmTopologyKey: "migStrategy: sometimes\n"}
+
+	topologyCM := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "topology", Namespace: "gpu-operator"},
+		Data:       invalidData,
+	}
+	resp := validator.Handle(context.Background(), newRequest(t, topologyCM))
+	assert.False(t, resp.Allowed)
+	assert.Contains(t, resp.Result.Message, `migStrategy: unsupported value "sometimes"`)
+
+	otherCM := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "topology-node-1", Namespace: "gpu-operator"},
+		Data:       invalidData,
+	}
+	resp = validator.Handle(context.Background(), newRequest(t, otherCM))
+	assert.True(t, resp.Allowed)
+}
@@ -0,0 +1,122 @@
+package admissionwebhook
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+
+	computedomainv1beta1 "github.com/NVIDIA/k8s-dra-driver-gpu/api/nvidia.com/resource/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
+)
+
+var (
+	utilizationPattern = regexp.MustCompile(`^(\d+)(?:-(\d+))?$`)
+
+	allocationModes = []string{
+		computedomainv1beta1.ComputeDomainChannelAllocationModeSingle,
+		computedomainv1beta1.ComputeDomainChannelAllocationModeAll,
+	}
+)
+
+// validateComputeDomain checks the fields the compute-domain controller and
+// plugins rely on. An empty allocation mode is allowed and defaults to Single.
+func validateComputeDomain(domain *computedomainv1beta1.ComputeDomain) field.ErrorList {
+	var errs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	if domain.Spec.NumNodes < 0 {
+		errs = append(errs, field.Invalid(specPath.Child("numNodes"), domain.Spec.NumNodes, "must be zero or greater"))
+	}
+
+	channel := domain.Spec.Channel
+	channelPath := specPath.Child("channel")
+	if channel == nil {
+		return append(errs, field.Required(channelPath, "a channel with a resourceClaimTemplate name is required"))
+	}
+
+	templateName := channel.ResourceClaimTemplate.Name
+	templatePath := channelPath.Child("resourceClaimTemplate", "name")
+	if templateName == "" {
+		errs = append(errs, field.Required(templatePath, "the ResourceClaimTemplate to generate needs a name"))
+	} else {
+		for _, msg := range validation.IsDNS1123Subdomain(templateName) {
+			errs = append(errs, field.Invalid(templatePath, templateName, msg))
+		}
+	}
+
+	if mode := channel.AllocationMode; mode != "" && !slices.Contains(allocationModes, mode) {
+		errs = append(errs, field.NotSupported(channelPath.Child("allocationMode"), mode, allocationModes))
+	}
+	return errs
+}
+
+// validatePodAnnotations checks the GPU annotations the status-updater reads
+// when computing a pod's simulated usage.
+func validatePodAnnotations(pod *corev1.Pod) field.ErrorList {
+	var errs field.ErrorList
+	annotationsPath := field.NewPath("metadata", "annotations")
+
+	if value, ok := pod.Annotations[constants.AnnotationGpuUtilization]; ok {
+		path := annotationsPath.Key(constants.AnnotationGpuUtilization)
+		if err := validateUtilization(value); err != nil {
+			errs = append(errs, field.Invalid(path, value, err.Error()))
+		}
+	}
+
+	if value, ok := pod.Annotations[constants.AnnotationGpuFraction]; ok {
+		path := annotationsPath.Key(constants.AnnotationGpuFraction)
+		fraction, err := strconv.ParseFloat(value, 64)
+		if err != nil || fraction <= 0 || fraction > 1 {
+			errs = append(errs, field.Invalid(path, value, "must be a number greater than 0 and at most 1"))
+		}
+	}
+	return errs
+}
+
+// validateUtilization accepts a percentage ("70") or a range ("20-80").
+func validateUtilization(value string) error {
+	match := utilizationPattern.FindStringSubmatch(value)
+	if match == nil {
+		return errors.New(`must be a percentage ("70") or a range ("20-80")`)
+	}
+
+	minUtilization, _ := strconv.Atoi(match[1])
+	maxUtilization := minUtilization
+	if match[2] != "" {
+		maxUtilization, _ = strconv.Atoi(match[2])
+	}
+	if maxUtilization > 100 {
+		return errors.New("must be between 0 and 100")
+	}
+	if minUtilization > maxUtilization {
+		return fmt.Errorf("range minimum %d is greater than its maximum %d", minUtilization, maxUtilization)
+	}
+	return nil
+}
+
+// validateTopologyConfigMap parses the topology ConfigMap the way the
+// components do and validates the resulting ClusterConfig.
+func validateTopologyConfigMap(cm *corev1.ConfigMap) field.ErrorList {
+	path := field.NewPath("data").Key(topology.CmTopologyKey)
+
+	data, ok := cm.Data[topology.CmTopologyKey]
+	if !ok {
+		return field.ErrorList{field.Required(path, "the topology ConfigMap must hold the cluster config")}
+	}
+
+	config, err := topology.ParseAndNormalizeTopology([]byte(data))
+	if err != nil {
+		return field.ErrorList{field.Invalid(path, field.OmitValueType{}, err.Error())}
+	}
+	if err := config.Validate(); err != nil {
+		return field.ErrorList{field.Invalid(path, field.OmitValueType{}, err.Error())}
+	}
+	return nil
+}
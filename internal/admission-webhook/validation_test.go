@@ -0,0 +1,180 @@
+package admissionwebhook
+
+import (
+	"testing"
+
+	computedomainv1beta1 "github.com/NVIDIA/k8s-dra-driver-gpu/api/nvidia.com/resource/v1beta1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
+)
+
+func TestValidateComputeDomain(t *testing.T) {
+	tests := map[string]struct {
+		spec          computedomainv1beta1.ComputeDomainSpec
+		expectedError string
+	}{
+		"valid": {
+			spec: computedomainv1beta1.ComputeDomainSpec{
+				NumNodes: 2,
+				Channel: &computedomainv1beta1.ComputeDomainChannelSpec{
+					ResourceClaimTemplate: computedomainv1beta1.ComputeDomainResourceClaimTemplate{Name: "imex-channel"},
+					AllocationMode:        computedomainv1beta1.ComputeDomainChannelAllocationModeAll,
+				},
+			},
+		},
+		"default allocation mode": {
+			spec: computedomainv1beta1.ComputeDomainSpec{
+				Channel: &computedomainv1beta1.ComputeDomainChannelSpec{
+					ResourceClaimTemplate: computedomainv1beta1.ComputeDomainResourceClaimTemplate{Name: "imex-channel"},
+				},
+			},
+		},
+		"unknown allocation mode": {
+			spec: computedomainv1beta1.ComputeDomainSpec{
+				Channel: &computedomainv1beta1.ComputeDomainChannelSpec{
+					ResourceClaimTemplate: computedomainv1beta1.ComputeDomainResourceClaimTemplate{Name: "imex-channel"},
+					AllocationMode:        "Many",
+				},
+			},
+			expectedError: `spec.channel.allocationMode: Unsupported value: "Many": supported values: "Single", "All"`,
+		},
+		"negative numNodes": {
+			spec: computedomainv1beta1.ComputeDomainSpec{
+				NumNodes: -1,
+				Channel: &computedomainv1beta1.ComputeDomainChannelSpec{
+					ResourceClaimTemplate: computedomainv1beta1.ComputeDomainResourceClaimTemplate{Name: "imex-channel"},
+				},
+			},
+			expectedError: "spec.numNodes: Invalid value: -1: must be zero or greater",
+		},
+		"missing channel": {
+			spec:          computedomainv1beta1.ComputeDomainSpec{},
+			expectedError: "spec.channel: Required value",
+		},
+		"invalid template name": {
+			spec: computedomainv1beta1.ComputeDomainSpec{
+				Channel: &computedomainv1beta1.ComputeDomainChannelSpec{
+					ResourceClaimTemplate: computedomainv1beta1.ComputeDomainResourceClaimTemplate{Name: "IMEX_Channel"},
+				},
+			},
+			expectedError: `spec.channel.resourceClaimTemplate.name: Invalid value: "IMEX_Channel"`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			errs := validateComputeDomain(&computedomainv1beta1.ComputeDomain{Spec: test.spec})
+			if test.expectedError == "" {
+				assert.Empty(t, errs)
+				return
+			}
+			assert.Contains(t, errs.ToAggregate().Error(), test.expectedError)
+		})
+	}
+}
+
+func TestValidatePodAnnotations(t *testing.T) {
+	tests := map[string]struct {
+		annotations   map[string]string
+		expectedError string
+	}{
+		"no annotations": {},
+		"single utilization and fraction": {
+			annotations: map[string]string{constants.AnnotationGpuUtilization: "70", constants.AnnotationGpuFraction: "0.5"},
+		},
+		"utilization range": {
+			annotations: map[string]string{constants.AnnotationGpuUtilization: "20-80"},
+		},
+		"non-numeric utilization": {
+			annotations:   map[string]string{constants.AnnotationGpuUtilization: "abc"},
+			expectedError: `metadata.annotations[run.ai/simulated-gpu-utilization]: Invalid value: "abc": must be a percentage ("70") or a range ("20-80")`,
+		},
+		"utilization above 100": {
+			annotations:   map[string]string{constants.AnnotationGpuUtilization: "50-150"},
+			expectedError: "must be between 0 and 100",
+		},
+		"inverted utilization range": {
+			annotations:   map[string]string{constants.AnnotationGpuUtilization: "80-20"},
+			expectedError: "range minimum 80 is greater than its maximum 20",
+		},
+		"fraction above one": {
+			annotations:   map[string]string{constants.AnnotationGpuFraction: "1.7"},
+			expectedError: `metadata.annotations[gpu-fraction]: Invalid value: "1.7": must be a number greater than 0 and at most 1`,
+		},
+		"zero fraction": {
+			annotations:   map[string]string{constants.AnnotationGpuFraction: "0"},
+			expectedError: "must be a number greater than 0 and at most 1",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: test.annotations}}
+			errs := validatePodAnnotations(pod)
+			if test.expectedError == "" {
+				assert.Empty(t, errs)
+				return
+			}
+			assert.Contains(t, errs.ToAggregate().Error(), test.expectedError)
+		})
+	}
+}
+
+func TestValidateTopologyConfigMap(t *testing.T) {
+	tests := map[string]struct {
+		data          map[string]string
+		expectedError string
+	}{
+		"valid new format": {
+			data: map[string]string{topology.CmTopologyKey: `
+nodePools:
+  default:
+    gpu:
+      backend: fake
+      profile: a100
+`},
+		},
+		"valid legacy format": {
+			data: map[string]string{topology.CmTopologyKey: `
+migStrategy: mixed
+nodePools:
+  default:
+    gpuCount: 2
+    gpuMemory: 11441
+    gpuProduct: Tesla-K80
+`},
+		},
+		"missing key": {
+			data:          map[string]string{},
+			expectedError: "data[topology.yml]: Required value",
+		},
+		"unparsable": {
+			data:          map[string]string{topology.CmTopologyKey: "nodePools: [unclosed"},
+			expectedError: "data[topology.yml]: Invalid value",
+		},
+		"schema violation": {
+			data: map[string]string{topology.CmTopologyKey: `
+nodePools:
+  default:
+    gpu:
+      backend: real
+`},
+			expectedError: `nodePools.default.gpu.backend: unsupported value "real"`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			errs := validateTopologyConfigMap(&corev1.ConfigMap{Data: test.data})
+			if test.expectedError == "" {
+				assert.Empty(t, errs)
+				return
+			}
+			assert.Contains(t, errs.ToAggregate().Error(), test.expectedError)
+		})
+	}
+}
@@ -3,13 +3,26 @@ package constants
 const (
 	AnnotationGpuIdx               = "runai-gpu"
 	AnnotationGpuFraction          = "gpu-fraction"
+	AnnotationGpuMemory            = "gpu-memory"
 	AnnotationPodGroupName         = "pod-group-name"
 	AnnotationReservationPodGpuIdx = "run.ai/reserve_for_gpu_index"
 	AnnotationMigMapping           = "run.ai/mig-mapping"
 	AnnotationKwokNode             = "kwok.x-k8s.io/node"
+	AnnotationGpuUtilization       = "run.ai/simulated-gpu-utilization"
+	// AnnotationComputeDomainGpus is set on ComputeDomains to a JSON map of
+	// node name to the GPUs bound to the domain's IMEX channel on that node.
+	AnnotationComputeDomainGpus = "run.ai/compute-domain-gpus"
+	// AnnotationHamiDevicesAllocated and AnnotationVolcanoVgpuIds hold the
+	// vGPUs HAMi and Volcano assigned to a pod's containers.
+	AnnotationHamiDevicesAllocated = "hami.io/vgpu-devices-allocated"
+	AnnotationVolcanoVgpuIds       = "volcano.sh/vgpu-ids-new"
+	// AnnotationAssignedGpus is set by the status-updater on GPU pods to a
+	// JSON list of the simulated GPUs they were assigned.
+	AnnotationAssignedGpus = "run.ai/simulated-gpus"
 
 	LabelGpuGroup                   = "runai-gpu-group"
 	LabelGpuProduct                 = "nvidia.com/gpu.product"
+	LabelGpuClique                  = "nvidia.com/gpu.clique"
 	LabelMigConfigState             = "nvidia.com/mig.config.state"
 	LabelFakeNodeDeploymentTemplate = "run.ai/fake-node-deployment-template"
 	LabelTopologyCMNodeTopology     = "node-topology"
@@ -21,6 +34,9 @@ const (
 	LabelManagedByValue = "fake-gpu-operator"
 	LabelComponent      = "fake-gpu-operator/component"
 	LabelPool           = "fake-gpu-operator/pool"
+	// LabelResourceSliceNode is set on ResourceSlices published by the KWOK
+	// DRA controllers so a node's slices can be listed and cleaned up together.
+	LabelResourceSliceNode = "fake-gpu-operator/node"
 
 	// Component identifier values for LabelComponent.
 	ComponentNvmlMock = "nvml-mock"
@@ -31,14 +47,18 @@ const (
 
 	ReservationNs = "runai-reservation"
 
+	KaiSchedulerName = "kai-scheduler"
+
 	GpuResourceName = "nvidia.com/gpu"
 
 	EnvFakeNode                        = "FAKE_NODE"
 	EnvNodeName                        = "NODE_NAME"
 	EnvTopologyCmName                  = "TOPOLOGY_CM_NAME"
 	EnvTopologyCmNamespace             = "TOPOLOGY_CM_NAMESPACE"
+	EnvWorkloadRulesCmName             = "WORKLOAD_RULES_CM_NAME"
 	EnvFakeGpuOperatorNs               = "FAKE_GPU_OPERATOR_NAMESPACE"
 	EnvResourceReservationNamespace    = "RESOURCE_RESERVATION_NAMESPACE"
+	EnvKaiReservationNamespace         = "KAI_RESERVATION_NAMESPACE"
 	EnvPrometheusURL                   = "PROMETHEUS_URL"
 	EnvDisableNodeLabeling             = "DISABLE_NODE_LABELING"
 	EnvNvmlMockImage                   = "NVML_MOCK_IMAGE"
@@ -46,4 +66,7 @@ const (
 	EnvRunaiIntegrationPollingInterval = "RUNAI_INTEGRATION_POLLING_INTERVAL"
 	EnvNodeResourceTopologyEnabled     = "NODE_RESOURCE_TOPOLOGY_ENABLED"
 	EnvPodResourcesEnabled             = "POD_RESOURCES_ENABLED"
+	EnvPodName                         = "POD_NAME"
+	EnvLeaderElect                     = "LEADER_ELECT"
+	EnvHealthProbeBindAddress          = "HEALTH_PROBE_BIND_ADDRESS"
 )
@@ -0,0 +1,110 @@
+package dra
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// DeviceConditionReady is set on every device a fake DRA plugin prepared.
+	DeviceConditionReady = "Ready"
+
+	deviceReasonPrepared = "Prepared"
+)
+
+// DeviceStatusDataFunc returns the driver-specific data to publish for one
+// allocated device, or nil to publish none.
+type DeviceStatusDataFunc func(result resourceapi.DeviceRequestAllocationResult) any
+
+// IsAdminAccess reports whether the device was allocated for administrative
+// access (e.g. a monitoring agent). Such devices are prepared like any other
+// but must not be counted as consumed.
+func IsAdminAccess(result resourceapi.DeviceRequestAllocationResult) bool {
+	return result.AdminAccess != nil && *result.AdminAccess
+}
+
+// PublishDeviceStatuses writes a ResourceClaim.status.devices entry for every
+// device allocated to the claim by driverName. Entries of other drivers are
+// kept as they are.
+func PublishDeviceStatuses(ctx context.Context, client kubernetes.Interface, claim *resourceapi.ResourceClaim, driverName string, dataFunc DeviceStatusDataFunc) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := client.ResourceV1().ResourceClaims(claim.Namespace).Get(ctx, claim.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if current.UID != claim.UID {
+			return fmt.Errorf("claim %s/%s was replaced (UID %s, expected %s)", claim.Namespace, claim.Name, current.UID, claim.UID)
+		}
+		if current.Status.Allocation == nil {
+			return fmt.Errorf("claim %s/%s is not allocated", claim.Namespace, claim.Name)
+		}
+
+		devices, err := buildDeviceStatuses(current, driverName, dataFunc)
+		if err != nil {
+			return err
+		}
+		current.Status.Devices = devices
+
+		_, err = client.ResourceV1().ResourceClaims(claim.Namespace).UpdateStatus(ctx, current, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+func buildDeviceStatuses(claim *resourceapi.ResourceClaim, driverName string, dataFunc DeviceStatusDataFunc) ([]resourceapi.AllocatedDeviceStatus, error) {
+	existing := make(map[string]resourceapi.AllocatedDeviceStatus)
+	var devices []resourceapi.AllocatedDeviceStatus
+	for _, device := range claim.Status.Devices {
+		if device.Driver != driverName {
+			devices = append(devices, device)
+			continue
+		}
+		existing[device.Pool+"/"+device.Device] = device
+	}
+
+	for _, result := range claim.Status.Allocation.Devices.Results {
+		if result.Driver != driverName {
+			continue
+		}
+
+		status := existing[result.Pool+"/"+result.Device]
+		status.Driver = driverName
+		status.Pool = result.Pool
+		status.Device = result.Device
+		status.ShareID = nil
+		if result.ShareID != nil {
+			shareID := string(*result.ShareID)
+			status.ShareID = &shareID
+		}
+
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               DeviceConditionReady,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: claim.Generation,
+			Reason:             deviceReasonPrepared,
+			Message:            "Device prepared by the fake DRA plugin",
+		})
+
+		status.Data = nil
+		if dataFunc != nil {
+			if data := dataFunc(result); data != nil {
+				raw, err := json.Marshal(data)
+				if err != nil {
+					return nil, fmt.Errorf("failed to encode status data for device %s: %w", result.Device, err)
+				}
+				status.Data = &runtime.RawExtension{Raw: raw}
+			}
+		}
+
+		devices = append(devices, status)
+	}
+
+	return devices, nil
+}
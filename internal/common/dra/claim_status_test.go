@@ -0,0 +1,105 @@
+package dra
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+const testDriver = "gpu.nvidia.com"
+
+func newAllocatedClaim(results ...resourceapi.DeviceRequestAllocationResult) *resourceapi.ResourceClaim {
+	return &resourceapi.ResourceClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "claim",
+			Namespace:  "default",
+			UID:        types.UID("claim-uid"),
+			Generation: 2,
+		},
+		Status: resourceapi.ResourceClaimStatus{
+			Allocation: &resourceapi.AllocationResult{
+				Devices: resourceapi.DeviceAllocationResult{Results: results},
+			},
+		},
+	}
+}
+
+func TestIsAdminAccess(t *testing.T) {
+	assert.False(t, IsAdminAccess(resourceapi.DeviceRequestAllocationResult{}))
+	assert.False(t, IsAdminAccess(resourceapi.DeviceRequestAllocationResult{AdminAccess: ptr.To(false)}))
+	assert.True(t, IsAdminAccess(resourceapi.DeviceRequestAllocationResult{AdminAccess: ptr.To(true)}))
+}
+
+func TestPublishDeviceStatuses(t *testing.T) {
+	claim := newAllocatedClaim(
+		resourceapi.DeviceRequestAllocationResult{Driver: testDriver, Pool: "node-1", Device: "gpu-0", Request: "gpu"},
+		resourceapi.DeviceRequestAllocationResult{Driver: testDriver, Pool: "node-1", Device: "gpu-1", Request: "gpu", AdminAccess: ptr.To(true)},
+		resourceapi.DeviceRequestAllocationResult{Driver: "other.example.com", Pool: "node-1", Device: "nic-0", Request: "nic"},
+	)
+	otherStatus := resourceapi.AllocatedDeviceStatus{Driver: "other.example.com", Pool: "node-1", Device: "nic-0"}
+	claim.Status.Devices = []resourceapi.AllocatedDeviceStatus{otherStatus}
+
+	client := fake.NewSimpleClientset(claim)
+	dataFunc := func(result resourceapi.DeviceRequestAllocationResult) any {
+		return map[string]any{"device": result.Device, "adminAccess": IsAdminAccess(result)}
+	}
+
+	require.NoError(t, PublishDeviceStatuses(context.Background(), client, claim, testDriver, dataFunc))
+	// Publishing again (e.g. a repeated prepare) must be idempotent.
+	require.NoError(t, PublishDeviceStatuses(context.Background(), client, claim, testDriver, dataFunc))
+
+	updated, err := client.ResourceV1().ResourceClaims("default").Get(context.Background(), "claim", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Len(t, updated.Status.Devices, 3)
+	assert.Equal(t, otherStatus, updated.Status.Devices[0])
+
+	for i, device := range []string{"gpu-0", "gpu-1"} {
+		status := updated.Status.Devices[i+1]
+		assert.Equal(t, testDriver, status.Driver)
+		assert.Equal(t, "node-1", status.Pool)
+		assert.Equal(t, device, status.Device)
+		require.Len(t, status.Conditions, 1)
+		ready := meta.FindStatusCondition(status.Conditions, DeviceConditionReady)
+		require.NotNil(t, ready)
+		assert.Equal(t, metav1.ConditionTrue, ready.Status)
+		assert.Equal(t, int64(2), ready.ObservedGeneration)
+
+		require.NotNil(t, status.Data)
+		var data map[string]any
+		require.NoError(t, json.Unmarshal(status.Data.Raw, &data))
+		assert.Equal(t, device, data["device"])
+		assert.Equal(t, device == "gpu-1", data["adminAccess"])
+	}
+}
+
+func TestPublishDeviceStatusesErrors(t *testing.T) {
+	claim := newAllocatedClaim(resourceapi.DeviceRequestAllocationResult{Driver: testDriver, Pool: "node-1", Device: "gpu-0"})
+
+	t.Run("claim not found", func(t *testing.T) {
+		err := PublishDeviceStatuses(context.Background(), fake.NewSimpleClientset(), claim, testDriver, nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("claim replaced", func(t *testing.T) {
+		replaced := claim.DeepCopy()
+		replaced.UID = "other-uid"
+		err := PublishDeviceStatuses(context.Background(), fake.NewSimpleClientset(replaced), claim, testDriver, nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("claim not allocated", func(t *testing.T) {
+		unallocated := claim.DeepCopy()
+		unallocated.Status.Allocation = nil
+		err := PublishDeviceStatuses(context.Background(), fake.NewSimpleClientset(unallocated), claim, testDriver, nil)
+		assert.Error(t, err)
+	})
+}
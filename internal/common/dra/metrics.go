@@ -0,0 +1,160 @@
+package dra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Operations reported by the kubelet plugins.
+const (
+	OperationPrepare   = "prepare"
+	OperationUnprepare = "unprepare"
+)
+
+// ReasonUnknown is reported for errors that carry no reason.
+const ReasonUnknown = "Unknown"
+
+// The metrics are registered in the controller-runtime registry, so
+// manager-based components expose them on their metrics endpoint and the
+// kubelet plugins serve the same registry through ServeMetrics.
+var (
+	operationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "fake_gpu_operator_dra_operations_total",
+		Help: "Number of claim prepare/unprepare operations handled by a DRA plugin.",
+	}, []string{"driver", "operation"})
+	operationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "fake_gpu_operator_dra_operation_duration_seconds",
+		Help:    "Latency of claim prepare/unprepare operations handled by a DRA plugin.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 14),
+	}, []string{"driver", "operation"})
+	operationErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "fake_gpu_operator_dra_operation_errors_total",
+		Help: "Number of failed claim prepare/unprepare operations, by reason.",
+	}, []string{"driver", "operation", "reason"})
+	preparedClaims = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "fake_gpu_operator_dra_prepared_claims",
+		Help: "Number of ResourceClaims currently prepared on a node.",
+	}, []string{"driver", "node"})
+	allocatableDevices = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "fake_gpu_operator_dra_allocatable_devices",
+		Help: "Number of devices published in a DRA resource pool.",
+	}, []string{"driver", "pool"})
+	allocatedDevices = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "fake_gpu_operator_dra_allocated_devices",
+		Help: "Number of devices of a DRA resource pool used by prepared claims.",
+	}, []string{"driver", "pool"})
+)
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(
+		operationsTotal,
+		operationDuration,
+		operationErrors,
+		preparedClaims,
+		allocatableDevices,
+		allocatedDevices,
+	)
+}
+
+// ObserveOperation records one prepare or unprepare operation that started at
+// start and ended with err.
+func ObserveOperation(driver, operation string, start time.Time, err error) {
+	operationsTotal.WithLabelValues(driver, operation).Inc()
+	operationDuration.WithLabelValues(driver, operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		operationErrors.WithLabelValues(driver, operation, ErrorReason(err)).Inc()
+	}
+}
+
+// SetPreparedClaims records how many claims are prepared on node.
+func SetPreparedClaims(driver, node string, count int) {
+	preparedClaims.WithLabelValues(driver, node).Set(float64(count))
+}
+
+// SetPoolDevices records how many devices pool publishes and how many of them
+// prepared claims use.
+func SetPoolDevices(driver, pool string, allocatable, allocated int) {
+	allocatableDevices.WithLabelValues(driver, pool).Set(float64(allocatable))
+	allocatedDevices.WithLabelValues(driver, pool).Set(float64(allocated))
+}
+
+// SetAllocatableDevices records how many devices pool publishes, for
+// components that publish pools without preparing claims.
+func SetAllocatableDevices(driver, pool string, allocatable int) {
+	allocatableDevices.WithLabelValues(driver, pool).Set(float64(allocatable))
+}
+
+// DeletePoolDevices drops the device gauges of a pool that no longer exists.
+func DeletePoolDevices(driver, pool string) {
+	allocatableDevices.DeleteLabelValues(driver, pool)
+	allocatedDevices.DeleteLabelValues(driver, pool)
+}
+
+// reasonError attaches a metrics reason to an error without changing its
+// message.
+type reasonError struct {
+	reason string
+	err    error
+}
+
+func (e *reasonError) Error() string { return e.err.Error() }
+func (e *reasonError) Unwrap() error { return e.err }
+
+// WithReason tags err with a short CamelCase reason reported in the
+// operation error metric. A nil err stays nil.
+func WithReason(reason string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &reasonError{reason: reason, err: err}
+}
+
+// ErrorReason returns the outermost reason err was tagged with, or
+// ReasonUnknown.
+func ErrorReason(err error) string {
+	var re *reasonError
+	if errors.As(err, &re) {
+		return re.reason
+	}
+	return ReasonUnknown
+}
+
+// ServeMetrics serves the metrics registry on addr until ctx is done. It is
+// used by the kubelet plugins, which have no controller-runtime manager.
+func ServeMetrics(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(ctrlmetrics.Registry, promhttp.HandlerOpts{}))
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	errCh := make(chan error, 1)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+		close(errCh)
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return fmt.Errorf("metrics server on %s failed: %w", addr, err)
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Failed to shut down metrics server: %v", err)
+	}
+	return nil
+}
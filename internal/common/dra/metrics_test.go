@@ -0,0 +1,47 @@
+package dra
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorReason(t *testing.T) {
+	tagged := WithReason("CDI", errors.New("disk full"))
+
+	assert.Equal(t, "CDI", ErrorReason(tagged))
+	assert.Equal(t, "CDI", ErrorReason(fmt.Errorf("prepare failed: %w", tagged)))
+	assert.Equal(t, "disk full", tagged.Error())
+	assert.Equal(t, ReasonUnknown, ErrorReason(errors.New("plain")))
+	assert.Equal(t, "Checkpoint", ErrorReason(WithReason("Checkpoint", tagged)), "outermost reason wins")
+	assert.NoError(t, WithReason("CDI", nil))
+}
+
+func TestObserveOperation(t *testing.T) {
+	const driver = "test-observe.example.com"
+
+	ObserveOperation(driver, OperationPrepare, time.Now(), nil)
+	ObserveOperation(driver, OperationPrepare, time.Now(), WithReason("NotAllocated", errors.New("not allocated")))
+	ObserveOperation(driver, OperationUnprepare, time.Now(), errors.New("boom"))
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(operationsTotal.WithLabelValues(driver, OperationPrepare)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(operationsTotal.WithLabelValues(driver, OperationUnprepare)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(operationErrors.WithLabelValues(driver, OperationPrepare, "NotAllocated")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(operationErrors.WithLabelValues(driver, OperationUnprepare, ReasonUnknown)))
+}
+
+func TestPoolDevices(t *testing.T) {
+	const driver = "test-pool.example.com"
+
+	SetPoolDevices(driver, "node-a", 8, 3)
+	assert.Equal(t, 8.0, testutil.ToFloat64(allocatableDevices.WithLabelValues(driver, "node-a")))
+	assert.Equal(t, 3.0, testutil.ToFloat64(allocatedDevices.WithLabelValues(driver, "node-a")))
+
+	DeletePoolDevices(driver, "node-a")
+	assert.False(t, allocatableDevices.DeleteLabelValues(driver, "node-a"), "gauge should already be gone")
+	assert.False(t, allocatedDevices.DeleteLabelValues(driver, "node-a"), "gauge should already be gone")
+}
@@ -0,0 +1,216 @@
+package dra
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+	resourceapi "k8s.io/api/resource/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/dynamic-resource-allocation/resourceslice"
+	"k8s.io/utils/ptr"
+)
+
+// MaxDevicesPerSlice is the number of devices the resource API accepts in a
+// single ResourceSlice.
+const MaxDevicesPerSlice = resourceapi.ResourceSliceMaxDevices
+
+// SplitDevices chunks devices into groups of at most MaxDevicesPerSlice,
+// preserving order. An empty device set yields a single empty chunk so the
+// pool is still published.
+func SplitDevices(devices []resourceapi.Device) [][]resourceapi.Device {
+	if len(devices) == 0 {
+		return [][]resourceapi.Device{{}}
+	}
+	var chunks [][]resourceapi.Device
+	for start := 0; start < len(devices); start += MaxDevicesPerSlice {
+		end := min(start+MaxDevicesPerSlice, len(devices))
+		chunks = append(chunks, devices[start:end])
+	}
+	return chunks
+}
+
+// PoolSlices returns the kubelet plugin helper slices for a pool holding
+// devices. The helper names the slices and maintains the pool generation.
+func PoolSlices(devices []resourceapi.Device) []resourceslice.Slice {
+	var slices []resourceslice.Slice
+	for _, chunk := range SplitDevices(devices) {
+		slices = append(slices, resourceslice.Slice{Devices: chunk})
+	}
+	return slices
+}
+
+// NodePool identifies the ResourceSlices a KWOK controller publishes for one
+// node and driver. The first slice is named SliceName, so single-slice pools
+// keep their existing name, and any further ones "<SliceName>-<index>".
+type NodePool struct {
+	Driver    string
+	NodeName  string
+	SliceName string
+}
+
+func (p NodePool) sliceName(index int) string {
+	if index == 0 {
+		return p.SliceName
+	}
+	return fmt.Sprintf("%s-%d", p.SliceName, index)
+}
+
+func (p NodePool) selector() string {
+	return labels.SelectorFromSet(labels.Set{
+		constants.LabelManagedBy:         constants.LabelManagedByValue,
+		constants.LabelResourceSliceNode: p.NodeName,
+	}).String()
+}
+
+// listSlices returns the pool's current slices, keyed by name.
+func (p NodePool) listSlices(ctx context.Context, kubeClient kubernetes.Interface) (map[string]resourceapi.ResourceSlice, error) {
+	list, err := kubeClient.ResourceV1().ResourceSlices().List(ctx, metav1.ListOptions{LabelSelector: p.selector()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ResourceSlices for node %s: %w", p.NodeName, err)
+	}
+	existing := make(map[string]resourceapi.ResourceSlice)
+	for _, slice := range list.Items {
+		if slice.Spec.Driver == p.Driver {
+			existing[slice.Name] = slice
+		}
+	}
+	return existing, nil
+}
+
+// SyncNodePool makes the pool's ResourceSlices match devices, splitting them
+// across as many slices as needed. When anything changes the pool generation
+// is bumped on every slice, so the scheduler never mixes old and new slices.
+func SyncNodePool(ctx context.Context, kubeClient kubernetes.Interface, pool NodePool, devices []resourceapi.Device) error {
+	existing, err := pool.listSlices(ctx, kubeClient)
+	if err != nil {
+		return err
+	}
+
+	chunks := SplitDevices(devices)
+	var generation int64
+	for _, slice := range existing {
+		generation = max(generation, slice.Spec.Pool.Generation)
+	}
+	if !poolUpToDate(pool, existing, chunks) {
+		generation++
+	}
+
+	for i, chunk := range chunks {
+		desired := &resourceapi.ResourceSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: pool.sliceName(i),
+				Labels: map[string]string{
+					constants.LabelManagedBy:         constants.LabelManagedByValue,
+					constants.LabelResourceSliceNode: pool.NodeName,
+				},
+			},
+			Spec: resourceapi.ResourceSliceSpec{
+				Driver:   pool.Driver,
+				NodeName: ptr.To(pool.NodeName),
+				Pool: resourceapi.ResourcePool{
+					Name:               pool.NodeName,
+					Generation:         generation,
+					ResourceSliceCount: int64(len(chunks)),
+				},
+				Devices: chunk,
+			},
+		}
+
+		current, found := existing[desired.Name]
+		switch {
+		case !found:
+			if err := createOrAdoptSlice(ctx, kubeClient, desired); err != nil {
+				return err
+			}
+		case !apiequality.Semantic.DeepEqual(current.Spec, desired.Spec) || !apiequality.Semantic.DeepEqual(current.Labels, desired.Labels):
+			desired.ResourceVersion = current.ResourceVersion
+			if _, err := kubeClient.ResourceV1().ResourceSlices().Update(ctx, desired, metav1.UpdateOptions{}); err != nil {
+				return fmt.Errorf("failed to update ResourceSlice %s: %w", desired.Name, err)
+			}
+		}
+		delete(existing, desired.Name)
+	}
+
+	for name := range existing {
+		if err := deleteSlice(ctx, kubeClient, name); err != nil {
+			return err
+		}
+	}
+
+	SetAllocatableDevices(pool.Driver, pool.NodeName, len(devices))
+	log.Printf("Synced %d ResourceSlice(s) for driver %s on node %s with %d devices (generation %d)\n",
+		len(chunks), pool.Driver, pool.NodeName, len(devices), generation)
+	return nil
+}
+
+// DeleteNodePool removes every ResourceSlice of the pool.
+func DeleteNodePool(ctx context.Context, kubeClient kubernetes.Interface, pool NodePool) error {
+	existing, err := pool.listSlices(ctx, kubeClient)
+	if err != nil {
+		return err
+	}
+	// The first slice is deleted by name as well, since slices published
+	// before pools were split carry no labels.
+	existing[pool.sliceName(0)] = resourceapi.ResourceSlice{}
+	for name := range existing {
+		if err := deleteSlice(ctx, kubeClient, name); err != nil {
+			return err
+		}
+	}
+	DeletePoolDevices(pool.Driver, pool.NodeName)
+	return nil
+}
+
+// poolUpToDate reports whether existing already holds exactly the desired
+// chunks, in which case the generation must not change.
+func poolUpToDate(pool NodePool, existing map[string]resourceapi.ResourceSlice, chunks [][]resourceapi.Device) bool {
+	if len(existing) != len(chunks) {
+		return false
+	}
+	for i, chunk := range chunks {
+		slice, found := existing[pool.sliceName(i)]
+		if !found || slice.Spec.Pool.ResourceSliceCount != int64(len(chunks)) {
+			return false
+		}
+		if !apiequality.Semantic.DeepEqual(slice.Spec.Devices, chunk) {
+			return false
+		}
+	}
+	return true
+}
+
+// createOrAdoptSlice creates slice, or overwrites an unlabeled slice of the
+// same name left behind by an older version.
+func createOrAdoptSlice(ctx context.Context, kubeClient kubernetes.Interface, slice *resourceapi.ResourceSlice) error {
+	_, err := kubeClient.ResourceV1().ResourceSlices().Create(ctx, slice, metav1.CreateOptions{})
+	if !errors.IsAlreadyExists(err) {
+		if err != nil {
+			return fmt.Errorf("failed to create ResourceSlice %s: %w", slice.Name, err)
+		}
+		return nil
+	}
+
+	current, err := kubeClient.ResourceV1().ResourceSlices().Get(ctx, slice.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get ResourceSlice %s: %w", slice.Name, err)
+	}
+	slice.ResourceVersion = current.ResourceVersion
+	if _, err := kubeClient.ResourceV1().ResourceSlices().Update(ctx, slice, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update ResourceSlice %s: %w", slice.Name, err)
+	}
+	return nil
+}
+
+func deleteSlice(ctx context.Context, kubeClient kubernetes.Interface, name string) error {
+	err := kubeClient.ResourceV1().ResourceSlices().Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete ResourceSlice %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package dra
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	resourceapi "k8s.io/api/resource/v1"
+)
+
+func newDevices(count int) []resourceapi.Device {
+	devices := make([]resourceapi.Device, count)
+	for i := range devices {
+		devices[i].Name = fmt.Sprintf("channel-%d", i)
+	}
+	return devices
+}
+
+func TestSplitDevices(t *testing.T) {
+	tests := map[string]struct {
+		devices    int
+		wantChunks []int
+	}{
+		"empty pool":         {devices: 0, wantChunks: []int{0}},
+		"single slice":       {devices: 8, wantChunks: []int{8}},
+		"exactly full":       {devices: MaxDevicesPerSlice, wantChunks: []int{MaxDevicesPerSlice}},
+		"2048 channels":      {devices: 2048, wantChunks: []int{128, 128, 128, 128, 128, 128, 128, 128, 128, 128, 128, 128, 128, 128, 128, 128}},
+		"partial last slice": {devices: MaxDevicesPerSlice + 1, wantChunks: []int{MaxDevicesPerSlice, 1}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			devices := newDevices(test.devices)
+			chunks := SplitDevices(devices)
+			require.Len(t, chunks, len(test.wantChunks))
+
+			var flattened []resourceapi.Device
+			for i, chunk := range chunks {
+				assert.Len(t, chunk, test.wantChunks[i])
+				flattened = append(flattened, chunk...)
+			}
+			assert.Equal(t, len(devices), len(flattened))
+			if len(devices) > 0 {
+				assert.Equal(t, devices, flattened)
+			}
+		})
+	}
+}
+
+func TestPoolSlices(t *testing.T) {
+	slices := PoolSlices(newDevices(300))
+	require.Len(t, slices, 3)
+	assert.Equal(t, "channel-0", slices[0].Devices[0].Name)
+	assert.Equal(t, "channel-256", slices[2].Devices[0].Name)
+	assert.Len(t, slices[2].Devices, 300-2*MaxDevicesPerSlice)
+}
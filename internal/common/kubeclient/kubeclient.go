@@ -5,10 +5,12 @@ import (
 	"log"
 
 	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
 	"github.com/spf13/viper"
-	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
@@ -17,15 +19,17 @@ import (
 
 type KubeClientInterface interface {
 	SetNodeLabels(lables map[string]string) error
+	RemoveNodeLabels(keys []string) error
 	SetNodeAnnotations(annotations map[string]string) error
 	GetNodeLabels() (map[string]string, error)
-	WatchConfigMap(namespace string, configmapName string) (chan *corev1.ConfigMap, error)
-	GetConfigMap(namespace string, configmapName string) (*corev1.ConfigMap, bool)
+	WatchFakeGpuNode(name string) (chan *unstructured.Unstructured, error)
+	GetFakeGpuNode(name string) (*unstructured.Unstructured, bool)
 }
 
 type KubeClient struct {
-	ClientSet kubernetes.Interface
-	stopChan  chan struct{}
+	ClientSet     kubernetes.Interface
+	DynamicClient dynamic.Interface
+	stopChan      chan struct{}
 }
 
 func NewKubeClient(config *rest.Config, stop chan struct{}) *KubeClient {
@@ -39,8 +43,9 @@ func NewKubeClient(config *rest.Config, stop chan struct{}) *KubeClient {
 
 	clientset := kubernetes.NewForConfigOrDie(config)
 	return &KubeClient{
-		ClientSet: clientset,
-		stopChan:  stop,
+		ClientSet:     clientset,
+		DynamicClient: dynamic.NewForConfigOrDie(config),
+		stopChan:      stop,
 	}
 }
 
@@ -60,6 +65,22 @@ func (client *KubeClient) SetNodeLabels(lables map[string]string) error {
 	return err
 }
 
+func (client *KubeClient) RemoveNodeLabels(keys []string) error {
+	nodeName := viper.GetString(constants.EnvNodeName)
+	node, err := client.ClientSet.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		delete(node.Labels, k)
+	}
+
+	log.Printf("Removing labels %v from node %s\n", keys, nodeName)
+	_, err = client.ClientSet.CoreV1().Nodes().Update(context.TODO(), node, metav1.UpdateOptions{})
+	return err
+}
+
 func (client *KubeClient) SetNodeAnnotations(annotations map[string]string) error {
 	nodeName := viper.GetString(constants.EnvNodeName)
 	node, err := client.ClientSet.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
@@ -85,41 +106,39 @@ func (client *KubeClient) GetNodeLabels() (map[string]string, error) {
 	return node.Labels, nil
 }
 
-func (client *KubeClient) GetConfigMap(namespace string, configmapName string) (*corev1.ConfigMap, bool) {
-	cm, err := client.ClientSet.CoreV1().ConfigMaps(
-		namespace).Get(
-		context.TODO(), configmapName, metav1.GetOptions{})
+func (client *KubeClient) GetFakeGpuNode(name string) (*unstructured.Unstructured, bool) {
+	fakeGpuNode, err := client.DynamicClient.Resource(topology.FakeGpuNodeGVR).Get(
+		context.TODO(), name, metav1.GetOptions{})
 	if err != nil {
-		log.Printf("Error getting configmap: %s", configmapName)
-		return cm, false
+		log.Printf("Error getting FakeGpuNode: %s", name)
+		return fakeGpuNode, false
 	}
-	return cm, true
+	return fakeGpuNode, true
 }
 
-func (client *KubeClient) WatchConfigMap(namespace string, configmapName string) (chan *corev1.ConfigMap, error) {
-	cmWatch, err := client.ClientSet.CoreV1().ConfigMaps(
-		namespace).Watch(context.TODO(), metav1.ListOptions{
-		FieldSelector: "metadata.name=" + configmapName,
+func (client *KubeClient) WatchFakeGpuNode(name string) (chan *unstructured.Unstructured, error) {
+	fakeGpuNodeWatch, err := client.DynamicClient.Resource(topology.FakeGpuNodeGVR).Watch(context.TODO(), metav1.ListOptions{
+		FieldSelector: "metadata.name=" + name,
 		Watch:         true,
 	})
 	if err != nil {
-		log.Printf("Error watching configmap: %s", configmapName)
+		log.Printf("Error watching FakeGpuNode: %s", name)
 		return nil, err
 	}
 
-	configMapsChan := make(chan *corev1.ConfigMap)
-	go client.watchCmChange(cmWatch, configMapsChan)
+	fakeGpuNodesChan := make(chan *unstructured.Unstructured)
+	go client.watchFakeGpuNodeChange(fakeGpuNodeWatch, fakeGpuNodesChan)
 
-	return configMapsChan, nil
+	return fakeGpuNodesChan, nil
 }
 
-func (client *KubeClient) watchCmChange(cmWatch watch.Interface, configMapsChan chan *corev1.ConfigMap) {
+func (client *KubeClient) watchFakeGpuNodeChange(fakeGpuNodeWatch watch.Interface, fakeGpuNodesChan chan *unstructured.Unstructured) {
 	for {
 		select {
-		case result := <-cmWatch.ResultChan():
+		case result := <-fakeGpuNodeWatch.ResultChan():
 			if result.Type == "ADDED" || result.Type == "MODIFIED" {
-				if cm, ok := result.Object.(*corev1.ConfigMap); ok {
-					configMapsChan <- cm
+				if fakeGpuNode, ok := result.Object.(*unstructured.Unstructured); ok {
+					fakeGpuNodesChan <- fakeGpuNode
 				}
 			}
 		case <-client.stopChan:
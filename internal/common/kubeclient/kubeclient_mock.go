@@ -1,14 +1,15 @@
 package kubeclient
 
 import (
-	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 type KubeClientMock struct {
 	ActualSetNodeLabels      func(labels map[string]string)
+	ActualRemoveNodeLabels   func(keys []string)
 	ActualSetNodeAnnotations func(annotations map[string]string)
 	ActualGetNodeLabels      func() (map[string]string, error)
-	ActualWatchConfigMap     func(namespace string, configmapName string)
+	ActualWatchFakeGpuNode   func(name string)
 }
 
 // SetNodeAnnotations implements kubeclient.KubeClientInterface
@@ -22,15 +23,20 @@ func (client *KubeClientMock) SetNodeLabels(labels map[string]string) error {
 	return nil
 }
 
+func (client *KubeClientMock) RemoveNodeLabels(keys []string) error {
+	client.ActualRemoveNodeLabels(keys)
+	return nil
+}
+
 func (client *KubeClientMock) GetNodeLabels() (map[string]string, error) {
 	return client.ActualGetNodeLabels()
 }
 
-func (client *KubeClientMock) WatchConfigMap(namespace string, configmapName string) (chan *corev1.ConfigMap, error) {
-	client.ActualWatchConfigMap(namespace, configmapName)
+func (client *KubeClientMock) WatchFakeGpuNode(name string) (chan *unstructured.Unstructured, error) {
+	client.ActualWatchFakeGpuNode(name)
 	return nil, nil
 }
 
-func (client *KubeClientMock) GetConfigMap(namespace string, configmapName string) (*corev1.ConfigMap, bool) {
+func (client *KubeClientMock) GetFakeGpuNode(name string) (*unstructured.Unstructured, bool) {
 	return nil, true
 }
@@ -9,4 +9,12 @@ const (
 
 	// CmNamePrefix is the prefix for profile ConfigMap names.
 	CmNamePrefix = "gpu-profile-"
+
+	// LabelGpuProfileFragment is the label used to identify the ConfigMaps of
+	// profile fragments, which profiles list in their mixins.
+	LabelGpuProfileFragment = "fake-gpu-operator/gpu-profile-fragment"
+
+	// FragmentCmNamePrefix is the prefix for profile fragment ConfigMap names.
+	// Fragments keep their YAML under CmProfileKey too.
+	FragmentCmNamePrefix = "gpu-fragment-"
 )
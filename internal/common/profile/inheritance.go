@@ -0,0 +1,274 @@
+package profile
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// KeyExtends names the profile a profile is based on.
+	KeyExtends = "extends"
+	// KeyMixins lists the fragments merged over the base profile, in order.
+	KeyMixins = "mixins"
+)
+
+// LayerKind is the kind of ConfigMap a layer of a resolved profile comes from.
+type LayerKind string
+
+const (
+	LayerProfile  LayerKind = "profile"
+	LayerFragment LayerKind = "fragment"
+)
+
+// Layer is a profile or fragment of an inheritance chain.
+type Layer struct {
+	Kind LayerKind
+	Name string
+}
+
+func (l Layer) String() string {
+	return fmt.Sprintf("%s %q", l.Kind, l.Name)
+}
+
+func (l Layer) cmName() string {
+	if l.Kind == LayerFragment {
+		return FragmentCmNamePrefix + l.Name
+	}
+	return CmNamePrefix + l.Name
+}
+
+// Resolved is a profile with its extends chain and mixins applied. It records
+// the layer each field was set by, so problems with a field can be traced to
+// the ConfigMap to fix.
+type Resolved struct {
+	Data map[string]interface{}
+
+	// origins maps the dotted path of each field to the layer that last set
+	// it, or that introduced it for nested maps.
+	origins map[string]Layer
+}
+
+// Origin returns the layer that set the field at the dotted path, e.g.
+// "device_defaults.memory.total_bytes" or "devices[1].name", or that set its
+// closest parent.
+func (r *Resolved) Origin(path string) (Layer, bool) {
+	for {
+		if layer, found := r.origins[path]; found {
+			return layer, true
+		}
+		idx := strings.LastIndexAny(path, ".[")
+		if idx < 0 {
+			return Layer{}, false
+		}
+		path = path[:idx]
+	}
+}
+
+// Resolve loads a GPU profile and the profiles and fragments it builds on.
+// The profile named by extends is resolved first, recursively; the fragments
+// listed in mixins are then merged over it in order, and the profile's own
+// fields last. Fragments may list mixins of their own but not extend a
+// profile. Inheritance cycles are reported with the chain that loops.
+func Resolve(kubeClient kubernetes.Interface, namespace, profileName string) (*Resolved, error) {
+	r := &resolver{
+		kubeClient: kubeClient,
+		namespace:  namespace,
+		loaded:     map[Layer]map[string]interface{}{},
+	}
+	return r.resolve(Layer{Kind: LayerProfile, Name: profileName}, nil)
+}
+
+type resolver struct {
+	kubeClient kubernetes.Interface
+	namespace  string
+	loaded     map[Layer]map[string]interface{}
+}
+
+func (r *resolver) resolve(layer Layer, chain []Layer) (*Resolved, error) {
+	for idx, previous := range chain {
+		if previous == layer {
+			return nil, fmt.Errorf("GPU profile inheritance cycle: %s", describeChain(append(chain[idx:], layer)))
+		}
+	}
+	chain = append(chain, layer)
+
+	data, err := r.load(layer)
+	if err != nil {
+		return nil, err
+	}
+	extends, mixins, err := parseInheritance(layer, data)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := &Resolved{Data: map[string]interface{}{}, origins: map[string]Layer{}}
+	if extends != "" {
+		base, err := r.resolve(Layer{Kind: LayerProfile, Name: extends}, chain)
+		if err != nil {
+			return nil, fmt.Errorf("%s extends profile %q: %w", layer, extends, err)
+		}
+		resolved = base
+	}
+
+	for _, mixin := range mixins {
+		fragment, err := r.resolve(Layer{Kind: LayerFragment, Name: mixin}, chain)
+		if err != nil {
+			return nil, fmt.Errorf("%s mixes in fragment %q: %w", layer, mixin, err)
+		}
+		err = mergeLayer(resolved.Data, fragment.Data, "", resolved.origins, func(path string) Layer {
+			origin, _ := fragment.Origin(path)
+			return origin
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	own := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		if key != KeyExtends && key != KeyMixins {
+			own[key] = value
+		}
+	}
+	err = mergeLayer(resolved.Data, own, "", resolved.origins, func(string) Layer { return layer })
+	if err != nil {
+		return nil, err
+	}
+
+	return resolved, nil
+}
+
+// load reads the YAML of a profile or fragment ConfigMap. Each layer is read
+// once per resolution, however many times it is mixed in.
+func (r *resolver) load(layer Layer) (map[string]interface{}, error) {
+	if data, found := r.loaded[layer]; found {
+		return data, nil
+	}
+
+	cmName := layer.cmName()
+	cm, err := r.kubeClient.CoreV1().ConfigMaps(r.namespace).Get(context.TODO(), cmName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load GPU %s: %w", layer, err)
+	}
+
+	raw, ok := cm.Data[CmProfileKey]
+	if !ok {
+		return nil, fmt.Errorf("GPU %s ConfigMap %q missing key %q", layer.Kind, cmName, CmProfileKey)
+	}
+
+	var data map[string]interface{}
+	if err := yaml.Unmarshal([]byte(raw), &data); err != nil {
+		return nil, fmt.Errorf("failed to parse GPU %s: %w", layer, err)
+	}
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+
+	r.loaded[layer] = data
+	return data, nil
+}
+
+func parseInheritance(layer Layer, data map[string]interface{}) (extends string, mixins []string, err error) {
+	if value, found := data[KeyExtends]; found {
+		var ok bool
+		if extends, ok = value.(string); !ok || extends == "" {
+			return "", nil, fmt.Errorf("%s: %s must be a profile name", layer, KeyExtends)
+		}
+		if layer.Kind == LayerFragment {
+			return "", nil, fmt.Errorf("%s: fragments cannot extend a profile, only list %s", layer, KeyMixins)
+		}
+	}
+
+	if value, found := data[KeyMixins]; found {
+		list, ok := value.([]interface{})
+		if !ok {
+			return "", nil, fmt.Errorf("%s: %s must be a list of fragment names", layer, KeyMixins)
+		}
+		for idx, item := range list {
+			name, ok := item.(string)
+			if !ok || name == "" {
+				return "", nil, fmt.Errorf("%s: %s[%d] must be a fragment name", layer, KeyMixins, idx)
+			}
+			mixins = append(mixins, name)
+		}
+	}
+
+	return extends, mixins, nil
+}
+
+// mergeLayer deep-merges src over dst like Merge, recording the origin of
+// each field src sets. A layer replacing a map with a scalar or list, or the
+// other way round, is reported with both layers, as it is most likely a typo
+// in one of them.
+func mergeLayer(dst, src map[string]interface{}, prefix string, origins map[string]Layer, originOf func(path string) Layer) error {
+	for key, srcVal := range src {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		dstVal, exists := dst[key]
+		srcMap, srcIsMap := srcVal.(map[string]interface{})
+		dstMap, dstIsMap := dstVal.(map[string]interface{})
+
+		switch {
+		case exists && dstVal != nil && srcVal != nil && srcIsMap != dstIsMap:
+			dstOrigin, _ := (&Resolved{origins: origins}).Origin(path)
+			return fmt.Errorf("%s: %s sets %s over %s from %s", path, originOf(path), describeKind(srcVal), describeKind(dstVal), dstOrigin)
+		case srcIsMap && dstIsMap:
+			// Copy the map first, so that layers shared by several
+			// profiles are never modified.
+			merged := copyMap(dstMap)
+			if err := mergeLayer(merged, srcMap, path, origins, originOf); err != nil {
+				return err
+			}
+			dst[key] = merged
+		case srcIsMap:
+			merged := map[string]interface{}{}
+			origins[path] = originOf(path)
+			if err := mergeLayer(merged, srcMap, path, origins, originOf); err != nil {
+				return err
+			}
+			dst[key] = merged
+		default:
+			dst[key] = srcVal
+			forgetOrigins(origins, path)
+			origins[path] = originOf(path)
+		}
+	}
+	return nil
+}
+
+// forgetOrigins drops the origins of the fields nested under path, as the
+// field was replaced as a whole.
+func forgetOrigins(origins map[string]Layer, path string) {
+	for nested := range origins {
+		if strings.HasPrefix(nested, path+".") {
+			delete(origins, nested)
+		}
+	}
+}
+
+func describeKind(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "a map"
+	case []interface{}:
+		return "a list"
+	default:
+		return "a scalar"
+	}
+}
+
+func describeChain(chain []Layer) string {
+	names := make([]string, len(chain))
+	for idx, layer := range chain {
+		names[idx] = layer.String()
+	}
+	return strings.Join(names, " -> ")
+}
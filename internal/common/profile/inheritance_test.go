@@ -0,0 +1,226 @@
+package profile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testNamespace = "gpu-operator"
+
+func profileCM(name, data string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      CmNamePrefix + name,
+			Namespace: testNamespace,
+			Labels:    map[string]string{LabelGpuProfile: "true"},
+		},
+		Data: map[string]string{CmProfileKey: data},
+	}
+}
+
+func fragmentCM(name, data string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      FragmentCmNamePrefix + name,
+			Namespace: testNamespace,
+			Labels:    map[string]string{LabelGpuProfileFragment: "true"},
+		},
+		Data: map[string]string{CmProfileKey: data},
+	}
+}
+
+func TestResolve_ExtendsChain(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		profileCM("a100", `
+system:
+  driver_version: "550.163.01"
+device_defaults:
+  name: "NVIDIA A100-SXM4-40GB"
+  architecture: "ampere"
+  memory:
+    total_bytes: 42949672960
+devices:
+  - {}
+  - {}
+`),
+		profileCM("a100-80gb", `
+extends: a100
+device_defaults:
+  name: "NVIDIA A100-SXM4-80GB"
+  memory:
+    total_bytes: 85899345920
+`),
+		profileCM("a100-80gb-lab", `
+extends: a100-80gb
+system:
+  driver_version: "560.35.03"
+`),
+	)
+
+	resolved, err := Resolve(client, testNamespace, "a100-80gb-lab")
+	require.NoError(t, err)
+
+	spec := Extract(resolved.Data)
+	assert.Equal(t, "NVIDIA A100-SXM4-80GB", spec.GpuProduct)
+	assert.Equal(t, 81920, spec.GpuMemory)
+	assert.Equal(t, "ampere", spec.Architecture)
+	assert.Equal(t, "560.35.03", spec.DriverVersion)
+	assert.Equal(t, 2, spec.GpuCount)
+	assert.NotContains(t, resolved.Data, KeyExtends)
+
+	for path, want := range map[string]Layer{
+		"system.driver_version":              {Kind: LayerProfile, Name: "a100-80gb-lab"},
+		"device_defaults.name":               {Kind: LayerProfile, Name: "a100-80gb"},
+		"device_defaults.memory.total_bytes": {Kind: LayerProfile, Name: "a100-80gb"},
+		"device_defaults.architecture":       {Kind: LayerProfile, Name: "a100"},
+		"devices":                            {Kind: LayerProfile, Name: "a100"},
+	} {
+		origin, found := resolved.Origin(path)
+		require.True(t, found, path)
+		assert.Equal(t, want, origin, path)
+	}
+}
+
+func TestResolve_Mixins(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		profileCM("h100", `
+device_defaults:
+  name: "NVIDIA H100 80GB HBM3"
+  power:
+    default_limit_mw: 700000
+    max_limit_mw: 700000
+  pci:
+    device_id: 0x233010DE
+device_count: 8
+`),
+		fragmentCM("sxm-power-limits", `
+device_defaults:
+  power:
+    default_limit_mw: 500000
+    max_limit_mw: 500000
+`),
+		fragmentCM("pcie-variant", `
+mixins: [sxm-power-limits]
+device_defaults:
+  name: "NVIDIA H100 PCIe"
+  power:
+    max_limit_mw: 350000
+`),
+		profileCM("h100-pcie", `
+extends: h100
+mixins: [pcie-variant]
+device_count: 2
+`),
+	)
+
+	resolved, err := Resolve(client, testNamespace, "h100-pcie")
+	require.NoError(t, err)
+
+	dd := resolved.Data["device_defaults"].(map[string]interface{})
+	power := dd["power"].(map[string]interface{})
+	assert.Equal(t, "NVIDIA H100 PCIe", dd["name"])
+	assert.Equal(t, 500000, power["default_limit_mw"])
+	assert.Equal(t, 350000, power["max_limit_mw"])
+	assert.Equal(t, 2, DeviceCount(resolved.Data))
+	assert.NotContains(t, resolved.Data, KeyMixins)
+
+	origin, _ := resolved.Origin("device_defaults.power.default_limit_mw")
+	assert.Equal(t, Layer{Kind: LayerFragment, Name: "sxm-power-limits"}, origin)
+	origin, _ = resolved.Origin("device_defaults.power.max_limit_mw")
+	assert.Equal(t, Layer{Kind: LayerFragment, Name: "pcie-variant"}, origin)
+	origin, _ = resolved.Origin("device_defaults.pci.device_id")
+	assert.Equal(t, Layer{Kind: LayerProfile, Name: "h100"}, origin)
+
+	// Load returns the same resolved profile.
+	data, err := Load(client, testNamespace, "h100-pcie")
+	require.NoError(t, err)
+	assert.Equal(t, resolved.Data, data)
+}
+
+func TestResolve_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		configMaps []*corev1.ConfigMap
+		profile    string
+		wantErr    []string
+	}{
+		{
+			name: "extends cycle",
+			configMaps: []*corev1.ConfigMap{
+				profileCM("a", "extends: b\n"),
+				profileCM("b", "extends: c\n"),
+				profileCM("c", "extends: a\n"),
+			},
+			profile: "a",
+			wantErr: []string{`cycle: profile "a" -> profile "b" -> profile "c" -> profile "a"`},
+		},
+		{
+			name: "mixins cycle",
+			configMaps: []*corev1.ConfigMap{
+				profileCM("a", "mixins: [x]\n"),
+				fragmentCM("x", "mixins: [y]\n"),
+				fragmentCM("y", "mixins: [x]\n"),
+			},
+			profile: "a",
+			wantErr: []string{`cycle: fragment "x" -> fragment "y" -> fragment "x"`},
+		},
+		{
+			name:       "missing base profile",
+			configMaps: []*corev1.ConfigMap{profileCM("a", "extends: missing\n")},
+			profile:    "a",
+			wantErr:    []string{`profile "a" extends profile "missing"`, `failed to load GPU profile "missing"`},
+		},
+		{
+			name:       "missing fragment",
+			configMaps: []*corev1.ConfigMap{profileCM("a", "mixins: [missing]\n")},
+			profile:    "a",
+			wantErr:    []string{`profile "a" mixes in fragment "missing"`, `failed to load GPU fragment "missing"`},
+		},
+		{
+			name: "fragment extending a profile",
+			configMaps: []*corev1.ConfigMap{
+				profileCM("a", "mixins: [x]\n"),
+				profileCM("b", "device_count: 1\n"),
+				fragmentCM("x", "extends: b\n"),
+			},
+			profile: "a",
+			wantErr: []string{`fragment "x": fragments cannot extend a profile`},
+		},
+		{
+			name:       "malformed mixins",
+			configMaps: []*corev1.ConfigMap{profileCM("a", "mixins: x\n")},
+			profile:    "a",
+			wantErr:    []string{`profile "a": mixins must be a list of fragment names`},
+		},
+		{
+			name: "map replaced by a scalar",
+			configMaps: []*corev1.ConfigMap{
+				profileCM("base", "device_defaults:\n  memory:\n    total_bytes: 1024\n"),
+				profileCM("a", "extends: base\nmixins: [x]\n"),
+				fragmentCM("x", "device_defaults:\n  memory: 40GB\n"),
+			},
+			profile: "a",
+			wantErr: []string{`device_defaults.memory: fragment "x" sets a scalar over a map from profile "base"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			for _, cm := range tt.configMaps {
+				require.NoError(t, client.Tracker().Add(cm))
+			}
+
+			_, err := Resolve(client, testNamespace, tt.profile)
+			require.Error(t, err)
+			for _, want := range tt.wantErr {
+				assert.Contains(t, err.Error(), want)
+			}
+		})
+	}
+}
@@ -1,11 +1,9 @@
 package profile
 
 import (
-	"context"
 	"fmt"
+	"strconv"
 
-	"gopkg.in/yaml.v3"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -17,29 +15,31 @@ type GpuSpec struct {
 	Architecture  string
 	DriverVersion string
 	CudaVersion   string
+	// Devices holds one entry per GPU, resolved from the profile's devices
+	// list merged over device_defaults.
+	Devices []DeviceSpec
 }
 
-// Load reads a GPU profile ConfigMap by name and returns the parsed profile data.
+// DeviceSpec holds the identity of a single GPU of a profile. Fields the
+// profile leaves unset are empty.
+type DeviceSpec struct {
+	Product      string
+	Memory       int // MiB
+	Architecture string
+	Serial       string
+	PciBusID     string
+	UUID         string
+}
+
+// Load reads a GPU profile ConfigMap by name and returns the parsed profile data,
+// with the profile it extends and the fragments it mixes in applied (see Resolve).
 // The ConfigMap is expected to be named "gpu-profile-{name}" in the given namespace.
 func Load(kubeClient kubernetes.Interface, namespace, profileName string) (map[string]interface{}, error) {
-	cmName := CmNamePrefix + profileName
-	cm, err := kubeClient.CoreV1().ConfigMaps(namespace).Get(
-		context.TODO(), cmName, metav1.GetOptions{})
+	resolved, err := Resolve(kubeClient, namespace, profileName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load GPU profile %q: %w", profileName, err)
-	}
-
-	data, ok := cm.Data[CmProfileKey]
-	if !ok {
-		return nil, fmt.Errorf("GPU profile ConfigMap %q missing key %q", cmName, CmProfileKey)
-	}
-
-	var profile map[string]interface{}
-	if err := yaml.Unmarshal([]byte(data), &profile); err != nil {
-		return nil, fmt.Errorf("failed to parse GPU profile %q: %w", profileName, err)
+		return nil, err
 	}
-
-	return profile, nil
+	return resolved.Data, nil
 }
 
 // Merge deep-merges overrides onto a base profile. Scalars and lists are replaced;
@@ -82,7 +82,8 @@ func Extract(profile map[string]interface{}) GpuSpec {
 		spec.Architecture, _ = dd["architecture"].(string)
 
 		if mem, ok := getMap(dd, "memory"); ok {
-			spec.GpuMemory = toMiB(mem["total_bytes"])
+			// Validate reports memory that can't be converted.
+			spec.GpuMemory, _ = ToMiB(mem["total_bytes"])
 		}
 	}
 
@@ -92,7 +93,48 @@ func Extract(profile map[string]interface{}) GpuSpec {
 	}
 
 	spec.GpuCount = DeviceCount(profile)
+	spec.Devices = ExtractDevices(profile)
+
+	return spec
+}
+
+// ExtractDevices resolves the GPUs of a profile: the i-th entry of the devices
+// list merged over device_defaults. GPUs past the end of the list, when
+// device_count is larger, get device_defaults alone.
+func ExtractDevices(profile map[string]interface{}) []DeviceSpec {
+	count := DeviceCount(profile)
+	if count == 0 {
+		return nil
+	}
+
+	defaults, _ := getMap(profile, "device_defaults")
+	entries, _ := profile["devices"].([]interface{})
+
+	devices := make([]DeviceSpec, count)
+	for idx := range devices {
+		device := defaults
+		if idx < len(entries) {
+			if entry, ok := entries[idx].(map[string]interface{}); ok {
+				device = Merge(defaults, entry)
+			}
+		}
+		devices[idx] = extractDevice(device)
+	}
+	return devices
+}
 
+func extractDevice(device map[string]interface{}) DeviceSpec {
+	var spec DeviceSpec
+	spec.Product, _ = device["name"].(string)
+	spec.Architecture, _ = device["architecture"].(string)
+	spec.UUID, _ = device["uuid"].(string)
+	spec.Serial = toString(device["serial"])
+	if mem, ok := getMap(device, "memory"); ok {
+		spec.Memory, _ = ToMiB(mem["total_bytes"])
+	}
+	if pci, ok := getMap(device, "pci"); ok {
+		spec.PciBusID, _ = pci["bus_id"].(string)
+	}
 	return spec
 }
 
@@ -136,17 +178,56 @@ func DeviceCount(profile map[string]interface{}) int {
 	return 0
 }
 
-// toMiB converts a bytes value (which may be int, int64, or float64 from YAML
-// unmarshaling) to MiB.
-func toMiB(v interface{}) int {
+// toString returns a string or number as a string, as YAML decodes unquoted
+// serials as numbers.
+func toString(v interface{}) string {
+	switch n := v.(type) {
+	case string:
+		return n
+	case int, int64:
+		return fmt.Sprintf("%d", n)
+	case float64:
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+// ToMiB converts a bytes value (which may be int, int64, or float64 from YAML
+// unmarshaling) to MiB. Other types, such as a quoted "80GB", and negative
+// values are rejected rather than read as zero.
+func ToMiB(v interface{}) (int, error) {
+	var mib int
 	switch n := v.(type) {
 	case int:
-		return n / (1024 * 1024)
+		mib = n / (1024 * 1024)
 	case int64:
-		return int(n / (1024 * 1024))
+		mib = int(n / (1024 * 1024))
+	case uint64:
+		mib = int(n / (1024 * 1024))
 	case float64:
-		return int(n / (1024 * 1024))
+		mib = int(n / (1024 * 1024))
+	case nil:
+		return 0, fmt.Errorf("must be a number of bytes")
+	default:
+		return 0, fmt.Errorf("must be a number of bytes, got %s", describeValue(v))
+	}
+	if mib < 0 {
+		return 0, fmt.Errorf("must not be negative, got %v", v)
+	}
+	return mib, nil
+}
+
+// describeValue names the YAML type of a value for error messages.
+func describeValue(v interface{}) string {
+	switch n := v.(type) {
+	case string:
+		return fmt.Sprintf("string %q", n)
+	case bool:
+		return fmt.Sprintf("boolean %t", n)
+	case int, int64, uint64, float64:
+		return fmt.Sprintf("number %v", n)
 	default:
-		return 0
+		return describeKind(v)
 	}
 }
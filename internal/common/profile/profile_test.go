@@ -274,3 +274,58 @@ func TestExtract_MemoryAsInt(t *testing.T) {
 	spec := Extract(data)
 	assert.Equal(t, 16384, spec.GpuMemory)
 }
+
+func TestExtract_MixedDevices(t *testing.T) {
+	data := map[string]interface{}{
+		"device_defaults": map[string]interface{}{
+			"name":         "NVIDIA A10",
+			"architecture": "ampere",
+			"serial":       "1324821083800",
+			"memory": map[string]interface{}{
+				"total_bytes": 24146608128,
+			},
+		},
+		"device_count": 3,
+		"devices": []interface{}{
+			map[string]interface{}{
+				"name":         "NVIDIA L4",
+				"architecture": "ada",
+				"uuid":         "GPU-L4",
+				"memory": map[string]interface{}{
+					"total_bytes": 24152899584,
+				},
+				"pci": map[string]interface{}{
+					"bus_id": "0000:01:00.0",
+				},
+			},
+			map[string]interface{}{
+				"serial": 1324821083801,
+			},
+		},
+	}
+
+	spec := Extract(data)
+	assert.Equal(t, "NVIDIA A10", spec.GpuProduct)
+	require.Len(t, spec.Devices, 3)
+	assert.Equal(t, DeviceSpec{
+		Product:      "NVIDIA L4",
+		Memory:       23034,
+		Architecture: "ada",
+		Serial:       "1324821083800",
+		PciBusID:     "0000:01:00.0",
+		UUID:         "GPU-L4",
+	}, spec.Devices[0])
+	assert.Equal(t, DeviceSpec{
+		Product:      "NVIDIA A10",
+		Memory:       23028,
+		Architecture: "ampere",
+		Serial:       "1324821083801",
+	}, spec.Devices[1])
+	// Past the end of the devices list, GPUs get the device defaults.
+	assert.Equal(t, DeviceSpec{
+		Product:      "NVIDIA A10",
+		Memory:       23028,
+		Architecture: "ampere",
+		Serial:       "1324821083800",
+	}, spec.Devices[2])
+}
@@ -0,0 +1,141 @@
+package profile
+
+import (
+	"fmt"
+)
+
+// FieldError is a problem with a field of a profile, at a dotted path such as
+// "device_defaults.memory.total_bytes" or "devices[1].name".
+type FieldError struct {
+	Path    string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// Validate checks the fields of a resolved profile that FGO reads, which
+// Extract would otherwise read as zero values: GPU names and versions must be
+// strings, memory a number of bytes, and the profile must describe at least
+// one GPU with a name and memory. All problems are returned together.
+func Validate(profile map[string]interface{}) []*FieldError {
+	v := &validator{}
+
+	defaults, _ := v.optionalMap(profile, "", "device_defaults")
+	v.device(defaults, "device_defaults")
+
+	if system, ok := v.optionalMap(profile, "", "system"); ok {
+		v.optionalString(system, "system", "driver_version")
+		v.optionalString(system, "system", "cuda_version")
+	}
+
+	switch n := profile["device_count"].(type) {
+	case nil, int64, float64:
+	case int:
+		if n < 0 {
+			v.add("device_count", "must not be negative, got %d", n)
+		}
+	default:
+		v.add("device_count", "must be a whole number, got %s", describeValue(n))
+	}
+
+	var entries []interface{}
+	if value, found := profile["devices"]; found && value != nil {
+		var ok bool
+		if entries, ok = value.([]interface{}); !ok {
+			v.add("devices", "must be a list, got %s", describeValue(value))
+		}
+	}
+	for idx, entry := range entries {
+		path := fmt.Sprintf("devices[%d]", idx)
+		device, ok := entry.(map[string]interface{})
+		if entry != nil && !ok {
+			v.add(path, "must be a map, got %s", describeValue(entry))
+			continue
+		}
+		v.device(device, path)
+	}
+
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+
+	if DeviceCount(profile) == 0 {
+		v.add("device_count", "no GPUs, set device_count or list devices")
+	}
+	for idx, device := range ExtractDevices(profile) {
+		path := "device_defaults"
+		if idx < len(entries) {
+			path = fmt.Sprintf("devices[%d]", idx)
+		}
+		if device.Product == "" {
+			v.add(path+".name", "GPU %d has no name", idx)
+		}
+		if device.Memory == 0 {
+			v.add(path+".memory.total_bytes", "GPU %d has no memory", idx)
+		}
+	}
+	return v.errs
+}
+
+type validator struct {
+	errs []*FieldError
+}
+
+func (v *validator) add(path, format string, args ...interface{}) {
+	for _, err := range v.errs {
+		// GPUs sharing device_defaults report the same problem once.
+		if err.Path == path {
+			return
+		}
+	}
+	v.errs = append(v.errs, &FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// device checks the fields of device_defaults or of an entry of devices.
+func (v *validator) device(device map[string]interface{}, path string) {
+	if device == nil {
+		return
+	}
+	v.optionalString(device, path, "name")
+	v.optionalString(device, path, "architecture")
+	v.optionalString(device, path, "uuid")
+	if memory, ok := v.optionalMap(device, path, "memory"); ok {
+		if value, found := memory["total_bytes"]; found {
+			if _, err := ToMiB(value); err != nil {
+				v.add(path+".memory.total_bytes", "%v", err)
+			}
+		}
+	}
+	if pci, ok := v.optionalMap(device, path, "pci"); ok {
+		v.optionalString(pci, path+".pci", "bus_id")
+	}
+}
+
+func (v *validator) optionalString(m map[string]interface{}, path, key string) {
+	value, found := m[key]
+	if _, ok := value.(string); !found || value == nil || ok {
+		return
+	}
+	v.add(join(path, key), "must be a string, got %s; quote the value", describeValue(value))
+}
+
+func (v *validator) optionalMap(m map[string]interface{}, path, key string) (map[string]interface{}, bool) {
+	value, found := m[key]
+	if !found || value == nil {
+		return nil, false
+	}
+	asMap, ok := value.(map[string]interface{})
+	if !ok {
+		v.add(join(path, key), "must be a map, got %s", describeValue(value))
+	}
+	return asMap, ok
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,106 @@
+package profile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestToMiB(t *testing.T) {
+	for _, value := range []interface{}{85899345920, int64(85899345920), uint64(85899345920), float64(85899345920)} {
+		mib, err := ToMiB(value)
+		require.NoError(t, err)
+		assert.Equal(t, 81920, mib)
+	}
+
+	_, err := ToMiB("80GB")
+	assert.EqualError(t, err, `must be a number of bytes, got string "80GB"`)
+	_, err = ToMiB(nil)
+	assert.EqualError(t, err, "must be a number of bytes")
+	_, err = ToMiB(-1024 * 1024)
+	assert.EqualError(t, err, "must not be negative, got -1048576")
+}
+
+func TestValidate(t *testing.T) {
+	tests := map[string]struct {
+		profile        string
+		expectedErrors []string
+	}{
+		"valid profile": {
+			profile: `
+system:
+  driver_version: "550.163.01"
+  cuda_version: "12.4"
+device_defaults:
+  name: "NVIDIA A100-SXM4-40GB"
+  memory:
+    total_bytes: 42949672960
+  pci:
+    bus_id: "00000000:07:00.0"
+devices:
+  - {}
+  - name: "NVIDIA A10"
+    serial: 1324821083812
+`,
+		},
+		"wrong types": {
+			profile: `
+system:
+  cuda_version: 12.4
+device_defaults:
+  name: "NVIDIA A100-SXM4-80GB"
+  memory:
+    total_bytes: "80GB"
+device_count: two
+devices:
+  - name: 10
+  - pci: 0
+  - []
+`,
+			expectedErrors: []string{
+				`device_defaults.memory.total_bytes: must be a number of bytes, got string "80GB"`,
+				`system.cuda_version: must be a string, got number 12.4; quote the value`,
+				`device_count: must be a whole number, got string "two"`,
+				`devices[0].name: must be a string, got number 10; quote the value`,
+				`devices[1].pci: must be a map, got number 0`,
+				`devices[2]: must be a map, got a list`,
+			},
+		},
+		"no GPUs": {
+			profile: `
+device_defaults:
+  name: "NVIDIA H100 80GB HBM3"
+  memory:
+    total_bytes: 85899345920
+`,
+			expectedErrors: []string{"device_count: no GPUs, set device_count or list devices"},
+		},
+		"GPUs without name or memory": {
+			profile: `
+device_count: 2
+devices:
+  - name: "NVIDIA L4"
+`,
+			expectedErrors: []string{
+				"devices[0].memory.total_bytes: GPU 0 has no memory",
+				"device_defaults.name: GPU 1 has no name",
+				"device_defaults.memory.total_bytes: GPU 1 has no memory",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var data map[string]interface{}
+			require.NoError(t, yaml.Unmarshal([]byte(test.profile), &data))
+
+			var messages []string
+			for _, err := range Validate(data) {
+				messages = append(messages, err.Error())
+			}
+			assert.ElementsMatch(t, test.expectedErrors, messages)
+		})
+	}
+}
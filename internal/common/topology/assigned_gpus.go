@@ -0,0 +1,73 @@
+package topology
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+)
+
+// AssignedGpu is a GPU the status-updater assigned to a pod, as listed in the
+// pod's run.ai/simulated-gpus annotation.
+type AssignedGpu struct {
+	UUID  string `json:"uuid"`
+	Index int    `json:"index"`
+	// Container is the container the GPU is allocated to. It is empty for GPUs
+	// the pod shares with others.
+	Container string `json:"container,omitempty"`
+	Shared    bool   `json:"shared,omitempty"`
+	// Utilization is the simulated utilization of a running pod, e.g. "80-100",
+	// or "knative" when it follows the pod's request rate.
+	Utilization string `json:"utilization,omitempty"`
+}
+
+// VisibleDevice returns the GPU as seen by the fake nvidia-smi.
+func (g AssignedGpu) VisibleDevice() VisibleDevice {
+	return VisibleDevice{UUID: g.UUID, Index: g.Index}
+}
+
+// FormatAssignedGpus encodes the GPUs as the value of the
+// run.ai/simulated-gpus annotation.
+func FormatAssignedGpus(gpus []AssignedGpu) (string, error) {
+	if gpus == nil {
+		gpus = []AssignedGpu{}
+	}
+	value, err := json.Marshal(gpus)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode assigned GPUs: %w", err)
+	}
+	return string(value), nil
+}
+
+// ParseAssignedGpus decodes a value produced by FormatAssignedGpus.
+func ParseAssignedGpus(value string) ([]AssignedGpu, error) {
+	var gpus []AssignedGpu
+	if err := json.Unmarshal([]byte(value), &gpus); err != nil {
+		return nil, fmt.Errorf("failed to parse assigned GPUs %q: %w", value, err)
+	}
+	return gpus, nil
+}
+
+// AssignedGpusFromDownwardAPI reads the run.ai/simulated-gpus annotation from
+// a downward API annotations file, whose lines are key="quoted value". It
+// returns no GPUs when the pod has no such annotation.
+func AssignedGpusFromDownwardAPI(annotations io.Reader) ([]AssignedGpu, error) {
+	scanner := bufio.NewScanner(annotations)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		key, quoted, ok := strings.Cut(scanner.Text(), "=")
+		if !ok || key != constants.AnnotationAssignedGpus {
+			continue
+		}
+		value, err := strconv.Unquote(quoted)
+		if err != nil {
+			return nil, fmt.Errorf("malformed %s annotation: %w", key, err)
+		}
+		return ParseAssignedGpus(value)
+	}
+	return nil, scanner.Err()
+}
@@ -0,0 +1,50 @@
+package topology
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+)
+
+func TestAssignedGpusRoundTrip(t *testing.T) {
+	gpus := []AssignedGpu{
+		{UUID: "GPU-A", Index: 0, Container: "main", Utilization: "80-100"},
+		{UUID: "GPU-B", Index: 3, Shared: true},
+	}
+
+	value, err := FormatAssignedGpus(gpus)
+	require.NoError(t, err)
+	parsed, err := ParseAssignedGpus(value)
+	require.NoError(t, err)
+	assert.Equal(t, gpus, parsed)
+
+	value, err = FormatAssignedGpus(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "[]", value)
+}
+
+func TestAssignedGpusFromDownwardAPI(t *testing.T) {
+	value, err := FormatAssignedGpus([]AssignedGpu{{UUID: "GPU-A", Index: 1, Container: "main"}})
+	require.NoError(t, err)
+	annotations := strings.Join([]string{
+		`kubernetes.io/config.seen="2026-01-01T00:00:00Z"`,
+		constants.AnnotationAssignedGpus + "=" + strconv.Quote(value),
+	}, "\n")
+
+	gpus, err := AssignedGpusFromDownwardAPI(strings.NewReader(annotations))
+	require.NoError(t, err)
+	require.Len(t, gpus, 1)
+	assert.Equal(t, VisibleDevice{UUID: "GPU-A", Index: 1}, gpus[0].VisibleDevice())
+
+	gpus, err = AssignedGpusFromDownwardAPI(strings.NewReader(`other="value"`))
+	require.NoError(t, err)
+	assert.Empty(t, gpus)
+
+	_, err = AssignedGpusFromDownwardAPI(strings.NewReader(constants.AnnotationAssignedGpus + `=not-quoted`))
+	assert.Error(t, err)
+}
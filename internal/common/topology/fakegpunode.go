@@ -0,0 +1,247 @@
+package topology
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	FakeGpuNodeGroup    = "fake-gpu-operator.run.ai"
+	FakeGpuNodeVersion  = "v1alpha1"
+	FakeGpuNodeKind     = "FakeGpuNode"
+	FakeGpuNodeListKind = "FakeGpuNodeList"
+)
+
+// FakeGpuNodeGVR identifies the cluster-scoped FakeGpuNode resource, named
+// after the node it simulates.
+var FakeGpuNodeGVR = schema.GroupVersionResource{
+	Group:    FakeGpuNodeGroup,
+	Version:  FakeGpuNodeVersion,
+	Resource: "fakegpunodes",
+}
+
+// FakeGpuNode stores the topology of a fake GPU node: the GPU inventory the
+// node was generated with in its spec, and the allocation and usage of each
+// GPU in its status.
+type FakeGpuNode struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   FakeGpuNodeSpec   `json:"spec"`
+	Status FakeGpuNodeStatus `json:"status,omitempty"`
+}
+
+type FakeGpuNodeSpec struct {
+	NodePool      string        `json:"nodePool,omitempty"`
+	GpuProduct    string        `json:"gpuProduct"`
+	GpuMemory     int           `json:"gpuMemory"`
+	DriverVersion string        `json:"driverVersion,omitempty"`
+	CudaVersion   string        `json:"cudaVersion,omitempty"`
+	MigStrategy   string        `json:"migStrategy,omitempty"`
+	CliqueID      string        `json:"cliqueId,omitempty"`
+	Gpus          []FakeGpu     `json:"gpus"`
+	OtherDevices  []OtherDevice `json:"otherDevices,omitempty"`
+}
+
+type FakeGpu struct {
+	ID     string    `json:"id"`
+	Health GpuHealth `json:"health,omitempty"`
+	// Product and Memory are set on GPUs that differ from the node's.
+	Product      string `json:"product,omitempty"`
+	Memory       int    `json:"memory,omitempty"`
+	Architecture string `json:"architecture,omitempty"`
+	Serial       string `json:"serial,omitempty"`
+	PciBusID     string `json:"pciBusId,omitempty"`
+}
+
+type OtherDevice struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type FakeGpuNodeStatus struct {
+	// Capacity and Allocated count the node's GPUs, for printing.
+	Capacity  int             `json:"capacity"`
+	Allocated int             `json:"allocated"`
+	Gpus      []FakeGpuStatus `json:"gpus,omitempty"`
+}
+
+type FakeGpuStatus struct {
+	ID                string                    `json:"id"`
+	AllocatedBy       *GpuAllocation            `json:"allocatedBy,omitempty"`
+	PodGpuUsageStatus map[types.UID]PodGpuUsage `json:"podGpuUsageStatus,omitempty"`
+}
+
+type GpuAllocation struct {
+	Namespace     string `json:"namespace"`
+	Pod           string `json:"pod"`
+	Container     string `json:"container"`
+	ComputeDomain string `json:"computeDomain,omitempty"`
+}
+
+type PodGpuUsage struct {
+	Utilization           UtilizationRange `json:"utilization"`
+	FbUsed                int              `json:"fbUsed"`
+	UseKnativeUtilization bool             `json:"useKnativeUtilization,omitempty"`
+}
+
+type UtilizationRange struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+// ToFakeGpuNode converts the topology of a node to its FakeGpuNode.
+func ToFakeGpuNode(nodeTopology *NodeTopology, nodeName string) *FakeGpuNode {
+	fakeGpuNode := &FakeGpuNode{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: FakeGpuNodeGroup + "/" + FakeGpuNodeVersion,
+			Kind:       FakeGpuNodeKind,
+		},
+		ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+		Spec: FakeGpuNodeSpec{
+			NodePool:      nodeTopology.NodePool,
+			GpuProduct:    nodeTopology.GpuProduct,
+			GpuMemory:     nodeTopology.GpuMemory,
+			DriverVersion: nodeTopology.DriverVersion,
+			CudaVersion:   nodeTopology.CudaVersion,
+			MigStrategy:   nodeTopology.MigStrategy,
+			CliqueID:      nodeTopology.CliqueID,
+			Gpus:          make([]FakeGpu, 0, len(nodeTopology.Gpus)),
+		},
+		Status: FakeGpuNodeStatus{Capacity: len(nodeTopology.Gpus)},
+	}
+
+	for _, device := range nodeTopology.OtherDevices {
+		fakeGpuNode.Spec.OtherDevices = append(fakeGpuNode.Spec.OtherDevices, OtherDevice{Name: device.Name, Count: device.Count})
+	}
+
+	for _, gpu := range nodeTopology.Gpus {
+		fakeGpuNode.Spec.Gpus = append(fakeGpuNode.Spec.Gpus, FakeGpu{
+			ID:           gpu.ID,
+			Health:       gpu.Health,
+			Product:      gpu.Product,
+			Memory:       gpu.Memory,
+			Architecture: gpu.Architecture,
+			Serial:       gpu.Serial,
+			PciBusID:     gpu.PciBusID,
+		})
+
+		gpuStatus := FakeGpuStatus{ID: gpu.ID}
+		if allocatedBy := gpu.Status.AllocatedBy; allocatedBy != (ContainerDetails{}) {
+			gpuStatus.AllocatedBy = &GpuAllocation{
+				Namespace:     allocatedBy.Namespace,
+				Pod:           allocatedBy.Pod,
+				Container:     allocatedBy.Container,
+				ComputeDomain: allocatedBy.ComputeDomain,
+			}
+		}
+		if gpu.Status.AllocatedBy.Pod != "" {
+			fakeGpuNode.Status.Allocated++
+		}
+		for podUID, usage := range gpu.Status.PodGpuUsageStatus {
+			if gpuStatus.PodGpuUsageStatus == nil {
+				gpuStatus.PodGpuUsageStatus = make(map[types.UID]PodGpuUsage)
+			}
+			gpuStatus.PodGpuUsageStatus[podUID] = PodGpuUsage{
+				Utilization:           UtilizationRange{Min: usage.Utilization.Min, Max: usage.Utilization.Max},
+				FbUsed:                usage.FbUsed,
+				UseKnativeUtilization: usage.UseKnativeUtilization,
+			}
+		}
+		fakeGpuNode.Status.Gpus = append(fakeGpuNode.Status.Gpus, gpuStatus)
+	}
+
+	return fakeGpuNode
+}
+
+// FromFakeGpuNode converts a FakeGpuNode to the topology of its node.
+func FromFakeGpuNode(fakeGpuNode *FakeGpuNode) *NodeTopology {
+	nodeTopology := &NodeTopology{
+		GpuMemory:     fakeGpuNode.Spec.GpuMemory,
+		GpuProduct:    fakeGpuNode.Spec.GpuProduct,
+		DriverVersion: fakeGpuNode.Spec.DriverVersion,
+		CudaVersion:   fakeGpuNode.Spec.CudaVersion,
+		Gpus:          make([]GpuDetails, 0, len(fakeGpuNode.Spec.Gpus)),
+		MigStrategy:   fakeGpuNode.Spec.MigStrategy,
+		NodePool:      fakeGpuNode.Spec.NodePool,
+		CliqueID:      fakeGpuNode.Spec.CliqueID,
+	}
+
+	for _, device := range fakeGpuNode.Spec.OtherDevices {
+		nodeTopology.OtherDevices = append(nodeTopology.OtherDevices, GenericDevice{Name: device.Name, Count: device.Count})
+	}
+
+	gpuStatuses := make(map[string]FakeGpuStatus, len(fakeGpuNode.Status.Gpus))
+	for _, gpuStatus := range fakeGpuNode.Status.Gpus {
+		gpuStatuses[gpuStatus.ID] = gpuStatus
+	}
+
+	for _, gpu := range fakeGpuNode.Spec.Gpus {
+		gpuDetails := GpuDetails{
+			ID:           gpu.ID,
+			Health:       gpu.Health,
+			Status:       GpuStatus{PodGpuUsageStatus: PodGpuUsageStatusMap{}},
+			Product:      gpu.Product,
+			Memory:       gpu.Memory,
+			Architecture: gpu.Architecture,
+			Serial:       gpu.Serial,
+			PciBusID:     gpu.PciBusID,
+		}
+
+		gpuStatus := gpuStatuses[gpu.ID]
+		if allocatedBy := gpuStatus.AllocatedBy; allocatedBy != nil {
+			gpuDetails.Status.AllocatedBy = ContainerDetails{
+				Namespace:     allocatedBy.Namespace,
+				Pod:           allocatedBy.Pod,
+				Container:     allocatedBy.Container,
+				ComputeDomain: allocatedBy.ComputeDomain,
+			}
+		}
+		for podUID, usage := range gpuStatus.PodGpuUsageStatus {
+			gpuDetails.Status.PodGpuUsageStatus[podUID] = GpuUsageStatus{
+				Utilization:           Range{Min: usage.Utilization.Min, Max: usage.Utilization.Max},
+				FbUsed:                usage.FbUsed,
+				UseKnativeUtilization: usage.UseKnativeUtilization,
+			}
+		}
+		nodeTopology.Gpus = append(nodeTopology.Gpus, gpuDetails)
+	}
+
+	return nodeTopology
+}
+
+// FakeGpuNodeFromUnstructured converts a FakeGpuNode read through the dynamic
+// client or a dynamic informer.
+func FakeGpuNodeFromUnstructured(obj *unstructured.Unstructured) (*FakeGpuNode, error) {
+	var fakeGpuNode FakeGpuNode
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &fakeGpuNode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse FakeGpuNode %s: %w", obj.GetName(), err)
+	}
+	return &fakeGpuNode, nil
+}
+
+// NodeTopologyFromUnstructured returns the node topology stored in a
+// FakeGpuNode read through the dynamic client or a dynamic informer.
+func NodeTopologyFromUnstructured(obj *unstructured.Unstructured) (*NodeTopology, error) {
+	fakeGpuNode, err := FakeGpuNodeFromUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	return FromFakeGpuNode(fakeGpuNode), nil
+}
+
+// ToUnstructured converts the FakeGpuNode for the dynamic client.
+func (n *FakeGpuNode) ToUnstructured() (*unstructured.Unstructured, error) {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(n)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert FakeGpuNode %s: %w", n.Name, err)
+	}
+	unstructured.RemoveNestedField(obj, "metadata", "creationTimestamp")
+	return &unstructured.Unstructured{Object: obj}, nil
+}
@@ -1,63 +1,231 @@
 package topology
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	stderrors "errors"
 	"fmt"
+	"log"
 
 	"gopkg.in/yaml.v3"
 
 	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
 	"github.com/spf13/viper"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	apcorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
 )
 
-func GetNodeTopologyFromCM(kubeclient kubernetes.Interface, nodeName string) (*NodeTopology, error) {
-	cmName := GetNodeTopologyCMName(nodeName)
-	cm, err := kubeclient.CoreV1().ConfigMaps(
-		viper.GetString(constants.EnvTopologyCmNamespace)).Get(
-		context.TODO(), cmName, metav1.GetOptions{})
+// ErrFakeGpuNodeCRDMissing is returned while the FakeGpuNode CRD is not
+// installed, e.g. after a helm upgrade, which does not apply the chart's crds/.
+var ErrFakeGpuNodeCRDMissing = stderrors.New("the FakeGpuNode CRD is not installed")
+
+func GetNodeTopology(client dynamic.Interface, nodeName string) (*NodeTopology, error) {
+	obj, err := client.Resource(FakeGpuNodeGVR).Get(context.TODO(), nodeName, metav1.GetOptions{})
 	if err != nil {
 		return nil, err
 	}
 
-	return FromNodeTopologyCM(cm)
+	return NodeTopologyFromUnstructured(obj)
 }
 
-func CreateNodeTopologyCM(kubeclient kubernetes.Interface, nodeTopology *NodeTopology, node *corev1.Node) error {
-	cm, _, err := ToNodeTopologyCM(nodeTopology, node.Name)
+// ListNodeTopologies returns the topology of every node, keyed by node name.
+func ListNodeTopologies(client dynamic.Interface) (map[string]*NodeTopology, error) {
+	list, err := client.Resource(FakeGpuNodeGVR).List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	topologies := make(map[string]*NodeTopology, len(list.Items))
+	for i := range list.Items {
+		nodeTopology, err := NodeTopologyFromUnstructured(&list.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		topologies[list.Items[i].GetName()] = nodeTopology
 	}
+	return topologies, nil
+}
+
+// CreateNodeTopology creates the FakeGpuNode of the node. The FakeGpuNode is
+// owned by the node, so it is garbage collected with it.
+func CreateNodeTopology(client dynamic.Interface, nodeTopology *NodeTopology, node *corev1.Node) error {
+	fakeGpuNode := ToFakeGpuNode(nodeTopology, node.Name)
 	if value, found := node.Annotations[constants.AnnotationKwokNode]; found {
-		if cm.Annotations == nil {
-			cm.Annotations = make(map[string]string)
+		fakeGpuNode.Annotations = map[string]string{constants.AnnotationKwokNode: value}
+	}
+	if node.UID != "" {
+		fakeGpuNode.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: "v1",
+			Kind:       "Node",
+			Name:       node.Name,
+			UID:        node.UID,
+		}}
+	}
+
+	obj, err := fakeGpuNode.ToUnstructured()
+	if err != nil {
+		return err
+	}
+
+	created, err := client.Resource(FakeGpuNodeGVR).Create(context.TODO(), obj, metav1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+
+	// The status subresource is not written on creation.
+	return updateStatus(client, created, fakeGpuNode.Status)
+}
+
+// MutateNodeTopology applies mutate to the node's topology and writes it back
+// guarded by the FakeGpuNode's resourceVersion, re-reading and re-applying
+// mutate on conflicts so concurrent writers cannot overwrite each other.
+// Nothing is written when mutate leaves the topology unchanged.
+func MutateNodeTopology(client dynamic.Interface, nodeName string, mutate func(*NodeTopology) error) error {
+	fakeGpuNodes := client.Resource(FakeGpuNodeGVR)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj, err := fakeGpuNodes.Get(context.TODO(), nodeName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		current, err := FakeGpuNodeFromUnstructured(obj)
+		if err != nil {
+			return err
+		}
+
+		nodeTopology := FromFakeGpuNode(current)
+		err = mutate(nodeTopology)
+		if err != nil {
+			return err
+		}
+		next := ToFakeGpuNode(nodeTopology, nodeName)
+
+		specChanged, err := changed(current.Spec, next.Spec)
+		if err != nil {
+			return err
+		}
+		if specChanged {
+			spec, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&next.Spec)
+			if err != nil {
+				return err
+			}
+			obj.Object["spec"] = spec
+			obj, err = fakeGpuNodes.Update(context.TODO(), obj, metav1.UpdateOptions{})
+			if err != nil {
+				return err
+			}
 		}
-		cm.Annotations[constants.AnnotationKwokNode] = value
+
+		statusChanged, err := changed(current.Status, next.Status)
+		if err != nil || !statusChanged {
+			return err
+		}
+		return updateStatus(client, obj, next.Status)
+	})
+}
+
+func DeleteNodeTopology(client dynamic.Interface, nodeName string) error {
+	return client.Resource(FakeGpuNodeGVR).Delete(context.TODO(), nodeName, metav1.DeleteOptions{})
+}
+
+// MigrateNodeTopologyCMs moves the node topologies that earlier releases kept
+// in per-node ConfigMaps to FakeGpuNodes, allocations included, and deletes
+// the ConfigMaps. Nodes that already have a FakeGpuNode, e.g. from a migration
+// that was interrupted before its status was written, keep its spec and get
+// the allocations of the ConfigMap. A ConfigMap is only deleted once its
+// allocations are written.
+//
+// ErrFakeGpuNodeCRDMissing is returned, before anything is migrated, while the
+// FakeGpuNode CRD is not installed.
+func MigrateNodeTopologyCMs(kubeclient kubernetes.Interface, client dynamic.Interface) error {
+	_, err := client.Resource(FakeGpuNodeGVR).List(context.TODO(), metav1.ListOptions{Limit: 1})
+	if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		log.Printf("FakeGpuNode CRD not found, apply the chart's crds/ before upgrading: %v\n", err)
+		return ErrFakeGpuNodeCRDMissing
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list FakeGpuNodes: %w", err)
 	}
 
-	_, err = kubeclient.CoreV1().ConfigMaps(
-		viper.GetString(constants.EnvTopologyCmNamespace)).Create(context.TODO(), cm, metav1.CreateOptions{})
-	return err
+	configMaps := kubeclient.CoreV1().ConfigMaps(viper.GetString(constants.EnvTopologyCmNamespace))
+	cms, err := configMaps.List(context.TODO(), metav1.ListOptions{LabelSelector: constants.LabelTopologyCMNodeTopology + "=true"})
+	if err != nil {
+		return fmt.Errorf("failed to list node topology ConfigMaps: %w", err)
+	}
+
+	for i := range cms.Items {
+		cm := &cms.Items[i]
+		nodeTopology, err := FromNodeTopologyCM(cm)
+		if err != nil {
+			return fmt.Errorf("failed to parse node topology ConfigMap %s: %w", cm.Name, err)
+		}
+
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name:        cm.Labels[constants.LabelTopologyCMNodeName],
+			Annotations: cm.Annotations,
+		}}
+		if existing, err := kubeclient.CoreV1().Nodes().Get(context.TODO(), node.Name, metav1.GetOptions{}); err == nil {
+			node.UID = existing.UID
+		}
+
+		err = CreateNodeTopology(client, nodeTopology, node)
+		if errors.IsAlreadyExists(err) {
+			err = migrateNodeTopologyStatus(client, nodeTopology, node.Name)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to migrate node topology ConfigMap %s: %w", cm.Name, err)
+		}
+
+		err = configMaps.Delete(context.TODO(), cm.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete migrated node topology ConfigMap %s: %w", cm.Name, err)
+		}
+		log.Printf("Migrated node topology ConfigMap %s to FakeGpuNode %s\n", cm.Name, node.Name)
+	}
+
+	return nil
 }
 
-func UpdateNodeTopologyCM(kubeclient kubernetes.Interface, nodeTopology *NodeTopology, nodeName string) error {
-	_, cm, err := ToNodeTopologyCM(nodeTopology, nodeName)
+// migrateNodeTopologyStatus writes the allocations of a node topology to the
+// node's existing FakeGpuNode.
+func migrateNodeTopologyStatus(client dynamic.Interface, nodeTopology *NodeTopology, nodeName string) error {
+	existing, err := client.Resource(FakeGpuNodeGVR).Get(context.TODO(), nodeName, metav1.GetOptions{})
 	if err != nil {
 		return err
 	}
+	return updateStatus(client, existing, ToFakeGpuNode(nodeTopology, nodeName).Status)
+}
 
-	_, err = kubeclient.CoreV1().ConfigMaps(
-		viper.GetString(constants.EnvTopologyCmNamespace)).Apply(context.TODO(), cm, metav1.ApplyOptions{FieldManager: "fake-gpu-operator", Force: true})
+func updateStatus(client dynamic.Interface, obj *unstructured.Unstructured, status FakeGpuNodeStatus) error {
+	statusObj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&status)
+	if err != nil {
+		return err
+	}
+	obj.Object["status"] = statusObj
+	_, err = client.Resource(FakeGpuNodeGVR).UpdateStatus(context.TODO(), obj, metav1.UpdateOptions{})
 	return err
 }
 
-func DeleteNodeTopologyCM(kubeclient kubernetes.Interface, nodeName string) error {
-	err := kubeclient.CoreV1().ConfigMaps(
-		viper.GetString(constants.EnvTopologyCmNamespace)).Delete(context.TODO(), GetNodeTopologyCMName(nodeName), metav1.DeleteOptions{})
-	return err
+// changed compares the serialized forms, as nil and empty fields read back the
+// same way.
+func changed(current, next interface{}) (bool, error) {
+	currentJSON, err := json.Marshal(current)
+	if err != nil {
+		return false, err
+	}
+	nextJSON, err := json.Marshal(next)
+	if err != nil {
+		return false, err
+	}
+	return !bytes.Equal(currentJSON, nextJSON), nil
 }
 
 func GetClusterTopologyFromCM(kubeclient kubernetes.Interface) (*ClusterTopology, error) {
@@ -138,32 +306,6 @@ func ToClusterTopologyCM(clusterTopology *ClusterTopology) (*corev1.ConfigMap, e
 	return cm, nil
 }
 
-func ToNodeTopologyCM(nodeTopology *NodeTopology, nodeName string) (*corev1.ConfigMap, *apcorev1.ConfigMapApplyConfiguration, error) {
-	cm := &corev1.ConfigMap{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      GetNodeTopologyCMName(nodeName),
-			Namespace: viper.GetString(constants.EnvTopologyCmNamespace),
-			Labels: map[string]string{
-				constants.LabelTopologyCMNodeTopology: "true",
-				constants.LabelTopologyCMNodeName:     nodeName,
-			},
-		},
-		Data: make(map[string]string),
-	}
-	cmApplyConfig := apcorev1.ConfigMap(cm.Name, cm.Namespace).WithLabels(cm.Labels)
-
-	topologyData, err := yaml.Marshal(nodeTopology)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	cm.Data[CmTopologyKey] = string(topologyData)
-
-	cmApplyConfig = cmApplyConfig.WithData(cm.Data)
-
-	return cm, cmApplyConfig, nil
-}
-
 func GetNodeTopologyCMName(nodeName string) string {
 	return viper.GetString(constants.EnvTopologyCmName) + "-" + nodeName
 }
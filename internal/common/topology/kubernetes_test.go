@@ -0,0 +1,276 @@
+package topology
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+)
+
+func newFakeDynamicClient() *dfake.FakeDynamicClient {
+	return dfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		FakeGpuNodeGVR: FakeGpuNodeListKind,
+	})
+}
+
+func TestFakeGpuNodeRoundTrip(t *testing.T) {
+	nodeTopology := &NodeTopology{
+		GpuMemory:     40960,
+		GpuProduct:    "NVIDIA-A100-SXM4-40GB",
+		DriverVersion: "550.54.15",
+		CudaVersion:   "12.4",
+		MigStrategy:   "none",
+		NodePool:      "default",
+		CliqueID:      "clique-0",
+		OtherDevices:  []GenericDevice{{Name: "rdma/ib", Count: 2}},
+		Gpus: []GpuDetails{
+			{
+				ID: "GPU-0",
+				Status: GpuStatus{
+					AllocatedBy: ContainerDetails{Namespace: "ns", Pod: "pod", Container: "main"},
+					PodGpuUsageStatus: PodGpuUsageStatusMap{
+						"uid": {Utilization: Range{Min: 10, Max: 20}, FbUsed: 100},
+					},
+				},
+			},
+			{ID: "GPU-1", Health: GpuHealthUnhealthy, Status: GpuStatus{PodGpuUsageStatus: PodGpuUsageStatusMap{}}},
+		},
+	}
+
+	fakeGpuNode := ToFakeGpuNode(nodeTopology, "node")
+	assert.Equal(t, "node", fakeGpuNode.Name)
+	assert.Equal(t, 2, fakeGpuNode.Status.Capacity)
+	assert.Equal(t, 1, fakeGpuNode.Status.Allocated)
+
+	obj, err := fakeGpuNode.ToUnstructured()
+	require.NoError(t, err)
+	assert.Equal(t, FakeGpuNodeKind, obj.GetKind())
+
+	parsed, err := NodeTopologyFromUnstructured(obj)
+	require.NoError(t, err)
+	assert.Equal(t, nodeTopology, parsed)
+}
+
+func TestMutateNodeTopology(t *testing.T) {
+	newClient := func(t *testing.T) *dfake.FakeDynamicClient {
+		client := newFakeDynamicClient()
+		nodeTopology := &NodeTopology{Gpus: []GpuDetails{{ID: "GPU-0"}, {ID: "GPU-1"}}}
+		require.NoError(t, CreateNodeTopology(client, nodeTopology, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}}))
+		return client
+	}
+
+	allocate := func(gpuIdx int, pod string) func(*NodeTopology) error {
+		return func(nodeTopology *NodeTopology) error {
+			nodeTopology.Gpus[gpuIdx].Status.AllocatedBy.Pod = pod
+			return nil
+		}
+	}
+
+	t.Run("retries on conflict and keeps the concurrent write", func(t *testing.T) {
+		client := newClient(t)
+		conflicted := false
+		client.PrependReactor("update", "fakegpunodes", func(k8stesting.Action) (bool, runtime.Object, error) {
+			if conflicted {
+				return false, nil, nil
+			}
+			conflicted = true
+			// Another writer wins the race before our update lands. The tracker
+			// is used directly since the client is locked while reactors run.
+			obj, err := client.Tracker().Get(FakeGpuNodeGVR, "", "node")
+			require.NoError(t, err)
+			fakeGpuNode, err := FakeGpuNodeFromUnstructured(obj.(*unstructured.Unstructured))
+			require.NoError(t, err)
+			nodeTopology := FromFakeGpuNode(fakeGpuNode)
+			require.NoError(t, allocate(1, "other")(nodeTopology))
+			updated, err := ToFakeGpuNode(nodeTopology, "node").ToUnstructured()
+			require.NoError(t, err)
+			require.NoError(t, client.Tracker().Update(FakeGpuNodeGVR, updated, ""))
+			return true, nil, apierrors.NewConflict(FakeGpuNodeGVR.GroupResource(), "node", nil)
+		})
+
+		require.NoError(t, MutateNodeTopology(client, "node", allocate(0, "pod")))
+
+		nodeTopology, err := GetNodeTopology(client, "node")
+		require.NoError(t, err)
+		assert.Equal(t, "pod", nodeTopology.Gpus[0].Status.AllocatedBy.Pod)
+		assert.Equal(t, "other", nodeTopology.Gpus[1].Status.AllocatedBy.Pod)
+	})
+
+	t.Run("skips the write when nothing changed", func(t *testing.T) {
+		client := newClient(t)
+		client.PrependReactor("update", "fakegpunodes", func(k8stesting.Action) (bool, runtime.Object, error) {
+			t.Fatal("unexpected update")
+			return true, nil, nil
+		})
+
+		require.NoError(t, MutateNodeTopology(client, "node", func(*NodeTopology) error { return nil }))
+	})
+
+	t.Run("only writes the status when allocations change", func(t *testing.T) {
+		client := newClient(t)
+		client.PrependReactor("update", "fakegpunodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			assert.Equal(t, "status", action.GetSubresource())
+			return false, nil, nil
+		})
+
+		require.NoError(t, MutateNodeTopology(client, "node", allocate(0, "pod")))
+	})
+
+	t.Run("fails for a node without topology", func(t *testing.T) {
+		err := MutateNodeTopology(newClient(t), "missing", allocate(0, "pod"))
+		assert.True(t, apierrors.IsNotFound(err))
+	})
+}
+
+func TestMigrateNodeTopologyCMs(t *testing.T) {
+	viper.Set(constants.EnvTopologyCmName, "topology")
+	viper.Set(constants.EnvTopologyCmNamespace, "gpu-operator")
+	t.Cleanup(viper.Reset)
+
+	nodeTopology := &NodeTopology{
+		GpuProduct: "Tesla-K80",
+		Gpus: []GpuDetails{{
+			ID:     "GPU-0",
+			Status: GpuStatus{AllocatedBy: ContainerDetails{Namespace: "ns", Pod: "pod", Container: "main"}},
+		}},
+	}
+	topologyData, err := yaml.Marshal(nodeTopology)
+	require.NoError(t, err)
+
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node", UID: "node-uid"}}
+	kubeclient := fake.NewSimpleClientset(node, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      GetNodeTopologyCMName(node.Name),
+			Namespace: "gpu-operator",
+			Labels: map[string]string{
+				constants.LabelTopologyCMNodeTopology: "true",
+				constants.LabelTopologyCMNodeName:     node.Name,
+			},
+			Annotations: map[string]string{constants.AnnotationKwokNode: "fake"},
+		},
+		Data: map[string]string{CmTopologyKey: string(topologyData)},
+	})
+	client := newFakeDynamicClient()
+
+	require.NoError(t, MigrateNodeTopologyCMs(kubeclient, client))
+
+	obj, err := client.Resource(FakeGpuNodeGVR).Get(context.TODO(), node.Name, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "fake", obj.GetAnnotations()[constants.AnnotationKwokNode])
+	require.Len(t, obj.GetOwnerReferences(), 1)
+	assert.Equal(t, node.UID, obj.GetOwnerReferences()[0].UID)
+
+	migrated, err := NodeTopologyFromUnstructured(obj)
+	require.NoError(t, err)
+	assert.Equal(t, "Tesla-K80", migrated.GpuProduct)
+	assert.Equal(t, "pod", migrated.Gpus[0].Status.AllocatedBy.Pod)
+
+	_, err = kubeclient.CoreV1().ConfigMaps("gpu-operator").Get(context.TODO(), GetNodeTopologyCMName(node.Name), metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+
+	// A second run finds nothing left to migrate.
+	require.NoError(t, MigrateNodeTopologyCMs(kubeclient, client))
+}
+
+func TestMigrateNodeTopologyCMs_Interrupted(t *testing.T) {
+	viper.Set(constants.EnvTopologyCmName, "topology")
+	viper.Set(constants.EnvTopologyCmNamespace, "gpu-operator")
+	t.Cleanup(viper.Reset)
+
+	nodeTopology := &NodeTopology{
+		GpuProduct: "Tesla-K80",
+		Gpus: []GpuDetails{{
+			ID:     "GPU-0",
+			Status: GpuStatus{AllocatedBy: ContainerDetails{Namespace: "ns", Pod: "pod", Container: "main"}},
+		}},
+	}
+	topologyData, err := yaml.Marshal(nodeTopology)
+	require.NoError(t, err)
+
+	cmName := GetNodeTopologyCMName("node")
+	kubeclient := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cmName,
+			Namespace: "gpu-operator",
+			Labels: map[string]string{
+				constants.LabelTopologyCMNodeTopology: "true",
+				constants.LabelTopologyCMNodeName:     "node",
+			},
+		},
+		Data: map[string]string{CmTopologyKey: string(topologyData)},
+	})
+
+	// A previous run created the FakeGpuNode but failed to write its status.
+	client := newFakeDynamicClient()
+	fakeGpuNode := ToFakeGpuNode(nodeTopology, "node")
+	fakeGpuNode.Status = FakeGpuNodeStatus{}
+	obj, err := fakeGpuNode.ToUnstructured()
+	require.NoError(t, err)
+	_, err = client.Resource(FakeGpuNodeGVR).Create(context.TODO(), obj, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	// The status write fails again: the ConfigMap is kept.
+	client.PrependReactor("update", "fakegpunodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() == "status" {
+			return true, nil, apierrors.NewServiceUnavailable("unavailable")
+		}
+		return false, nil, nil
+	})
+	require.Error(t, MigrateNodeTopologyCMs(kubeclient, client))
+	_, err = kubeclient.CoreV1().ConfigMaps("gpu-operator").Get(context.TODO(), cmName, metav1.GetOptions{})
+	require.NoError(t, err)
+
+	client.ReactionChain = client.ReactionChain[1:]
+	require.NoError(t, MigrateNodeTopologyCMs(kubeclient, client))
+
+	obj, err = client.Resource(FakeGpuNodeGVR).Get(context.TODO(), "node", metav1.GetOptions{})
+	require.NoError(t, err)
+	migrated, err := NodeTopologyFromUnstructured(obj)
+	require.NoError(t, err)
+	assert.Equal(t, "pod", migrated.Gpus[0].Status.AllocatedBy.Pod)
+
+	_, err = kubeclient.CoreV1().ConfigMaps("gpu-operator").Get(context.TODO(), cmName, metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+}
+
+func TestMigrateNodeTopologyCMs_CRDMissing(t *testing.T) {
+	viper.Set(constants.EnvTopologyCmNamespace, "gpu-operator")
+	t.Cleanup(viper.Reset)
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      GetNodeTopologyCMName("node"),
+			Namespace: "gpu-operator",
+			Labels: map[string]string{
+				constants.LabelTopologyCMNodeTopology: "true",
+				constants.LabelTopologyCMNodeName:     "node",
+			},
+		},
+		Data: map[string]string{CmTopologyKey: "gpuProduct: Tesla-K80\n"},
+	}
+	kubeclient := fake.NewSimpleClientset(cm)
+
+	// A helm upgrade does not apply the chart's crds/.
+	client := newFakeDynamicClient()
+	client.PrependReactor("*", "fakegpunodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewNotFound(FakeGpuNodeGVR.GroupResource(), "")
+	})
+
+	assert.ErrorIs(t, MigrateNodeTopologyCMs(kubeclient, client), ErrFakeGpuNodeCRDMissing)
+	_, err := kubeclient.CoreV1().ConfigMaps("gpu-operator").Get(context.TODO(), cm.Name, metav1.GetOptions{})
+	assert.NoError(t, err, "the ConfigMap is kept for the next attempt")
+}
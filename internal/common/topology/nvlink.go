@@ -0,0 +1,42 @@
+package topology
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// FabricClusterUUID returns the NVLink cluster UUID of a pool, deriving a
+// stable one from the pool name when none is configured.
+func (c NvLinkConfig) FabricClusterUUID(poolName string) string {
+	if c.ClusterUUID != "" {
+		return c.ClusterUUID
+	}
+	return uuid.NewSHA1(uuid.Nil, []byte("nvlink-"+poolName)).String()
+}
+
+// CliqueID formats a clique ID the way NVIDIA's GPU feature discovery does.
+func CliqueID(clusterUUID string, clique int) string {
+	return fmt.Sprintf("%s.%d", clusterUUID, clique)
+}
+
+// AssignClique returns the first clique of the pool's fabric that still has
+// room for a node, given the clique IDs of the nodes already placed.
+func AssignClique(config NvLinkConfig, poolName string, assigned []string) (string, error) {
+	if config.CliqueSize <= 0 {
+		return "", fmt.Errorf("nvlink cliqueSize must be positive, got %d", config.CliqueSize)
+	}
+
+	members := make(map[string]int)
+	for _, cliqueID := range assigned {
+		members[cliqueID]++
+	}
+
+	clusterUUID := config.FabricClusterUUID(poolName)
+	for clique := 0; ; clique++ {
+		cliqueID := CliqueID(clusterUUID, clique)
+		if members[cliqueID] < config.CliqueSize {
+			return cliqueID, nil
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package topology
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssignClique(t *testing.T) {
+	config := NvLinkConfig{ClusterUUID: "cluster", CliqueSize: 2}
+
+	tests := map[string]struct {
+		assigned []string
+		want     string
+	}{
+		"first node":        {assigned: nil, want: "cluster.0"},
+		"clique has room":   {assigned: []string{"cluster.0"}, want: "cluster.0"},
+		"clique full":       {assigned: []string{"cluster.0", "cluster.0"}, want: "cluster.1"},
+		"gap is reused":     {assigned: []string{"cluster.0", "cluster.1", "cluster.1"}, want: "cluster.0"},
+		"other fabric only": {assigned: []string{"other.0", "other.0"}, want: "cluster.0"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			cliqueID, err := AssignClique(config, "pool", test.assigned)
+			require.NoError(t, err)
+			assert.Equal(t, test.want, cliqueID)
+		})
+	}
+}
+
+func TestAssignCliqueInvalidSize(t *testing.T) {
+	_, err := AssignClique(NvLinkConfig{}, "pool", nil)
+	assert.Error(t, err)
+}
+
+func TestFabricClusterUUID(t *testing.T) {
+	assert.Equal(t, "cluster", NvLinkConfig{ClusterUUID: "cluster"}.FabricClusterUUID("pool"))
+
+	derived := NvLinkConfig{}.FabricClusterUUID("pool")
+	assert.Equal(t, derived, NvLinkConfig{}.FabricClusterUUID("pool"))
+	assert.NotEqual(t, derived, NvLinkConfig{}.FabricClusterUUID("other-pool"))
+}
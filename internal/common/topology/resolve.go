@@ -18,6 +18,9 @@ type ResolvedPool struct {
 	DriverVersion string
 	CudaVersion   string
 	OtherDevices  []GenericDevice
+	// Devices holds the product, memory and identity of each GPU, which may
+	// differ from GpuProduct and GpuMemory on nodes with mixed cards.
+	Devices []profile.DeviceSpec
 }
 
 // ResolveNodePool resolves a NodePoolConfig into concrete GPU spec fields.
@@ -67,10 +70,29 @@ func resolveWithProfile(kubeClient kubernetes.Interface, namespace string, pool
 	resolved.GpuCount = spec.GpuCount
 	resolved.DriverVersion = spec.DriverVersion
 	resolved.CudaVersion = spec.CudaVersion
+	resolved.Devices = spec.Devices
+	// The UUIDs a profile sets, like those of the built-in profiles, are
+	// shared by pools, so only the pool's own overrides set them. Each node
+	// derives its GPU UUIDs from them and its name.
+	for idx := range resolved.Devices {
+		resolved.Devices[idx].UUID = overriddenDeviceUUID(pool.Gpu.Overrides, idx)
+	}
 
 	return resolved, nil
 }
 
+// overriddenDeviceUUID returns the UUID the overrides set for the idx-th
+// device, if any.
+func overriddenDeviceUUID(overrides map[string]interface{}, idx int) string {
+	devices, _ := overrides["devices"].([]interface{})
+	if idx >= len(devices) {
+		return ""
+	}
+	device, _ := devices[idx].(map[string]interface{})
+	uuid, _ := device["uuid"].(string)
+	return uuid
+}
+
 // resolveFromOverrides extracts GPU spec directly from overrides.
 // This is the backwards-compatible path used when normalization converts
 // old-format fields (gpuProduct, gpuMemory, gpuCount) into overrides.
@@ -83,21 +105,13 @@ func resolveFromOverrides(pool NodePoolConfig, resolved *ResolvedPool) (*Resolve
 	if dd, ok := overrides["device_defaults"].(map[string]interface{}); ok {
 		resolved.GpuProduct, _ = dd["name"].(string)
 		if mem, ok := dd["memory"].(map[string]interface{}); ok {
-			// Normalization stores total_bytes (int64); convert to MiB
-			if totalBytes, ok := mem["total_bytes"]; ok {
-				switch v := totalBytes.(type) {
-				case int:
-					resolved.GpuMemory = v / (1024 * 1024)
-				case int64:
-					resolved.GpuMemory = int(v / (1024 * 1024))
-				case float64:
-					resolved.GpuMemory = int(v / (1024 * 1024))
-				}
-			}
+			// Normalization stores total_bytes (int64)
+			resolved.GpuMemory, _ = profile.ToMiB(mem["total_bytes"])
 		}
 	}
 
 	resolved.GpuCount = profile.DeviceCount(overrides)
+	resolved.Devices = profile.ExtractDevices(overrides)
 
 	return resolved, nil
 }
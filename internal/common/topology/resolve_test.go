@@ -204,3 +204,48 @@ func TestResolveNodePool_ProfileNotFound(t *testing.T) {
 		t.Fatal("expected error for missing profile, got nil")
 	}
 }
+
+func TestResolveNodePool_DeviceUUIDs(t *testing.T) {
+	profileData := `
+device_defaults:
+  name: "NVIDIA H100 80GB HBM3"
+  memory:
+    total_bytes: 85899345920
+devices:
+  - uuid: "GPU-11111111-1111-1111-1111-111111111111"
+  - uuid: "GPU-22222222-2222-2222-2222-222222222222"
+`
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "gpu-profile-h100", Namespace: "default"},
+		Data:       map[string]string{profile.CmProfileKey: profileData},
+	}
+	client := fake.NewSimpleClientset(cm)
+
+	pool := NodePoolConfig{Gpu: GpuConfig{Backend: "fake", Profile: "h100"}}
+	resolved, err := ResolveNodePool(client, "default", pool)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for idx, device := range resolved.Devices {
+		if device.UUID != "" {
+			t.Errorf("Devices[%d].UUID = %q, want the profile's UUID dropped", idx, device.UUID)
+		}
+	}
+
+	pool.Gpu.Overrides = map[string]interface{}{
+		"devices": []interface{}{
+			map[string]interface{}{"uuid": "GPU-33333333-3333-3333-3333-333333333333"},
+			map[string]interface{}{},
+		},
+	}
+	resolved, err = ResolveNodePool(client, "default", pool)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resolved.Devices[0].UUID != "GPU-33333333-3333-3333-3333-333333333333" {
+		t.Errorf("Devices[0].UUID = %q, want the overridden UUID", resolved.Devices[0].UUID)
+	}
+	if resolved.Devices[1].UUID != "" {
+		t.Errorf("Devices[1].UUID = %q, want none", resolved.Devices[1].UUID)
+	}
+}
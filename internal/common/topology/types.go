@@ -30,6 +30,7 @@ type ClusterConfig struct {
 type NodePoolConfig struct {
 	Gpu       GpuConfig        `yaml:"gpu"`
 	Numa      *NumaConfig      `yaml:"numa,omitempty"`
+	NvLink    *NvLinkConfig    `yaml:"nvlink,omitempty"`
 	Resources []map[string]int `yaml:"resources,omitempty"`
 }
 
@@ -45,6 +46,16 @@ type NumaConfig struct {
 	Distances             *NumaDistances `yaml:"distances,omitempty"`
 }
 
+// NvLinkConfig declares a node pool's simulated NVLink fabric. The pool's nodes
+// are grouped into cliques (racks) of CliqueSize nodes; a ComputeDomain can
+// only span nodes of a single clique.
+type NvLinkConfig struct {
+	// ClusterUUID identifies the NVLink fabric. Defaults to a UUID derived
+	// from the pool name.
+	ClusterUUID string `yaml:"clusterUUID,omitempty"`
+	CliqueSize  int    `yaml:"cliqueSize"`
+}
+
 type NumaDistances struct {
 	Self   int `yaml:"self"`
 	Remote int `yaml:"remote"`
@@ -62,6 +73,8 @@ type GpuOperatorConfig struct {
 }
 
 type NodeTopology struct {
+	// GpuMemory (MiB) and GpuProduct describe the node's GPUs unless a GPU
+	// has its own, as on nodes with mixed cards.
 	GpuMemory     int             `yaml:"gpuMemory"`
 	GpuProduct    string          `yaml:"gpuProduct"`
 	DriverVersion string          `yaml:"driverVersion,omitempty"`
@@ -69,11 +82,67 @@ type NodeTopology struct {
 	Gpus          []GpuDetails    `yaml:"gpus"`
 	MigStrategy   string          `yaml:"migStrategy"`
 	OtherDevices  []GenericDevice `yaml:"otherDevices,omitempty"`
+	// NodePool is the pool the topology was generated from, so a node moved
+	// to another pool can be detected.
+	NodePool string `yaml:"nodePool,omitempty"`
+	// CliqueID is the node's NVLink clique ("<clusterUUID>.<clique>"), empty
+	// when the pool has no NVLink fabric.
+	CliqueID string `yaml:"cliqueId,omitempty"`
 }
 
 type GpuDetails struct {
+	// ID is the UUID of the GPU.
 	ID     string    `yaml:"id"`
 	Status GpuStatus `yaml:"status"`
+	// Health is the simulated health of the GPU. Empty means healthy; set it
+	// to GpuHealthUnhealthy in the node topology to inject a fault.
+	Health GpuHealth `yaml:"health,omitempty"`
+
+	// Product and Memory (MiB) override the node's GpuProduct and GpuMemory.
+	Product      string `yaml:"product,omitempty"`
+	Memory       int    `yaml:"memory,omitempty"`
+	Architecture string `yaml:"architecture,omitempty"`
+	Serial       string `yaml:"serial,omitempty"`
+	PciBusID     string `yaml:"pciBusId,omitempty"`
+}
+
+// GpuProductOf returns the product of the GPU at idx.
+func (t *NodeTopology) GpuProductOf(idx int) string {
+	if idx < len(t.Gpus) && t.Gpus[idx].Product != "" {
+		return t.Gpus[idx].Product
+	}
+	return t.GpuProduct
+}
+
+// GpuMemoryOf returns the memory (MiB) of the GPU at idx.
+func (t *NodeTopology) GpuMemoryOf(idx int) int {
+	if idx < len(t.Gpus) && t.Gpus[idx].Memory > 0 {
+		return t.Gpus[idx].Memory
+	}
+	return t.GpuMemory
+}
+
+// IsHeterogeneous reports whether the node's GPUs are not all of one product
+// and memory size.
+func (t *NodeTopology) IsHeterogeneous() bool {
+	for idx := range t.Gpus {
+		if t.GpuProductOf(idx) != t.GpuProductOf(0) || t.GpuMemoryOf(idx) != t.GpuMemoryOf(0) {
+			return true
+		}
+	}
+	return false
+}
+
+type GpuHealth string
+
+const (
+	GpuHealthHealthy   GpuHealth = "Healthy"
+	GpuHealthUnhealthy GpuHealth = "Unhealthy"
+)
+
+// IsHealthy reports whether the GPU has no injected fault.
+func (g GpuDetails) IsHealthy() bool {
+	return g.Health != GpuHealthUnhealthy
 }
 
 type PodGpuUsageStatusMap map[types.UID]GpuUsageStatus
@@ -88,6 +157,9 @@ type ContainerDetails struct {
 	Namespace string `yaml:"namespace"`
 	Pod       string `yaml:"pod"`
 	Container string `yaml:"container"`
+	// ComputeDomain is the ComputeDomain whose IMEX channel the pod claims
+	// alongside the GPU, if any.
+	ComputeDomain string `yaml:"computeDomain,omitempty"`
 }
 
 type GpuUsageStatus struct {
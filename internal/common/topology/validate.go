@@ -0,0 +1,334 @@
+package topology
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+	"github.com/run-ai/fake-gpu-operator/internal/common/profile"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	validMigStrategies         = []string{"", "none", "single", "mixed"}
+	validBackends              = []string{"", constants.BackendFake, constants.BackendMock}
+	validTopologyManagerPolicy = []string{"", "none", "best-effort", "restricted", "single-numa-node"}
+	validTopologyManagerScope  = []string{"", "container", "pod"}
+
+	// legacyPoolFields are the pool fields of the old format, which are
+	// ignored once any pool of the topology uses the new gpu block.
+	legacyPoolFields = []string{"gpuProduct", "gpuCount", "gpuMemory", "otherDevices"}
+)
+
+// Validate checks the config for values the components would otherwise reject
+// or silently misinterpret at runtime. All problems are reported together,
+// each prefixed with the path of the offending field.
+func (c *ClusterConfig) Validate() error {
+	return errors.Join(c.validate()...)
+}
+
+func (c *ClusterConfig) validate() []error {
+	var errs []error
+	if !slices.Contains(validMigStrategies, c.MigStrategy) {
+		errs = append(errs, fmt.Errorf("migStrategy: unsupported value %q, must be one of none, single, mixed", c.MigStrategy))
+	}
+
+	names := make([]string, 0, len(c.NodePools))
+	for name := range c.NodePools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, err := range c.NodePools[name].validate() {
+			errs = append(errs, fmt.Errorf("nodePools.%s.%w", name, err))
+		}
+	}
+	return errs
+}
+
+func (p NodePoolConfig) validate() []error {
+	var errs []error
+	if !slices.Contains(validBackends, p.Gpu.Backend) {
+		errs = append(errs, fmt.Errorf("gpu.backend: unsupported value %q, must be %s or %s", p.Gpu.Backend, constants.BackendFake, constants.BackendMock))
+	}
+
+	if p.Numa != nil {
+		errs = append(errs, p.Numa.validate()...)
+	}
+
+	if p.NvLink != nil && p.NvLink.CliqueSize <= 0 {
+		errs = append(errs, fmt.Errorf("nvlink.cliqueSize: must be positive, got %d", p.NvLink.CliqueSize))
+	}
+
+	for i, res := range p.Resources {
+		for name, count := range res {
+			if count < 0 {
+				errs = append(errs, fmt.Errorf("resources[%d].%s: must not be negative, got %d", i, name, count))
+			}
+		}
+	}
+	return errs
+}
+
+func (n NumaConfig) validate() []error {
+	var errs []error
+	if n.Zones <= 0 {
+		errs = append(errs, fmt.Errorf("numa.zones: must be positive, got %d", n.Zones))
+	}
+	if len(n.GpusPerZone) > 0 && len(n.GpusPerZone) != n.Zones {
+		errs = append(errs, fmt.Errorf("numa.gpusPerZone: has %d entries, expected one per zone (%d)", len(n.GpusPerZone), n.Zones))
+	}
+	for i, gpus := range n.GpusPerZone {
+		if gpus < 0 {
+			errs = append(errs, fmt.Errorf("numa.gpusPerZone[%d]: must not be negative, got %d", i, gpus))
+		}
+	}
+	if !slices.Contains(validTopologyManagerPolicy, n.TopologyManagerPolicy) {
+		errs = append(errs, fmt.Errorf("numa.topologyManagerPolicy: unsupported value %q", n.TopologyManagerPolicy))
+	}
+	if !slices.Contains(validTopologyManagerScope, n.TopologyManagerScope) {
+		errs = append(errs, fmt.Errorf("numa.topologyManagerScope: unsupported value %q", n.TopologyManagerScope))
+	}
+	if err := validateQuantity(n.CPUPerZone); err != nil {
+		errs = append(errs, fmt.Errorf("numa.cpuPerZone: %w", err))
+	}
+	if err := validateQuantity(n.MemPerZone); err != nil {
+		errs = append(errs, fmt.Errorf("numa.memPerZone: %w", err))
+	}
+	return errs
+}
+
+func validateQuantity(value string) error {
+	if value == "" {
+		return nil
+	}
+	if _, err := resource.ParseQuantity(value); err != nil {
+		return fmt.Errorf("invalid quantity %q", value)
+	}
+	return nil
+}
+
+// ValidationReport is the outcome of ValidateTopology.
+type ValidationReport struct {
+	// Config is the parsed topology, or nil when it could not be parsed.
+	Config *ClusterConfig
+	// Pools holds the GPU spec of each node pool that resolved.
+	Pools map[string]*ResolvedPool
+	// Errors lists every problem found, each prefixed with the path of the
+	// offending field.
+	Errors []error
+}
+
+// ValidateTopology parses topology ConfigMap data and resolves its node pools
+// the way the components do, reporting what they would reject or silently
+// read as zero values: unknown fields, unsupported values, profiles that
+// fail to resolve, and profile fields of the wrong type. Problems in a
+// pool's profile name the profile or fragment that set the field.
+func ValidateTopology(kubeClient kubernetes.Interface, namespace string, data []byte) *ValidationReport {
+	report := &ValidationReport{Pools: map[string]*ResolvedPool{}}
+
+	config, err := ParseAndNormalizeTopology(data)
+	if err != nil {
+		report.Errors = append(report.Errors, err)
+		return report
+	}
+	report.Config = config
+
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err == nil {
+		if isNewFormat(data) {
+			report.Errors = append(report.Errors, unknownFields(raw, reflect.TypeOf(ClusterConfig{}), "")...)
+		} else {
+			report.Errors = append(report.Errors, unknownFields(raw, reflect.TypeOf(ClusterTopology{}), "")...)
+		}
+	}
+	report.Errors = append(report.Errors, config.validate()...)
+
+	var legacy ClusterTopology
+	if !isNewFormat(data) {
+		// The old format can't be wrong about types, only about counts.
+		_ = yaml.Unmarshal(data, &legacy)
+	}
+
+	names := make([]string, 0, len(config.NodePools))
+	for name := range config.NodePools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if pool, ok := legacy.NodePools[name]; ok {
+			report.Errors = append(report.Errors, pool.validate(name)...)
+		}
+		report.validatePool(kubeClient, namespace, name, config.NodePools[name], legacy.NodePools == nil)
+	}
+
+	return report
+}
+
+func (p NodePoolTopology) validate(name string) []error {
+	var errs []error
+	if p.GpuCount <= 0 {
+		errs = append(errs, fmt.Errorf("nodePools.%s.gpuCount: must be positive, got %d", name, p.GpuCount))
+	}
+	if p.GpuMemory <= 0 {
+		errs = append(errs, fmt.Errorf("nodePools.%s.gpuMemory: must be positive, got %d", name, p.GpuMemory))
+	}
+	if p.GpuProduct == "" {
+		errs = append(errs, fmt.Errorf("nodePools.%s.gpuProduct: must be set", name))
+	}
+	return errs
+}
+
+// validatePool resolves a pool and, for new format pools, checks its
+// profile merged with the overrides.
+func (r *ValidationReport) validatePool(kubeClient kubernetes.Interface, namespace, name string, pool NodePoolConfig, checkProfile bool) {
+	path := fmt.Sprintf("nodePools.%s.gpu", name)
+	resolved, err := ResolveNodePool(kubeClient, namespace, pool)
+	if err != nil {
+		r.Errors = append(r.Errors, fmt.Errorf("%s.profile: %w", path, err))
+		return
+	}
+	r.Pools[name] = resolved
+
+	// Nodes derive their GPU UUIDs from the overridden ones, so two devices
+	// with the same override would get the same UUID on every node.
+	seen := map[string]int{}
+	for idx, device := range resolved.Devices {
+		if device.UUID == "" {
+			continue
+		}
+		if first, found := seen[device.UUID]; found {
+			r.Errors = append(r.Errors, fmt.Errorf("%s.overrides.devices[%d].uuid: %q is already set on devices[%d]", path, idx, device.UUID, first))
+			continue
+		}
+		seen[device.UUID] = idx
+	}
+
+	if !checkProfile {
+		return
+	}
+
+	if pool.Gpu.Profile == "" && len(pool.Gpu.Overrides) == 0 {
+		r.Errors = append(r.Errors, fmt.Errorf("%s: the pool has no GPUs, set gpu.profile or gpu.overrides", path))
+		return
+	}
+
+	data := pool.Gpu.Overrides
+	var base *profile.Resolved
+	if pool.Gpu.Profile != "" {
+		base, err = profile.Resolve(kubeClient, namespace, pool.Gpu.Profile)
+		if err != nil {
+			r.Errors = append(r.Errors, fmt.Errorf("%s.profile: %w", path, err))
+			return
+		}
+		data = profile.Merge(base.Data, pool.Gpu.Overrides)
+	}
+
+	for _, fieldErr := range profile.Validate(data) {
+		if base == nil || overridden(pool.Gpu.Overrides, fieldErr.Path) {
+			r.Errors = append(r.Errors, fmt.Errorf("%s.overrides.%w", path, fieldErr))
+			continue
+		}
+		layer, found := base.Origin(fieldErr.Path)
+		if !found {
+			layer = profile.Layer{Kind: profile.LayerProfile, Name: pool.Gpu.Profile}
+		}
+		r.Errors = append(r.Errors, fmt.Errorf("%s.profile: %s: %w", path, layer, fieldErr))
+	}
+}
+
+// overridden reports whether the overrides set the field at the dotted path,
+// or replace one of its parents as a whole.
+func overridden(overrides map[string]interface{}, path string) bool {
+	current := overrides
+	for _, key := range strings.Split(path, ".") {
+		key, _, _ = strings.Cut(key, "[")
+		value, found := current[key]
+		if !found {
+			return false
+		}
+		next, ok := value.(map[string]interface{})
+		if !ok {
+			return true
+		}
+		current = next
+	}
+	return true
+}
+
+// unknownFields reports the keys of the raw YAML that the type it is decoded
+// into has no field for, such as a misspelled gpuCount, which would otherwise
+// be dropped without notice. Free-form maps, like gpu.overrides, are not
+// checked.
+func unknownFields(raw interface{}, t reflect.Type, path string) []error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var errs []error
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		fields := make(map[string]reflect.Type, t.NumField())
+		for i := 0; i < t.NumField(); i++ {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+			if name != "" && name != "-" {
+				fields[name] = t.Field(i).Type
+			}
+		}
+		for _, key := range sortedKeys(m) {
+			fieldType, found := fields[key]
+			switch {
+			case !found && t == reflect.TypeOf(NodePoolConfig{}) && slices.Contains(legacyPoolFields, key):
+				errs = append(errs, fmt.Errorf("%s: old format field, ignored as the topology uses the gpu block; set gpu.profile or gpu.overrides instead", joinPath(path, key)))
+				continue
+			case !found:
+				errs = append(errs, fmt.Errorf("%s: unknown field", joinPath(path, key)))
+				continue
+			}
+			errs = append(errs, unknownFields(m[key], fieldType, joinPath(path, key))...)
+		}
+	case reflect.Map:
+		m, ok := raw.(map[string]interface{})
+		if !ok || t.Elem().Kind() == reflect.Interface {
+			return nil
+		}
+		for _, key := range sortedKeys(m) {
+			errs = append(errs, unknownFields(m[key], t.Elem(), joinPath(path, key))...)
+		}
+	case reflect.Slice:
+		list, ok := raw.([]interface{})
+		if !ok {
+			return nil
+		}
+		for idx, item := range list {
+			errs = append(errs, unknownFields(item, t.Elem(), fmt.Sprintf("%s[%d]", path, idx))...)
+		}
+	}
+	return errs
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
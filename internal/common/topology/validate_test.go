@@ -0,0 +1,224 @@
+package topology
+
+import (
+	"testing"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/profile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestClusterConfigValidate(t *testing.T) {
+	tests := map[string]struct {
+		config         ClusterConfig
+		expectedErrors []string
+	}{
+		"valid config": {
+			config: ClusterConfig{
+				MigStrategy: "mixed",
+				NodePools: map[string]NodePoolConfig{
+					"default": {
+						Gpu:       GpuConfig{Backend: "fake", Profile: "a100"},
+						Numa:      &NumaConfig{Zones: 2, GpusPerZone: []int{1, 1}, CPUPerZone: "16", MemPerZone: "64Gi"},
+						NvLink:    &NvLinkConfig{CliqueSize: 18},
+						Resources: []map[string]int{{"nvidia.com/gpu": 2}},
+					},
+				},
+			},
+		},
+		"legacy pool without backend": {
+			config: ClusterConfig{NodePools: map[string]NodePoolConfig{"default": {}}},
+		},
+		"all problems reported": {
+			config: ClusterConfig{
+				MigStrategy: "sometimes",
+				NodePools: map[string]NodePoolConfig{
+					"a": {
+						Gpu:    GpuConfig{Backend: "real"},
+						NvLink: &NvLinkConfig{CliqueSize: 0},
+					},
+					"b": {
+						Numa:      &NumaConfig{Zones: 2, GpusPerZone: []int{4}, TopologyManagerPolicy: "strict", CPUPerZone: "lots"},
+						Resources: []map[string]int{{"rdma/ib": -1}},
+					},
+				},
+			},
+			expectedErrors: []string{
+				`migStrategy: unsupported value "sometimes"`,
+				`nodePools.a.gpu.backend: unsupported value "real"`,
+				`nodePools.a.nvlink.cliqueSize: must be positive, got 0`,
+				`nodePools.b.numa.gpusPerZone: has 1 entries, expected one per zone (2)`,
+				`nodePools.b.numa.topologyManagerPolicy: unsupported value "strict"`,
+				`nodePools.b.numa.cpuPerZone: invalid quantity "lots"`,
+				`nodePools.b.resources[0].rdma/ib: must not be negative, got -1`,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.config.Validate()
+			if len(test.expectedErrors) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			for _, expected := range test.expectedErrors {
+				assert.Contains(t, err.Error(), expected)
+			}
+		})
+	}
+}
+
+func TestValidateTopology(t *testing.T) {
+	profileCM := func(prefix, name, data string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: prefix + name, Namespace: "gpu-operator"},
+			Data:       map[string]string{profile.CmProfileKey: data},
+		}
+	}
+	client := fake.NewSimpleClientset(
+		profileCM(profile.CmNamePrefix, "a100", `
+device_defaults:
+  name: "NVIDIA A100-SXM4-40GB"
+  memory:
+    total_bytes: 42949672960
+device_count: 8
+`),
+		profileCM(profile.CmNamePrefix, "a100-80gb", "extends: a100\nmixins: [big-memory]\n"),
+		profileCM(profile.FragmentCmNamePrefix, "big-memory", "device_defaults:\n  memory:\n    total_bytes: 80GB\n"),
+	)
+
+	tests := map[string]struct {
+		topology       string
+		expectedPools  map[string]int
+		expectedErrors []string
+	}{
+		"valid topology": {
+			topology: `
+nodePoolLabelKey: run.ai/simulated-gpu-node-pool
+nodePools:
+  default:
+    gpu:
+      backend: fake
+      profile: a100
+      overrides:
+        device_count: 2
+  legacy-style:
+    gpu:
+      backend: fake
+      overrides:
+        device_defaults:
+          name: Tesla-K80
+          memory:
+            total_bytes: 11996954624
+        device_count: 4
+`,
+			expectedPools: map[string]int{"default": 2, "legacy-style": 4},
+		},
+		"valid old format": {
+			topology: `
+nodePools:
+  default:
+    gpuProduct: Tesla-K80
+    gpuCount: 2
+    gpuMemory: 11441
+migStrategy: mixed
+`,
+			expectedPools: map[string]int{"default": 2},
+		},
+		"old format typo": {
+			topology: `
+nodePools:
+  default:
+    gpuProduct: Tesla-K80
+    gpuCont: 2
+    gpuMemory: 11441
+`,
+			expectedPools: map[string]int{"default": 0},
+			expectedErrors: []string{
+				"nodePools.default.gpuCont: unknown field",
+				"nodePools.default.gpuCount: must be positive, got 0",
+			},
+		},
+		"new format problems": {
+			topology: `
+nodePools:
+  default:
+    gpu:
+      backend: mok
+      profile: a100
+      overrides:
+        device_defaults:
+          name: 100
+  big:
+    gpu:
+      backend: fake
+      profile: a100-80gb
+  missing:
+    gpu:
+      backend: fake
+      profile: b300
+  legacy:
+    gpuProduct: Tesla-K80
+    gpuCount: 2
+`,
+			expectedPools: map[string]int{"default": 8, "big": 8, "legacy": 0},
+			expectedErrors: []string{
+				`nodePools.legacy.gpuCount: old format field`,
+				`nodePools.legacy.gpuProduct: old format field, ignored as the topology uses the gpu block`,
+				`nodePools.default.gpu.backend: unsupported value "mok"`,
+				`nodePools.big.gpu.profile: fragment "big-memory": device_defaults.memory.total_bytes: must be a number of bytes, got string "80GB"`,
+				`nodePools.default.gpu.overrides.device_defaults.name: must be a string, got number 100`,
+				`nodePools.legacy.gpu: the pool has no GPUs, set gpu.profile or gpu.overrides`,
+				`nodePools.missing.gpu.profile: failed to resolve profile "b300"`,
+			},
+		},
+		"duplicate device UUIDs": {
+			topology: `
+nodePools:
+  default:
+    gpu:
+      backend: fake
+      profile: a100
+      overrides:
+        device_count: 3
+        devices:
+          - uuid: GPU-11111111-1111-1111-1111-111111111111
+          - uuid: GPU-22222222-2222-2222-2222-222222222222
+          - uuid: GPU-11111111-1111-1111-1111-111111111111
+`,
+			expectedPools: map[string]int{"default": 3},
+			expectedErrors: []string{
+				`nodePools.default.gpu.overrides.devices[2].uuid: "GPU-11111111-1111-1111-1111-111111111111" is already set on devices[0]`,
+			},
+		},
+		"unparsable": {
+			topology:       "nodePools: [",
+			expectedErrors: []string{"failed to parse old format topology"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			report := ValidateTopology(client, "gpu-operator", []byte(test.topology))
+
+			require.Len(t, report.Errors, len(test.expectedErrors), "%v", report.Errors)
+			for idx, expected := range test.expectedErrors {
+				assert.Contains(t, report.Errors[idx].Error(), expected)
+			}
+
+			gpuCounts := map[string]int{}
+			for pool, resolved := range report.Pools {
+				gpuCounts[pool] = resolved.GpuCount
+			}
+			if test.expectedPools == nil {
+				test.expectedPools = map[string]int{}
+			}
+			assert.Equal(t, test.expectedPools, gpuCounts)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package topology
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EnvVisibleDevicePrefix prefixes the per-device environment variables the
+// DRA plugin injects through a claim's CDI spec. Each variable names a single
+// device so that several claims on one container never overwrite each other.
+const EnvVisibleDevicePrefix = "FAKE_GPU_VISIBLE_DEVICE_"
+
+// VisibleDevice is a GPU (or MIG device) allocated to a container, as seen
+// by the fake nvidia-smi.
+type VisibleDevice struct {
+	UUID  string
+	Index int
+	// MigProfile and ParentUUID are set when the device is a MIG instance of
+	// the GPU ParentUUID.
+	MigProfile string
+	ParentUUID string
+}
+
+// EnvValue encodes the device as
+// "uuid=<uuid>,index=<index>[,mig=<profile>][,parent=<uuid>]".
+func (d VisibleDevice) EnvValue() string {
+	value := fmt.Sprintf("uuid=%s,index=%d", d.UUID, d.Index)
+	if d.MigProfile != "" {
+		value += ",mig=" + d.MigProfile
+	}
+	if d.ParentUUID != "" {
+		value += ",parent=" + d.ParentUUID
+	}
+	return value
+}
+
+// GpuUUID returns the UUID of the GPU the device is, or is an instance of.
+func (d VisibleDevice) GpuUUID() string {
+	if d.ParentUUID != "" {
+		return d.ParentUUID
+	}
+	return d.UUID
+}
+
+// ParseVisibleDevice decodes a value produced by EnvValue.
+func ParseVisibleDevice(value string) (VisibleDevice, error) {
+	var device VisibleDevice
+	hasIndex := false
+	for _, field := range strings.Split(value, ",") {
+		key, val, ok := strings.Cut(field, "=")
+		if !ok {
+			return VisibleDevice{}, fmt.Errorf("malformed visible device field %q", field)
+		}
+		switch key {
+		case "uuid":
+			device.UUID = val
+		case "index":
+			index, err := strconv.Atoi(val)
+			if err != nil {
+				return VisibleDevice{}, fmt.Errorf("invalid visible device index %q: %w", val, err)
+			}
+			device.Index = index
+			hasIndex = true
+		case "mig":
+			device.MigProfile = val
+		case "parent":
+			device.ParentUUID = val
+		}
+	}
+	if device.UUID == "" || !hasIndex {
+		return VisibleDevice{}, fmt.Errorf("visible device %q must have a uuid and an index", value)
+	}
+	return device, nil
+}
+
+// VisibleDevicesFromEnv collects the visible devices from environment entries
+// in "KEY=value" form, ordered by index. Devices listed more than once (e.g.
+// the same GPU shared by two claims) are returned once.
+func VisibleDevicesFromEnv(environ []string) ([]VisibleDevice, []error) {
+	var errs []error
+	seen := make(map[string]bool)
+	var devices []VisibleDevice
+	for _, entry := range environ {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok || !strings.HasPrefix(key, EnvVisibleDevicePrefix) {
+			continue
+		}
+		device, err := ParseVisibleDevice(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			continue
+		}
+		if seen[strings.ToLower(device.UUID)] {
+			continue
+		}
+		seen[strings.ToLower(device.UUID)] = true
+		devices = append(devices, device)
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].Index < devices[j].Index
+	})
+	return devices, errs
+}
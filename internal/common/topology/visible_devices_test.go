@@ -0,0 +1,49 @@
+package topology
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVisibleDeviceRoundTrip(t *testing.T) {
+	for _, device := range []VisibleDevice{
+		{UUID: "GPU-1234", Index: 2},
+		{UUID: "MIG-5678", Index: 0, MigProfile: "1g.10gb", ParentUUID: "GPU-5678"},
+	} {
+		parsed, err := ParseVisibleDevice(device.EnvValue())
+		require.NoError(t, err)
+		assert.Equal(t, device, parsed)
+	}
+}
+
+func TestParseVisibleDeviceErrors(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"GPU-1234",
+		"uuid=GPU-1234",
+		"index=1",
+		"uuid=GPU-1234,index=one",
+	} {
+		_, err := ParseVisibleDevice(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestVisibleDevicesFromEnv(t *testing.T) {
+	devices, errs := VisibleDevicesFromEnv([]string{
+		"PATH=/usr/bin",
+		EnvVisibleDevicePrefix + "gpu_b=uuid=GPU-B,index=1",
+		EnvVisibleDevicePrefix + "gpu_a=uuid=GPU-A,index=0",
+		// The same GPU injected by a second claim.
+		EnvVisibleDevicePrefix + "GPU_A=uuid=gpu-a,index=0",
+		EnvVisibleDevicePrefix + "broken=index=7",
+	})
+
+	assert.Len(t, errs, 1)
+	assert.Equal(t, []VisibleDevice{
+		{UUID: "GPU-A", Index: 0},
+		{UUID: "GPU-B", Index: 1},
+	}, devices)
+}
@@ -3,6 +3,7 @@ package computedomaincontroller
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"go.uber.org/zap/zapcore"
 	resourceapi "k8s.io/api/resource/v1"
@@ -14,6 +15,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
 	computedomainv1beta1 "github.com/NVIDIA/k8s-dra-driver-gpu/api/nvidia.com/resource/v1beta1"
@@ -35,6 +37,12 @@ type Config struct {
 	MetricsBindAddress string `mapstructure:"METRICS_BIND_ADDRESS"`
 	HealthProbeAddress string `mapstructure:"HEALTH_PROBE_BIND_ADDRESS"`
 	LeaderElection     bool   `mapstructure:"LEADER_ELECT"`
+
+	// IMEX daemon simulation. When disabled, nodes of a ComputeDomain turn
+	// Ready as soon as a claim is allocated on them.
+	IMEXSimulationEnabled        bool          `mapstructure:"IMEX_SIMULATION_ENABLED"`
+	IMEXDaemonStartLatency       time.Duration `mapstructure:"IMEX_DAEMON_START_LATENCY"`
+	IMEXDaemonFailureProbability float64       `mapstructure:"IMEX_DAEMON_FAILURE_PROBABILITY"`
 }
 
 type ComputeDomainApp struct {
@@ -56,6 +64,8 @@ func (app *ComputeDomainApp) GetConfig() interface{} {
 			MetricsBindAddress: ":8080",
 			HealthProbeAddress: ":8081",
 			LeaderElection:     false,
+
+			IMEXDaemonStartLatency: 5 * time.Second,
 		}
 	}
 	return app.config
@@ -115,13 +125,21 @@ func (app *ComputeDomainApp) runController(ctx context.Context) error {
 	}
 
 	reconciler := &ComputeDomainReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("compute-domain-controller"),
+	}
+	if app.config.IMEXSimulationEnabled {
+		reconciler.IMEXSimulation = NewIMEXSimulation(app.config.IMEXDaemonStartLatency, app.config.IMEXDaemonFailureProbability)
 	}
 	if err := reconciler.SetupWithManager(mgr); err != nil {
 		return fmt.Errorf("failed to setup reconciler: %w", err)
 	}
 
+	if err := ctrlmetrics.Registry.Register(newComputeDomainCollector(mgr.GetCache())); err != nil {
+		return fmt.Errorf("failed to register metrics: %w", err)
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		return fmt.Errorf("failed to add health check: %w", err)
 	}
@@ -19,13 +19,18 @@ package computedomaincontroller
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
 	"sort"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	resourceapi "k8s.io/api/resource/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -33,6 +38,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	computedomainv1beta1 "github.com/NVIDIA/k8s-dra-driver-gpu/api/nvidia.com/resource/v1beta1"
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
 	"github.com/run-ai/fake-gpu-operator/pkg/compute-domain/consts"
 )
 
@@ -56,6 +62,11 @@ func isOwnedBy(template *resourceapi.ResourceClaimTemplate, domain *computedomai
 type ComputeDomainReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// Recorder, if set, receives IMEX daemon and domain readiness events.
+	Recorder record.EventRecorder
+	// IMEXSimulation, if set, delays node readiness by a simulated IMEX
+	// daemon rollout instead of marking nodes Ready once allocated.
+	IMEXSimulation *IMEXSimulation
 }
 
 //+kubebuilder:rbac:groups=resource.nvidia.com,resources=computedomains,verbs=get;list;watch;create;update;patch;delete
@@ -63,6 +74,8 @@ type ComputeDomainReconciler struct {
 //+kubebuilder:rbac:groups=resource.nvidia.com,resources=computedomains/finalizers,verbs=update
 //+kubebuilder:rbac:groups=resource.k8s.io,resources=resourceclaimtemplates,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=resource.k8s.io,resources=resourceclaims,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 func (r *ComputeDomainReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
@@ -85,12 +98,13 @@ func (r *ComputeDomainReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	if err := r.ensureResourceClaimTemplates(ctx, domain); err != nil {
 		return ctrl.Result{}, err
 	}
-	if err := r.updateStatus(ctx, domain); err != nil {
+	requeueAfter, err := r.updateStatus(ctx, domain)
+	if err != nil {
 		return ctrl.Result{}, err
 	}
 
 	logger.V(4).Info("reconciled ComputeDomain", "namespace", domain.Namespace, "name", domain.Name)
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
 func (r *ComputeDomainReconciler) ensureFinalizer(ctx context.Context, domain *computedomainv1beta1.ComputeDomain) error {
@@ -110,6 +124,9 @@ func (r *ComputeDomainReconciler) handleDeletion(ctx context.Context, domain *co
 	if err := r.deleteResourceClaimTemplates(ctx, domain); err != nil {
 		return err
 	}
+	if r.IMEXSimulation != nil {
+		r.IMEXSimulation.forgetDomain(domain.UID)
+	}
 
 	controllerutil.RemoveFinalizer(domain, consts.ComputeDomainFinalizer)
 	return r.Update(ctx, domain)
@@ -262,13 +279,16 @@ func (r *ComputeDomainReconciler) mapResourceClaimToComputeDomain(ctx context.Co
 	}}
 }
 
-func (r *ComputeDomainReconciler) updateStatus(ctx context.Context, domain *computedomainv1beta1.ComputeDomain) error {
+// updateStatus recomputes the domain's per-node and overall status. It returns
+// how long until the status should be recomputed while simulated IMEX daemons
+// are still starting.
+func (r *ComputeDomainReconciler) updateStatus(ctx context.Context, domain *computedomainv1beta1.ComputeDomain) (time.Duration, error) {
 	claimList := &resourceapi.ResourceClaimList{}
 	if err := r.List(ctx, claimList,
 		client.InNamespace(domain.Namespace),
 		client.MatchingLabels{consts.ComputeDomainClaimLabel: domain.Name},
 	); err != nil {
-		return err
+		return 0, err
 	}
 
 	nodeSet := make(map[string]struct{})
@@ -283,29 +303,81 @@ func (r *ComputeDomainReconciler) updateStatus(ctx context.Context, domain *comp
 		}
 	}
 
+	wasReady := make(map[string]bool, len(domain.Status.Nodes))
+	for _, node := range domain.Status.Nodes {
+		wasReady[node.Name] = node.Status == computedomainv1beta1.ComputeDomainStatusReady
+	}
+
+	var requeueAfter time.Duration
 	nodes := make([]*computedomainv1beta1.ComputeDomainNode, 0, len(nodeSet))
 	for nodeName := range nodeSet {
+		cliqueID, ipAddress, err := r.nodeInfo(ctx, nodeName)
+		if err != nil {
+			return 0, err
+		}
+
+		nodeStatus := computedomainv1beta1.ComputeDomainStatusReady
+		if r.IMEXSimulation != nil {
+			var event daemonEvent
+			var wait time.Duration
+			nodeStatus, event, wait = r.IMEXSimulation.daemonStatus(domain.UID, nodeName, wasReady[nodeName])
+			r.recordDaemonEvent(domain, nodeName, event)
+			if wait > 0 && (requeueAfter == 0 || wait < requeueAfter) {
+				requeueAfter = wait
+			}
+		}
+
 		nodes = append(nodes, &computedomainv1beta1.ComputeDomainNode{
-			Name:   nodeName,
-			Status: computedomainv1beta1.ComputeDomainStatusReady,
+			Name:      nodeName,
+			IPAddress: ipAddress,
+			CliqueID:  cliqueID,
+			Status:    nodeStatus,
 		})
 	}
 	sort.Slice(nodes, func(i, j int) bool {
 		return nodes[i].Name < nodes[j].Name
 	})
+	assignNodeIndices(nodes, domain.Status.Nodes)
+	if r.IMEXSimulation != nil {
+		r.IMEXSimulation.forgetNodes(domain.UID, nodeSet)
+	}
 
 	status := computedomainv1beta1.ComputeDomainStatusNotReady
 	if domain.Spec.NumNodes == 0 || len(nodes) >= domain.Spec.NumNodes {
 		status = computedomainv1beta1.ComputeDomainStatusReady
 	}
 
+	// A domain cannot span NVLink cliques: nodes outside the domain's clique
+	// are NotReady, and so is the domain.
+	domainClique := dominantClique(nodes)
+	for _, node := range nodes {
+		if node.CliqueID != domainClique {
+			log.FromContext(ctx).Info("node is outside the ComputeDomain's NVLink clique",
+				"domain", domain.Name, "node", node.Name, "nodeClique", node.CliqueID, "domainClique", domainClique)
+			node.Status = computedomainv1beta1.ComputeDomainStatusNotReady
+		}
+		if node.Status != computedomainv1beta1.ComputeDomainStatusReady {
+			status = computedomainv1beta1.ComputeDomainStatusNotReady
+		}
+	}
+
+	if err := r.updateGPUAnnotation(ctx, domain, domainGPUs(ctx, claimList.Items)); err != nil {
+		return 0, err
+	}
+
 	if !r.statusEqual(domain.Status, nodes, status) {
+		becameReady := status == computedomainv1beta1.ComputeDomainStatusReady && domain.Status.Status != status
 		domain.Status.Nodes = nodes
 		domain.Status.Status = status
-		return r.Status().Update(ctx, domain)
+		if err := r.Status().Update(ctx, domain); err != nil {
+			return 0, err
+		}
+		if becameReady {
+			r.event(domain, corev1.EventTypeNormal, "ComputeDomainReady", "All %d node(s) of the ComputeDomain are ready", len(nodes))
+		}
 	}
 
-	return nil
+	return requeueAfter, nil
 }
 
 func (r *ComputeDomainReconciler) statusEqual(current computedomainv1beta1.ComputeDomainStatus, newNodes []*computedomainv1beta1.ComputeDomainNode, newStatus string) bool {
@@ -316,13 +388,104 @@ func (r *ComputeDomainReconciler) statusEqual(current computedomainv1beta1.Compu
 		return false
 	}
 	for i, node := range current.Nodes {
-		if node.Name != newNodes[i].Name {
+		if *node != *newNodes[i] {
 			return false
 		}
 	}
 	return true
 }
 
+// nodeInfo returns the NVLink clique and internal IP of a node. The clique is
+// "" when the node has no NVLink fabric; both are "" if the node is gone.
+func (r *ComputeDomainReconciler) nodeInfo(ctx context.Context, nodeName string) (cliqueID, ipAddress string, err error) {
+	node := &corev1.Node{}
+	if err := r.Get(ctx, client.ObjectKey{Name: nodeName}, node); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", "", nil
+		}
+		return "", "", err
+	}
+	for _, address := range node.Status.Addresses {
+		if address.Type == corev1.NodeInternalIP {
+			ipAddress = address.Address
+			break
+		}
+	}
+	return node.Labels[constants.LabelGpuClique], ipAddress, nil
+}
+
+// assignNodeIndices gives every node an index unique within its clique,
+// keeping the index a node already had so its IMEX DNS name stays stable.
+func assignNodeIndices(nodes, previous []*computedomainv1beta1.ComputeDomainNode) {
+	previousIndex := make(map[string]int)
+	for _, node := range previous {
+		previousIndex[node.CliqueID+"/"+node.Name] = node.Index
+	}
+
+	taken := make(map[string]map[int]bool)
+	var unassigned []*computedomainv1beta1.ComputeDomainNode
+	for _, node := range nodes {
+		if taken[node.CliqueID] == nil {
+			taken[node.CliqueID] = make(map[int]bool)
+		}
+		index, ok := previousIndex[node.CliqueID+"/"+node.Name]
+		if ok && !taken[node.CliqueID][index] {
+			node.Index = index
+			taken[node.CliqueID][index] = true
+			continue
+		}
+		unassigned = append(unassigned, node)
+	}
+
+	for _, node := range unassigned {
+		index := 0
+		for taken[node.CliqueID][index] {
+			index++
+		}
+		node.Index = index
+		taken[node.CliqueID][index] = true
+	}
+}
+
+func (r *ComputeDomainReconciler) recordDaemonEvent(domain *computedomainv1beta1.ComputeDomain, nodeName string, event daemonEvent) {
+	switch event {
+	case daemonStarted:
+		r.event(domain, corev1.EventTypeNormal, "IMEXDaemonStarting", "IMEX daemon starting on node %s", nodeName)
+	case daemonFailed:
+		r.event(domain, corev1.EventTypeWarning, "IMEXDaemonFailed", "IMEX daemon failed on node %s, restarting", nodeName)
+	case daemonReady:
+		r.event(domain, corev1.EventTypeNormal, "IMEXDaemonReady", "IMEX daemon ready on node %s", nodeName)
+	}
+}
+
+func (r *ComputeDomainReconciler) event(domain *computedomainv1beta1.ComputeDomain, eventType, reason, messageFmt string, args ...any) {
+	if r.Recorder != nil {
+		r.Recorder.Eventf(domain, eventType, reason, messageFmt, args...)
+	}
+}
+
+// dominantClique returns the clique holding most of the domain's nodes. Ties
+// prefer a real clique over nodes without a fabric, then the lowest clique ID,
+// so the choice is stable across reconciles.
+func dominantClique(nodes []*computedomainv1beta1.ComputeDomainNode) string {
+	members := make(map[string]int)
+	for _, node := range nodes {
+		members[node.CliqueID]++
+	}
+
+	cliqueIDs := slices.Sorted(maps.Keys(members))
+	var dominant string
+	found := false
+	for _, cliqueID := range cliqueIDs {
+		count := members[cliqueID]
+		if !found || count > members[dominant] || (count == members[dominant] && dominant == "") {
+			dominant = cliqueID
+			found = true
+		}
+	}
+	return dominant
+}
+
 func templateName(domain *computedomainv1beta1.ComputeDomain) string {
 	templateName := domain.Name
 	if domain.Spec.Channel != nil {
@@ -24,14 +24,17 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	corev1 "k8s.io/api/core/v1"
 	resourceapi "k8s.io/api/resource/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
 	controller "github.com/run-ai/fake-gpu-operator/internal/compute-domain-controller"
 
 	computedomainv1beta1 "github.com/NVIDIA/k8s-dra-driver-gpu/api/nvidia.com/resource/v1beta1"
@@ -219,6 +222,7 @@ func TestComputeDomainReconciler_Reconcile_NotFound(t *testing.T) {
 
 func TestComputeDomainReconciler_StatusUpdate(t *testing.T) {
 	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
 	_ = resourceapi.AddToScheme(scheme)
 	_ = computedomainv1beta1.AddToScheme(scheme)
 
@@ -456,3 +460,320 @@ func createUnallocatedResourceClaim(name, namespace, domainName string) *resourc
 		Status: resourceapi.ResourceClaimStatus{},
 	}
 }
+
+func TestComputeDomainReconciler_StatusUpdate_NvLinkCliques(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = resourceapi.AddToScheme(scheme)
+	_ = computedomainv1beta1.AddToScheme(scheme)
+
+	tests := map[string]struct {
+		nodeCliques        map[string]string
+		expectedStatus     string
+		expectedNodeStatus map[string]string
+	}{
+		"all nodes in one clique": {
+			nodeCliques:    map[string]string{"node-1": "cluster.0", "node-2": "cluster.0"},
+			expectedStatus: computedomainv1beta1.ComputeDomainStatusReady,
+			expectedNodeStatus: map[string]string{
+				"node-1": computedomainv1beta1.ComputeDomainStatusReady,
+				"node-2": computedomainv1beta1.ComputeDomainStatusReady,
+			},
+		},
+		"nodes span cliques": {
+			nodeCliques:    map[string]string{"node-1": "cluster.0", "node-2": "cluster.0", "node-3": "cluster.1"},
+			expectedStatus: computedomainv1beta1.ComputeDomainStatusNotReady,
+			expectedNodeStatus: map[string]string{
+				"node-1": computedomainv1beta1.ComputeDomainStatusReady,
+				"node-2": computedomainv1beta1.ComputeDomainStatusReady,
+				"node-3": computedomainv1beta1.ComputeDomainStatusNotReady,
+			},
+		},
+		"node without fabric joins clique": {
+			nodeCliques:    map[string]string{"node-1": "cluster.0", "node-2": ""},
+			expectedStatus: computedomainv1beta1.ComputeDomainStatusNotReady,
+			expectedNodeStatus: map[string]string{
+				"node-1": computedomainv1beta1.ComputeDomainStatusReady,
+				"node-2": computedomainv1beta1.ComputeDomainStatusNotReady,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			domain := &computedomainv1beta1.ComputeDomain{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test-domain",
+					Namespace:  "default",
+					UID:        "test-uid",
+					Finalizers: []string{consts.ComputeDomainFinalizer},
+				},
+				Spec: computedomainv1beta1.ComputeDomainSpec{
+					NumNodes: 2,
+				},
+			}
+			objs := []client.Object{domain}
+			for nodeName, cliqueID := range test.nodeCliques {
+				node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}}
+				if cliqueID != "" {
+					node.Labels = map[string]string{constants.LabelGpuClique: cliqueID}
+				}
+				objs = append(objs, node, createAllocatedResourceClaim("claim-"+nodeName, "default", domain.Name, nodeName))
+			}
+
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(objs...).
+				WithStatusSubresource(domain).
+				Build()
+
+			reconciler := &controller.ComputeDomainReconciler{
+				Client: fakeClient,
+				Scheme: scheme,
+			}
+
+			req := ctrl.Request{NamespacedName: types.NamespacedName{Name: domain.Name, Namespace: domain.Namespace}}
+			_, err := reconciler.Reconcile(context.Background(), req)
+			require.NoError(t, err)
+
+			updatedDomain := &computedomainv1beta1.ComputeDomain{}
+			require.NoError(t, fakeClient.Get(context.Background(), req.NamespacedName, updatedDomain))
+
+			assert.Equal(t, test.expectedStatus, updatedDomain.Status.Status)
+			nodeStatus := make(map[string]string)
+			for _, node := range updatedDomain.Status.Nodes {
+				nodeStatus[node.Name] = node.Status
+				assert.Equal(t, test.nodeCliques[node.Name], node.CliqueID)
+			}
+			assert.Equal(t, test.expectedNodeStatus, nodeStatus)
+		})
+	}
+}
+
+func TestComputeDomainReconciler_StatusUpdate_NodeDetails(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = resourceapi.AddToScheme(scheme)
+	_ = computedomainv1beta1.AddToScheme(scheme)
+
+	domain := &computedomainv1beta1.ComputeDomain{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-domain",
+			Namespace:  "default",
+			UID:        "test-uid",
+			Finalizers: []string{consts.ComputeDomainFinalizer},
+		},
+		Spec: computedomainv1beta1.ComputeDomainSpec{
+			NumNodes: 3,
+		},
+		Status: computedomainv1beta1.ComputeDomainStatus{
+			Nodes: []*computedomainv1beta1.ComputeDomainNode{
+				{Name: "node-b", CliqueID: "cluster.0", Index: 0},
+			},
+		},
+	}
+	objs := []client.Object{domain}
+	nodeIPs := map[string]string{"node-a": "10.0.0.1", "node-b": "10.0.0.2", "node-c": "10.0.0.3"}
+	for nodeName, ip := range nodeIPs {
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   nodeName,
+				Labels: map[string]string{constants.LabelGpuClique: "cluster.0"},
+			},
+			Status: corev1.NodeStatus{
+				Addresses: []corev1.NodeAddress{
+					{Type: corev1.NodeHostName, Address: nodeName},
+					{Type: corev1.NodeInternalIP, Address: ip},
+				},
+			},
+		}
+		objs = append(objs, node, createAllocatedResourceClaim("claim-"+nodeName, "default", domain.Name, nodeName))
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithStatusSubresource(domain).
+		Build()
+
+	reconciler := &controller.ComputeDomainReconciler{
+		Client: fakeClient,
+		Scheme: scheme,
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: domain.Name, Namespace: domain.Namespace}}
+	_, err := reconciler.Reconcile(context.Background(), req)
+	require.NoError(t, err)
+
+	updatedDomain := &computedomainv1beta1.ComputeDomain{}
+	require.NoError(t, fakeClient.Get(context.Background(), req.NamespacedName, updatedDomain))
+
+	require.Len(t, updatedDomain.Status.Nodes, 3)
+	// node-b keeps the index it already had; the others fill the free ones.
+	expectedIndex := map[string]int{"node-a": 1, "node-b": 0, "node-c": 2}
+	for _, node := range updatedDomain.Status.Nodes {
+		assert.Equal(t, nodeIPs[node.Name], node.IPAddress, node.Name)
+		assert.Equal(t, expectedIndex[node.Name], node.Index, node.Name)
+	}
+}
+
+func TestComputeDomainReconciler_StatusUpdate_IMEXSimulation(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = resourceapi.AddToScheme(scheme)
+	_ = computedomainv1beta1.AddToScheme(scheme)
+
+	domain := &computedomainv1beta1.ComputeDomain{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-domain",
+			Namespace:  "default",
+			UID:        "test-uid",
+			Finalizers: []string{consts.ComputeDomainFinalizer},
+		},
+		Spec: computedomainv1beta1.ComputeDomainSpec{
+			NumNodes: 1,
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(domain, createAllocatedResourceClaim("claim-1", "default", domain.Name, "node-1")).
+		WithStatusSubresource(domain).
+		Build()
+
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &controller.ComputeDomainReconciler{
+		Client:         fakeClient,
+		Scheme:         scheme,
+		Recorder:       recorder,
+		IMEXSimulation: controller.NewIMEXSimulation(time.Hour, 0),
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: domain.Name, Namespace: domain.Namespace}}
+	result, err := reconciler.Reconcile(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, result.RequeueAfter)
+
+	updatedDomain := &computedomainv1beta1.ComputeDomain{}
+	require.NoError(t, fakeClient.Get(context.Background(), req.NamespacedName, updatedDomain))
+	assert.Equal(t, computedomainv1beta1.ComputeDomainStatusNotReady, updatedDomain.Status.Status)
+	require.Len(t, updatedDomain.Status.Nodes, 1)
+	assert.Equal(t, computedomainv1beta1.ComputeDomainStatusNotReady, updatedDomain.Status.Nodes[0].Status)
+
+	require.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, "IMEXDaemonStarting")
+}
+
+func TestComputeDomainReconciler_StatusUpdate_IMEXSimulationRestart(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = resourceapi.AddToScheme(scheme)
+	_ = computedomainv1beta1.AddToScheme(scheme)
+
+	domain := &computedomainv1beta1.ComputeDomain{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-domain",
+			Namespace:  "default",
+			UID:        "test-uid",
+			Finalizers: []string{consts.ComputeDomainFinalizer},
+		},
+		Spec: computedomainv1beta1.ComputeDomainSpec{
+			NumNodes: 2,
+		},
+		Status: computedomainv1beta1.ComputeDomainStatus{
+			Status: computedomainv1beta1.ComputeDomainStatusReady,
+			Nodes: []*computedomainv1beta1.ComputeDomainNode{
+				{Name: "node-1", Status: computedomainv1beta1.ComputeDomainStatusReady},
+			},
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(domain,
+			createAllocatedResourceClaim("claim-1", "default", domain.Name, "node-1"),
+			createAllocatedResourceClaim("claim-2", "default", domain.Name, "node-2")).
+		WithStatusSubresource(domain).
+		Build()
+
+	// A restarted controller starts with no daemons, and every start would fail.
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &controller.ComputeDomainReconciler{
+		Client:         fakeClient,
+		Scheme:         scheme,
+		Recorder:       recorder,
+		IMEXSimulation: controller.NewIMEXSimulation(time.Hour, 1),
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: domain.Name, Namespace: domain.Namespace}}
+	_, err := reconciler.Reconcile(context.Background(), req)
+	require.NoError(t, err)
+
+	updatedDomain := &computedomainv1beta1.ComputeDomain{}
+	require.NoError(t, fakeClient.Get(context.Background(), req.NamespacedName, updatedDomain))
+	require.Len(t, updatedDomain.Status.Nodes, 2)
+	nodeStatus := map[string]string{}
+	for _, node := range updatedDomain.Status.Nodes {
+		nodeStatus[node.Name] = node.Status
+	}
+	assert.Equal(t, computedomainv1beta1.ComputeDomainStatusReady, nodeStatus["node-1"], "the running daemon is kept")
+	assert.Equal(t, computedomainv1beta1.ComputeDomainStatusNotReady, nodeStatus["node-2"], "the new node starts its daemon")
+
+	require.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, "node-2")
+}
+
+func TestComputeDomainReconciler_StatusUpdate_BoundGPUs(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = resourceapi.AddToScheme(scheme)
+	_ = computedomainv1beta1.AddToScheme(scheme)
+
+	domain := &computedomainv1beta1.ComputeDomain{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-domain",
+			Namespace:  "default",
+			UID:        "test-uid",
+			Finalizers: []string{consts.ComputeDomainFinalizer},
+		},
+	}
+	withGPUs := func(claim *resourceapi.ResourceClaim, data string) *resourceapi.ResourceClaim {
+		result := claim.Status.Allocation.Devices.Results[0]
+		claim.Status.Devices = []resourceapi.AllocatedDeviceStatus{{
+			Driver: consts.ComputeDomainDriverName,
+			Pool:   result.Pool,
+			Device: result.Device,
+			Data:   &runtime.RawExtension{Raw: []byte(data)},
+		}}
+		return claim
+	}
+	objs := []client.Object{
+		domain,
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}},
+		withGPUs(createAllocatedResourceClaim("claim-a", "default", domain.Name, "node-1"), `{"domainID":"test-uid","channel":0,"gpus":["gpu-1","gpu-0"]}`),
+		withGPUs(createAllocatedResourceClaim("claim-b", "default", domain.Name, "node-1"), `{"domainID":"test-uid","channel":0,"gpus":["gpu-1","gpu-2"]}`),
+		withGPUs(createAllocatedResourceClaim("claim-c", "default", domain.Name, "node-2"), `{"domainID":"test-uid","channel":0}`),
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithStatusSubresource(domain).
+		Build()
+
+	reconciler := &controller.ComputeDomainReconciler{
+		Client: fakeClient,
+		Scheme: scheme,
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: domain.Name, Namespace: domain.Namespace}}
+	_, err := reconciler.Reconcile(context.Background(), req)
+	require.NoError(t, err)
+
+	updatedDomain := &computedomainv1beta1.ComputeDomain{}
+	require.NoError(t, fakeClient.Get(context.Background(), req.NamespacedName, updatedDomain))
+	assert.JSONEq(t, `{"node-1":["gpu-0","gpu-1","gpu-2"]}`, updatedDomain.Annotations[constants.AnnotationComputeDomainGpus])
+	assert.Equal(t, computedomainv1beta1.ComputeDomainStatusReady, updatedDomain.Status.Status)
+	assert.Len(t, updatedDomain.Status.Nodes, 2)
+}
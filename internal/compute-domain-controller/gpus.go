@@ -0,0 +1,96 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package computedomaincontroller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	computedomainv1beta1 "github.com/NVIDIA/k8s-dra-driver-gpu/api/nvidia.com/resource/v1beta1"
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+	"github.com/run-ai/fake-gpu-operator/pkg/compute-domain/consts"
+)
+
+// channelDeviceData is the part of the channel status data the compute-domain
+// plugin publishes that lists the GPUs bound to the channel.
+type channelDeviceData struct {
+	GPUs []string `json:"gpus"`
+}
+
+// domainGPUs collects, per node, the GPUs the compute-domain plugin bound to
+// the domain's channel claims. Nodes without bound GPUs are left out.
+func domainGPUs(ctx context.Context, claims []resourceapi.ResourceClaim) map[string][]string {
+	gpusByNode := make(map[string]sets.Set[string])
+	for _, claim := range claims {
+		for _, device := range claim.Status.Devices {
+			if device.Driver != consts.ComputeDomainDriverName || device.Data == nil {
+				continue
+			}
+			var data channelDeviceData
+			if err := json.Unmarshal(device.Data.Raw, &data); err != nil {
+				log.FromContext(ctx).Info("ignoring undecodable channel status data", "claim", claim.Name, "error", err.Error())
+				continue
+			}
+			if len(data.GPUs) == 0 {
+				continue
+			}
+			if gpusByNode[device.Pool] == nil {
+				gpusByNode[device.Pool] = sets.New[string]()
+			}
+			gpusByNode[device.Pool].Insert(data.GPUs...)
+		}
+	}
+
+	result := make(map[string][]string, len(gpusByNode))
+	for node, gpus := range gpusByNode {
+		result[node] = sets.List(gpus)
+	}
+	return result
+}
+
+// updateGPUAnnotation publishes gpusByNode in the domain's
+// AnnotationComputeDomainGpus annotation. The upstream ComputeDomain status
+// has no field for it.
+func (r *ComputeDomainReconciler) updateGPUAnnotation(ctx context.Context, domain *computedomainv1beta1.ComputeDomain, gpusByNode map[string][]string) error {
+	var value string
+	if len(gpusByNode) > 0 {
+		raw, err := json.Marshal(gpusByNode)
+		if err != nil {
+			return fmt.Errorf("failed to encode ComputeDomain GPUs: %w", err)
+		}
+		value = string(raw)
+	}
+
+	current, found := domain.Annotations[constants.AnnotationComputeDomainGpus]
+	if current == value && found == (value != "") {
+		return nil
+	}
+	if value == "" {
+		delete(domain.Annotations, constants.AnnotationComputeDomainGpus)
+	} else {
+		if domain.Annotations == nil {
+			domain.Annotations = make(map[string]string)
+		}
+		domain.Annotations[constants.AnnotationComputeDomainGpus] = value
+	}
+	return r.Update(ctx, domain)
+}
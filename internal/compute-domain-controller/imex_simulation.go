@@ -0,0 +1,140 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package computedomaincontroller
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	computedomainv1beta1 "github.com/NVIDIA/k8s-dra-driver-gpu/api/nvidia.com/resource/v1beta1"
+)
+
+type daemonEvent int
+
+const (
+	daemonUnchanged daemonEvent = iota
+	daemonStarted
+	daemonFailed
+	daemonReady
+)
+
+// minDaemonRequeue bounds how soon a starting daemon is checked again, so a
+// zero StartLatency still gets the domain requeued.
+const minDaemonRequeue = time.Second
+
+// IMEXSimulation simulates the per-node IMEX daemon rollout of NVIDIA's DRA
+// driver. A node of a ComputeDomain only turns Ready StartLatency after its
+// daemon started; each start fails with FailureProbability, in which case the
+// daemon is restarted.
+type IMEXSimulation struct {
+	StartLatency       time.Duration
+	FailureProbability float64
+
+	mu      sync.Mutex
+	daemons map[daemonKey]*imexDaemon
+	rand    func() float64
+	now     func() time.Time
+}
+
+type daemonKey struct {
+	domain types.UID
+	node   string
+}
+
+type imexDaemon struct {
+	startedAt time.Time
+	willFail  bool
+	ready     bool
+}
+
+// NewIMEXSimulation returns a simulation with no daemons running yet.
+func NewIMEXSimulation(startLatency time.Duration, failureProbability float64) *IMEXSimulation {
+	return &IMEXSimulation{
+		StartLatency:       startLatency,
+		FailureProbability: failureProbability,
+		daemons:            make(map[daemonKey]*imexDaemon),
+		rand:               rand.Float64,
+		now:                time.Now,
+	}
+}
+
+// daemonStatus advances the daemon of node in domain and returns its status,
+// the transition that happened, and how long until it should be checked again
+// (zero once the daemon is Ready). wasReady tells whether the domain's status
+// already reports the node Ready: a daemon the simulation does not know yet,
+// e.g. after a controller restart or leader failover, is then taken as
+// running instead of being started again.
+func (s *IMEXSimulation) daemonStatus(domain types.UID, node string, wasReady bool) (string, daemonEvent, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	key := daemonKey{domain: domain, node: node}
+	daemon, exists := s.daemons[key]
+	if !exists && wasReady {
+		s.daemons[key] = &imexDaemon{startedAt: now, ready: true}
+		return computedomainv1beta1.ComputeDomainStatusReady, daemonUnchanged, 0
+	}
+	if !exists {
+		s.daemons[key] = s.start(now)
+		return computedomainv1beta1.ComputeDomainStatusNotReady, daemonStarted, max(s.StartLatency, minDaemonRequeue)
+	}
+	if daemon.ready {
+		return computedomainv1beta1.ComputeDomainStatusReady, daemonUnchanged, 0
+	}
+
+	if elapsed := now.Sub(daemon.startedAt); elapsed < s.StartLatency {
+		return computedomainv1beta1.ComputeDomainStatusNotReady, daemonUnchanged, s.StartLatency - elapsed
+	}
+	if daemon.willFail {
+		s.daemons[key] = s.start(now)
+		return computedomainv1beta1.ComputeDomainStatusNotReady, daemonFailed, max(s.StartLatency, minDaemonRequeue)
+	}
+
+	daemon.ready = true
+	return computedomainv1beta1.ComputeDomainStatusReady, daemonReady, 0
+}
+
+func (s *IMEXSimulation) start(now time.Time) *imexDaemon {
+	return &imexDaemon{
+		startedAt: now,
+		willFail:  s.rand() < s.FailureProbability,
+	}
+}
+
+// forgetNodes drops the daemons of domain on nodes that left it.
+func (s *IMEXSimulation) forgetNodes(domain types.UID, keep map[string]struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key := range s.daemons {
+		if key.domain != domain {
+			continue
+		}
+		if _, ok := keep[key.node]; !ok {
+			delete(s.daemons, key)
+		}
+	}
+}
+
+// forgetDomain drops every daemon of domain.
+func (s *IMEXSimulation) forgetDomain(domain types.UID) {
+	s.forgetNodes(domain, nil)
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package computedomaincontroller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	computedomainv1beta1 "github.com/NVIDIA/k8s-dra-driver-gpu/api/nvidia.com/resource/v1beta1"
+)
+
+func newTestIMEXSimulation(latency time.Duration, failureProbability float64, rolls ...float64) (*IMEXSimulation, *time.Time) {
+	sim := NewIMEXSimulation(latency, failureProbability)
+	now := time.Unix(0, 0)
+	sim.now = func() time.Time { return now }
+	sim.rand = func() float64 {
+		if len(rolls) == 0 {
+			return 1
+		}
+		roll := rolls[0]
+		rolls = rolls[1:]
+		return roll
+	}
+	return sim, &now
+}
+
+func TestIMEXSimulation_DaemonStatus(t *testing.T) {
+	sim, now := newTestIMEXSimulation(5*time.Second, 0)
+
+	status, event, requeue := sim.daemonStatus("uid", "node-1", false)
+	assert.Equal(t, computedomainv1beta1.ComputeDomainStatusNotReady, status)
+	assert.Equal(t, daemonStarted, event)
+	assert.Equal(t, 5*time.Second, requeue)
+
+	*now = now.Add(2 * time.Second)
+	status, event, requeue = sim.daemonStatus("uid", "node-1", false)
+	assert.Equal(t, computedomainv1beta1.ComputeDomainStatusNotReady, status)
+	assert.Equal(t, daemonUnchanged, event)
+	assert.Equal(t, 3*time.Second, requeue)
+
+	*now = now.Add(3 * time.Second)
+	status, event, requeue = sim.daemonStatus("uid", "node-1", false)
+	assert.Equal(t, computedomainv1beta1.ComputeDomainStatusReady, status)
+	assert.Equal(t, daemonReady, event)
+	assert.Zero(t, requeue)
+
+	status, event, _ = sim.daemonStatus("uid", "node-1", false)
+	assert.Equal(t, computedomainv1beta1.ComputeDomainStatusReady, status)
+	assert.Equal(t, daemonUnchanged, event)
+}
+
+func TestIMEXSimulation_DaemonFailureRestarts(t *testing.T) {
+	sim, now := newTestIMEXSimulation(5*time.Second, 0.5, 0.1, 0.9)
+
+	_, event, _ := sim.daemonStatus("uid", "node-1", false)
+	assert.Equal(t, daemonStarted, event)
+
+	*now = now.Add(5 * time.Second)
+	status, event, requeue := sim.daemonStatus("uid", "node-1", false)
+	assert.Equal(t, computedomainv1beta1.ComputeDomainStatusNotReady, status)
+	assert.Equal(t, daemonFailed, event)
+	assert.Equal(t, 5*time.Second, requeue)
+
+	*now = now.Add(5 * time.Second)
+	status, event, _ = sim.daemonStatus("uid", "node-1", false)
+	assert.Equal(t, computedomainv1beta1.ComputeDomainStatusReady, status)
+	assert.Equal(t, daemonReady, event)
+}
+
+func TestIMEXSimulation_Forget(t *testing.T) {
+	sim, _ := newTestIMEXSimulation(0, 0)
+
+	sim.daemonStatus("uid-a", "node-1", false)
+	sim.daemonStatus("uid-a", "node-2", false)
+	sim.daemonStatus("uid-b", "node-1", false)
+
+	sim.forgetNodes("uid-a", map[string]struct{}{"node-1": {}})
+	assert.Contains(t, sim.daemons, daemonKey{domain: "uid-a", node: "node-1"})
+	assert.NotContains(t, sim.daemons, daemonKey{domain: "uid-a", node: "node-2"})
+
+	sim.forgetDomain("uid-a")
+	assert.Len(t, sim.daemons, 1)
+	assert.Contains(t, sim.daemons, daemonKey{domain: "uid-b", node: "node-1"})
+}
+
+func TestIMEXSimulation_DaemonAlreadyReady(t *testing.T) {
+	sim, now := newTestIMEXSimulation(5*time.Second, 1)
+
+	// A restarted controller finds the node Ready in the domain's status.
+	status, event, requeue := sim.daemonStatus("uid", "node-1", true)
+	assert.Equal(t, computedomainv1beta1.ComputeDomainStatusReady, status)
+	assert.Equal(t, daemonUnchanged, event)
+	assert.Zero(t, requeue)
+
+	*now = now.Add(5 * time.Second)
+	status, event, _ = sim.daemonStatus("uid", "node-1", true)
+	assert.Equal(t, computedomainv1beta1.ComputeDomainStatusReady, status)
+	assert.Equal(t, daemonUnchanged, event)
+
+	// A node new to the domain still starts its daemon.
+	status, event, _ = sim.daemonStatus("uid", "node-2", false)
+	assert.Equal(t, computedomainv1beta1.ComputeDomainStatusNotReady, status)
+	assert.Equal(t, daemonStarted, event)
+}
@@ -0,0 +1,58 @@
+package computedomaincontroller
+
+import (
+	"context"
+	"time"
+
+	computedomainv1beta1 "github.com/NVIDIA/k8s-dra-driver-gpu/api/nvidia.com/resource/v1beta1"
+	"github.com/prometheus/client_golang/prometheus"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var computeDomainsDesc = prometheus.NewDesc(
+	"fake_gpu_operator_compute_domains",
+	"Number of ComputeDomains by status.",
+	[]string{"status"}, nil,
+)
+
+// computeDomainCollector counts ComputeDomains by status at scrape time, so
+// the numbers always match the objects in the cluster.
+type computeDomainCollector struct {
+	reader client.Reader
+}
+
+func newComputeDomainCollector(reader client.Reader) *computeDomainCollector {
+	return &computeDomainCollector{reader: reader}
+}
+
+func (c *computeDomainCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- computeDomainsDesc
+}
+
+func (c *computeDomainCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	domains := &computedomainv1beta1.ComputeDomainList{}
+	if err := c.reader.List(ctx, domains); err != nil {
+		ctrl.Log.Error(err, "failed to list ComputeDomains for metrics")
+		return
+	}
+
+	counts := map[string]int{
+		computedomainv1beta1.ComputeDomainStatusReady:    0,
+		computedomainv1beta1.ComputeDomainStatusNotReady: 0,
+	}
+	for _, domain := range domains.Items {
+		status := domain.Status.Status
+		// Domains the controller has not reconciled yet have no status.
+		if status == "" {
+			status = computedomainv1beta1.ComputeDomainStatusNotReady
+		}
+		counts[status]++
+	}
+	for status, count := range counts {
+		ch <- prometheus.MustNewConstMetric(computeDomainsDesc, prometheus.GaugeValue, float64(count), status)
+	}
+}
@@ -0,0 +1,44 @@
+package computedomaincontroller
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	computedomainv1beta1 "github.com/NVIDIA/k8s-dra-driver-gpu/api/nvidia.com/resource/v1beta1"
+)
+
+func TestComputeDomainCollector(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = computedomainv1beta1.AddToScheme(scheme)
+
+	domain := func(name, status string) *computedomainv1beta1.ComputeDomain {
+		return &computedomainv1beta1.ComputeDomain{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Status:     computedomainv1beta1.ComputeDomainStatus{Status: status},
+		}
+	}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(
+			domain("ready-a", computedomainv1beta1.ComputeDomainStatusReady),
+			domain("ready-b", computedomainv1beta1.ComputeDomainStatusReady),
+			domain("not-ready", computedomainv1beta1.ComputeDomainStatusNotReady),
+			domain("new", ""),
+		).
+		Build()
+
+	expected := `
+# HELP fake_gpu_operator_compute_domains Number of ComputeDomains by status.
+# TYPE fake_gpu_operator_compute_domains gauge
+fake_gpu_operator_compute_domains{status="NotReady"} 2
+fake_gpu_operator_compute_domains{status="Ready"} 2
+`
+	err := testutil.CollectAndCompare(newComputeDomainCollector(fakeClient), strings.NewReader(expected))
+	assert.NoError(t, err)
+}
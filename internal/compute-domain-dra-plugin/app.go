@@ -8,8 +8,11 @@ import (
 	"os/signal"
 	"path/filepath"
 	"syscall"
+	"time"
 
+	nvidiaversioned "github.com/NVIDIA/k8s-dra-driver-gpu/pkg/nvidia.com/clientset/versioned"
 	"github.com/run-ai/fake-gpu-operator/internal/common/app"
+	"github.com/run-ai/fake-gpu-operator/internal/common/dra"
 	"github.com/run-ai/fake-gpu-operator/pkg/compute-domain/consts"
 	"go.uber.org/zap/zapcore"
 	coreclientset "k8s.io/client-go/kubernetes"
@@ -32,11 +35,13 @@ type Flags struct {
 	kubeletRegistrarDirectoryPath string
 	kubeletPluginsDirectoryPath   string
 	healthcheckPort               int
+	gcInterval                    time.Duration
 }
 
 type Config struct {
 	flags         *Flags
 	coreclient    coreclientset.Interface
+	domainclient  nvidiaversioned.Interface
 	cancelMainCtx func(error)
 }
 
@@ -50,6 +55,12 @@ type AppConfig struct {
 	KubeletRegistrarDirectoryPath string `mapstructure:"KUBELET_REGISTRAR_DIRECTORY_PATH"`
 	KubeletPluginsDirectoryPath   string `mapstructure:"KUBELET_PLUGINS_DIRECTORY_PATH"`
 	HealthcheckPort               int    `mapstructure:"HEALTHCHECK_PORT"`
+	// GCInterval is how often node state of deleted claims and
+	// ComputeDomains is garbage collected. Zero disables collection.
+	GCInterval time.Duration `mapstructure:"GC_INTERVAL"`
+	// MetricsBindAddress is where Prometheus metrics are served; "0"
+	// disables them.
+	MetricsBindAddress string `mapstructure:"METRICS_BIND_ADDRESS"`
 }
 
 type ComputeDomainDRAPluginApp struct {
@@ -72,6 +83,8 @@ func (app *ComputeDomainDRAPluginApp) GetConfig() interface{} {
 			KubeletRegistrarDirectoryPath: kubeletplugin.KubeletRegistryDir,
 			KubeletPluginsDirectoryPath:   kubeletplugin.KubeletPluginsDir,
 			HealthcheckPort:               -1,
+			GCInterval:                    5 * time.Minute,
+			MetricsBindAddress:            ":8080",
 		}
 	}
 	return app.config
@@ -113,6 +126,14 @@ func (app *ComputeDomainDRAPluginApp) runPlugin(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("create client: %v", err)
 	}
+	restConfig, err := kubeClientConfig.NewClientSetConfig()
+	if err != nil {
+		return fmt.Errorf("create client config: %v", err)
+	}
+	domainClient, err := nvidiaversioned.NewForConfig(restConfig)
+	if err != nil {
+		return fmt.Errorf("create ComputeDomain client: %v", err)
+	}
 
 	config := &Config{
 		flags: &Flags{
@@ -123,8 +144,10 @@ func (app *ComputeDomainDRAPluginApp) runPlugin(ctx context.Context) error {
 			kubeletRegistrarDirectoryPath: app.config.KubeletRegistrarDirectoryPath,
 			kubeletPluginsDirectoryPath:   app.config.KubeletPluginsDirectoryPath,
 			healthcheckPort:               app.config.HealthcheckPort,
+			gcInterval:                    app.config.GCInterval,
 		},
-		coreclient: clientSets.Core,
+		coreclient:   clientSets.Core,
+		domainclient: domainClient,
 	}
 
 	err = os.MkdirAll(config.DriverPluginPath(), 0750)
@@ -150,6 +173,14 @@ func (app *ComputeDomainDRAPluginApp) runPlugin(ctx context.Context) error {
 	ctx, cancel := context.WithCancelCause(ctx)
 	config.cancelMainCtx = cancel
 
+	if app.config.MetricsBindAddress != "0" {
+		go func() {
+			if err := dra.ServeMetrics(ctx, app.config.MetricsBindAddress); err != nil {
+				logger.Error(err, "metrics server stopped")
+			}
+		}()
+	}
+
 	driver, err := NewComputeDomainDriver(ctx, config)
 	if err != nil {
 		return err
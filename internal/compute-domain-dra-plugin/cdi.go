@@ -38,6 +38,7 @@ const (
 
 type ComputeDomainCDIHandler struct {
 	cache        *cdiapi.Cache
+	specDir      string
 	nvcdiDevice  *computeDomainNvcdiDevice
 	deviceRoot   string
 	claimDevName string
@@ -58,6 +59,7 @@ func NewComputeDomainCDIHandler(config *Config) (*ComputeDomainCDIHandler, error
 
 	handler := &ComputeDomainCDIHandler{
 		cache:        cache,
+		specDir:      config.flags.cdiRoot,
 		nvcdiDevice:  nvcdiDevice,
 		deviceRoot:   deviceRoot,
 		claimDevName: "channel",
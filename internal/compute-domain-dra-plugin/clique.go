@@ -0,0 +1,93 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package computedomaindraplugin
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	resourceapi "k8s.io/api/resource/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+	"github.com/run-ai/fake-gpu-operator/internal/common/dra"
+	"github.com/run-ai/fake-gpu-operator/pkg/compute-domain/consts"
+)
+
+// checkDomainClique refuses to prepare a ComputeDomain channel on this node
+// when the domain already has nodes in a different NVLink clique, since IMEX
+// cannot span cliques.
+func (d *computeDomainDriver) checkDomainClique(ctx context.Context, claim *resourceapi.ResourceClaim) error {
+	domainName := claim.Labels[consts.ComputeDomainClaimLabel]
+	if domainName == "" {
+		return nil
+	}
+
+	claims, err := d.client.ResourceV1().ResourceClaims(claim.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: consts.ComputeDomainClaimLabel + "=" + domainName,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list claims of ComputeDomain %s/%s: %w", claim.Namespace, domainName, err)
+	}
+
+	var peers []string
+	for _, domainClaim := range claims.Items {
+		if domainClaim.Status.Allocation == nil {
+			continue
+		}
+		for _, result := range domainClaim.Status.Allocation.Devices.Results {
+			if result.Driver == consts.ComputeDomainDriverName && result.Pool != d.nodeName && !slices.Contains(peers, result.Pool) {
+				peers = append(peers, result.Pool)
+			}
+		}
+	}
+	if len(peers) == 0 {
+		return nil
+	}
+
+	cliqueID, err := d.nodeCliqueID(ctx, d.nodeName)
+	if err != nil {
+		return err
+	}
+	slices.Sort(peers)
+	for _, peer := range peers {
+		peerCliqueID, err := d.nodeCliqueID(ctx, peer)
+		if err != nil {
+			return err
+		}
+		if peerCliqueID != cliqueID {
+			return dra.WithReason("CliqueMismatch", fmt.Errorf("node %s (NVLink clique %q) cannot join ComputeDomain %s/%s: node %s is in clique %q",
+				d.nodeName, cliqueID, claim.Namespace, domainName, peer, peerCliqueID))
+		}
+	}
+	return nil
+}
+
+// nodeCliqueID returns the NVLink clique of a node, or "" when the node has
+// no NVLink fabric or no longer exists.
+func (d *computeDomainDriver) nodeCliqueID(ctx context.Context, nodeName string) (string, error) {
+	node, err := d.client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get node %s: %w", nodeName, err)
+	}
+	return node.Labels[constants.LabelGpuClique], nil
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package computedomaindraplugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	corev1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+	"github.com/run-ai/fake-gpu-operator/pkg/compute-domain/consts"
+)
+
+func newCliqueNode(name, cliqueID string) *corev1.Node {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if cliqueID != "" {
+		node.Labels = map[string]string{constants.LabelGpuClique: cliqueID}
+	}
+	return node
+}
+
+func newDomainClaim(name, domainName, nodeName string) *resourceapi.ResourceClaim {
+	claim := &resourceapi.ResourceClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    map[string]string{consts.ComputeDomainClaimLabel: domainName},
+		},
+	}
+	if nodeName != "" {
+		claim.Status.Allocation = &resourceapi.AllocationResult{
+			Devices: resourceapi.DeviceAllocationResult{
+				Results: []resourceapi.DeviceRequestAllocationResult{{
+					Driver:  consts.ComputeDomainDriverName,
+					Pool:    nodeName,
+					Device:  "channel-0",
+					Request: "channel",
+				}},
+			},
+		}
+	}
+	return claim
+}
+
+func TestComputeDomainDriver_CheckDomainClique(t *testing.T) {
+	tests := map[string]struct {
+		objects []runtime.Object
+		wantErr bool
+	}{
+		"first node of the domain": {
+			objects: []runtime.Object{newCliqueNode("node-1", "cluster.0")},
+		},
+		"peer in the same clique": {
+			objects: []runtime.Object{
+				newCliqueNode("node-1", "cluster.0"),
+				newCliqueNode("node-2", "cluster.0"),
+				newDomainClaim("peer", "domain", "node-2"),
+			},
+		},
+		"peer in another clique": {
+			objects: []runtime.Object{
+				newCliqueNode("node-1", "cluster.0"),
+				newCliqueNode("node-2", "cluster.1"),
+				newDomainClaim("peer", "domain", "node-2"),
+			},
+			wantErr: true,
+		},
+		"peer of another domain": {
+			objects: []runtime.Object{
+				newCliqueNode("node-1", "cluster.0"),
+				newCliqueNode("node-2", "cluster.1"),
+				newDomainClaim("peer", "other-domain", "node-2"),
+			},
+		},
+		"no fabric on any node": {
+			objects: []runtime.Object{
+				newCliqueNode("node-1", ""),
+				newCliqueNode("node-2", ""),
+				newDomainClaim("peer", "domain", "node-2"),
+			},
+		},
+		"unallocated peer": {
+			objects: []runtime.Object{
+				newCliqueNode("node-1", "cluster.0"),
+				newDomainClaim("peer", "domain", ""),
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			driver := &computeDomainDriver{
+				client:   fake.NewSimpleClientset(test.objects...),
+				nodeName: "node-1",
+			}
+
+			err := driver.checkDomainClique(context.Background(), newDomainClaim("claim", "domain", "node-1"))
+			if test.wantErr {
+				assert.ErrorContains(t, err, "cannot join ComputeDomain")
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
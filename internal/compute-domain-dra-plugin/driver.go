@@ -20,30 +20,38 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
+	nvidiaversioned "github.com/NVIDIA/k8s-dra-driver-gpu/pkg/nvidia.com/clientset/versioned"
 	resourceapi "k8s.io/api/resource/v1"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
 	coreclientset "k8s.io/client-go/kubernetes"
 	"k8s.io/dynamic-resource-allocation/kubeletplugin"
 	"k8s.io/dynamic-resource-allocation/resourceslice"
 	"k8s.io/klog/v2"
 
+	"github.com/run-ai/fake-gpu-operator/internal/common/dra"
 	"github.com/run-ai/fake-gpu-operator/pkg/compute-domain/consts"
 )
 
 type computeDomainDriver struct {
-	client      coreclientset.Interface
-	helper      *kubeletplugin.Helper
-	state       *ComputeDomainState
-	healthcheck *healthcheck
-	cancelCtx   func(error)
+	client       coreclientset.Interface
+	domainClient nvidiaversioned.Interface
+	nodeName     string
+	helper       *kubeletplugin.Helper
+	state        *ComputeDomainState
+	healthcheck  *healthcheck
+	cancelCtx    func(error)
 }
 
 func NewComputeDomainDriver(ctx context.Context, config *Config) (*computeDomainDriver, error) {
 	driver := &computeDomainDriver{
-		client:    config.coreclient,
-		cancelCtx: config.cancelMainCtx,
+		client:       config.coreclient,
+		domainClient: config.domainclient,
+		nodeName:     config.flags.nodeName,
+		cancelCtx:    config.cancelMainCtx,
 	}
 
 	state, err := NewComputeDomainState(config)
@@ -73,11 +81,7 @@ func NewComputeDomainDriver(ctx context.Context, config *Config) (*computeDomain
 	resources := resourceslice.DriverResources{
 		Pools: map[string]resourceslice.Pool{
 			config.flags.nodeName: {
-				Slices: []resourceslice.Slice{
-					{
-						Devices: devices,
-					},
-				},
+				Slices: dra.PoolSlices(devices),
 			},
 		},
 	}
@@ -90,11 +94,24 @@ func NewComputeDomainDriver(ctx context.Context, config *Config) (*computeDomain
 	if err := helper.PublishResources(ctx, resources); err != nil {
 		return nil, err
 	}
+	if err := state.publish(devices); err != nil {
+		return nil, err
+	}
+
+	if config.flags.gcInterval > 0 && driver.client != nil {
+		go wait.UntilWithContext(ctx, driver.collectGarbage, config.flags.gcInterval)
+	}
 
 	klog.InfoS("ComputeDomain DRA plugin started", "nodeName", config.flags.nodeName)
 	return driver, nil
 }
 
+func (d *computeDomainDriver) collectGarbage(ctx context.Context) {
+	if err := d.state.collectGarbage(ctx, d.client, d.domainClient); err != nil {
+		klog.Warningf("ComputeDomain garbage collection failed: %v", err)
+	}
+}
+
 func (d *computeDomainDriver) Shutdown(logger klog.Logger) error {
 	if d.healthcheck != nil {
 		d.healthcheck.Stop(logger)
@@ -116,13 +133,37 @@ func (d *computeDomainDriver) PrepareResourceClaims(ctx context.Context, claims
 	return result, nil
 }
 
-func (d *computeDomainDriver) prepareResourceClaim(_ context.Context, claim *resourceapi.ResourceClaim) kubeletplugin.PrepareResult {
+func (d *computeDomainDriver) prepareResourceClaim(ctx context.Context, claim *resourceapi.ResourceClaim) (result kubeletplugin.PrepareResult) {
+	start := time.Now()
+	defer func() {
+		dra.ObserveOperation(consts.ComputeDomainDriverName, dra.OperationPrepare, start, result.Err)
+	}()
+
+	if d.client != nil {
+		if err := d.checkDomainClique(ctx, claim); err != nil {
+			return kubeletplugin.PrepareResult{
+				Err: fmt.Errorf("error preparing devices for claim %v: %w", claim.UID, err),
+			}
+		}
+	}
+
 	preparedPBs, err := d.state.Prepare(claim)
 	if err != nil {
 		return kubeletplugin.PrepareResult{
 			Err: fmt.Errorf("error preparing devices for claim %v: %w", claim.UID, err),
 		}
 	}
+	if d.client != nil {
+		gpus, err := d.podGPUs(ctx, claim)
+		if err != nil {
+			klog.Warningf("Failed to look up GPUs sharing a pod with claim '%v': %v", claim.UID, err)
+		} else if err := d.state.bindGPUs(claim, gpus); err != nil {
+			return kubeletplugin.PrepareResult{
+				Err: fmt.Errorf("error binding GPUs to claim %v: %w", claim.UID, err),
+			}
+		}
+	}
+
 	var prepared []kubeletplugin.Device
 	for _, preparedPB := range preparedPBs {
 		prepared = append(prepared, kubeletplugin.Device{
@@ -133,6 +174,12 @@ func (d *computeDomainDriver) prepareResourceClaim(_ context.Context, claim *res
 		})
 	}
 
+	if d.client != nil {
+		if err := dra.PublishDeviceStatuses(ctx, d.client, claim, consts.ComputeDomainDriverName, d.state.channelStatusData(claim)); err != nil {
+			klog.Warningf("Failed to publish device status for claim '%v': %v", claim.UID, err)
+		}
+	}
+
 	klog.Infof("Returning newly prepared devices for claim '%v': %v", claim.UID, prepared)
 	return kubeletplugin.PrepareResult{Devices: prepared}
 }
@@ -148,7 +195,12 @@ func (d *computeDomainDriver) UnprepareResourceClaims(ctx context.Context, claim
 	return result, nil
 }
 
-func (d *computeDomainDriver) unprepareResourceClaim(_ context.Context, claim kubeletplugin.NamespacedObject) error {
+func (d *computeDomainDriver) unprepareResourceClaim(_ context.Context, claim kubeletplugin.NamespacedObject) (err error) {
+	start := time.Now()
+	defer func() {
+		dra.ObserveOperation(consts.ComputeDomainDriverName, dra.OperationUnprepare, start, err)
+	}()
+
 	if err := d.state.Unprepare(string(claim.UID)); err != nil {
 		return fmt.Errorf("error unpreparing devices for claim %v: %w", claim.UID, err)
 	}
@@ -102,6 +102,15 @@ func TestComputeDomainDriver_PrepareResourceClaims(t *testing.T) {
 					},
 				},
 			},
+			Status: resourceapi.ResourceClaimStatus{
+				Allocation: &resourceapi.AllocationResult{
+					Devices: resourceapi.DeviceAllocationResult{
+						Results: []resourceapi.DeviceRequestAllocationResult{
+							{Driver: consts.ComputeDomainDriverName, Pool: "test-node", Device: deviceNameForChannel(0), Request: "channel"},
+						},
+					},
+				},
+			},
 		},
 	}
 
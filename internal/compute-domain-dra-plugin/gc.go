@@ -0,0 +1,210 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package computedomaindraplugin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	nvidiaversioned "github.com/NVIDIA/k8s-dra-driver-gpu/pkg/nvidia.com/clientset/versioned"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	coreclientset "k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	cdiapi "tags.cncf.io/container-device-interface/pkg/cdi"
+)
+
+// collectGarbage removes checkpointed claims whose ResourceClaim no longer
+// exists and domains whose ComputeDomain no longer exists, together with their
+// CDI specs and device-node directories. Claims are normally released by
+// UnprepareResourceClaims; this catches those kubelet never unprepared, e.g.
+// after pods were force-deleted.
+//
+// Only entries that were checkpointed before the API listing are considered,
+// so claims prepared while the listing is in flight are never collected.
+func (s *ComputeDomainState) collectGarbage(ctx context.Context, coreClient coreclientset.Interface, domainClient nvidiaversioned.Interface) error {
+	checkpointedClaims, checkpointedDomains, err := s.checkpointedIDs()
+	if err != nil {
+		return err
+	}
+
+	claims, err := coreClient.ResourceV1().ResourceClaims(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list ResourceClaims: %w", err)
+	}
+	liveClaims := sets.New[string]()
+	for _, claim := range claims.Items {
+		liveClaims.Insert(string(claim.UID))
+	}
+
+	orphanDomains := sets.New[string]()
+	if domainClient != nil {
+		domains, err := domainClient.ResourceV1beta1().ComputeDomains(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to list ComputeDomains: %w", err)
+		}
+		liveDomains := sets.New[string]()
+		for _, domain := range domains.Items {
+			liveDomains.Insert(string(domain.UID))
+		}
+		orphanDomains = checkpointedDomains.Difference(liveDomains)
+	}
+
+	return s.removeOrphans(checkpointedClaims.Difference(liveClaims), orphanDomains)
+}
+
+// checkpointedIDs returns the claim UIDs and domain IDs in the checkpoint.
+func (s *ComputeDomainState) checkpointedIDs() (claims, domains sets.Set[string], err error) {
+	s.Lock()
+	defer s.Unlock()
+
+	checkpoint := newComputeDomainCheckpoint()
+	if err := s.checkpointManager.GetCheckpoint(DriverPluginCheckpointFile, checkpoint); err != nil {
+		return nil, nil, fmt.Errorf("unable to sync from checkpoint: %v", err)
+	}
+	claims, domains = sets.New[string](), sets.New[string]()
+	if checkpoint.V1 != nil {
+		for claimUID := range checkpoint.V1.PreparedClaims {
+			claims.Insert(claimUID)
+		}
+		for domainID := range checkpoint.V1.Domains {
+			domains.Insert(domainID)
+		}
+	}
+	return claims, domains, nil
+}
+
+// removeOrphans drops orphanClaims and orphanDomains (with all their claims)
+// from the checkpoint, then deletes every claim CDI spec and domain device
+// directory the checkpoint no longer references.
+func (s *ComputeDomainState) removeOrphans(orphanClaims, orphanDomains sets.Set[string]) error {
+	s.Lock()
+	defer s.Unlock()
+
+	checkpoint := newComputeDomainCheckpoint()
+	if err := s.checkpointManager.GetCheckpoint(DriverPluginCheckpointFile, checkpoint); err != nil {
+		return fmt.Errorf("unable to sync from checkpoint: %v", err)
+	}
+	if checkpoint.V1 == nil {
+		checkpoint.V1 = &ComputeDomainCheckpointV1{
+			PreparedClaims: make(ComputeDomainPreparedClaims),
+			Domains:        make(map[string]*DomainInfo),
+		}
+	}
+	preparedClaims := checkpoint.V1.PreparedClaims
+	domains := checkpoint.V1.Domains
+
+	changed := false
+	for domainID, domainInfo := range domains {
+		if orphanDomains.Has(domainID) {
+			klog.Infof("Removing ComputeDomain %s: it no longer exists", domainID)
+			orphanClaims.Insert(domainInfo.Claims...)
+			delete(domains, domainID)
+			changed = true
+			continue
+		}
+		var claims []string
+		for _, claimUID := range domainInfo.Claims {
+			if !orphanClaims.Has(claimUID) {
+				claims = append(claims, claimUID)
+			} else {
+				delete(domainInfo.GPUs, claimUID)
+			}
+		}
+		if len(claims) != len(domainInfo.Claims) {
+			domainInfo.Claims = claims
+			changed = true
+		}
+		if len(claims) == 0 {
+			klog.Infof("Removing ComputeDomain %s: none of its claims exist anymore", domainID)
+			delete(domains, domainID)
+		}
+	}
+	for claimUID := range orphanClaims {
+		if _, ok := preparedClaims[claimUID]; ok {
+			klog.Infof("Removing claim %s: it no longer exists", claimUID)
+			delete(preparedClaims, claimUID)
+			changed = true
+		}
+	}
+
+	if changed {
+		if err := s.checkpointManager.CreateCheckpoint(DriverPluginCheckpointFile, checkpoint); err != nil {
+			return fmt.Errorf("unable to sync to checkpoint: %v", err)
+		}
+		s.domains = domains
+		s.recordMetrics(preparedClaims)
+	}
+
+	specClaims, err := s.cdi.ListClaimSpecs()
+	if err != nil {
+		return err
+	}
+	for _, claimUID := range specClaims {
+		if _, ok := preparedClaims[claimUID]; ok {
+			continue
+		}
+		if err := s.cdi.DeleteClaimSpecFile(claimUID); err != nil {
+			klog.Warningf("failed to delete CDI spec for claim %s: %v", claimUID, err)
+		}
+	}
+	return s.cdi.nvcdiDevice.removeDomainDirsExcept(domains)
+}
+
+// ListClaimSpecs returns the UIDs of the claims that have a CDI spec file.
+// The spec directory is read directly, since the cache only notices new files
+// asynchronously.
+func (cdi *ComputeDomainCDIHandler) ListClaimSpecs() ([]string, error) {
+	entries, err := os.ReadDir(cdi.specDir)
+	if err != nil {
+		return nil, fmt.Errorf("read CDI spec directory: %w", err)
+	}
+	prefix := cdiapi.GenerateTransientSpecName(computeDomainCDIVendor, computeDomainCDIClass, "")
+	var claimUIDs []string
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		claimUID, ok := strings.CutPrefix(name, prefix)
+		if !ok || claimUID == computeDomainCDICommonDeviceName {
+			continue
+		}
+		claimUIDs = append(claimUIDs, claimUID)
+	}
+	return claimUIDs, nil
+}
+
+// removeDomainDirsExcept deletes the device-node directories of all domains
+// not in keep.
+func (d *computeDomainNvcdiDevice) removeDomainDirsExcept(keep map[string]*DomainInfo) error {
+	entries, err := os.ReadDir(d.deviceRoot)
+	if err != nil {
+		return fmt.Errorf("read device root: %w", err)
+	}
+	for _, entry := range entries {
+		if _, ok := keep[entry.Name()]; ok || !entry.IsDir() {
+			continue
+		}
+		klog.Infof("Removing device nodes of ComputeDomain %s", entry.Name())
+		if err := os.RemoveAll(filepath.Join(d.deviceRoot, entry.Name())); err != nil {
+			return fmt.Errorf("remove device nodes of domain %s: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package computedomaindraplugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	computedomainv1beta1 "github.com/NVIDIA/k8s-dra-driver-gpu/api/nvidia.com/resource/v1beta1"
+	nvidiafake "github.com/NVIDIA/k8s-dra-driver-gpu/pkg/nvidia.com/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestComputeDomainState_CollectGarbage(t *testing.T) {
+	tmpDir := t.TempDir()
+	cdiRoot := filepath.Join(tmpDir, "cdi")
+	pluginPath := filepath.Join(tmpDir, "plugin")
+	require.NoError(t, os.MkdirAll(cdiRoot, 0750))
+	require.NoError(t, os.MkdirAll(pluginPath, 0750))
+
+	config := &Config{
+		flags: &Flags{
+			cdiRoot:                     cdiRoot,
+			nodeName:                    "test-node",
+			kubeletPluginsDirectoryPath: pluginPath,
+		},
+	}
+	state, err := NewComputeDomainState(config)
+	require.NoError(t, err)
+
+	liveClaim := newTestDomainClaim("claim-live", "domain-live")
+	forceDeletedClaim := newTestDomainClaim("claim-force-deleted", "domain-live")
+	deletedDomainClaim := newTestDomainClaim("claim-deleted-domain", "domain-deleted")
+	_, err = state.Prepare(liveClaim)
+	require.NoError(t, err)
+	_, err = state.Prepare(forceDeletedClaim)
+	require.NoError(t, err)
+	_, err = state.Prepare(deletedDomainClaim)
+	require.NoError(t, err)
+
+	// A device directory left behind without any checkpointed domain.
+	deviceRoot := state.cdi.deviceRoot
+	require.NoError(t, os.MkdirAll(filepath.Join(deviceRoot, "domain-stale"), 0750))
+
+	coreClient := fake.NewSimpleClientset(liveClaim, deletedDomainClaim)
+	domainClient := nvidiafake.NewSimpleClientset(&computedomainv1beta1.ComputeDomain{
+		ObjectMeta: metav1.ObjectMeta{Name: "live", Namespace: "default", UID: types.UID("domain-live")},
+	})
+
+	require.NoError(t, state.collectGarbage(context.Background(), coreClient, domainClient))
+
+	require.Contains(t, state.domains, "domain-live")
+	assert.Equal(t, []string{"claim-live"}, state.domains["domain-live"].Claims)
+	assert.NotContains(t, state.domains, "domain-deleted")
+
+	checkpointedClaims, checkpointedDomains, err := state.checkpointedIDs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"claim-live"}, checkpointedClaims.UnsortedList())
+	assert.Equal(t, []string{"domain-live"}, checkpointedDomains.UnsortedList())
+
+	assert.True(t, claimSpecExists(cdiRoot, "claim-live"))
+	assert.False(t, claimSpecExists(cdiRoot, "claim-force-deleted"))
+	assert.False(t, claimSpecExists(cdiRoot, "claim-deleted-domain"))
+
+	assert.DirExists(t, filepath.Join(deviceRoot, "domain-live"))
+	assert.NoDirExists(t, filepath.Join(deviceRoot, "domain-deleted"))
+	assert.NoDirExists(t, filepath.Join(deviceRoot, "domain-stale"))
+
+	// Collecting again is a no-op.
+	require.NoError(t, state.collectGarbage(context.Background(), coreClient, domainClient))
+	assert.True(t, claimSpecExists(cdiRoot, "claim-live"))
+	assert.DirExists(t, filepath.Join(deviceRoot, "domain-live"))
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package computedomaindraplugin
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/dra"
+)
+
+// gpuDriverName is the driver whose devices are bound to IMEX channels.
+const gpuDriverName = "gpu.nvidia.com"
+
+// podGPUs returns the GPUs on this node allocated to the other claims of the
+// pods the channel claim is reserved for, sorted by name. On a real NVL72
+// system these are the GPUs whose fabric the IMEX channel connects.
+func (d *computeDomainDriver) podGPUs(ctx context.Context, claim *resourceapi.ResourceClaim) ([]string, error) {
+	gpus := sets.New[string]()
+	for _, consumer := range claim.Status.ReservedFor {
+		if consumer.APIGroup != "" || consumer.Resource != "pods" {
+			continue
+		}
+		pod, err := d.client.CoreV1().Pods(claim.Namespace).Get(ctx, consumer.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pod %s/%s: %w", claim.Namespace, consumer.Name, err)
+		}
+		if pod.UID != consumer.UID {
+			continue
+		}
+
+		for _, claimName := range podClaimNames(pod) {
+			if claimName == claim.Name {
+				continue
+			}
+			podClaim, err := d.client.ResourceV1().ResourceClaims(claim.Namespace).Get(ctx, claimName, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to get ResourceClaim %s/%s: %w", claim.Namespace, claimName, err)
+			}
+			if podClaim.Status.Allocation == nil {
+				continue
+			}
+			for _, result := range podClaim.Status.Allocation.Devices.Results {
+				if result.Driver == gpuDriverName && result.Pool == d.nodeName && !dra.IsAdminAccess(result) {
+					gpus.Insert(result.Device)
+				}
+			}
+		}
+	}
+	return sets.List(gpus), nil
+}
+
+// podClaimNames returns the names of the ResourceClaims a pod uses, both
+// generated from templates and referenced directly.
+func podClaimNames(pod *corev1.Pod) []string {
+	names := sets.New[string]()
+	for _, status := range pod.Status.ResourceClaimStatuses {
+		if status.ResourceClaimName != nil && *status.ResourceClaimName != "" {
+			names.Insert(*status.ResourceClaimName)
+		}
+	}
+	for _, podClaim := range pod.Spec.ResourceClaims {
+		if podClaim.ResourceClaimName != nil && *podClaim.ResourceClaimName != "" {
+			names.Insert(*podClaim.ResourceClaimName)
+		}
+	}
+	return sets.List(names)
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package computedomaindraplugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+func newGPUClaim(name, nodeName string, devices ...string) *resourceapi.ResourceClaim {
+	claim := &resourceapi.ResourceClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Status: resourceapi.ResourceClaimStatus{
+			Allocation: &resourceapi.AllocationResult{},
+		},
+	}
+	for _, device := range devices {
+		claim.Status.Allocation.Devices.Results = append(claim.Status.Allocation.Devices.Results,
+			resourceapi.DeviceRequestAllocationResult{Driver: gpuDriverName, Pool: nodeName, Device: device, Request: "gpu"})
+	}
+	return claim
+}
+
+func TestComputeDomainDriver_PodGPUs(t *testing.T) {
+	channelClaim := newTestDomainClaim("channel-uid", "domain-a")
+	channelClaim.Name = "channel"
+	channelClaim.Status.ReservedFor = []resourceapi.ResourceClaimConsumerReference{
+		{Resource: "pods", Name: "worker", UID: "worker-uid"},
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "default", UID: "worker-uid"},
+		Spec: corev1.PodSpec{
+			ResourceClaims: []corev1.PodResourceClaim{
+				{Name: "channel", ResourceClaimName: ptr.To("channel")},
+				{Name: "shared", ResourceClaimName: ptr.To("shared-gpus")},
+			},
+		},
+		Status: corev1.PodStatus{
+			ResourceClaimStatuses: []corev1.PodResourceClaimStatus{
+				{Name: "gpus", ResourceClaimName: ptr.To("worker-gpus")},
+			},
+		},
+	}
+	adminClaim := newGPUClaim("shared-gpus", "test-node", "gpu-2")
+	adminClaim.Status.Allocation.Devices.Results[0].AdminAccess = ptr.To(true)
+
+	driver := &computeDomainDriver{
+		nodeName: "test-node",
+		client: fake.NewSimpleClientset(
+			pod,
+			channelClaim,
+			newGPUClaim("worker-gpus", "test-node", "gpu-1", "gpu-0"),
+			adminClaim,
+			newGPUClaim("other-node-gpus", "other-node", "gpu-9"),
+		),
+	}
+
+	gpus, err := driver.podGPUs(context.Background(), channelClaim)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gpu-0", "gpu-1"}, gpus, "admin-access devices are not bound")
+
+	// A replaced pod of the same name is not the claim's consumer.
+	channelClaim.Status.ReservedFor[0].UID = types.UID("old-uid")
+	gpus, err = driver.podGPUs(context.Background(), channelClaim)
+	require.NoError(t, err)
+	assert.Empty(t, gpus)
+}
+
+func TestComputeDomainState_BindGPUs(t *testing.T) {
+	tmpDir := t.TempDir()
+	cdiRoot := filepath.Join(tmpDir, "cdi")
+	pluginPath := filepath.Join(tmpDir, "plugin")
+	require.NoError(t, os.MkdirAll(cdiRoot, 0750))
+	require.NoError(t, os.MkdirAll(pluginPath, 0750))
+
+	config := &Config{
+		flags: &Flags{
+			cdiRoot:                     cdiRoot,
+			nodeName:                    "test-node",
+			kubeletPluginsDirectoryPath: pluginPath,
+		},
+	}
+	state, err := NewComputeDomainState(config)
+	require.NoError(t, err)
+
+	claimA := newTestDomainClaim("claim-a", "domain-a")
+	claimB := newTestDomainClaim("claim-b", "domain-a")
+	for _, claim := range []*resourceapi.ResourceClaim{claimA, claimB} {
+		_, err := state.Prepare(claim)
+		require.NoError(t, err)
+	}
+	require.NoError(t, state.bindGPUs(claimA, []string{"gpu-0", "gpu-1"}))
+	require.NoError(t, state.bindGPUs(claimB, []string{"gpu-2"}))
+
+	data := state.channelStatusData(claimA)(resourceapi.DeviceRequestAllocationResult{Device: deviceNameForChannel(0)})
+	assert.Equal(t, []string{"gpu-0", "gpu-1"}, data.(ChannelStatusData).GPUs)
+
+	// The binding is checkpointed.
+	restarted, err := NewComputeDomainState(config)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"claim-a": {"gpu-0", "gpu-1"},
+		"claim-b": {"gpu-2"},
+	}, restarted.domains["domain-a"].GPUs)
+
+	// Unpreparing a claim drops its GPUs only.
+	require.NoError(t, state.Unprepare("claim-a"))
+	assert.Equal(t, map[string][]string{"claim-b": {"gpu-2"}}, state.domains["domain-a"].GPUs)
+
+	// Claims that are not prepared are ignored.
+	require.NoError(t, state.bindGPUs(newTestDomainClaim("claim-x", "domain-a"), []string{"gpu-3"}))
+	assert.NotContains(t, state.domains["domain-a"].GPUs, "claim-x")
+}
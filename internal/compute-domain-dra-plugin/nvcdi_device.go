@@ -50,7 +50,7 @@ func (d *computeDomainNvcdiDevice) ensureDomainDeviceNodes(info *DomainInfo) ([]
 		return nil, fmt.Errorf("prepare domain device directory: %w", err)
 	}
 
-	channelPath := filepath.Join(domainDir, "channel-0")
+	channelPath := filepath.Join(domainDir, deviceNameForChannel(info.Channel))
 	if err := ensureSymlink(channelPath, defaultHostDeviceTarget); err != nil {
 		return nil, fmt.Errorf("ensure channel device: %w", err)
 	}
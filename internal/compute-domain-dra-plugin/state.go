@@ -19,16 +19,20 @@ package computedomaindraplugin
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 
 	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
 	"k8s.io/kubernetes/pkg/kubelet/checkpointmanager"
 
 	cdiapi "tags.cncf.io/container-device-interface/pkg/cdi"
 
+	"github.com/run-ai/fake-gpu-operator/internal/common/dra"
 	"github.com/run-ai/fake-gpu-operator/pkg/compute-domain/consts"
 )
 
@@ -49,11 +53,22 @@ func (pds ComputeDomainPreparedDevices) GetDevices() []*drapbv1.Device {
 }
 
 type DomainInfo struct {
-	DomainID  string
+	DomainID string
+	// Channel is the IMEX channel allocated to the domain on this node, or
+	// noChannel while only "All" claims use it. Checkpoints written before
+	// per-domain allocation decode it as 0; see reassignSharedChannels.
+	Channel   int
 	Claims    []string // ResourceClaim UIDs
 	CreatedAt time.Time
+	// GPUs holds, per channel claim, the gpu.nvidia.com devices on this node
+	// allocated to the pods sharing the claim.
+	GPUs map[string][]string `json:",omitempty"`
 }
 
+// noChannel marks a domain that holds no channel of its own, as its claims
+// all use "All" allocation.
+const noChannel = -1
+
 type ComputeDomainState struct {
 	sync.Mutex
 	cdi               *ComputeDomainCDIHandler
@@ -61,6 +76,7 @@ type ComputeDomainState struct {
 	checkpointManager checkpointmanager.CheckpointManager
 	domains           map[string]*DomainInfo // domainID -> DomainInfo
 	nodeName          string
+	published         sets.Set[string] // device names in the node's ResourceSlice
 }
 
 func NewComputeDomainState(config *Config) (*ComputeDomainState, error) {
@@ -109,6 +125,11 @@ func NewComputeDomainState(config *Config) (*ComputeDomainState, error) {
 				return nil, fmt.Errorf("checkpoint checksum verification failed: %v", err)
 			}
 			if checkpoint.V1 != nil {
+				if state.reassignSharedChannels(checkpoint.V1.Domains, checkpoint.V1.PreparedClaims) {
+					if err := state.checkpointManager.CreateCheckpoint(DriverPluginCheckpointFile, checkpoint); err != nil {
+						return nil, fmt.Errorf("unable to update checkpoint: %v", err)
+					}
+				}
 				state.domains = checkpoint.V1.Domains
 			}
 			return state, nil
@@ -132,7 +153,7 @@ func (s *ComputeDomainState) Prepare(claim *resourceapi.ResourceClaim) (ComputeD
 
 	checkpoint := newComputeDomainCheckpoint()
 	if err := s.checkpointManager.GetCheckpoint(DriverPluginCheckpointFile, checkpoint); err != nil {
-		return nil, fmt.Errorf("unable to sync from checkpoint: %v", err)
+		return nil, dra.WithReason("Checkpoint", fmt.Errorf("unable to sync from checkpoint: %v", err))
 	}
 
 	if checkpoint.V1 == nil {
@@ -154,24 +175,40 @@ func (s *ComputeDomainState) Prepare(claim *resourceapi.ResourceClaim) (ComputeD
 
 	deviceClassName := s.getDeviceClassName(claim)
 	if deviceClassName == "" {
-		return nil, fmt.Errorf("unable to determine device class from claim")
+		return nil, dra.WithReason("InvalidConfig", fmt.Errorf("unable to determine device class from claim"))
 	}
 
 	computeDomainID, isAllAllocation := s.extractComputeDomainParams(claim)
 	if computeDomainID == "" {
-		return nil, fmt.Errorf("unable to extract ComputeDomain ID from claim")
+		return nil, dra.WithReason("InvalidConfig", fmt.Errorf("unable to extract ComputeDomain ID from claim"))
+	}
+
+	results := s.channelResults(claim)
+	if !isAllAllocation && len(results) == 0 {
+		return nil, dra.WithReason("NotAllocated", fmt.Errorf("claim has no channel allocated on node %s", s.nodeName))
 	}
 
-	domainID := s.getOrCreateDomain(domains, computeDomainID, claim)
+	domainID, err := s.getOrCreateDomain(domains, computeDomainID, claim, !isAllAllocation)
+	if err != nil {
+		return nil, err
+	}
 
 	domainInfo := domains[domainID]
 	if domainInfo == nil {
 		return nil, fmt.Errorf("domain %s not found", domainID)
 	}
 
-	cdiEdits, err := s.cdi.CreateDomainCDIDevice(domainInfo)
+	editsInfo := domainInfo
+	if isAllAllocation {
+		// "All" claims get every channel; channel 0 stands in for them in
+		// the container, as it did before channels were allocated per domain.
+		allChannels := *domainInfo
+		allChannels.Channel = 0
+		editsInfo = &allChannels
+	}
+	cdiEdits, err := s.cdi.CreateDomainCDIDevice(editsInfo)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create CDI device: %w", err)
+		return nil, dra.WithReason("CDI", fmt.Errorf("failed to create CDI device: %w", err))
 	}
 
 	var preparedDevices ComputeDomainPreparedDevices
@@ -189,15 +226,19 @@ func (s *ComputeDomainState) Prepare(claim *resourceapi.ResourceClaim) (ComputeD
 			preparedDevices = append(preparedDevices, preparedDevice)
 		}
 	} else {
-		preparedDevice := &ComputeDomainPreparedDevice{
-			Device: drapbv1.Device{
-				RequestNames: []string{"channel"},
-				PoolName:     claim.Namespace,
-				DeviceName:   deviceNameForChannel(0),
-			},
-			ContainerEdits: cdiEdits,
+		// The allocated devices are reported as prepared; the container gets
+		// the domain's channel through the CDI edits.
+		for _, result := range results {
+			preparedDevice := &ComputeDomainPreparedDevice{
+				Device: drapbv1.Device{
+					RequestNames: []string{result.Request},
+					PoolName:     result.Pool,
+					DeviceName:   result.Device,
+				},
+				ContainerEdits: cdiEdits,
+			}
+			preparedDevices = append(preparedDevices, preparedDevice)
 		}
-		preparedDevices = ComputeDomainPreparedDevices{preparedDevice}
 	}
 
 	if err := s.ensureClaimCDIArtifacts(claimUID, preparedDevices); err != nil {
@@ -206,8 +247,10 @@ func (s *ComputeDomainState) Prepare(claim *resourceapi.ResourceClaim) (ComputeD
 	preparedClaims[claimUID] = preparedDevices
 
 	if err := s.checkpointManager.CreateCheckpoint(DriverPluginCheckpointFile, checkpoint); err != nil {
-		return nil, fmt.Errorf("unable to sync to checkpoint: %v", err)
+		return nil, dra.WithReason("Checkpoint", fmt.Errorf("unable to sync to checkpoint: %v", err))
 	}
+	s.domains = domains
+	s.recordMetrics(preparedClaims)
 
 	return preparedDevices, nil
 }
@@ -218,7 +261,7 @@ func (s *ComputeDomainState) Unprepare(claimUID string) error {
 
 	checkpoint := newComputeDomainCheckpoint()
 	if err := s.checkpointManager.GetCheckpoint(DriverPluginCheckpointFile, checkpoint); err != nil {
-		return fmt.Errorf("unable to sync from checkpoint: %v", err)
+		return dra.WithReason("Checkpoint", fmt.Errorf("unable to sync from checkpoint: %v", err))
 	}
 
 	if checkpoint.V1 == nil {
@@ -236,6 +279,7 @@ func (s *ComputeDomainState) Unprepare(claimUID string) error {
 		for i, claim := range domainInfo.Claims {
 			if claim == claimUID {
 				domainInfo.Claims = append(domainInfo.Claims[:i], domainInfo.Claims[i+1:]...)
+				delete(domainInfo.GPUs, claimUID)
 				if len(domainInfo.Claims) == 0 {
 					delete(domains, domainID)
 				}
@@ -247,8 +291,10 @@ func (s *ComputeDomainState) Unprepare(claimUID string) error {
 	delete(preparedClaims, claimUID)
 
 	if err := s.checkpointManager.CreateCheckpoint(DriverPluginCheckpointFile, checkpoint); err != nil {
-		return fmt.Errorf("unable to sync to checkpoint: %v", err)
+		return dra.WithReason("Checkpoint", fmt.Errorf("unable to sync to checkpoint: %v", err))
 	}
+	s.domains = domains
+	s.recordMetrics(preparedClaims)
 
 	if err := s.cdi.DeleteClaimSpecFile(claimUID); err != nil {
 		klog.Warningf("failed to delete CDI spec for claim %s: %v", claimUID, err)
@@ -257,6 +303,43 @@ func (s *ComputeDomainState) Unprepare(claimUID string) error {
 	return nil
 }
 
+// ChannelStatusData is published in ResourceClaim.status.devices[].data for
+// every prepared IMEX channel.
+type ChannelStatusData struct {
+	DomainID    string   `json:"domainID"`
+	Channel     int64    `json:"channel"`
+	GPUs        []string `json:"gpus,omitempty"`
+	AdminAccess bool     `json:"adminAccess,omitempty"`
+}
+
+// channelStatusData returns the status data func for the channels of claim.
+// In "Single" mode the channel is the one allocated to the domain.
+func (s *ComputeDomainState) channelStatusData(claim *resourceapi.ResourceClaim) dra.DeviceStatusDataFunc {
+	domainID, isAllAllocation := s.extractComputeDomainParams(claim)
+
+	s.Lock()
+	domainInfo := s.domains[domainID]
+	var gpus []string
+	if domainInfo != nil {
+		gpus = domainInfo.GPUs[string(claim.UID)]
+	}
+	s.Unlock()
+
+	return func(result resourceapi.DeviceRequestAllocationResult) any {
+		data := ChannelStatusData{
+			DomainID:    domainID,
+			GPUs:        gpus,
+			AdminAccess: dra.IsAdminAccess(result),
+		}
+		if !isAllAllocation && domainInfo != nil {
+			data.Channel = int64(domainInfo.Channel)
+		} else if attr, ok := s.allocatable[result.Device].Attributes["compute-domain.nvidia.com/id"]; ok && attr.IntValue != nil {
+			data.Channel = *attr.IntValue
+		}
+		return data
+	}
+}
+
 func (s *ComputeDomainState) getDeviceClassName(claim *resourceapi.ResourceClaim) string {
 	if claim.Spec.Devices.Requests != nil {
 		for _, req := range claim.Spec.Devices.Requests {
@@ -268,6 +351,20 @@ func (s *ComputeDomainState) getDeviceClassName(claim *resourceapi.ResourceClaim
 	return ""
 }
 
+// channelResults returns the channels allocated to the claim on this node.
+func (s *ComputeDomainState) channelResults(claim *resourceapi.ResourceClaim) []resourceapi.DeviceRequestAllocationResult {
+	if claim.Status.Allocation == nil {
+		return nil
+	}
+	var results []resourceapi.DeviceRequestAllocationResult
+	for _, result := range claim.Status.Allocation.Devices.Results {
+		if result.Driver == consts.ComputeDomainDriverName && result.Pool == s.nodeName {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
 func (s *ComputeDomainState) extractComputeDomainParams(claim *resourceapi.ResourceClaim) (domainID string, isAllAllocation bool) {
 	for _, config := range claim.Spec.Devices.Config {
 		if config.Opaque != nil && config.Opaque.Driver == consts.ComputeDomainDriverName {
@@ -287,39 +384,203 @@ func (s *ComputeDomainState) extractComputeDomainParams(claim *resourceapi.Resou
 	return domainID, isAllAllocation
 }
 
-func (s *ComputeDomainState) getOrCreateDomain(domains map[string]*DomainInfo, computeDomainID string, claim *resourceapi.ResourceClaim) string {
+// getOrCreateDomain adds the claim to its domain, creating the domain when
+// it is the first claim. A channel is allocated to the domain once a claim
+// needs one, i.e. a "Single" claim.
+func (s *ComputeDomainState) getOrCreateDomain(domains map[string]*DomainInfo, computeDomainID string, claim *resourceapi.ResourceClaim, needsChannel bool) (string, error) {
 	// Check if domain already exists
 	if domainInfo, exists := domains[computeDomainID]; exists {
+		if needsChannel && domainInfo.Channel == noChannel {
+			channel, err := s.allocateChannel(domains)
+			if err != nil {
+				return "", dra.WithReason("ChannelsExhausted", fmt.Errorf("unable to prepare ComputeDomain %s: %w", computeDomainID, err))
+			}
+			domainInfo.Channel = channel
+		}
 		domainInfo.Claims = append(domainInfo.Claims, string(claim.UID))
-		return computeDomainID
+		return computeDomainID, nil
+	}
+
+	channel := noChannel
+	if needsChannel {
+		var err error
+		channel, err = s.allocateChannel(domains)
+		if err != nil {
+			return "", dra.WithReason("ChannelsExhausted", fmt.Errorf("unable to create ComputeDomain %s: %w", computeDomainID, err))
+		}
 	}
 
 	// Create new domain
 	domainInfo := &DomainInfo{
 		DomainID:  computeDomainID,
+		Channel:   channel,
 		Claims:    []string{string(claim.UID)},
 		CreatedAt: time.Now(),
 	}
 
 	domains[computeDomainID] = domainInfo
-	return computeDomainID
+	return computeDomainID, nil
+}
+
+// allocateChannel returns the lowest IMEX channel not held by any domain on
+// this node.
+func (s *ComputeDomainState) allocateChannel(domains map[string]*DomainInfo) (int, error) {
+	inUse := make(map[int]bool, len(domains))
+	for _, domainInfo := range domains {
+		inUse[domainInfo.Channel] = true
+	}
+	for channel := range maxChannelID {
+		if !inUse[channel] {
+			return channel, nil
+		}
+	}
+	return 0, fmt.Errorf("all %d IMEX channels on node %s are in use by other ComputeDomains", maxChannelID, s.nodeName)
+}
+
+// reassignSharedChannels gives a channel of their own to domains restored
+// from a checkpoint written before per-domain allocation, which all decode
+// channel 0. Domains with a prepared claim keep the channel: their running
+// pods hold its device nodes through the claims' CDI specs, and moving the
+// domain would put its later claims on another channel than those pods.
+// Otherwise the oldest domain keeps it. It reports whether any domain was
+// reassigned.
+func (s *ComputeDomainState) reassignSharedChannels(domains map[string]*DomainInfo, preparedClaims ComputeDomainPreparedClaims) bool {
+	pinned := make(map[string]bool, len(domains))
+	ordered := make([]*DomainInfo, 0, len(domains))
+	for _, domainInfo := range domains {
+		pinned[domainInfo.DomainID] = slices.ContainsFunc(domainInfo.Claims, func(claimUID string) bool {
+			return preparedClaims[claimUID] != nil
+		})
+		ordered = append(ordered, domainInfo)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		if pinned[ordered[i].DomainID] != pinned[ordered[j].DomainID] {
+			return pinned[ordered[i].DomainID]
+		}
+		if !ordered[i].CreatedAt.Equal(ordered[j].CreatedAt) {
+			return ordered[i].CreatedAt.Before(ordered[j].CreatedAt)
+		}
+		return ordered[i].DomainID < ordered[j].DomainID
+	})
+
+	reassigned := false
+	held := make(map[int]bool, len(ordered))
+	for _, domainInfo := range ordered {
+		if domainInfo.Channel == noChannel || !held[domainInfo.Channel] {
+			held[domainInfo.Channel] = true
+			continue
+		}
+		if pinned[domainInfo.DomainID] {
+			klog.Warningf("ComputeDomain %s keeps shared channel %d while it has prepared claims", domainInfo.DomainID, domainInfo.Channel)
+			continue
+		}
+		// allocateChannel skips the channels of all domains, including the
+		// ones still to be reassigned, so no new collision is created.
+		channel, err := s.allocateChannel(domains)
+		if err != nil {
+			klog.Warningf("ComputeDomain %s keeps shared channel %d: %v", domainInfo.DomainID, domainInfo.Channel, err)
+			continue
+		}
+		klog.Infof("ComputeDomain %s restored with shared channel %d, moving it to channel %d", domainInfo.DomainID, domainInfo.Channel, channel)
+		domainInfo.Channel = channel
+		held[channel] = true
+		reassigned = true
+	}
+	return reassigned
 }
 
 func (s *ComputeDomainState) ensureClaimCDIArtifacts(claimUID string, devices ComputeDomainPreparedDevices) error {
 	if len(devices) == 0 {
-		return fmt.Errorf("no devices prepared for claim %s", claimUID)
+		return dra.WithReason("CDI", fmt.Errorf("no devices prepared for claim %s", claimUID))
 	}
 	for _, device := range devices {
 		ids := s.cdi.GetClaimDevices(claimUID, []string{device.DeviceName})
 		if len(ids) == 0 {
-			return fmt.Errorf("failed to build CDI device IDs for claim %s", claimUID)
+			return dra.WithReason("CDI", fmt.Errorf("failed to build CDI device IDs for claim %s", claimUID))
 		}
 		device.CDIDeviceIDs = ids
 	}
 
 	if err := s.cdi.CreateClaimSpecFile(claimUID, devices); err != nil {
-		return fmt.Errorf("unable to create CDI spec for claim %s: %w", claimUID, err)
+		return dra.WithReason("CDI", fmt.Errorf("unable to create CDI spec for claim %s: %w", claimUID, err))
+	}
+
+	return nil
+}
+
+// publish records the devices published in the node's ResourceSlice and
+// initializes the device metrics from the checkpoint.
+func (s *ComputeDomainState) publish(devices []resourceapi.Device) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.published = sets.New[string]()
+	for _, device := range devices {
+		s.published.Insert(device.Name)
+	}
+
+	checkpoint := newComputeDomainCheckpoint()
+	if err := s.checkpointManager.GetCheckpoint(DriverPluginCheckpointFile, checkpoint); err != nil {
+		return fmt.Errorf("unable to sync from checkpoint: %v", err)
+	}
+	var preparedClaims ComputeDomainPreparedClaims
+	if checkpoint.V1 != nil {
+		preparedClaims = checkpoint.V1.PreparedClaims
+	}
+	s.recordMetrics(preparedClaims)
+	return nil
+}
+
+// recordMetrics publishes the number of prepared claims and of published
+// channels they use. Callers must hold the lock.
+func (s *ComputeDomainState) recordMetrics(preparedClaims ComputeDomainPreparedClaims) {
+	allocated := sets.New[string]()
+	for _, devices := range preparedClaims {
+		for _, device := range devices {
+			if s.published.Has(device.DeviceName) {
+				allocated.Insert(device.DeviceName)
+			}
+		}
+	}
+	dra.SetPreparedClaims(consts.ComputeDomainDriverName, s.nodeName, len(preparedClaims))
+	dra.SetPoolDevices(consts.ComputeDomainDriverName, s.nodeName, s.published.Len(), allocated.Len())
+}
+
+// bindGPUs records gpus as the GPUs bound to the channel of the prepared
+// claim, replacing any earlier binding.
+func (s *ComputeDomainState) bindGPUs(claim *resourceapi.ResourceClaim, gpus []string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	checkpoint := newComputeDomainCheckpoint()
+	if err := s.checkpointManager.GetCheckpoint(DriverPluginCheckpointFile, checkpoint); err != nil {
+		return dra.WithReason("Checkpoint", fmt.Errorf("unable to sync from checkpoint: %v", err))
+	}
+	if checkpoint.V1 == nil {
+		return nil
 	}
 
+	claimUID := string(claim.UID)
+	domainID, _ := s.extractComputeDomainParams(claim)
+	domainInfo := checkpoint.V1.Domains[domainID]
+	if domainInfo == nil || !slices.Contains(domainInfo.Claims, claimUID) {
+		return nil
+	}
+	if slices.Equal(domainInfo.GPUs[claimUID], gpus) {
+		return nil
+	}
+	if len(gpus) == 0 {
+		delete(domainInfo.GPUs, claimUID)
+	} else {
+		if domainInfo.GPUs == nil {
+			domainInfo.GPUs = make(map[string][]string)
+		}
+		domainInfo.GPUs[claimUID] = gpus
+	}
+
+	if err := s.checkpointManager.CreateCheckpoint(DriverPluginCheckpointFile, checkpoint); err != nil {
+		return dra.WithReason("Checkpoint", fmt.Errorf("unable to sync to checkpoint: %v", err))
+	}
+	s.domains = checkpoint.V1.Domains
 	return nil
 }
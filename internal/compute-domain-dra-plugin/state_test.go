@@ -22,6 +22,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -32,9 +33,11 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/fake"
 	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+	"k8s.io/kubernetes/pkg/kubelet/checkpointmanager"
 
 	cdiparser "tags.cncf.io/container-device-interface/pkg/parser"
 
+	"github.com/run-ai/fake-gpu-operator/internal/common/dra"
 	"github.com/run-ai/fake-gpu-operator/pkg/compute-domain/consts"
 )
 
@@ -189,6 +192,213 @@ func TestComputeDomainState_Unprepare(t *testing.T) {
 	assert.False(t, claimSpecExists(cdiRoot, string(claim.UID)))
 }
 
+func TestComputeDomainState_PrepareAllocatesChannelPerDomain(t *testing.T) {
+	tmpDir := t.TempDir()
+	cdiRoot := filepath.Join(tmpDir, "cdi")
+	pluginPath := filepath.Join(tmpDir, "plugin")
+	require.NoError(t, os.MkdirAll(cdiRoot, 0750))
+	require.NoError(t, os.MkdirAll(pluginPath, 0750))
+
+	config := &Config{
+		flags: &Flags{
+			cdiRoot:                     cdiRoot,
+			nodeName:                    "test-node",
+			kubeletPluginsDirectoryPath: pluginPath,
+		},
+		coreclient: fake.NewSimpleClientset(),
+	}
+
+	state, err := NewComputeDomainState(config)
+	require.NoError(t, err)
+
+	channelOf := func(claim *resourceapi.ResourceClaim) (int64, string) {
+		prepared, err := state.Prepare(claim)
+		require.NoError(t, err)
+		require.Len(t, prepared, 1)
+		require.Len(t, prepared[0].ContainerEdits.DeviceNodes, 1)
+		data := state.channelStatusData(claim)(resourceapi.DeviceRequestAllocationResult{Device: prepared[0].DeviceName})
+		channel := data.(ChannelStatusData).Channel
+		assert.Equal(t, claim.Status.Allocation.Devices.Results[0].Device, prepared[0].DeviceName, "the prepared device is the allocated one")
+		assert.Equal(t, deviceNameForChannel(int(channel)), filepath.Base(prepared[0].ContainerEdits.DeviceNodes[0].Path), "the container gets the domain's channel")
+		return channel, prepared[0].ContainerEdits.DeviceNodes[0].Path
+	}
+
+	first, firstPath := channelOf(newTestDomainClaim("claim-a", "domain-a"))
+	second, secondPath := channelOf(newTestDomainClaim("claim-b", "domain-b"))
+	shared, _ := channelOf(newTestDomainClaim("claim-c", "domain-a"))
+
+	assert.Equal(t, int64(0), first)
+	assert.Equal(t, int64(1), second)
+	assert.Equal(t, first, shared, "claims of one domain share its channel")
+	assert.True(t, strings.HasSuffix(firstPath, filepath.Join("domain-a", "channel-0")))
+	assert.True(t, strings.HasSuffix(secondPath, filepath.Join("domain-b", "channel-1")))
+
+	// The allocation survives a plugin restart.
+	restarted, err := NewComputeDomainState(config)
+	require.NoError(t, err)
+	require.Contains(t, restarted.domains, "domain-b")
+	assert.Equal(t, 1, restarted.domains["domain-b"].Channel)
+
+	// A freed channel is handed out again.
+	require.NoError(t, state.Unprepare("claim-a"))
+	require.NoError(t, state.Unprepare("claim-c"))
+	reused, _ := channelOf(newTestDomainClaim("claim-d", "domain-d"))
+	assert.Equal(t, int64(0), reused)
+}
+
+func TestComputeDomainState_AllocateChannelExhausted(t *testing.T) {
+	state := &ComputeDomainState{nodeName: "test-node"}
+
+	domains := make(map[string]*DomainInfo, maxChannelID)
+	for channel := range maxChannelID {
+		domainID := fmt.Sprintf("domain-%d", channel)
+		domains[domainID] = &DomainInfo{DomainID: domainID, Channel: channel}
+	}
+
+	_, err := state.allocateChannel(domains)
+	assert.ErrorContains(t, err, "all 2048 IMEX channels on node test-node are in use")
+
+	_, err = state.getOrCreateDomain(domains, "new-domain", newTestDomainClaim("claim-new", "new-domain"), true)
+	assert.Equal(t, "ChannelsExhausted", dra.ErrorReason(err))
+
+	delete(domains, "domain-42")
+	channel, err := state.allocateChannel(domains)
+	require.NoError(t, err)
+	assert.Equal(t, 42, channel)
+}
+
+func TestComputeDomainState_AllAllocationHoldsNoChannel(t *testing.T) {
+	tmpDir := t.TempDir()
+	config := &Config{
+		flags: &Flags{
+			cdiRoot:                     filepath.Join(tmpDir, "cdi"),
+			nodeName:                    "test-node",
+			kubeletPluginsDirectoryPath: filepath.Join(tmpDir, "plugin"),
+		},
+		coreclient: fake.NewSimpleClientset(),
+	}
+	require.NoError(t, os.MkdirAll(config.flags.cdiRoot, 0750))
+	require.NoError(t, os.MkdirAll(config.flags.kubeletPluginsDirectoryPath, 0750))
+
+	state, err := NewComputeDomainState(config)
+	require.NoError(t, err)
+
+	allClaim := newTestDomainClaim("claim-all", "domain-all")
+	allClaim.Spec.Devices.Config[0].Opaque.Parameters.Raw = []byte(`{"allocationMode": "All", "domainID": "domain-all"}`)
+	prepared, err := state.Prepare(allClaim)
+	require.NoError(t, err)
+	assert.Len(t, prepared, len(state.allocatable))
+	assert.Equal(t, noChannel, state.domains["domain-all"].Channel)
+
+	prepared, err = state.Prepare(newTestDomainClaim("claim-single", "domain-single"))
+	require.NoError(t, err)
+	assert.Equal(t, deviceNameForChannel(0), filepath.Base(prepared[0].ContainerEdits.DeviceNodes[0].Path))
+
+	// A "Single" claim of the domain gets it a channel.
+	prepared, err = state.Prepare(newTestDomainClaim("claim-single-2", "domain-all"))
+	require.NoError(t, err)
+	assert.Equal(t, deviceNameForChannel(1), filepath.Base(prepared[0].ContainerEdits.DeviceNodes[0].Path))
+	assert.Equal(t, 1, state.domains["domain-all"].Channel)
+}
+
+func TestNewComputeDomainState_ReassignsSharedChannels(t *testing.T) {
+	tmpDir := t.TempDir()
+	config := &Config{
+		flags: &Flags{
+			cdiRoot:                     filepath.Join(tmpDir, "cdi"),
+			nodeName:                    "test-node",
+			kubeletPluginsDirectoryPath: filepath.Join(tmpDir, "plugin"),
+		},
+		coreclient: fake.NewSimpleClientset(),
+	}
+	require.NoError(t, os.MkdirAll(config.flags.cdiRoot, 0750))
+	require.NoError(t, os.MkdirAll(config.flags.kubeletPluginsDirectoryPath, 0750))
+
+	// Checkpoints written before per-domain allocation hold no channel.
+	created := time.Now()
+	legacy := newComputeDomainCheckpoint()
+	for idx, domainID := range []string{"domain-c", "domain-a", "domain-b"} {
+		legacy.V1.Domains[domainID] = &DomainInfo{
+			DomainID:  domainID,
+			Claims:    []string{"claim-" + domainID},
+			CreatedAt: created.Add(time.Duration(idx) * time.Minute),
+		}
+	}
+	checkpointManager, err := checkpointmanager.NewCheckpointManager(config.DriverPluginPath())
+	require.NoError(t, err)
+	require.NoError(t, checkpointManager.CreateCheckpoint(DriverPluginCheckpointFile, legacy))
+
+	state, err := NewComputeDomainState(config)
+	require.NoError(t, err)
+	assert.Equal(t, 0, state.domains["domain-c"].Channel, "the oldest domain keeps channel 0")
+	assert.Equal(t, 1, state.domains["domain-a"].Channel)
+	assert.Equal(t, 2, state.domains["domain-b"].Channel)
+
+	// The reassignment is written back to the checkpoint.
+	restarted, err := NewComputeDomainState(config)
+	require.NoError(t, err)
+	assert.Equal(t, 2, restarted.domains["domain-b"].Channel)
+
+	prepared, err := restarted.Prepare(newTestDomainClaim("claim-new", "domain-new"))
+	require.NoError(t, err)
+	assert.Equal(t, deviceNameForChannel(3), filepath.Base(prepared[0].ContainerEdits.DeviceNodes[0].Path))
+}
+
+func TestNewComputeDomainState_KeepsChannelOfPreparedClaims(t *testing.T) {
+	tmpDir := t.TempDir()
+	config := &Config{
+		flags: &Flags{
+			cdiRoot:                     filepath.Join(tmpDir, "cdi"),
+			nodeName:                    "test-node",
+			kubeletPluginsDirectoryPath: filepath.Join(tmpDir, "plugin"),
+		},
+		coreclient: fake.NewSimpleClientset(),
+	}
+	require.NoError(t, os.MkdirAll(config.flags.cdiRoot, 0750))
+	require.NoError(t, os.MkdirAll(config.flags.kubeletPluginsDirectoryPath, 0750))
+
+	// domain-b has a running pod on channel 0; the older domain-a has none.
+	created := time.Now()
+	legacy := newComputeDomainCheckpoint()
+	legacy.V1.Domains["domain-a"] = &DomainInfo{DomainID: "domain-a", Claims: []string{"claim-a"}, CreatedAt: created}
+	legacy.V1.Domains["domain-b"] = &DomainInfo{DomainID: "domain-b", Claims: []string{"claim-b"}, CreatedAt: created.Add(time.Minute)}
+	legacy.V1.PreparedClaims["claim-b"] = ComputeDomainPreparedDevices{
+		{Device: drapbv1.Device{RequestNames: []string{"channel"}, DeviceName: deviceNameForChannel(0)}},
+	}
+	checkpointManager, err := checkpointmanager.NewCheckpointManager(config.DriverPluginPath())
+	require.NoError(t, err)
+	require.NoError(t, checkpointManager.CreateCheckpoint(DriverPluginCheckpointFile, legacy))
+
+	state, err := NewComputeDomainState(config)
+	require.NoError(t, err)
+	assert.Equal(t, 0, state.domains["domain-b"].Channel, "a domain with prepared claims keeps its channel")
+	assert.Equal(t, 1, state.domains["domain-a"].Channel)
+
+	// Later claims of domain-b join the running pod on its channel.
+	claim := newTestDomainClaim("claim-b2", "domain-b")
+	prepared, err := state.Prepare(claim)
+	require.NoError(t, err)
+	require.Len(t, prepared, 1)
+	assert.Equal(t, deviceNameForChannel(0), prepared[0].DeviceName)
+	require.Len(t, prepared[0].ContainerEdits.DeviceNodes, 1)
+	assert.True(t, strings.HasSuffix(prepared[0].ContainerEdits.DeviceNodes[0].Path, filepath.Join("domain-b", "channel-0")))
+	data := state.channelStatusData(claim)(resourceapi.DeviceRequestAllocationResult{Device: prepared[0].DeviceName})
+	assert.Equal(t, int64(0), data.(ChannelStatusData).Channel)
+}
+
+func newTestDomainClaim(uid, domainID string) *resourceapi.ResourceClaim {
+	claim := newTestComputeDomainClaim()
+	claim.UID = types.UID(uid)
+	claim.Name = uid
+	claim.Spec.Devices.Config[0].Opaque.Parameters.Raw = []byte(fmt.Sprintf(`{
+		"allocationMode": "Single",
+		"apiVersion": "resource.nvidia.com/v1beta1",
+		"domainID": %q,
+		"kind": "ComputeDomainChannelConfig"
+	}`, domainID))
+	return claim
+}
+
 func newTestComputeDomainClaim() *resourceapi.ResourceClaim {
 	return &resourceapi.ResourceClaim{
 		ObjectMeta: metav1.ObjectMeta{
@@ -230,6 +440,15 @@ func newTestComputeDomainClaim() *resourceapi.ResourceClaim {
 				},
 			},
 		},
+		Status: resourceapi.ResourceClaimStatus{
+			Allocation: &resourceapi.AllocationResult{
+				Devices: resourceapi.DeviceAllocationResult{
+					Results: []resourceapi.DeviceRequestAllocationResult{
+						{Driver: consts.ComputeDomainDriverName, Pool: "test-node", Device: deviceNameForChannel(0), Request: "channel"},
+					},
+				},
+			},
+		},
 	}
 }
 
@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 
+	"github.com/run-ai/fake-gpu-operator/internal/common/dra"
 	"github.com/run-ai/fake-gpu-operator/internal/common/kubeclient"
 	"k8s.io/dynamic-resource-allocation/kubeletplugin"
 )
@@ -43,6 +44,9 @@ func (app *DraPluginGpuApp) Init(stop chan struct{}) {
 	if app.Flags.HealthcheckPort == 0 {
 		app.Flags.HealthcheckPort = -1
 	}
+	if app.Flags.MetricsBindAddress == "" {
+		app.Flags.MetricsBindAddress = ":8080"
+	}
 
 	app.ctx, app.cancel = context.WithCancel(context.Background())
 	go func() {
@@ -50,6 +54,14 @@ func (app *DraPluginGpuApp) Init(stop chan struct{}) {
 		app.cancel()
 	}()
 
+	if app.Flags.MetricsBindAddress != "0" {
+		go func() {
+			if err := dra.ServeMetrics(app.ctx, app.Flags.MetricsBindAddress); err != nil {
+				log.Printf("Metrics server stopped: %v", err)
+			}
+		}()
+	}
+
 	app.kubeClient = kubeclient.NewKubeClient(nil, stop)
 
 	if err := app.validateAndCreateDirectories(); err != nil {
@@ -2,8 +2,12 @@ package dra_plugin_gpu
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
 
+	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
+	resourceapi "k8s.io/api/resource/v1"
+
 	cdiapi "tags.cncf.io/container-device-interface/pkg/cdi"
 	cdiparser "tags.cncf.io/container-device-interface/pkg/parser"
 	cdispec "tags.cncf.io/container-device-interface/specs-go"
@@ -114,6 +118,53 @@ func (cdi *CDIHandler) DeleteClaimSpecFile(claimUID string) error {
 	return err
 }
 
+// ListClaimSpecFiles returns the devices of every claim spec file in the CDI
+// root, by claim UID.
+func (cdi *CDIHandler) ListClaimSpecFiles() map[string][]string {
+	prefix := cdiapi.GenerateSpecName(cdiVendor, cdiClass) + "_"
+	claims := make(map[string][]string)
+	for _, spec := range cdi.cache.GetVendorSpecs(cdiVendor) {
+		if spec.GetClass() != cdiClass {
+			continue
+		}
+		path := spec.GetPath()
+		claimUID, ok := strings.CutPrefix(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), prefix)
+		if !ok || claimUID == cdiCommonDeviceName {
+			continue
+		}
+		var devices []string
+		for _, device := range spec.Devices {
+			devices = append(devices, strings.TrimPrefix(device.Name, claimUID+"-"))
+		}
+		claims[claimUID] = devices
+	}
+	return claims
+}
+
+// visibleDeviceEnv returns the environment variable that tells the fake
+// nvidia-smi which GPU a claim's device is, so it lists exactly the claimed
+// devices regardless of pod name or UID.
+func visibleDeviceEnv(device resourceapi.Device) string {
+	visible := topology.VisibleDevice{UUID: device.Name}
+	if attr, ok := device.Attributes["gpu.nvidia.com/uuid"]; ok && attr.StringValue != nil {
+		visible.UUID = *attr.StringValue
+	}
+	if attr, ok := device.Attributes["gpu.nvidia.com/index"]; ok && attr.IntValue != nil {
+		visible.Index = int(*attr.IntValue)
+	}
+	// MIG devices, as NVIDIA's driver publishes them, name their profile and
+	// the GPU they are carved from.
+	if attr, ok := device.Attributes["gpu.nvidia.com/type"]; ok && attr.StringValue != nil && *attr.StringValue == "mig" {
+		if profile, ok := device.Attributes["gpu.nvidia.com/profile"]; ok && profile.StringValue != nil {
+			visible.MigProfile = *profile.StringValue
+		}
+		if parent, ok := device.Attributes["gpu.nvidia.com/parentUUID"]; ok && parent.StringValue != nil {
+			visible.ParentUUID = *parent.StringValue
+		}
+	}
+	return fmt.Sprintf("%s%s=%s", topology.EnvVisibleDevicePrefix, sanitizeDeviceNameForEnvVar(device.Name), visible.EnvValue())
+}
+
 func (cdi *CDIHandler) GetClaimDevices(claimUID string, devices []string) []string {
 	cdiDevices := []string{
 		cdiparser.QualifiedName(cdiVendor, cdiClass, cdiCommonDeviceName),
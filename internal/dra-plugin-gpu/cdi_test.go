@@ -10,7 +10,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v3"
+	resourceapi "k8s.io/api/resource/v1"
 	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+	"k8s.io/utils/ptr"
 
 	cdiapi "tags.cncf.io/container-device-interface/pkg/cdi"
 	cdiparser "tags.cncf.io/container-device-interface/pkg/parser"
@@ -282,3 +284,45 @@ func TestCDIHandler_GetClaimDevices(t *testing.T) {
 
 // Note: CDI error scenarios require mocking the CDI library which is complex.
 // Error handling is covered by e2e tests.
+
+func TestVisibleDeviceEnv(t *testing.T) {
+	tests := map[string]struct {
+		device resourceapi.Device
+		want   string
+	}{
+		"full GPU": {
+			device: resourceapi.Device{
+				Name: "gpu-abc-1",
+				Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+					"gpu.nvidia.com/uuid":  {StringValue: ptr.To("GPU-ABC-1")},
+					"gpu.nvidia.com/index": {IntValue: ptr.To(int64(3))},
+					"gpu.nvidia.com/type":  {StringValue: ptr.To("gpu")},
+				},
+			},
+			want: "FAKE_GPU_VISIBLE_DEVICE_gpu_abc_1=uuid=GPU-ABC-1,index=3",
+		},
+		"MIG device": {
+			device: resourceapi.Device{
+				Name: "gpu-0-mig-1g-10gb-0",
+				Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+					"gpu.nvidia.com/uuid":       {StringValue: ptr.To("MIG-0")},
+					"gpu.nvidia.com/index":      {IntValue: ptr.To(int64(0))},
+					"gpu.nvidia.com/type":       {StringValue: ptr.To("mig")},
+					"gpu.nvidia.com/profile":    {StringValue: ptr.To("1g.10gb")},
+					"gpu.nvidia.com/parentUUID": {StringValue: ptr.To("GPU-0")},
+				},
+			},
+			want: "FAKE_GPU_VISIBLE_DEVICE_gpu_0_mig_1g_10gb_0=uuid=MIG-0,index=0,mig=1g.10gb,parent=GPU-0",
+		},
+		"missing attributes fall back to the device name": {
+			device: resourceapi.Device{Name: "gpu-0"},
+			want:   "FAKE_GPU_VISIBLE_DEVICE_gpu_0=uuid=gpu-0,index=0",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.want, visibleDeviceEnv(test.device))
+		})
+	}
+}
@@ -17,6 +17,9 @@ type Flags struct {
 	KubeletRegistrarDirectoryPath string `mapstructure:"KUBELET_REGISTRAR_DIRECTORY_PATH"`
 	KubeletPluginsDirectoryPath   string `mapstructure:"KUBELET_PLUGINS_DIRECTORY_PATH"`
 	HealthcheckPort               int    `mapstructure:"HEALTHCHECK_PORT"`
+	// MetricsBindAddress is where Prometheus metrics are served; "0"
+	// disables them.
+	MetricsBindAddress string `mapstructure:"METRICS_BIND_ADDRESS"`
 }
 
 // Config contains the configuration for the DRA plugin
@@ -50,12 +50,9 @@ func enumerateAllPossibleDevices(nodeName string) (AllocatableDevices, error) {
 		return nil, fmt.Errorf("topology server returned no GPUs for node %s", nodeName)
 	}
 
-	// Convert GpuMemory from MB to bytes for resource.Quantity
-	memoryBytes := int64(nodeTopology.GpuMemory) * 1024 * 1024
-
 	// Map GPU info to resourceapi.Device structures
 	alldevices := make(AllocatableDevices)
-	for _, gpu := range nodeTopology.Gpus {
+	for idx, gpu := range nodeTopology.Gpus {
 		if gpu.ID == "" {
 			return nil, fmt.Errorf("GPU entry missing ID in topology")
 		}
@@ -70,7 +67,7 @@ func enumerateAllPossibleDevices(nodeName string) (AllocatableDevices, error) {
 				StringValue: ptr.To(gpu.ID),
 			},
 			"model": {
-				StringValue: ptr.To(nodeTopology.GpuProduct),
+				StringValue: ptr.To(nodeTopology.GpuProductOf(idx)),
 			},
 			"gpu.nvidia.com/type": {
 				StringValue: ptr.To("gpu"),
@@ -79,12 +76,30 @@ func enumerateAllPossibleDevices(nodeName string) (AllocatableDevices, error) {
 				StringValue: ptr.To(gpu.ID),
 			},
 			"gpu.nvidia.com/productName": {
-				StringValue: ptr.To(nodeTopology.GpuProduct),
+				StringValue: ptr.To(nodeTopology.GpuProductOf(idx)),
+			},
+			"gpu.nvidia.com/index": {
+				IntValue: ptr.To(int64(idx)),
 			},
 		}
+		if nodeTopology.CliqueID != "" {
+			attributes["gpu.nvidia.com/cliqueId"] = resourceapi.DeviceAttribute{
+				StringValue: ptr.To(nodeTopology.CliqueID),
+			}
+		}
+		if gpu.Architecture != "" {
+			attributes["gpu.nvidia.com/architecture"] = resourceapi.DeviceAttribute{
+				StringValue: ptr.To(gpu.Architecture),
+			}
+		}
+		if gpu.PciBusID != "" {
+			attributes["gpu.nvidia.com/pcieBusID"] = resourceapi.DeviceAttribute{
+				StringValue: ptr.To(gpu.PciBusID),
+			}
+		}
 
-		// Convert memory to resource.Quantity
-		memoryQuantity := resource.NewQuantity(memoryBytes, resource.BinarySI)
+		// Convert the GPU memory from MiB to bytes for resource.Quantity
+		memoryQuantity := resource.NewQuantity(int64(nodeTopology.GpuMemoryOf(idx))*1024*1024, resource.BinarySI)
 
 		device := resourceapi.Device{
 			Name:       deviceName,
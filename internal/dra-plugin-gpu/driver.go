@@ -6,6 +6,11 @@ import (
 	"fmt"
 	"log"
 	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/dra"
 
 	resourceapi "k8s.io/api/resource/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -13,15 +18,20 @@ import (
 	coreclientset "k8s.io/client-go/kubernetes"
 	"k8s.io/dynamic-resource-allocation/kubeletplugin"
 	"k8s.io/dynamic-resource-allocation/resourceslice"
+	drahealthv1alpha1 "k8s.io/kubelet/pkg/apis/dra-health/v1alpha1"
 )
 
-// Driver implements the DRA driver interface
+// Driver implements the DRA driver interface and the DRA resource health
+// service, which the kubelet plugin helper registers automatically.
 type Driver struct {
-	client      coreclientset.Interface
-	helper      *kubeletplugin.Helper
-	state       *DeviceState
-	healthcheck *healthcheck
-	cancelCtx   func(error)
+	drahealthv1alpha1.UnimplementedDRAResourceHealthServer
+
+	client             coreclientset.Interface
+	helper             *kubeletplugin.Helper
+	state              *DeviceState
+	healthcheck        *healthcheck
+	cancelCtx          func(error)
+	healthPollInterval time.Duration
 }
 
 // GetState returns the device state (for use by node controller)
@@ -31,8 +41,9 @@ func (d *Driver) GetState() *DeviceState {
 
 func NewDriver(ctx context.Context, config *Config) (*Driver, error) {
 	driver := &Driver{
-		client:    config.CoreClient,
-		cancelCtx: config.CancelMainCtx,
+		client:             config.CoreClient,
+		cancelCtx:          config.CancelMainCtx,
+		healthPollInterval: defaultHealthPollInterval,
 	}
 
 	// Start helper first (needed for NewDeviceState)
@@ -56,18 +67,14 @@ func NewDriver(ctx context.Context, config *Config) (*Driver, error) {
 	}
 	driver.state = state
 
-	devices := make([]resourceapi.Device, 0, len(state.allocatable))
-	for device := range maps.Values(state.allocatable) {
-		devices = append(devices, device)
-	}
+	// Sort by name so devices keep their slice across restarts.
+	devices := slices.SortedFunc(maps.Values(state.allocatable), func(a, b resourceapi.Device) int {
+		return strings.Compare(a.Name, b.Name)
+	})
 	resources := resourceslice.DriverResources{
 		Pools: map[string]resourceslice.Pool{
 			config.Flags.NodeName: {
-				Slices: []resourceslice.Slice{
-					{
-						Devices: devices,
-					},
-				},
+				Slices: dra.PoolSlices(devices),
 			},
 		},
 	}
@@ -103,7 +110,10 @@ func (d *Driver) PrepareResourceClaims(ctx context.Context, claims []*resourceap
 	return result, nil
 }
 
-func (d *Driver) prepareResourceClaim(ctx context.Context, claim *resourceapi.ResourceClaim) kubeletplugin.PrepareResult {
+func (d *Driver) prepareResourceClaim(ctx context.Context, claim *resourceapi.ResourceClaim) (result kubeletplugin.PrepareResult) {
+	start := time.Now()
+	defer func() { dra.ObserveOperation(DriverName, dra.OperationPrepare, start, result.Err) }()
+
 	preparedPBs, err := d.state.Prepare(ctx, claim)
 	if err != nil {
 		return kubeletplugin.PrepareResult{
@@ -120,6 +130,12 @@ func (d *Driver) prepareResourceClaim(ctx context.Context, claim *resourceapi.Re
 		})
 	}
 
+	if d.client != nil {
+		if err := dra.PublishDeviceStatuses(ctx, d.client, claim, DriverName, d.state.deviceStatusData); err != nil {
+			log.Printf("Failed to publish device status for claim %v: %v", claim.UID, err)
+		}
+	}
+
 	log.Printf("Prepared devices for claim %v: %v", claim.UID, prepared)
 	return kubeletplugin.PrepareResult{Devices: prepared}
 }
@@ -135,7 +151,10 @@ func (d *Driver) UnprepareResourceClaims(ctx context.Context, claims []kubeletpl
 	return result, nil
 }
 
-func (d *Driver) unprepareResourceClaim(_ context.Context, claim kubeletplugin.NamespacedObject) error {
+func (d *Driver) unprepareResourceClaim(_ context.Context, claim kubeletplugin.NamespacedObject) (err error) {
+	start := time.Now()
+	defer func() { dra.ObserveOperation(DriverName, dra.OperationUnprepare, start, err) }()
+
 	if err := d.state.Unprepare(string(claim.UID)); err != nil {
 		return fmt.Errorf("error unpreparing devices for claim %v: %w", claim.UID, err)
 	}
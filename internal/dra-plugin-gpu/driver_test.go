@@ -14,6 +14,8 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/dra"
 )
 
 // Test constants (reuse from state_test.go where applicable)
@@ -123,6 +125,7 @@ func TestDriver_PrepareResourceClaim(t *testing.T) {
 	tests := map[string]struct {
 		claim       *resourceapi.ResourceClaim
 		wantErr     bool
+		wantReason  string
 		wantDevices bool
 	}{
 		"successful preparation": {
@@ -137,7 +140,8 @@ func TestDriver_PrepareResourceClaim(t *testing.T) {
 				},
 				Status: resourceapi.ResourceClaimStatus{},
 			},
-			wantErr: true,
+			wantErr:    true,
+			wantReason: "NotAllocated",
 		},
 	}
 
@@ -148,6 +152,7 @@ func TestDriver_PrepareResourceClaim(t *testing.T) {
 			if test.wantErr {
 				assert.NotNil(t, result.Err)
 				assert.Nil(t, result.Devices)
+				assert.Equal(t, test.wantReason, dra.ErrorReason(result.Err))
 			} else {
 				assert.Nil(t, result.Err)
 				if test.wantDevices {
@@ -0,0 +1,188 @@
+package dra_plugin_gpu
+
+import (
+	"fmt"
+
+	nvconfigapi "github.com/NVIDIA/k8s-dra-driver-gpu/api/nvidia.com/resource/v1beta1"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer/json"
+	"k8s.io/utils/ptr"
+
+	configapi "sigs.k8s.io/dra-example-driver/api/example.com/resource/gpu/v1alpha1"
+
+	cdiapi "tags.cncf.io/container-device-interface/pkg/cdi"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// ConfigDecoder decodes opaque device configs written either for NVIDIA's DRA
+// driver (resource.nvidia.com) or for the DRA example driver. The API group of
+// the parameters picks the decoder, so claims written for a real cluster are
+// accepted unchanged.
+var ConfigDecoder runtime.Decoder = configDecoder{}
+
+type configDecoder struct{}
+
+func (configDecoder) Decode(data []byte, defaults *schema.GroupVersionKind, into runtime.Object) (runtime.Object, *schema.GroupVersionKind, error) {
+	gvk, err := json.DefaultMetaFactory.Interpret(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if gvk.Group == nvconfigapi.GroupName {
+		return nvconfigapi.StrictDecoder.Decode(data, defaults, into)
+	}
+	return configapi.Decoder.Decode(data, defaults, into)
+}
+
+// normalizeNvidiaGpuConfig fills in the defaults NVIDIA's driver implies for a
+// GpuConfig. Upstream gates sharing behind feature gates; the fake driver
+// always behaves as if TimeSlicingSettings and MPSSupport are enabled.
+func normalizeNvidiaGpuConfig(config *nvconfigapi.GpuConfig) {
+	if config.Sharing == nil {
+		return
+	}
+	if config.Sharing.IsTimeSlicing() {
+		if config.Sharing.TimeSlicingConfig == nil {
+			config.Sharing.TimeSlicingConfig = &nvconfigapi.TimeSlicingConfig{}
+		}
+		if config.Sharing.TimeSlicingConfig.Interval == nil {
+			config.Sharing.TimeSlicingConfig.Interval = ptr.To(nvconfigapi.DefaultTimeSlice)
+		}
+	}
+	if config.Sharing.IsMps() && config.Sharing.MpsConfig == nil {
+		config.Sharing.MpsConfig = &nvconfigapi.MpsConfig{}
+	}
+}
+
+// normalizeNvidiaMigDeviceConfig fills in the defaults NVIDIA's driver implies
+// for a MigDeviceConfig.
+func normalizeNvidiaMigDeviceConfig(config *nvconfigapi.MigDeviceConfig) {
+	if config.Sharing == nil {
+		return
+	}
+	if config.Sharing.IsMps() && config.Sharing.MpsConfig == nil {
+		config.Sharing.MpsConfig = &nvconfigapi.MpsConfig{}
+	}
+}
+
+// validateNvidiaSharing checks a normalized NVIDIA sharing configuration.
+func validateNvidiaSharing(strategy nvconfigapi.GpuSharingStrategy, sharing nvconfigapi.Sharing) error {
+	switch {
+	case sharing.IsTimeSlicing():
+		tsconfig, err := sharing.GetTimeSlicingConfig()
+		if err != nil {
+			return err
+		}
+		if tsconfig != nil && tsconfig.Interval != nil {
+			return tsconfig.Interval.Validate()
+		}
+		return nil
+	case sharing.IsMps():
+		mpsconfig, err := sharing.GetMpsConfig()
+		if err != nil {
+			return err
+		}
+		return mpsconfig.Validate()
+	}
+	return fmt.Errorf("unknown GPU sharing strategy: %v", strategy)
+}
+
+// applyNvidiaConfig turns an NVIDIA GpuConfig or MigDeviceConfig into the
+// container edits for the devices it applies to. Sharing is simulated: the
+// strategy and its settings are exposed as environment variables next to the
+// ones injected for the DRA example driver config.
+func (s *DeviceState) applyNvidiaConfig(config runtime.Object, results []*resourceapi.DeviceRequestAllocationResult) (PerDeviceCDIContainerEdits, error) {
+	var strategy nvconfigapi.GpuSharingStrategy
+	var sharing nvconfigapi.Sharing
+	switch castConfig := config.(type) {
+	case *nvconfigapi.GpuConfig:
+		normalizeNvidiaGpuConfig(castConfig)
+		if castConfig.Sharing != nil {
+			strategy, sharing = castConfig.Sharing.Strategy, castConfig.Sharing
+		}
+	case *nvconfigapi.MigDeviceConfig:
+		normalizeNvidiaMigDeviceConfig(castConfig)
+		if castConfig.Sharing != nil {
+			strategy, sharing = castConfig.Sharing.Strategy, castConfig.Sharing
+		}
+	default:
+		return nil, fmt.Errorf("runtime object is not a recognized configuration")
+	}
+
+	if sharing != nil {
+		if err := validateNvidiaSharing(strategy, sharing); err != nil {
+			return nil, fmt.Errorf("error validating GPU config: %w", err)
+		}
+	}
+
+	uuids := make([]string, 0, len(results))
+	for _, result := range results {
+		uuids = append(uuids, s.deviceUUID(result.Device))
+	}
+
+	var pinnedMemoryLimits map[string]string
+	var activeThreadPercentage *int
+	if sharing != nil && sharing.IsMps() {
+		mpsconfig, err := sharing.GetMpsConfig()
+		if err != nil {
+			return nil, err
+		}
+		pinnedMemoryLimits, err = mpsconfig.DefaultPerDevicePinnedMemoryLimit.Normalize(uuids, mpsconfig.DefaultPinnedDeviceMemoryLimit)
+		if err != nil {
+			return nil, fmt.Errorf("error normalizing MPS pinned memory limits: %w", err)
+		}
+		activeThreadPercentage = mpsconfig.DefaultActiveThreadPercentage
+	}
+
+	perDeviceEdits := make(PerDeviceCDIContainerEdits)
+	for i, result := range results {
+		deviceID := sanitizeDeviceNameForEnvVar(result.Device)
+		envs := []string{
+			fmt.Sprintf("GPU_DEVICE_%s=%s", deviceID, result.Device),
+		}
+
+		if sharing != nil {
+			envs = append(envs, fmt.Sprintf("GPU_DEVICE_%s_SHARING_STRATEGY=%s", deviceID, strategy))
+		}
+
+		switch {
+		case sharing != nil && sharing.IsTimeSlicing():
+			tsconfig, err := sharing.GetTimeSlicingConfig()
+			if err != nil {
+				return nil, fmt.Errorf("unable to get time slicing config for device %v: %w", result.Device, err)
+			}
+			if tsconfig != nil && tsconfig.Interval != nil {
+				envs = append(envs, fmt.Sprintf("GPU_DEVICE_%s_TIMESLICE_INTERVAL=%v", deviceID, *tsconfig.Interval))
+			}
+		case sharing != nil && sharing.IsMps():
+			if activeThreadPercentage != nil {
+				envs = append(envs, fmt.Sprintf("GPU_DEVICE_%s_MPS_ACTIVE_THREAD_PERCENTAGE=%d", deviceID, *activeThreadPercentage))
+			}
+			if limit, ok := pinnedMemoryLimits[uuids[i]]; ok {
+				envs = append(envs, fmt.Sprintf("GPU_DEVICE_%s_MPS_PINNED_DEVICE_MEMORY_LIMIT=%s", deviceID, limit))
+			}
+		}
+
+		edits := &cdispec.ContainerEdits{
+			Env: envs,
+		}
+
+		perDeviceEdits[result.Device] = &cdiapi.ContainerEdits{ContainerEdits: edits}
+	}
+
+	return perDeviceEdits, nil
+}
+
+// deviceUUID returns the GPU UUID published for an allocatable device, falling
+// back to the device name when the attribute is missing.
+func (s *DeviceState) deviceUUID(deviceName string) string {
+	device, ok := s.allocatable[deviceName]
+	if !ok {
+		return deviceName
+	}
+	if attr, ok := device.Attributes["gpu.nvidia.com/uuid"]; ok && attr.StringValue != nil {
+		return *attr.StringValue
+	}
+	return deviceName
+}
@@ -0,0 +1,91 @@
+package dra_plugin_gpu
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	drahealthv1alpha1 "k8s.io/kubelet/pkg/apis/dra-health/v1alpha1"
+)
+
+const (
+	// defaultHealthPollInterval is how often the node topology is re-read to
+	// pick up injected GPU faults for the resource health stream.
+	defaultHealthPollInterval = 10 * time.Second
+)
+
+type DeviceHealthMap map[string]drahealthv1alpha1.HealthStatus
+
+// DeviceHealth returns the health of every allocatable device as recorded in
+// the node topology. Devices missing from the topology are reported as
+// UNKNOWN.
+func (s *DeviceState) DeviceHealth() (DeviceHealthMap, error) {
+	nodeTopology, err := s.getTopology(s.nodeName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get topology for node %s: %w", s.nodeName, err)
+	}
+
+	health := make(DeviceHealthMap, len(s.allocatable))
+	for deviceName := range s.allocatable {
+		health[deviceName] = drahealthv1alpha1.HealthStatus_UNKNOWN
+	}
+	for _, gpu := range nodeTopology.Gpus {
+		deviceName := strings.ToLower(gpu.ID)
+		if _, ok := health[deviceName]; !ok {
+			continue
+		}
+		if gpu.IsHealthy() {
+			health[deviceName] = drahealthv1alpha1.HealthStatus_HEALTHY
+		} else {
+			health[deviceName] = drahealthv1alpha1.HealthStatus_UNHEALTHY
+		}
+	}
+
+	return health, nil
+}
+
+// NodeWatchResources implements [drahealthv1alpha1.DRAResourceHealthServer].
+// It streams the full device health list to the kubelet on start and again
+// whenever the health of any device changes.
+func (d *Driver) NodeWatchResources(_ *drahealthv1alpha1.NodeWatchResourcesRequest, stream grpc.ServerStreamingServer[drahealthv1alpha1.NodeWatchResourcesResponse]) error {
+	ticker := time.NewTicker(d.healthPollInterval)
+	defer ticker.Stop()
+
+	var last DeviceHealthMap
+	for {
+		health, err := d.state.DeviceHealth()
+		if err != nil {
+			log.Printf("Failed to read device health: %v", err)
+		} else if last == nil || !maps.Equal(health, last) {
+			if err := stream.Send(d.healthResponse(health)); err != nil {
+				return fmt.Errorf("failed to send device health: %w", err)
+			}
+			last = health
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Driver) healthResponse(health DeviceHealthMap) *drahealthv1alpha1.NodeWatchResourcesResponse {
+	now := time.Now().Unix()
+	resp := &drahealthv1alpha1.NodeWatchResourcesResponse{}
+	for deviceName, status := range health {
+		resp.Devices = append(resp.Devices, &drahealthv1alpha1.DeviceHealth{
+			Device: &drahealthv1alpha1.DeviceIdentifier{
+				PoolName:   d.state.nodeName,
+				DeviceName: deviceName,
+			},
+			Health:          status,
+			LastUpdatedTime: now,
+		})
+	}
+	return resp
+}
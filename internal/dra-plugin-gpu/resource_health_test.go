@@ -0,0 +1,175 @@
+package dra_plugin_gpu
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	resourceapi "k8s.io/api/resource/v1"
+	drahealthv1alpha1 "k8s.io/kubelet/pkg/apis/dra-health/v1alpha1"
+)
+
+type fakeHealthStream struct {
+	grpc.ServerStream
+	ctx context.Context
+
+	mu        sync.Mutex
+	responses []*drahealthv1alpha1.NodeWatchResourcesResponse
+}
+
+func (f *fakeHealthStream) Context() context.Context {
+	return f.ctx
+}
+
+func (f *fakeHealthStream) Send(resp *drahealthv1alpha1.NodeWatchResourcesResponse) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses = append(f.responses, resp)
+	return nil
+}
+
+func (f *fakeHealthStream) received() []*drahealthv1alpha1.NodeWatchResourcesResponse {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*drahealthv1alpha1.NodeWatchResourcesResponse{}, f.responses...)
+}
+
+// switchableTopology serves a node topology that tests can change between polls.
+type switchableTopology struct {
+	mu       sync.Mutex
+	topology *topology.NodeTopology
+	err      error
+}
+
+func (s *switchableTopology) get(string) (*topology.NodeTopology, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.topology, s.err
+}
+
+func (s *switchableTopology) set(nodeTopology *topology.NodeTopology) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.topology = nodeTopology
+}
+
+func newHealthTestState(getTopology getTopologyFunc) *DeviceState {
+	return &DeviceState{
+		nodeName: testNodeName,
+		allocatable: AllocatableDevices{
+			"gpu-test-0": resourceapi.Device{Name: "gpu-test-0"},
+			"gpu-test-1": resourceapi.Device{Name: "gpu-test-1"},
+		},
+		getTopology: getTopology,
+	}
+}
+
+func TestDeviceState_DeviceHealth(t *testing.T) {
+	tests := map[string]struct {
+		topology *topology.NodeTopology
+		err      error
+		want     DeviceHealthMap
+		wantErr  bool
+	}{
+		"all healthy": {
+			topology: &topology.NodeTopology{Gpus: []topology.GpuDetails{
+				{ID: "GPU-test-0"},
+				{ID: "GPU-test-1", Health: topology.GpuHealthHealthy},
+			}},
+			want: DeviceHealthMap{
+				"gpu-test-0": drahealthv1alpha1.HealthStatus_HEALTHY,
+				"gpu-test-1": drahealthv1alpha1.HealthStatus_HEALTHY,
+			},
+		},
+		"injected fault": {
+			topology: &topology.NodeTopology{Gpus: []topology.GpuDetails{
+				{ID: "GPU-test-0"},
+				{ID: "GPU-test-1", Health: topology.GpuHealthUnhealthy},
+			}},
+			want: DeviceHealthMap{
+				"gpu-test-0": drahealthv1alpha1.HealthStatus_HEALTHY,
+				"gpu-test-1": drahealthv1alpha1.HealthStatus_UNHEALTHY,
+			},
+		},
+		"device missing from topology": {
+			topology: &topology.NodeTopology{Gpus: []topology.GpuDetails{
+				{ID: "GPU-test-0"},
+				{ID: "GPU-not-allocatable"},
+			}},
+			want: DeviceHealthMap{
+				"gpu-test-0": drahealthv1alpha1.HealthStatus_HEALTHY,
+				"gpu-test-1": drahealthv1alpha1.HealthStatus_UNKNOWN,
+			},
+		},
+		"topology unavailable": {
+			err:     errors.New("topology server down"),
+			wantErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			state := newHealthTestState(func(string) (*topology.NodeTopology, error) {
+				return test.topology, test.err
+			})
+
+			health, err := state.DeviceHealth()
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, health)
+		})
+	}
+}
+
+func TestDriver_NodeWatchResources(t *testing.T) {
+	source := &switchableTopology{topology: &topology.NodeTopology{Gpus: []topology.GpuDetails{
+		{ID: "GPU-test-0"},
+		{ID: "GPU-test-1"},
+	}}}
+	d := &Driver{
+		state:              newHealthTestState(source.get),
+		healthPollInterval: 10 * time.Millisecond,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &fakeHealthStream{ctx: ctx}
+	done := make(chan error)
+	go func() {
+		done <- d.NodeWatchResources(&drahealthv1alpha1.NodeWatchResourcesRequest{}, stream)
+	}()
+
+	require.Eventually(t, func() bool { return len(stream.received()) == 1 }, time.Second, 5*time.Millisecond)
+
+	// Unchanged health is not re-sent.
+	time.Sleep(50 * time.Millisecond)
+	require.Len(t, stream.received(), 1)
+
+	source.set(&topology.NodeTopology{Gpus: []topology.GpuDetails{
+		{ID: "GPU-test-0"},
+		{ID: "GPU-test-1", Health: topology.GpuHealthUnhealthy},
+	}})
+	require.Eventually(t, func() bool { return len(stream.received()) == 2 }, time.Second, 5*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	latest := map[string]drahealthv1alpha1.HealthStatus{}
+	for _, device := range stream.received()[1].Devices {
+		assert.Equal(t, testNodeName, device.Device.PoolName)
+		assert.NotZero(t, device.LastUpdatedTime)
+		latest[device.Device.DeviceName] = device.Health
+	}
+	assert.Equal(t, map[string]drahealthv1alpha1.HealthStatus{
+		"gpu-test-0": drahealthv1alpha1.HealthStatus_HEALTHY,
+		"gpu-test-1": drahealthv1alpha1.HealthStatus_UNHEALTHY,
+	}, latest)
+}
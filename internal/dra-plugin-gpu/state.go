@@ -9,12 +9,15 @@ import (
 	"sync"
 	"time"
 
+	"github.com/run-ai/fake-gpu-operator/internal/common/dra"
+	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
 	resourceapi "k8s.io/api/resource/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	coreclientset "k8s.io/client-go/kubernetes"
 	"k8s.io/dynamic-resource-allocation/kubeletplugin"
 	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
 
+	nvconfigapi "github.com/NVIDIA/k8s-dra-driver-gpu/api/nvidia.com/resource/v1beta1"
 	configapi "sigs.k8s.io/dra-example-driver/api/example.com/resource/gpu/v1alpha1"
 
 	cdiapi "tags.cncf.io/container-device-interface/pkg/cdi"
@@ -49,6 +52,9 @@ func (pds PreparedDevices) GetDevices() []*drapbv1.Device {
 	return devices
 }
 
+// getTopologyFunc fetches the topology of a node; swapped out in tests.
+type getTopologyFunc func(nodeName string) (*topology.NodeTopology, error)
+
 type DeviceState struct {
 	sync.Mutex
 	cdi         *CDIHandler
@@ -56,6 +62,13 @@ type DeviceState struct {
 	nodeName    string
 	coreclient  coreclientset.Interface
 	helper      *kubeletplugin.Helper
+	getTopology getTopologyFunc
+
+	// prepared holds the devices of every prepared claim, for the
+	// prepared-claims and allocated-devices metrics. Kubelet does not prepare
+	// claims again after a plugin restart, so it is restored from the claim
+	// spec files left in the CDI root.
+	prepared map[string][]string
 }
 
 // waitForTopology polls for the topology from the HTTP server every 3 seconds until available.
@@ -95,13 +108,30 @@ func NewDeviceState(ctx context.Context, config *Config, helper *kubeletplugin.H
 		return nil, fmt.Errorf("unable to create CDI spec file for common edits: %v", err)
 	}
 
-	return &DeviceState{
+	state := &DeviceState{
 		cdi:         cdi,
 		allocatable: allocatable,
 		nodeName:    config.Flags.NodeName,
 		coreclient:  config.CoreClient,
 		helper:      helper,
-	}, nil
+		getTopology: getTopologyFromHTTP,
+	}
+	state.restorePrepared()
+
+	return state, nil
+}
+
+// restorePrepared rebuilds the prepared claims from the claim spec files of
+// the CDI root and publishes them.
+func (s *DeviceState) restorePrepared() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.prepared = s.cdi.ListClaimSpecFiles()
+	if len(s.prepared) > 0 {
+		log.Printf("Restored %d prepared claims from CDI spec files", len(s.prepared))
+	}
+	s.recordMetrics()
 }
 
 func (s *DeviceState) Prepare(ctx context.Context, claim *resourceapi.ResourceClaim) ([]*drapbv1.Device, error) {
@@ -112,13 +142,23 @@ func (s *DeviceState) Prepare(ctx context.Context, claim *resourceapi.ResourceCl
 
 	preparedDevices, err := s.prepareDevices(claim)
 	if err != nil {
-		return nil, fmt.Errorf("prepare failed: %v", err)
+		return nil, fmt.Errorf("prepare failed: %w", err)
 	}
 
 	// CDI file creation is idempotent (overwrites if exists)
 	if err = s.cdi.CreateClaimSpecFile(claimUID, preparedDevices); err != nil {
-		return nil, fmt.Errorf("unable to create CDI spec file for claim: %v", err)
+		return nil, dra.WithReason("CDI", fmt.Errorf("unable to create CDI spec file for claim: %v", err))
+	}
+
+	if s.prepared == nil {
+		s.prepared = make(map[string][]string)
+	}
+	var devices []string
+	for _, device := range preparedDevices {
+		devices = append(devices, device.DeviceName)
 	}
+	s.prepared[claimUID] = devices
+	s.recordMetrics()
 
 	return preparedDevices.GetDevices(), nil
 }
@@ -128,22 +168,42 @@ func (s *DeviceState) Unprepare(claimUID string) error {
 	defer s.Unlock()
 
 	// CDI file deletion is idempotent (handles missing files gracefully)
-	return s.cdi.DeleteClaimSpecFile(claimUID)
+	if err := s.cdi.DeleteClaimSpecFile(claimUID); err != nil {
+		return dra.WithReason("CDI", err)
+	}
+	delete(s.prepared, claimUID)
+	s.recordMetrics()
+	return nil
+}
+
+// recordMetrics publishes the number of prepared claims and of allocatable
+// devices they use. Callers must hold the lock.
+func (s *DeviceState) recordMetrics() {
+	allocated := make(map[string]bool)
+	for _, devices := range s.prepared {
+		for _, device := range devices {
+			if _, ok := s.allocatable[device]; ok {
+				allocated[device] = true
+			}
+		}
+	}
+	dra.SetPreparedClaims(DriverName, s.nodeName, len(s.prepared))
+	dra.SetPoolDevices(DriverName, s.nodeName, len(s.allocatable), len(allocated))
 }
 
 func (s *DeviceState) prepareDevices(claim *resourceapi.ResourceClaim) (PreparedDevices, error) {
 	if claim.Status.Allocation == nil {
-		return nil, fmt.Errorf("claim not yet allocated")
+		return nil, dra.WithReason("NotAllocated", fmt.Errorf("claim not yet allocated"))
 	}
 
 	// Retrieve the full set of device configs for the driver.
 	configs, err := GetOpaqueDeviceConfigs(
-		configapi.Decoder,
+		ConfigDecoder,
 		DriverName,
 		claim.Status.Allocation.Devices.Config,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("error getting opaque device configs: %v", err)
+		return nil, dra.WithReason("InvalidConfig", fmt.Errorf("error getting opaque device configs: %v", err))
 	}
 
 	// Add the default GPU Config to the front of the config list with the
@@ -159,7 +219,7 @@ func (s *DeviceState) prepareDevices(claim *resourceapi.ResourceClaim) (Prepared
 	configResultsMap := make(map[runtime.Object][]*resourceapi.DeviceRequestAllocationResult)
 	for _, result := range claim.Status.Allocation.Devices.Results {
 		if _, exists := s.allocatable[result.Device]; !exists {
-			return nil, fmt.Errorf("requested GPU is not allocatable: %v", result.Device)
+			return nil, dra.WithReason("DeviceNotAllocatable", fmt.Errorf("requested GPU is not allocatable: %v", result.Device))
 		}
 		for _, c := range slices.Backward(configs) {
 			if len(c.Requests) == 0 || slices.Contains(c.Requests, result.Request) {
@@ -174,29 +234,31 @@ func (s *DeviceState) prepareDevices(claim *resourceapi.ResourceClaim) (Prepared
 	// config to the set of device allocation results.
 	perDeviceCDIContainerEdits := make(PerDeviceCDIContainerEdits)
 	for c, results := range configResultsMap {
-		// Cast the opaque config to a GpuConfig
-		var config *configapi.GpuConfig
-		switch castConfig := c.(type) {
+		var containerEdits PerDeviceCDIContainerEdits
+		switch config := c.(type) {
 		case *configapi.GpuConfig:
-			config = castConfig
-		default:
-			return nil, fmt.Errorf("runtime object is not a regognized configuration")
-		}
-
-		// Normalize the config to set any implied defaults.
-		if err := config.Normalize(); err != nil {
-			return nil, fmt.Errorf("error normalizing GPU config: %w", err)
-		}
+			// Normalize the config to set any implied defaults.
+			if err := config.Normalize(); err != nil {
+				return nil, dra.WithReason("InvalidConfig", fmt.Errorf("error normalizing GPU config: %w", err))
+			}
 
-		// Validate the config to ensure its integrity.
-		if err := config.Validate(); err != nil {
-			return nil, fmt.Errorf("error validating GPU config: %w", err)
-		}
+			// Validate the config to ensure its integrity.
+			if err := config.Validate(); err != nil {
+				return nil, dra.WithReason("InvalidConfig", fmt.Errorf("error validating GPU config: %w", err))
+			}
 
-		// Apply the config to the list of results associated with it.
-		containerEdits, err := s.applyConfig(config, results)
-		if err != nil {
-			return nil, fmt.Errorf("error applying GPU config: %w", err)
+			// Apply the config to the list of results associated with it.
+			containerEdits, err = s.applyConfig(config, results)
+			if err != nil {
+				return nil, fmt.Errorf("error applying GPU config: %w", err)
+			}
+		case *nvconfigapi.GpuConfig, *nvconfigapi.MigDeviceConfig:
+			containerEdits, err = s.applyNvidiaConfig(config, results)
+			if err != nil {
+				return nil, fmt.Errorf("error applying GPU config: %w", err)
+			}
+		default:
+			return nil, dra.WithReason("InvalidConfig", fmt.Errorf("runtime object is not a regognized configuration"))
 		}
 
 		// Merge any new container edits with the overall per device map.
@@ -205,6 +267,18 @@ func (s *DeviceState) prepareDevices(claim *resourceapi.ResourceClaim) (Prepared
 		}
 	}
 
+	// Tell nvidia-smi which GPUs this claim holds.
+	for _, results := range configResultsMap {
+		for _, result := range results {
+			edits := perDeviceCDIContainerEdits[result.Device]
+			if edits == nil || edits.ContainerEdits == nil {
+				edits = &cdiapi.ContainerEdits{ContainerEdits: &cdispec.ContainerEdits{}}
+				perDeviceCDIContainerEdits[result.Device] = edits
+			}
+			edits.Env = append(edits.Env, visibleDeviceEnv(s.allocatable[result.Device]))
+		}
+	}
+
 	// Walk through each config and its associated device allocation results
 	// and construct the list of prepared devices to return.
 	var preparedDevices PreparedDevices
@@ -226,6 +300,25 @@ func (s *DeviceState) prepareDevices(claim *resourceapi.ResourceClaim) (Prepared
 	return preparedDevices, nil
 }
 
+// GpuDeviceStatusData is published in ResourceClaim.status.devices[].data for
+// every prepared GPU.
+type GpuDeviceStatusData struct {
+	UUID        string `json:"uuid"`
+	Index       int    `json:"index"`
+	AdminAccess bool   `json:"adminAccess,omitempty"`
+}
+
+func (s *DeviceState) deviceStatusData(result resourceapi.DeviceRequestAllocationResult) any {
+	data := GpuDeviceStatusData{
+		UUID:        s.deviceUUID(result.Device),
+		AdminAccess: dra.IsAdminAccess(result),
+	}
+	if attr, ok := s.allocatable[result.Device].Attributes["gpu.nvidia.com/index"]; ok && attr.IntValue != nil {
+		data.Index = int(*attr.IntValue)
+	}
+	return data
+}
+
 // sanitizeDeviceNameForEnvVar replaces hyphens with underscores in device names
 // to make them valid shell environment variable names.
 func sanitizeDeviceNameForEnvVar(deviceName string) string {
@@ -10,12 +10,16 @@ import (
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/fake"
 	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+	"k8s.io/utils/ptr"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 
+	nvconfigapi "github.com/NVIDIA/k8s-dra-driver-gpu/api/nvidia.com/resource/v1beta1"
 	configapi "sigs.k8s.io/dra-example-driver/api/example.com/resource/gpu/v1alpha1"
 )
 
@@ -273,6 +277,60 @@ func TestDeviceState_Unprepare(t *testing.T) {
 	}
 }
 
+func TestDeviceState_RestorePrepared(t *testing.T) {
+	config, cleanup := createTestConfig(t)
+	defer cleanup()
+
+	// A previous plugin process prepared two claims.
+	previous := createTestStateWithDevices(t, config, testGpuDevice0, testGpuDevice1)
+	require.NoError(t, previous.cdi.CreateCommonSpecFile())
+	_, err := previous.Prepare(context.Background(), createTestClaim(testClaimUID1, testGpuDevice0, testRequest1, testNodeName))
+	require.NoError(t, err)
+	_, err = previous.Prepare(context.Background(), createTestClaim(testClaimUID2, testGpuDevice1, testRequest1, testNodeName))
+	require.NoError(t, err)
+
+	state := createTestStateWithDevices(t, config, testGpuDevice0, testGpuDevice1)
+	state.restorePrepared()
+
+	assert.Equal(t, map[string][]string{
+		testClaimUID1: {testGpuDevice0},
+		testClaimUID2: {testGpuDevice1},
+	}, state.prepared)
+	nodeLabels := map[string]string{"driver": DriverName, "node": testNodeName}
+	poolLabels := map[string]string{"driver": DriverName, "pool": testNodeName}
+	assert.Equal(t, 2.0, gaugeValue(t, "fake_gpu_operator_dra_prepared_claims", nodeLabels))
+	assert.Equal(t, 2.0, gaugeValue(t, "fake_gpu_operator_dra_allocatable_devices", poolLabels))
+	assert.Equal(t, 2.0, gaugeValue(t, "fake_gpu_operator_dra_allocated_devices", poolLabels))
+
+	require.NoError(t, state.Unprepare(testClaimUID1))
+	assert.Equal(t, 1.0, gaugeValue(t, "fake_gpu_operator_dra_prepared_claims", nodeLabels))
+	assert.Equal(t, 1.0, gaugeValue(t, "fake_gpu_operator_dra_allocated_devices", poolLabels))
+}
+
+// gaugeValue reads the gauge with the given labels from the metrics registry.
+func gaugeValue(t *testing.T, metric string, labels map[string]string) float64 {
+	families, err := ctrlmetrics.Registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != metric {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			matched := 0
+			for _, label := range m.GetLabel() {
+				if labels[label.GetName()] == label.GetValue() {
+					matched++
+				}
+			}
+			if matched == len(labels) {
+				return m.GetGauge().GetValue()
+			}
+		}
+	}
+	t.Fatalf("gauge %s%v not found", metric, labels)
+	return 0
+}
+
 func TestGetOpaqueDeviceConfigs(t *testing.T) {
 	decoder := configapi.Decoder
 	driverName := DriverName
@@ -557,6 +615,209 @@ func TestDeviceState_PrepareDevices(t *testing.T) {
 	}
 }
 
+func TestConfigDecoder(t *testing.T) {
+	tests := map[string]struct {
+		raw      string
+		wantErr  bool
+		wantType runtime.Object
+	}{
+		"example driver GpuConfig": {
+			raw:      `{"apiVersion":"gpu.resource.example.com/v1alpha1","kind":"GpuConfig"}`,
+			wantType: &configapi.GpuConfig{},
+		},
+		"NVIDIA GpuConfig": {
+			raw:      `{"apiVersion":"resource.nvidia.com/v1beta1","kind":"GpuConfig","sharing":{"strategy":"TimeSlicing"}}`,
+			wantType: &nvconfigapi.GpuConfig{},
+		},
+		"NVIDIA MigDeviceConfig": {
+			raw:      `{"apiVersion":"resource.nvidia.com/v1beta1","kind":"MigDeviceConfig","sharing":{"strategy":"MPS"}}`,
+			wantType: &nvconfigapi.MigDeviceConfig{},
+		},
+		"NVIDIA GpuConfig with unknown field": {
+			raw:     `{"apiVersion":"resource.nvidia.com/v1beta1","kind":"GpuConfig","bogus":true}`,
+			wantErr: true,
+		},
+		"unknown kind": {
+			raw:     `{"apiVersion":"resource.nvidia.com/v1beta1","kind":"Unknown"}`,
+			wantErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			obj, err := runtime.Decode(ConfigDecoder, []byte(test.raw))
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.IsType(t, test.wantType, obj)
+		})
+	}
+}
+
+func TestDeviceState_ApplyNvidiaConfig(t *testing.T) {
+	state := &DeviceState{
+		allocatable: AllocatableDevices{
+			"gpu-test-0": resourceapi.Device{
+				Name: "gpu-test-0",
+				Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+					"gpu.nvidia.com/uuid": {StringValue: ptr.To("GPU-test-0")},
+				},
+			},
+			"gpu-test-1": resourceapi.Device{Name: "gpu-test-1"},
+		},
+	}
+	results := []*resourceapi.DeviceRequestAllocationResult{
+		{Device: "gpu-test-0", Request: "request-1", Pool: "test-node"},
+		{Device: "gpu-test-1", Request: "request-1", Pool: "test-node"},
+	}
+	pinnedLimit := resource.MustParse("1Gi")
+
+	tests := map[string]struct {
+		config   runtime.Object
+		wantErr  bool
+		validate func(*testing.T, PerDeviceCDIContainerEdits)
+	}{
+		"GpuConfig without sharing": {
+			config: &nvconfigapi.GpuConfig{},
+			validate: func(t *testing.T, edits PerDeviceCDIContainerEdits) {
+				assert.Equal(t, []string{"GPU_DEVICE_gpu_test_0=gpu-test-0"}, edits["gpu-test-0"].Env)
+			},
+		},
+		"GpuConfig time-slicing defaults the interval": {
+			config: &nvconfigapi.GpuConfig{
+				Sharing: &nvconfigapi.GpuSharing{Strategy: nvconfigapi.TimeSlicingStrategy},
+			},
+			validate: func(t *testing.T, edits PerDeviceCDIContainerEdits) {
+				envs := edits["gpu-test-1"].Env
+				assert.Contains(t, envs, "GPU_DEVICE_gpu_test_1_SHARING_STRATEGY=TimeSlicing")
+				assert.Contains(t, envs, "GPU_DEVICE_gpu_test_1_TIMESLICE_INTERVAL=Default")
+			},
+		},
+		"GpuConfig time-slicing with interval": {
+			config: &nvconfigapi.GpuConfig{
+				Sharing: &nvconfigapi.GpuSharing{
+					Strategy:          nvconfigapi.TimeSlicingStrategy,
+					TimeSlicingConfig: &nvconfigapi.TimeSlicingConfig{Interval: ptr.To(nvconfigapi.LongTimeSlice)},
+				},
+			},
+			validate: func(t *testing.T, edits PerDeviceCDIContainerEdits) {
+				assert.Contains(t, edits["gpu-test-0"].Env, "GPU_DEVICE_gpu_test_0_TIMESLICE_INTERVAL=Long")
+			},
+		},
+		"GpuConfig MPS with limits": {
+			config: &nvconfigapi.GpuConfig{
+				Sharing: &nvconfigapi.GpuSharing{
+					Strategy: nvconfigapi.MpsStrategy,
+					MpsConfig: &nvconfigapi.MpsConfig{
+						DefaultActiveThreadPercentage:  ptr.To(50),
+						DefaultPinnedDeviceMemoryLimit: &pinnedLimit,
+						DefaultPerDevicePinnedMemoryLimit: nvconfigapi.MpsPerDevicePinnedMemoryLimit{
+							"GPU-test-0": resource.MustParse("2Gi"),
+						},
+					},
+				},
+			},
+			validate: func(t *testing.T, edits PerDeviceCDIContainerEdits) {
+				envs0 := edits["gpu-test-0"].Env
+				assert.Contains(t, envs0, "GPU_DEVICE_gpu_test_0_SHARING_STRATEGY=MPS")
+				assert.Contains(t, envs0, "GPU_DEVICE_gpu_test_0_MPS_ACTIVE_THREAD_PERCENTAGE=50")
+				assert.Contains(t, envs0, "GPU_DEVICE_gpu_test_0_MPS_PINNED_DEVICE_MEMORY_LIMIT=2048M")
+				assert.Contains(t, edits["gpu-test-1"].Env, "GPU_DEVICE_gpu_test_1_MPS_PINNED_DEVICE_MEMORY_LIMIT=1024M")
+			},
+		},
+		"MigDeviceConfig MPS": {
+			config: &nvconfigapi.MigDeviceConfig{
+				Sharing: &nvconfigapi.MigDeviceSharing{Strategy: nvconfigapi.MpsStrategy},
+			},
+			validate: func(t *testing.T, edits PerDeviceCDIContainerEdits) {
+				assert.Contains(t, edits["gpu-test-0"].Env, "GPU_DEVICE_gpu_test_0_SHARING_STRATEGY=MPS")
+			},
+		},
+		"unknown strategy": {
+			config: &nvconfigapi.GpuConfig{
+				Sharing: &nvconfigapi.GpuSharing{Strategy: "Bogus"},
+			},
+			wantErr: true,
+		},
+		"time-slicing with MPS config": {
+			config: &nvconfigapi.GpuConfig{
+				Sharing: &nvconfigapi.GpuSharing{
+					Strategy:  nvconfigapi.TimeSlicingStrategy,
+					MpsConfig: &nvconfigapi.MpsConfig{},
+				},
+			},
+			wantErr: true,
+		},
+		"invalid time-slice interval": {
+			config: &nvconfigapi.GpuConfig{
+				Sharing: &nvconfigapi.GpuSharing{
+					Strategy:          nvconfigapi.TimeSlicingStrategy,
+					TimeSlicingConfig: &nvconfigapi.TimeSlicingConfig{Interval: ptr.To(nvconfigapi.TimeSliceInterval("Forever"))},
+				},
+			},
+			wantErr: true,
+		},
+		"MPS thread percentage out of range": {
+			config: &nvconfigapi.GpuConfig{
+				Sharing: &nvconfigapi.GpuSharing{
+					Strategy:  nvconfigapi.MpsStrategy,
+					MpsConfig: &nvconfigapi.MpsConfig{DefaultActiveThreadPercentage: ptr.To(150)},
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			edits, err := state.applyNvidiaConfig(test.config, results)
+			if test.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, edits)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, edits, 2)
+			if test.validate != nil {
+				test.validate(t, edits)
+			}
+		})
+	}
+}
+
+func TestDeviceState_PrepareDevicesWithNvidiaConfig(t *testing.T) {
+	config, cleanup := createTestConfig(t)
+	defer cleanup()
+
+	state := createTestStateWithDevices(t, config, testGpuDevice0)
+
+	claim := createTestClaim(testClaimUID1, testGpuDevice0, testRequest1, testNodeName)
+	claim.Status.Allocation.Devices.Config = []resourceapi.DeviceAllocationConfiguration{
+		{
+			Source:   resourceapi.AllocationConfigSourceClaim,
+			Requests: []string{testRequest1},
+			DeviceConfiguration: resourceapi.DeviceConfiguration{
+				Opaque: &resourceapi.OpaqueDeviceConfiguration{
+					Driver: DriverName,
+					Parameters: runtime.RawExtension{
+						Raw: []byte(`{"apiVersion":"resource.nvidia.com/v1beta1","kind":"GpuConfig","sharing":{"strategy":"TimeSlicing","timeSlicingConfig":{"interval":"Short"}}}`),
+					},
+				},
+			},
+		},
+	}
+
+	devices, err := state.prepareDevices(claim)
+	require.NoError(t, err)
+	require.Len(t, devices, 1)
+	envs := devices[0].ContainerEdits.Env
+	assert.Contains(t, envs, "GPU_DEVICE_GPU_test_0_SHARING_STRATEGY=TimeSlicing")
+	assert.Contains(t, envs, "GPU_DEVICE_GPU_test_0_TIMESLICE_INTERVAL=Short")
+	assert.Contains(t, envs, "FAKE_GPU_VISIBLE_DEVICE_GPU_test_0=uuid=GPU-test-0,index=0")
+}
+
 // Helper function
 func mustMarshalJSON(t *testing.T, v interface{}) []byte {
 	data, err := json.Marshal(v)
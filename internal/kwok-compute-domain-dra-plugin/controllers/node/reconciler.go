@@ -6,8 +6,6 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	resourceapi "k8s.io/api/resource/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
@@ -17,6 +15,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+	"github.com/run-ai/fake-gpu-operator/internal/common/dra"
 	"github.com/run-ai/fake-gpu-operator/pkg/compute-domain/consts"
 )
 
@@ -54,53 +53,28 @@ func (r *NodeReconciler) resourceSliceName(nodeName string) string {
 
 func (r *NodeReconciler) createOrUpdateResourceSlice(ctx context.Context, node *corev1.Node) error {
 	devices := r.enumerateComputeDomainDevices()
-
-	resourceSlice := &resourceapi.ResourceSlice{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: r.resourceSliceName(node.Name),
-		},
-		Spec: resourceapi.ResourceSliceSpec{
-			Driver:   consts.ComputeDomainDriverName,
-			NodeName: ptr.To(node.Name),
-			Pool: resourceapi.ResourcePool{
-				Name:               node.Name,
-				ResourceSliceCount: 1,
-			},
-			Devices: devices,
-		},
-	}
-
-	existing, err := r.kubeClient.ResourceV1().ResourceSlices().Get(ctx, resourceSlice.Name, metav1.GetOptions{})
-	if err != nil {
-		if errors.IsNotFound(err) {
-			_, err = r.kubeClient.ResourceV1().ResourceSlices().Create(ctx, resourceSlice, metav1.CreateOptions{})
-			if err != nil {
-				return fmt.Errorf("failed to create ResourceSlice for node %s: %w", node.Name, err)
-			}
-			klog.InfoS("Created ResourceSlice for KWOK node", "node", node.Name, "deviceCount", len(devices))
-			return nil
-		}
-		return fmt.Errorf("failed to get ResourceSlice for node %s: %w", node.Name, err)
-	}
-
-	resourceSlice.ResourceVersion = existing.ResourceVersion
-	_, err = r.kubeClient.ResourceV1().ResourceSlices().Update(ctx, resourceSlice, metav1.UpdateOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to update ResourceSlice for node %s: %w", node.Name, err)
+	if err := dra.SyncNodePool(ctx, r.kubeClient, r.nodePool(node.Name), devices); err != nil {
+		return fmt.Errorf("failed to sync ResourceSlices for node %s: %w", node.Name, err)
 	}
-	klog.InfoS("Updated ResourceSlice for KWOK node", "node", node.Name, "deviceCount", len(devices))
 	return nil
 }
 
 func (r *NodeReconciler) deleteResourceSlice(ctx context.Context, nodeName string) error {
-	err := r.kubeClient.ResourceV1().ResourceSlices().Delete(ctx, r.resourceSliceName(nodeName), metav1.DeleteOptions{})
-	if err != nil && !errors.IsNotFound(err) {
-		return fmt.Errorf("failed to delete ResourceSlice for node %s: %w", nodeName, err)
+	if err := dra.DeleteNodePool(ctx, r.kubeClient, r.nodePool(nodeName)); err != nil {
+		return fmt.Errorf("failed to delete ResourceSlices for node %s: %w", nodeName, err)
 	}
-	klog.InfoS("Deleted ResourceSlice for KWOK node", "node", nodeName)
+	klog.InfoS("Deleted ResourceSlices for KWOK node", "node", nodeName)
 	return nil
 }
 
+func (r *NodeReconciler) nodePool(nodeName string) dra.NodePool {
+	return dra.NodePool{
+		Driver:    consts.ComputeDomainDriverName,
+		NodeName:  nodeName,
+		SliceName: r.resourceSliceName(nodeName),
+	}
+}
+
 func (r *NodeReconciler) enumerateComputeDomainDevices() []resourceapi.Device {
 	devices := make([]resourceapi.Device, 0, 1)
 	device := r.newChannelDevice(0)
@@ -4,7 +4,6 @@ import (
 	"context"
 	"log"
 
-	"github.com/spf13/viper"
 	corev1 "k8s.io/api/core/v1"
 	resourceapi "k8s.io/api/resource/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -12,8 +11,7 @@ import (
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
 
-	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
-	cmcontroller "github.com/run-ai/fake-gpu-operator/internal/kwok-dra-plugin/controllers/configmap"
+	fgncontroller "github.com/run-ai/fake-gpu-operator/internal/kwok-dra-plugin/controllers/fakegpunode"
 )
 
 type KWOKDraPluginAppConfiguration struct {
@@ -65,8 +63,6 @@ func (app *KWOKDraPluginApp) Init(stopCh chan struct{}) {
 	}
 
 	// Create manager
-	namespace := viper.GetString(constants.EnvTopologyCmNamespace)
-	topologyCMName := viper.GetString(constants.EnvTopologyCmName)
 	app.mgr, err = ctrl.NewManager(cfg, ctrl.Options{
 		Scheme: scheme,
 	})
@@ -80,9 +76,9 @@ func (app *KWOKDraPluginApp) Init(stopCh chan struct{}) {
 		log.Fatalf("Failed to create kubernetes client: %v", err)
 	}
 
-	// Setup ConfigMap reconciler
-	if err := cmcontroller.SetupWithManager(app.mgr, kubeClient, namespace, topologyCMName); err != nil {
-		log.Fatalf("Failed to setup ConfigMap controller: %v", err)
+	// Setup FakeGpuNode reconciler
+	if err := fgncontroller.SetupWithManager(app.mgr, kubeClient); err != nil {
+		log.Fatalf("Failed to setup FakeGpuNode controller: %v", err)
 	}
 }
 
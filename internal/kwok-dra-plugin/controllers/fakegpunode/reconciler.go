@@ -0,0 +1,94 @@
+package fakegpunode
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
+	rshandler "github.com/run-ai/fake-gpu-operator/internal/kwok-dra-plugin/handlers/resourceslice"
+)
+
+// FakeGpuNodeReconciler reconciles FakeGpuNode objects for KWOK nodes
+type FakeGpuNodeReconciler struct {
+	client.Client
+	Scheme  *runtime.Scheme
+	handler *rshandler.ResourceSliceHandler
+}
+
+// Reconcile handles FakeGpuNode events for KWOK nodes
+func (r *FakeGpuNodeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	obj := newFakeGpuNodeObject()
+	if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
+		// FakeGpuNode was deleted
+		if client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, err
+		}
+		// Handle deletion - the FakeGpuNode is named after its node
+		if err := r.handler.HandleDelete(req.Name); err != nil {
+			log.Printf("Failed to handle FakeGpuNode deletion for ResourceSlice: %v", err)
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	fakeGpuNode, err := topology.FakeGpuNodeFromUnstructured(obj)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Handle create/update
+	if err := r.handler.HandleAddOrUpdate(fakeGpuNode); err != nil {
+		log.Printf("Failed to handle FakeGpuNode for ResourceSlice: %v", err)
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *FakeGpuNodeReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Create a predicate to filter only FakeGpuNodes of KWOK nodes
+	kwokNodePredicate := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetAnnotations()[constants.AnnotationKwokNode] == "fake"
+	})
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(newFakeGpuNodeObject()).
+		WithEventFilter(kwokNodePredicate).
+		Complete(r)
+}
+
+// newFakeGpuNodeObject returns an empty FakeGpuNode for the controller-runtime
+// client, which reads custom resources without generated types as unstructured.
+func newFakeGpuNodeObject() *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(topology.FakeGpuNodeGVR.GroupVersion().WithKind(topology.FakeGpuNodeKind))
+	return obj
+}
+
+// SetupWithManager creates and sets up the FakeGpuNode reconciler with the manager
+func SetupWithManager(mgr ctrl.Manager, kubeClient kubernetes.Interface) error {
+	handler := rshandler.NewResourceSliceHandler(kubeClient)
+
+	reconciler := &FakeGpuNodeReconciler{
+		Client:  mgr.GetClient(),
+		Scheme:  mgr.GetScheme(),
+		handler: handler,
+	}
+
+	if err := reconciler.SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("failed to setup FakeGpuNode reconciler: %w", err)
+	}
+
+	log.Println("FakeGpuNode reconciler setup complete for KWOK DRA plugin")
+	return nil
+}
@@ -6,14 +6,11 @@ import (
 	"log"
 	"strings"
 
-	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+	"github.com/run-ai/fake-gpu-operator/internal/common/dra"
 	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
 
-	corev1 "k8s.io/api/core/v1"
 	resourceapi "k8s.io/api/resource/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/utils/ptr"
 )
@@ -24,7 +21,7 @@ const (
 
 // Interface defines the operations for handling ResourceSlices
 type Interface interface {
-	HandleAddOrUpdate(cm *corev1.ConfigMap) error
+	HandleAddOrUpdate(fakeGpuNode *topology.FakeGpuNode) error
 	HandleDelete(nodeName string) error
 }
 
@@ -42,79 +39,58 @@ func NewResourceSliceHandler(kubeClient kubernetes.Interface) *ResourceSliceHand
 	}
 }
 
-// HandleAddOrUpdate handles ConfigMap additions and updates by creating/updating the ResourceSlice
-func (h *ResourceSliceHandler) HandleAddOrUpdate(cm *corev1.ConfigMap) error {
-	nodeName := cm.Labels[constants.LabelTopologyCMNodeName]
-	if nodeName == "" {
-		return fmt.Errorf("ConfigMap %s/%s is missing node name label", cm.Namespace, cm.Name)
-	}
-
-	log.Printf("Handling ConfigMap add/update for KWOK node: %s\n", nodeName)
-
-	nodeTopology, err := topology.FromNodeTopologyCM(cm)
-	if err != nil {
-		return fmt.Errorf("failed to read node topology from ConfigMap: %w", err)
-	}
+// HandleAddOrUpdate handles FakeGpuNode additions and updates by creating/updating the ResourceSlice
+func (h *ResourceSliceHandler) HandleAddOrUpdate(fakeGpuNode *topology.FakeGpuNode) error {
+	nodeName := fakeGpuNode.Name
+	log.Printf("Handling FakeGpuNode add/update for KWOK node: %s\n", nodeName)
 
-	return h.createOrUpdateResourceSlice(nodeName, nodeTopology)
+	return h.createOrUpdateResourceSlice(nodeName, topology.FromFakeGpuNode(fakeGpuNode))
 }
 
-// HandleDelete handles ConfigMap deletions by deleting the ResourceSlice
+// HandleDelete handles FakeGpuNode deletions by deleting the ResourceSlice
 func (h *ResourceSliceHandler) HandleDelete(nodeName string) error {
-	log.Printf("Handling ConfigMap deletion for KWOK node: %s\n", nodeName)
+	log.Printf("Handling FakeGpuNode deletion for KWOK node: %s\n", nodeName)
 	return h.deleteResourceSlice(nodeName)
 }
 
 func (h *ResourceSliceHandler) createOrUpdateResourceSlice(nodeName string, nodeTopology *topology.NodeTopology) error {
 	devices := h.devicesFromTopology(nodeTopology)
-
-	resourceSlice := &resourceapi.ResourceSlice{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: h.resourceSliceName(nodeName),
-		},
-		Spec: resourceapi.ResourceSliceSpec{
-			Driver:   DriverName,
-			NodeName: ptr.To(nodeName),
-			Pool: resourceapi.ResourcePool{
-				Name:               nodeName,
-				ResourceSliceCount: 1,
-			},
-			Devices: devices,
-		},
+	if err := dra.SyncNodePool(context.TODO(), h.kubeClient, h.nodePool(nodeName), devices); err != nil {
+		return fmt.Errorf("failed to sync ResourceSlices for node %s: %w", nodeName, err)
 	}
+	// KWOK nodes run no kubelet plugin, so the allocations the status updater
+	// records in the FakeGpuNode stand in for prepared claims.
+	dra.SetPoolDevices(DriverName, nodeName, len(devices), allocatedGpus(nodeTopology))
+	return nil
+}
 
-	// Try to get existing ResourceSlice
-	existing, err := h.kubeClient.ResourceV1().ResourceSlices().Get(context.TODO(), resourceSlice.Name, metav1.GetOptions{})
-	if err != nil {
-		if errors.IsNotFound(err) {
-			// Create new ResourceSlice
-			_, err = h.kubeClient.ResourceV1().ResourceSlices().Create(context.TODO(), resourceSlice, metav1.CreateOptions{})
-			if err != nil {
-				return fmt.Errorf("failed to create ResourceSlice for node %s: %w", nodeName, err)
-			}
-			log.Printf("Created ResourceSlice for KWOK node %s with %d devices\n", nodeName, len(devices))
-			return nil
+// allocatedGpus counts the published GPUs of the topology that pods use.
+func allocatedGpus(nodeTopology *topology.NodeTopology) int {
+	allocated := 0
+	for _, gpu := range nodeTopology.Gpus {
+		if gpu.ID != "" && gpu.Status.AllocatedBy.Pod != "" {
+			allocated++
 		}
-		return fmt.Errorf("failed to get ResourceSlice for node %s: %w", nodeName, err)
 	}
+	return allocated
+}
 
-	// Update existing ResourceSlice
-	resourceSlice.ResourceVersion = existing.ResourceVersion
-	_, err = h.kubeClient.ResourceV1().ResourceSlices().Update(context.TODO(), resourceSlice, metav1.UpdateOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to update ResourceSlice for node %s: %w", nodeName, err)
+func (h *ResourceSliceHandler) deleteResourceSlice(nodeName string) error {
+	if err := dra.DeleteNodePool(context.TODO(), h.kubeClient, h.nodePool(nodeName)); err != nil {
+		return fmt.Errorf("failed to delete ResourceSlices for node %s: %w", nodeName, err)
 	}
-	log.Printf("Updated ResourceSlice for KWOK node %s with %d devices\n", nodeName, len(devices))
+	log.Printf("Deleted ResourceSlices for KWOK node %s\n", nodeName)
 	return nil
 }
 
-func (h *ResourceSliceHandler) deleteResourceSlice(nodeName string) error {
-	err := h.kubeClient.ResourceV1().ResourceSlices().Delete(context.TODO(), h.resourceSliceName(nodeName), metav1.DeleteOptions{})
-	if err != nil && !errors.IsNotFound(err) {
-		return fmt.Errorf("failed to delete ResourceSlice for node %s: %w", nodeName, err)
+// nodePool describes the node's GPU pool. GPUs beyond the per-slice limit
+// spill over into kwok-<node>-gpu-1, kwok-<node>-gpu-2, ...
+func (h *ResourceSliceHandler) nodePool(nodeName string) dra.NodePool {
+	return dra.NodePool{
+		Driver:    DriverName,
+		NodeName:  nodeName,
+		SliceName: h.resourceSliceName(nodeName),
 	}
-	log.Printf("Deleted ResourceSlice for KWOK node %s\n", nodeName)
-	return nil
 }
 
 func (h *ResourceSliceHandler) resourceSliceName(nodeName string) string {
@@ -124,10 +100,7 @@ func (h *ResourceSliceHandler) resourceSliceName(nodeName string) string {
 func (h *ResourceSliceHandler) devicesFromTopology(nodeTopology *topology.NodeTopology) []resourceapi.Device {
 	devices := make([]resourceapi.Device, 0, len(nodeTopology.Gpus))
 
-	// Convert GpuMemory from MB to bytes for resource.Quantity
-	memoryBytes := int64(nodeTopology.GpuMemory) * 1024 * 1024
-
-	for _, gpu := range nodeTopology.Gpus {
+	for idx, gpu := range nodeTopology.Gpus {
 		if gpu.ID == "" {
 			log.Printf("Warning: GPU entry missing ID in topology, skipping")
 			continue
@@ -143,7 +116,7 @@ func (h *ResourceSliceHandler) devicesFromTopology(nodeTopology *topology.NodeTo
 				StringValue: ptr.To(gpu.ID),
 			},
 			"model": {
-				StringValue: ptr.To(nodeTopology.GpuProduct),
+				StringValue: ptr.To(nodeTopology.GpuProductOf(idx)),
 			},
 			"gpu.nvidia.com/type": {
 				StringValue: ptr.To("gpu"),
@@ -152,12 +125,30 @@ func (h *ResourceSliceHandler) devicesFromTopology(nodeTopology *topology.NodeTo
 				StringValue: ptr.To(gpu.ID),
 			},
 			"gpu.nvidia.com/productName": {
-				StringValue: ptr.To(nodeTopology.GpuProduct),
+				StringValue: ptr.To(nodeTopology.GpuProductOf(idx)),
+			},
+			"gpu.nvidia.com/index": {
+				IntValue: ptr.To(int64(idx)),
 			},
 		}
+		if nodeTopology.CliqueID != "" {
+			attributes["gpu.nvidia.com/cliqueId"] = resourceapi.DeviceAttribute{
+				StringValue: ptr.To(nodeTopology.CliqueID),
+			}
+		}
+		if gpu.Architecture != "" {
+			attributes["gpu.nvidia.com/architecture"] = resourceapi.DeviceAttribute{
+				StringValue: ptr.To(gpu.Architecture),
+			}
+		}
+		if gpu.PciBusID != "" {
+			attributes["gpu.nvidia.com/pcieBusID"] = resourceapi.DeviceAttribute{
+				StringValue: ptr.To(gpu.PciBusID),
+			}
+		}
 
-		// Convert memory to resource.Quantity
-		memoryQuantity := resource.NewQuantity(memoryBytes, resource.BinarySI)
+		// Convert the GPU memory from MiB to bytes for resource.Quantity
+		memoryQuantity := resource.NewQuantity(int64(nodeTopology.GpuMemoryOf(idx))*1024*1024, resource.BinarySI)
 
 		device := resourceapi.Device{
 			Name:       deviceName,
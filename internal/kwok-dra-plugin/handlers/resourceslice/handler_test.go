@@ -2,15 +2,17 @@ package resourceslice
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"testing"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
 	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
-	"gopkg.in/yaml.v3"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 
-	corev1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
 )
@@ -33,25 +35,12 @@ var _ = Describe("ResourceSliceHandler", func() {
 				},
 			}
 
-			topologyData, err := yaml.Marshal(nodeTopology)
-			Expect(err).NotTo(HaveOccurred())
-
-			configMap := &corev1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: nodeName,
-					Labels: map[string]string{
-						constants.LabelTopologyCMNodeName: nodeName,
-					},
-				},
-				Data: map[string]string{
-					topology.CmTopologyKey: string(topologyData),
-				},
-			}
+			fakeGpuNode := topology.ToFakeGpuNode(nodeTopology, nodeName)
 
 			fakeClient := fake.NewSimpleClientset()
 
 			handler := NewResourceSliceHandler(fakeClient)
-			err = handler.HandleAddOrUpdate(configMap)
+			err := handler.HandleAddOrUpdate(fakeGpuNode)
 			Expect(err).NotTo(HaveOccurred())
 
 			// Verify ResourceSlice was created
@@ -73,27 +62,14 @@ var _ = Describe("ResourceSliceHandler", func() {
 				},
 			}
 
-			topologyData, err := yaml.Marshal(nodeTopology)
-			Expect(err).NotTo(HaveOccurred())
-
-			configMap := &corev1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: nodeName,
-					Labels: map[string]string{
-						constants.LabelTopologyCMNodeName: nodeName,
-					},
-				},
-				Data: map[string]string{
-					topology.CmTopologyKey: string(topologyData),
-				},
-			}
+			fakeGpuNode := topology.ToFakeGpuNode(nodeTopology, nodeName)
 
 			fakeClient := fake.NewSimpleClientset()
 
 			handler := NewResourceSliceHandler(fakeClient)
 
 			// Create initial ResourceSlice
-			err = handler.HandleAddOrUpdate(configMap)
+			err := handler.HandleAddOrUpdate(fakeGpuNode)
 			Expect(err).NotTo(HaveOccurred())
 
 			// Verify initial state
@@ -103,12 +79,10 @@ var _ = Describe("ResourceSliceHandler", func() {
 
 			// Update topology with more GPUs
 			nodeTopology.Gpus = append(nodeTopology.Gpus, topology.GpuDetails{ID: "GPU-0002-0002-0002-0002"})
-			topologyData, err = yaml.Marshal(nodeTopology)
-			Expect(err).NotTo(HaveOccurred())
-			configMap.Data[topology.CmTopologyKey] = string(topologyData)
+			fakeGpuNode = topology.ToFakeGpuNode(nodeTopology, nodeName)
 
 			// Update ResourceSlice
-			err = handler.HandleAddOrUpdate(configMap)
+			err = handler.HandleAddOrUpdate(fakeGpuNode)
 			Expect(err).NotTo(HaveOccurred())
 
 			// Verify updated state
@@ -116,6 +90,75 @@ var _ = Describe("ResourceSliceHandler", func() {
 			Expect(err).NotTo(HaveOccurred())
 			Expect(resourceSlice.Spec.Devices).To(HaveLen(2))
 		})
+
+		It("should split large nodes across slices and bump the pool generation", func() {
+			nodeName := "kwok-node4"
+			nodeTopology := &topology.NodeTopology{
+				GpuProduct: "NVIDIA-GB200",
+				GpuMemory:  189471,
+			}
+			for i := range 200 {
+				nodeTopology.Gpus = append(nodeTopology.Gpus, topology.GpuDetails{ID: fmt.Sprintf("GPU-%04d", i)})
+			}
+			fakeGpuNode := topology.ToFakeGpuNode(nodeTopology, nodeName)
+
+			// A single, unlabeled slice published by an older version is adopted.
+			fakeClient := fake.NewSimpleClientset(&resourceapi.ResourceSlice{
+				ObjectMeta: metav1.ObjectMeta{Name: "kwok-kwok-node4-gpu"},
+			})
+			handler := NewResourceSliceHandler(fakeClient)
+
+			Expect(handler.HandleAddOrUpdate(fakeGpuNode)).To(Succeed())
+
+			slices := listNodeSlices(fakeClient, nodeName)
+			Expect(slices).To(HaveLen(2))
+			Expect(slices[0].Name).To(Equal("kwok-kwok-node4-gpu"))
+			Expect(slices[0].Spec.Devices).To(HaveLen(resourceapi.ResourceSliceMaxDevices))
+			Expect(slices[1].Name).To(Equal("kwok-kwok-node4-gpu-1"))
+			Expect(slices[1].Spec.Devices).To(HaveLen(200 - resourceapi.ResourceSliceMaxDevices))
+			for _, slice := range slices {
+				Expect(slice.Spec.Pool.Name).To(Equal(nodeName))
+				Expect(slice.Spec.Pool.ResourceSliceCount).To(Equal(int64(2)))
+				Expect(slice.Spec.Pool.Generation).To(Equal(int64(1)))
+			}
+			// An unchanged topology keeps the generation.
+			Expect(handler.HandleAddOrUpdate(fakeGpuNode)).To(Succeed())
+			Expect(listNodeSlices(fakeClient, nodeName)[0].Spec.Pool.Generation).To(Equal(int64(1)))
+
+			// Shrinking the node removes the extra slice and bumps the generation.
+			nodeTopology.Gpus = nodeTopology.Gpus[:8]
+			Expect(handler.HandleAddOrUpdate(topology.ToFakeGpuNode(nodeTopology, nodeName))).To(Succeed())
+
+			slices = listNodeSlices(fakeClient, nodeName)
+			Expect(slices).To(HaveLen(1))
+			Expect(slices[0].Spec.Devices).To(HaveLen(8))
+			Expect(slices[0].Spec.Pool.ResourceSliceCount).To(Equal(int64(1)))
+			Expect(slices[0].Spec.Pool.Generation).To(Equal(int64(2)))
+		})
+
+		It("should report the GPUs allocated to pods", func() {
+			nodeName := "kwok-node-allocated"
+			nodeTopology := &topology.NodeTopology{
+				GpuProduct: "NVIDIA-A100-SXM4-40GB",
+				GpuMemory:  40960,
+				Gpus: []topology.GpuDetails{
+					{ID: "GPU-0001-0001-0001-0001"},
+					{ID: "GPU-0002-0002-0002-0002"},
+					{ID: "GPU-0003-0003-0003-0003"},
+				},
+			}
+			nodeTopology.Gpus[1].Status.AllocatedBy = topology.ContainerDetails{Namespace: "default", Pod: "pod-a", Container: "main"}
+
+			handler := NewResourceSliceHandler(fake.NewSimpleClientset())
+			Expect(handler.HandleAddOrUpdate(topology.ToFakeGpuNode(nodeTopology, nodeName))).To(Succeed())
+			Expect(poolDevices("fake_gpu_operator_dra_allocatable_devices", nodeName)).To(Equal(3.0))
+			Expect(poolDevices("fake_gpu_operator_dra_allocated_devices", nodeName)).To(Equal(1.0))
+
+			// Releasing the GPU drops it from the allocated devices.
+			nodeTopology.Gpus[1].Status.AllocatedBy = topology.ContainerDetails{}
+			Expect(handler.HandleAddOrUpdate(topology.ToFakeGpuNode(nodeTopology, nodeName))).To(Succeed())
+			Expect(poolDevices("fake_gpu_operator_dra_allocated_devices", nodeName)).To(Equal(0.0))
+		})
 	})
 
 	Describe("HandleDelete", func() {
@@ -129,27 +172,14 @@ var _ = Describe("ResourceSliceHandler", func() {
 				},
 			}
 
-			topologyData, err := yaml.Marshal(nodeTopology)
-			Expect(err).NotTo(HaveOccurred())
-
-			configMap := &corev1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: nodeName,
-					Labels: map[string]string{
-						constants.LabelTopologyCMNodeName: nodeName,
-					},
-				},
-				Data: map[string]string{
-					topology.CmTopologyKey: string(topologyData),
-				},
-			}
+			fakeGpuNode := topology.ToFakeGpuNode(nodeTopology, nodeName)
 
 			fakeClient := fake.NewSimpleClientset()
 
 			handler := NewResourceSliceHandler(fakeClient)
 
 			// Create ResourceSlice
-			err = handler.HandleAddOrUpdate(configMap)
+			err := handler.HandleAddOrUpdate(fakeGpuNode)
 			Expect(err).NotTo(HaveOccurred())
 
 			// Verify it exists
@@ -201,6 +231,33 @@ var _ = Describe("ResourceSliceHandler", func() {
 			Expect(*devices[1].Attributes["gpu.nvidia.com/uuid"].StringValue).To(Equal("GPU-0002-0002-0002-0002"))
 		})
 
+		It("should describe each GPU of a node with mixed cards", func() {
+			handler := &ResourceSliceHandler{}
+
+			nodeTopology := &topology.NodeTopology{
+				GpuProduct: "NVIDIA A10",
+				GpuMemory:  23028,
+				Gpus: []topology.GpuDetails{
+					{ID: "GPU-0001-0001-0001-0001", Product: "NVIDIA L4", Memory: 23034, Architecture: "ada", PciBusID: "0000:01:00.0"},
+					{ID: "GPU-0002-0002-0002-0002", Architecture: "ampere"},
+				},
+			}
+
+			devices := handler.devicesFromTopology(nodeTopology)
+
+			Expect(devices).To(HaveLen(2))
+			Expect(*devices[0].Attributes["gpu.nvidia.com/productName"].StringValue).To(Equal("NVIDIA L4"))
+			Expect(*devices[0].Attributes["gpu.nvidia.com/architecture"].StringValue).To(Equal("ada"))
+			Expect(*devices[0].Attributes["gpu.nvidia.com/pcieBusID"].StringValue).To(Equal("0000:01:00.0"))
+			memory := devices[0].Capacity["memory"].Value
+			Expect(memory.Value()).To(Equal(int64(23034) * 1024 * 1024))
+
+			Expect(*devices[1].Attributes["gpu.nvidia.com/productName"].StringValue).To(Equal("NVIDIA A10"))
+			Expect(devices[1].Attributes).ToNot(HaveKey(resourceapi.QualifiedName("gpu.nvidia.com/pcieBusID")))
+			memory = devices[1].Capacity["memory"].Value
+			Expect(memory.Value()).To(Equal(int64(23028) * 1024 * 1024))
+		})
+
 		It("should skip GPUs without ID", func() {
 			handler := &ResourceSliceHandler{}
 
@@ -220,3 +277,36 @@ var _ = Describe("ResourceSliceHandler", func() {
 		})
 	})
 })
+
+func listNodeSlices(kubeClient *fake.Clientset, nodeName string) []resourceapi.ResourceSlice {
+	list, err := kubeClient.ResourceV1().ResourceSlices().List(context.TODO(), metav1.ListOptions{
+		LabelSelector: constants.LabelResourceSliceNode + "=" + nodeName,
+	})
+	Expect(err).NotTo(HaveOccurred())
+	slices := list.Items
+	sort.Slice(slices, func(i, j int) bool { return slices[i].Name < slices[j].Name })
+	return slices
+}
+
+// poolDevices reads a device gauge of the node's GPU pool from the metrics
+// registry.
+func poolDevices(metric, nodeName string) float64 {
+	families, err := ctrlmetrics.Registry.Gather()
+	Expect(err).NotTo(HaveOccurred())
+	for _, family := range families {
+		if family.GetName() != metric {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range m.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["driver"] == DriverName && labels["pool"] == nodeName {
+				return m.GetGauge().GetValue()
+			}
+		}
+	}
+	Fail(fmt.Sprintf("metric %s of pool %s not found", metric, nodeName))
+	return 0
+}
@@ -1,14 +1,13 @@
 package kwokgdp
 
 import (
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
-	"github.com/spf13/viper"
 	ctrl "sigs.k8s.io/controller-runtime"
 
-	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
-	cmcontroller "github.com/run-ai/fake-gpu-operator/internal/kwok-gpu-device-plugin/controllers/configmap"
+	fgncontroller "github.com/run-ai/fake-gpu-operator/internal/kwok-gpu-device-plugin/controllers/fakegpunode"
 	"github.com/run-ai/fake-gpu-operator/internal/status-updater/controllers"
 )
 
@@ -16,6 +15,9 @@ var InClusterConfigFn = ctrl.GetConfigOrDie
 var KubeClientFn = func(c *rest.Config) kubernetes.Interface {
 	return kubernetes.NewForConfigOrDie(c)
 }
+var DynamicClientFn = func(c *rest.Config) dynamic.Interface {
+	return dynamic.NewForConfigOrDie(c)
+}
 
 type StatusUpdaterAppConfiguration struct {
 	TopologyCmName      string `mapstructure:"TOPOLOGY_CM_NAME" validate:"required"`
@@ -23,9 +25,10 @@ type StatusUpdaterAppConfiguration struct {
 }
 
 type KWOKDevicePluginApp struct {
-	Controllers []controllers.Interface
-	kubeClient  kubernetes.Interface
-	stopCh      chan struct{}
+	Controllers   []controllers.Interface
+	kubeClient    kubernetes.Interface
+	dynamicClient dynamic.Interface
+	stopCh        chan struct{}
 }
 
 func (app *KWOKDevicePluginApp) Run() {
@@ -44,10 +47,11 @@ func (app *KWOKDevicePluginApp) Init(stopCh chan struct{}) {
 	clusterConfig.Burst = 200
 
 	app.kubeClient = KubeClientFn(clusterConfig)
+	app.dynamicClient = DynamicClientFn(clusterConfig)
 
 	app.Controllers = append(
-		app.Controllers, cmcontroller.NewConfigMapController(
-			app.kubeClient, viper.GetString(constants.EnvTopologyCmNamespace),
+		app.Controllers, fgncontroller.NewFakeGpuNodeController(
+			app.kubeClient, app.dynamicClient,
 		),
 	)
 }
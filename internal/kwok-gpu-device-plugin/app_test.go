@@ -2,6 +2,7 @@ package kwokgdp
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -11,13 +12,18 @@ import (
 	"github.com/spf13/viper"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dfake "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 
 	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
 	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
-	cmcontroller "github.com/run-ai/fake-gpu-operator/internal/kwok-gpu-device-plugin/controllers/configmap"
+	fgncontroller "github.com/run-ai/fake-gpu-operator/internal/kwok-gpu-device-plugin/controllers/fakegpunode"
 	"github.com/run-ai/fake-gpu-operator/internal/status-updater/controllers"
 )
 
@@ -34,10 +40,11 @@ func TestKwokGpuDevicePlugin(t *testing.T) {
 
 var _ = Describe("KwokGpuDevicePlugin", func() {
 	var (
-		app        *KWOKDevicePluginApp
-		kubeClient kubernetes.Interface
-		stopChan   chan struct{}
-		wg         *sync.WaitGroup
+		app           *KWOKDevicePluginApp
+		kubeClient    kubernetes.Interface
+		dynamicClient dynamic.Interface
+		stopChan      chan struct{}
+		wg            *sync.WaitGroup
 	)
 
 	BeforeEach(func() {
@@ -58,6 +65,10 @@ var _ = Describe("KwokGpuDevicePlugin", func() {
 		clusterTopologyCM.Namespace = gpuOperatorNamespace
 
 		kubeClient = fake.NewSimpleClientset(clusterTopologyCM)
+		dynamicClient = dfake.NewSimpleDynamicClientWithCustomListKinds(
+			runtime.NewScheme(),
+			map[schema.GroupVersionResource]string{topology.FakeGpuNodeGVR: topology.FakeGpuNodeListKind},
+		)
 		stopChan = make(chan struct{})
 
 		viper.SetDefault(constants.EnvTopologyCmName, clusterTopologyCM.Name)
@@ -65,12 +76,13 @@ var _ = Describe("KwokGpuDevicePlugin", func() {
 
 		app = &KWOKDevicePluginApp{
 			Controllers: []controllers.Interface{
-				cmcontroller.NewConfigMapController(
-					kubeClient, gpuOperatorNamespace,
+				fgncontroller.NewFakeGpuNodeController(
+					kubeClient, dynamicClient,
 				),
 			},
-			kubeClient: kubeClient,
-			stopCh:     stopChan,
+			kubeClient:    kubeClient,
+			dynamicClient: dynamicClient,
+			stopCh:        stopChan,
 		}
 		wg = &sync.WaitGroup{}
 		wg.Add(1)
@@ -88,18 +100,32 @@ var _ = Describe("KwokGpuDevicePlugin", func() {
 	Context("app", func() {
 		It("should run until channel is closed", func() {})
 
-		Context("ConfigMap", func() {
-			It("should handle new Config Map without node labels", func() {
-				_, err := kubeClient.CoreV1().ConfigMaps(gpuOperatorNamespace).Create(context.TODO(), &v1.ConfigMap{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "configmap1",
-						Namespace: gpuOperatorNamespace,
-					},
-				}, metav1.CreateOptions{})
+		Context("FakeGpuNode", func() {
+			It("should ignore FakeGpuNodes of non-kwok nodes", func() {
+				node := &v1.Node{
+					ObjectMeta: metav1.ObjectMeta{Name: "node2"},
+				}
+				_, err := kubeClient.CoreV1().Nodes().Create(context.TODO(), node, metav1.CreateOptions{})
+				Expect(err).ToNot(HaveOccurred())
+
+				fakeGpuNode, err := topology.ToFakeGpuNode(&topology.NodeTopology{
+					Gpus: []topology.GpuDetails{{ID: "fake-gpu-id-1"}},
+				}, node.Name).ToUnstructured()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = dynamicClient.Resource(topology.FakeGpuNodeGVR).Create(context.TODO(), fakeGpuNode, metav1.CreateOptions{})
 				Expect(err).ToNot(HaveOccurred())
+
+				Consistently(func() bool {
+					node, err := kubeClient.CoreV1().Nodes().Get(context.TODO(), node.Name, metav1.GetOptions{})
+					if err != nil {
+						return false
+					}
+					_, found := node.Status.Capacity[constants.GpuResourceName]
+					return found
+				}, time.Second, 100*time.Millisecond).Should(BeFalse())
 			})
 
-			It("should add gpu devices to kwok nodes by configmap data", func() {
+			It("should add gpu devices to kwok nodes by FakeGpuNode spec", func() {
 				node1 := &v1.Node{
 					ObjectMeta: metav1.ObjectMeta{
 						Name: "node1",
@@ -124,14 +150,14 @@ var _ = Describe("KwokGpuDevicePlugin", func() {
 						{ID: "fake-gpu-id-4", Status: topology.GpuStatus{}},
 					},
 				}
-				cm, _, err := topology.ToNodeTopologyCM(&nodeTopology, node1.Name)
-				Expect(err).ToNot(HaveOccurred())
-				cm.Namespace = gpuOperatorNamespace
-				cm.Annotations = map[string]string{
+				fakeGpuNode := topology.ToFakeGpuNode(&nodeTopology, node1.Name)
+				fakeGpuNode.Annotations = map[string]string{
 					constants.AnnotationKwokNode: "fake",
 				}
+				obj, err := fakeGpuNode.ToUnstructured()
+				Expect(err).ToNot(HaveOccurred())
 
-				_, err = kubeClient.CoreV1().ConfigMaps(gpuOperatorNamespace).Create(context.TODO(), cm, metav1.CreateOptions{})
+				_, err = dynamicClient.Resource(topology.FakeGpuNodeGVR).Create(context.TODO(), obj, metav1.CreateOptions{})
 				Expect(err).ToNot(HaveOccurred())
 
 				Eventually(func() bool {
@@ -144,6 +170,83 @@ var _ = Describe("KwokGpuDevicePlugin", func() {
 					return gpuQuantity.Value() == int64(4)
 				}, 10*time.Second, 100*time.Millisecond).Should(BeTrue())
 			})
+
+			It("should apply quick resizes of a FakeGpuNode in order", func() {
+				createKwokNode(kubeClient, "node3")
+				applyKwokFakeGpuNode(dynamicClient, "node3", 4)
+				Eventually(func() int64 {
+					return gpuCapacity(kubeClient, "node3")
+				}, 10*time.Second, 100*time.Millisecond).Should(Equal(int64(4)))
+
+				for _, gpuCount := range []int{2, 6, 3} {
+					applyKwokFakeGpuNode(dynamicClient, "node3", gpuCount)
+				}
+
+				Eventually(func() int64 {
+					return gpuCapacity(kubeClient, "node3")
+				}, 10*time.Second, 100*time.Millisecond).Should(Equal(int64(3)))
+				Consistently(func() int64 {
+					return gpuCapacity(kubeClient, "node3")
+				}, time.Second, 100*time.Millisecond).Should(Equal(int64(3)))
+			})
+
+			It("should zero the devices of a deleted FakeGpuNode", func() {
+				createKwokNode(kubeClient, "node4")
+				applyKwokFakeGpuNode(dynamicClient, "node4", 2)
+				Eventually(func() int64 {
+					return gpuCapacity(kubeClient, "node4")
+				}, 10*time.Second, 100*time.Millisecond).Should(Equal(int64(2)))
+
+				err := dynamicClient.Resource(topology.FakeGpuNodeGVR).Delete(context.TODO(), "node4", metav1.DeleteOptions{})
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(func() int64 {
+					return gpuCapacity(kubeClient, "node4")
+				}, 10*time.Second, 100*time.Millisecond).Should(Equal(int64(0)))
+			})
 		})
 	})
 })
+
+func createKwokNode(kubeClient kubernetes.Interface, nodeName string) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        nodeName,
+			Labels:      map[string]string{nodePoolLabelKey: defaultNodePoolName},
+			Annotations: map[string]string{constants.AnnotationKwokNode: "fake"},
+		},
+	}
+	_, err := kubeClient.CoreV1().Nodes().Create(context.TODO(), node, metav1.CreateOptions{})
+	Expect(err).ToNot(HaveOccurred())
+}
+
+// applyKwokFakeGpuNode creates or updates the FakeGpuNode of a KWOK node with
+// gpuCount GPUs.
+func applyKwokFakeGpuNode(dynamicClient dynamic.Interface, nodeName string, gpuCount int) {
+	nodeTopology := &topology.NodeTopology{GpuMemory: 1000, GpuProduct: "nvidia-tesla-t4"}
+	for idx := 0; idx < gpuCount; idx++ {
+		nodeTopology.Gpus = append(nodeTopology.Gpus, topology.GpuDetails{ID: fmt.Sprintf("%s-gpu-%d", nodeName, idx)})
+	}
+	fakeGpuNode := topology.ToFakeGpuNode(nodeTopology, nodeName)
+	fakeGpuNode.Annotations = map[string]string{constants.AnnotationKwokNode: "fake"}
+	obj, err := fakeGpuNode.ToUnstructured()
+	Expect(err).ToNot(HaveOccurred())
+
+	current, err := dynamicClient.Resource(topology.FakeGpuNodeGVR).Get(context.TODO(), nodeName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = dynamicClient.Resource(topology.FakeGpuNodeGVR).Create(context.TODO(), obj, metav1.CreateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		return
+	}
+	Expect(err).ToNot(HaveOccurred())
+	obj.SetResourceVersion(current.GetResourceVersion())
+	_, err = dynamicClient.Resource(topology.FakeGpuNodeGVR).Update(context.TODO(), obj, metav1.UpdateOptions{})
+	Expect(err).ToNot(HaveOccurred())
+}
+
+func gpuCapacity(kubeClient kubernetes.Interface, nodeName string) int64 {
+	node, err := kubeClient.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+	Expect(err).ToNot(HaveOccurred())
+	gpuQuantity := node.Status.Capacity[constants.GpuResourceName]
+	return gpuQuantity.Value()
+}
@@ -0,0 +1,181 @@
+package fakegpunode
+
+import (
+	"log"
+	"time"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
+	"github.com/run-ai/fake-gpu-operator/internal/status-updater/controllers"
+
+	fgnhandler "github.com/run-ai/fake-gpu-operator/internal/kwok-gpu-device-plugin/handlers/fakegpunode"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// maxRetries bounds how often a failing node sync is retried.
+const maxRetries = 15
+
+// FakeGpuNodeController applies the devices of FakeGpuNodes to their KWOK
+// nodes. Events are queued per node and synced against the latest
+// FakeGpuNode, so quick resizes of a node are applied in order.
+type FakeGpuNodeController struct {
+	kubeClient kubernetes.Interface
+	informer   cache.SharedIndexInformer
+	handler    fgnhandler.Interface
+	queue      workqueue.TypedRateLimitingInterface[string]
+
+	// applied holds, per node, the FakeGpuNode whose devices were last
+	// applied, so devices dropped since are zeroed. Only the worker uses it.
+	applied map[string]*topology.FakeGpuNode
+}
+
+var _ controllers.Interface = &FakeGpuNodeController{}
+
+func NewFakeGpuNodeController(
+	kubeClient kubernetes.Interface, dynamicClient dynamic.Interface,
+) *FakeGpuNodeController {
+	informerFactory := dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, 0)
+	c := &FakeGpuNodeController{
+		kubeClient: kubeClient,
+		informer:   informerFactory.ForResource(topology.FakeGpuNodeGVR).Informer(),
+		handler:    fgnhandler.NewFakeGpuNodeHandler(kubeClient),
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.DefaultTypedControllerRateLimiter[string](),
+			workqueue.TypedRateLimitingQueueConfig[string]{Name: "fakegpunodes"},
+		),
+		applied: make(map[string]*topology.FakeGpuNode),
+	}
+
+	// Node topologies follow the cluster topology: the status-updater
+	// regenerates or deletes them when pools change, and the devices of the
+	// node follow here.
+	_, err := c.informer.AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: func(obj interface{}) bool {
+			fakeGpuNode := fakeGpuNodeFromObj(obj)
+			return fakeGpuNode != nil && fakeGpuNode.GetAnnotations()[constants.AnnotationKwokNode] == "fake"
+		},
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				c.enqueue(obj)
+			},
+			UpdateFunc: func(_, newObj interface{}) {
+				c.enqueue(newObj)
+			},
+			DeleteFunc: func(obj interface{}) {
+				c.enqueue(obj)
+			},
+		},
+	})
+	if err != nil {
+		log.Fatalf("Failed to add FakeGpuNode event handler: %v", err)
+	}
+
+	return c
+}
+
+func (c *FakeGpuNodeController) Run(stopCh <-chan struct{}) {
+	defer c.queue.ShutDown()
+
+	log.Println("Starting FakeGpuNode controller")
+	go c.informer.Run(stopCh)
+	go wait.Until(c.runWorker, time.Second, stopCh)
+
+	<-stopCh
+}
+
+func (c *FakeGpuNodeController) enqueue(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		log.Printf("Failed to get FakeGpuNode key: %v", err)
+		return
+	}
+	c.queue.Add(key)
+}
+
+func (c *FakeGpuNodeController) runWorker() {
+	for c.processNextNode() {
+	}
+}
+
+func (c *FakeGpuNodeController) processNextNode() bool {
+	key, shutdown := c.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	err := c.syncNode(key)
+	if err == nil {
+		c.queue.Forget(key)
+		return true
+	}
+
+	if c.queue.NumRequeues(key) >= maxRetries {
+		log.Printf("Dropping FakeGpuNode %s out of the queue: %v", key, err)
+		c.queue.Forget(key)
+		return true
+	}
+
+	log.Printf("Failed to sync FakeGpuNode %s, retrying: %v", key, err)
+	c.queue.AddRateLimited(key)
+	return true
+}
+
+// syncNode applies the devices of the latest FakeGpuNode of a node, or zeroes
+// them once the FakeGpuNode is gone.
+func (c *FakeGpuNodeController) syncNode(key string) error {
+	obj, exists, err := c.informer.GetIndexer().GetByKey(key)
+	if err != nil {
+		return err
+	}
+	applied := c.applied[key]
+
+	if !exists {
+		if applied == nil {
+			return nil
+		}
+		if err := c.handler.HandleDelete(applied); err != nil {
+			return err
+		}
+		delete(c.applied, key)
+		return nil
+	}
+
+	fakeGpuNode, err := topology.FakeGpuNodeFromUnstructured(obj.(*unstructured.Unstructured))
+	if err != nil {
+		log.Printf("Ignoring invalid FakeGpuNode %s: %v", key, err)
+		return nil
+	}
+
+	if applied == nil {
+		err = c.handler.HandleAdd(fakeGpuNode)
+	} else {
+		err = c.handler.HandleUpdate(applied, fakeGpuNode)
+	}
+	if err != nil {
+		return err
+	}
+	c.applied[key] = fakeGpuNode
+	return nil
+}
+
+// fakeGpuNodeFromObj returns the FakeGpuNode of an informer event, unwrapping
+// the tombstone of a deletion the informer missed.
+func fakeGpuNodeFromObj(obj interface{}) *unstructured.Unstructured {
+	switch o := obj.(type) {
+	case *unstructured.Unstructured:
+		return o
+	case cache.DeletedFinalStateUnknown:
+		fakeGpuNode, _ := o.Obj.(*unstructured.Unstructured)
+		return fakeGpuNode
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,125 @@
+package fakegpunode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+type Interface interface {
+	HandleAdd(fakeGpuNode *topology.FakeGpuNode) error
+	HandleUpdate(oldFakeGpuNode, newFakeGpuNode *topology.FakeGpuNode) error
+	HandleDelete(fakeGpuNode *topology.FakeGpuNode) error
+}
+
+type FakeGpuNodeHandler struct {
+	kubeClient kubernetes.Interface
+}
+
+var _ Interface = &FakeGpuNodeHandler{}
+
+func NewFakeGpuNodeHandler(kubeClient kubernetes.Interface) *FakeGpuNodeHandler {
+	return &FakeGpuNodeHandler{
+		kubeClient: kubeClient,
+	}
+}
+
+func (p *FakeGpuNodeHandler) HandleAdd(fakeGpuNode *topology.FakeGpuNode) error {
+	log.Printf("Handling FakeGpuNode addition: %s\n", fakeGpuNode.Name)
+
+	return p.applyFakeDevicePlugin(topology.FromFakeGpuNode(fakeGpuNode), fakeGpuNode.Name)
+}
+
+// HandleUpdate re-applies the node's devices when the status-updater
+// regenerated its topology with a different number of devices, e.g. after the
+// node's pool was resized.
+func (p *FakeGpuNodeHandler) HandleUpdate(oldFakeGpuNode, newFakeGpuNode *topology.FakeGpuNode) error {
+	oldTopology := topology.FromFakeGpuNode(oldFakeGpuNode)
+	newTopology := topology.FromFakeGpuNode(newFakeGpuNode)
+	if len(oldTopology.Gpus) == len(newTopology.Gpus) && reflect.DeepEqual(oldTopology.OtherDevices, newTopology.OtherDevices) {
+		return nil
+	}
+
+	log.Printf("Handling FakeGpuNode device change: %s\n", newFakeGpuNode.Name)
+
+	// Devices dropped from the topology are zeroed rather than left behind.
+	applied := *newTopology
+	applied.OtherDevices = append([]topology.GenericDevice(nil), newTopology.OtherDevices...)
+	for _, oldDevice := range oldTopology.OtherDevices {
+		if !hasDevice(newTopology.OtherDevices, oldDevice.Name) {
+			applied.OtherDevices = append(applied.OtherDevices, topology.GenericDevice{Name: oldDevice.Name})
+		}
+	}
+
+	return p.applyFakeDevicePlugin(&applied, newFakeGpuNode.Name)
+}
+
+// HandleDelete zeroes the node's devices once it has no topology anymore,
+// e.g. when its pool was removed from the cluster topology.
+func (p *FakeGpuNodeHandler) HandleDelete(fakeGpuNode *topology.FakeGpuNode) error {
+	log.Printf("Handling FakeGpuNode deletion: %s\n", fakeGpuNode.Name)
+
+	cleared := &topology.NodeTopology{}
+	for _, otherDevice := range fakeGpuNode.Spec.OtherDevices {
+		cleared.OtherDevices = append(cleared.OtherDevices, topology.GenericDevice{Name: otherDevice.Name})
+	}
+
+	err := p.applyFakeDevicePlugin(cleared, fakeGpuNode.Name)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func hasDevice(devices []topology.GenericDevice, name string) bool {
+	for _, device := range devices {
+		if device.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *FakeGpuNodeHandler) applyFakeDevicePlugin(nodeTopology *topology.NodeTopology, nodeName string) error {
+	nodePatch := &v1.Node{
+		Status: v1.NodeStatus{
+			Capacity: v1.ResourceList{
+				v1.ResourceName(constants.GpuResourceName): *resource.NewQuantity(int64(len(nodeTopology.Gpus)), resource.DecimalSI),
+			},
+			Allocatable: v1.ResourceList{
+				v1.ResourceName(constants.GpuResourceName): *resource.NewQuantity(int64(len(nodeTopology.Gpus)), resource.DecimalSI),
+			},
+		},
+	}
+
+	for _, otherDevice := range nodeTopology.OtherDevices {
+		nodePatch.Status.Capacity[v1.ResourceName(otherDevice.Name)] = *resource.NewQuantity(int64(otherDevice.Count), resource.DecimalSI)
+		nodePatch.Status.Allocatable[v1.ResourceName(otherDevice.Name)] = *resource.NewQuantity(int64(otherDevice.Count), resource.DecimalSI)
+	}
+
+	patchBytes, err := json.Marshal(nodePatch)
+	if err != nil {
+		return fmt.Errorf("failed to update node: failed to marshal patch: %v", err)
+	}
+
+	_, err = p.kubeClient.CoreV1().Nodes().Patch(
+		context.TODO(), nodeName, types.MergePatchType, patchBytes, metav1.PatchOptions{}, "status",
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update node capacity and allocatable: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package fakegpunode
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestKWOKGPUDevicePlugin(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "KWOK GPU DevicePlugin Suite")
+}
+
+var _ = Describe("HandleAdd", func() {
+	It("should update the node capacity and allocatable", func() {
+		nodeName := "node1"
+		nodeTopology := &topology.NodeTopology{
+			Gpus: []topology.GpuDetails{
+				{ID: "0"},
+			},
+			OtherDevices: []topology.GenericDevice{
+				{Name: "device1", Count: 2},
+			},
+		}
+
+		node := &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: nodeName,
+			},
+		}
+
+		fakeClient := fake.NewSimpleClientset(node)
+
+		handler := NewFakeGpuNodeHandler(fakeClient)
+		err := handler.HandleAdd(topology.ToFakeGpuNode(nodeTopology, nodeName))
+		Expect(err).ToNot(HaveOccurred())
+
+		updateNode, err := fakeClient.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(testResourceListCondition(updateNode.Status.Capacity, v1.ResourceName(constants.GpuResourceName), 1)).To(BeTrue())
+		Expect(testResourceListCondition(updateNode.Status.Allocatable, v1.ResourceName(constants.GpuResourceName), 1)).To(BeTrue())
+		Expect(testResourceListCondition(updateNode.Status.Capacity, v1.ResourceName("device1"), 2)).To(BeTrue())
+		Expect(testResourceListCondition(updateNode.Status.Allocatable, v1.ResourceName("device1"), 2)).To(BeTrue())
+	})
+})
+
+var _ = Describe("HandleUpdate", func() {
+	It("should follow a resized node topology", func() {
+		nodeName := "node1"
+		oldFakeGpuNode := topology.ToFakeGpuNode(&topology.NodeTopology{
+			Gpus:         []topology.GpuDetails{{ID: "0"}, {ID: "1"}},
+			OtherDevices: []topology.GenericDevice{{Name: "device1", Count: 2}},
+		}, nodeName)
+		newFakeGpuNode := topology.ToFakeGpuNode(&topology.NodeTopology{
+			Gpus: []topology.GpuDetails{{ID: "0"}, {ID: "1"}, {ID: "2"}, {ID: "3"}},
+		}, nodeName)
+
+		fakeClient := fake.NewSimpleClientset(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}})
+		handler := NewFakeGpuNodeHandler(fakeClient)
+		Expect(handler.HandleAdd(oldFakeGpuNode)).To(Succeed())
+		Expect(handler.HandleUpdate(oldFakeGpuNode, newFakeGpuNode)).To(Succeed())
+
+		updateNode, err := fakeClient.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(testResourceListCondition(updateNode.Status.Capacity, v1.ResourceName(constants.GpuResourceName), 4)).To(BeTrue())
+		Expect(testResourceListCondition(updateNode.Status.Allocatable, v1.ResourceName(constants.GpuResourceName), 4)).To(BeTrue())
+		Expect(testResourceListCondition(updateNode.Status.Capacity, v1.ResourceName("device1"), 0)).To(BeTrue())
+	})
+})
+
+var _ = Describe("HandleDelete", func() {
+	It("should zero the node devices", func() {
+		nodeName := "node1"
+		fakeGpuNode := topology.ToFakeGpuNode(&topology.NodeTopology{
+			Gpus:         []topology.GpuDetails{{ID: "0"}},
+			OtherDevices: []topology.GenericDevice{{Name: "device1", Count: 2}},
+		}, nodeName)
+
+		fakeClient := fake.NewSimpleClientset(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}})
+		handler := NewFakeGpuNodeHandler(fakeClient)
+		Expect(handler.HandleAdd(fakeGpuNode)).To(Succeed())
+		Expect(handler.HandleDelete(fakeGpuNode)).To(Succeed())
+
+		updateNode, err := fakeClient.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(testResourceListCondition(updateNode.Status.Capacity, v1.ResourceName(constants.GpuResourceName), 0)).To(BeTrue())
+		Expect(testResourceListCondition(updateNode.Status.Capacity, v1.ResourceName("device1"), 0)).To(BeTrue())
+	})
+
+	It("should ignore deleted nodes", func() {
+		fakeGpuNode := topology.ToFakeGpuNode(&topology.NodeTopology{Gpus: []topology.GpuDetails{{ID: "0"}}}, "gone")
+		Expect(NewFakeGpuNodeHandler(fake.NewSimpleClientset()).HandleDelete(fakeGpuNode)).To(Succeed())
+	})
+})
+
+func testResourceListCondition(resourceList v1.ResourceList, resourceName v1.ResourceName, value int64) bool {
+	quantity, found := resourceList[resourceName]
+	if !found {
+		return false
+	}
+	return quantity.Value() == value
+}
@@ -15,6 +15,10 @@ import (
 	dto "github.com/prometheus/client_model/go"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dfake "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 
@@ -52,7 +56,8 @@ func TestStatusExporter(t *testing.T) {
 // - node metrics
 var _ = Describe("StatusExporter", func() {
 	var (
-		clientset kubernetes.Interface
+		clientset     kubernetes.Interface
+		dynamicClient dynamic.Interface
 	)
 
 	fakeNode := &corev1.Node{
@@ -62,11 +67,16 @@ var _ = Describe("StatusExporter", func() {
 		},
 	}
 	clientset = fake.NewSimpleClientset(fakeNode)
+	dynamicClient = dfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{topology.FakeGpuNodeGVR: topology.FakeGpuNodeListKind},
+	)
 	setupConfig()
 
 	exporter := &status_exporter.StatusExporterApp{
 		Kubeclient: &kubeclient.KubeClient{
-			ClientSet: clientset,
+			ClientSet:     clientset,
+			DynamicClient: dynamicClient,
 		},
 	}
 	appRunner := app.NewAppRunner(exporter)
@@ -75,9 +85,7 @@ var _ = Describe("StatusExporter", func() {
 	time.Sleep(1000 * time.Millisecond)
 
 	initialTopology := createNodeTopology()
-	cm, _, err := topology.ToNodeTopologyCM(initialTopology, nodeName)
-	Expect(err).To(Not(HaveOccurred()))
-	_, err = clientset.CoreV1().ConfigMaps(topologyCmNamespace).Create(context.TODO(), cm, metav1.CreateOptions{})
+	err := topology.CreateNodeTopology(dynamicClient, initialTopology, fakeNode)
 	Expect(err).To(Not(HaveOccurred()))
 
 	cases := getTestCases()
@@ -87,10 +95,10 @@ var _ = Describe("StatusExporter", func() {
 		caseDetails := caseDetails
 
 		It(caseName, func() {
-			cm, _, err := topology.ToNodeTopologyCM(caseDetails.nodeTopologies[nodeName], nodeName)
-			Expect(err).ToNot(HaveOccurred())
-
-			_, err = clientset.CoreV1().ConfigMaps(topologyCmNamespace).Update(context.TODO(), cm, metav1.UpdateOptions{})
+			err := topology.MutateNodeTopology(dynamicClient, nodeName, func(nodeTopology *topology.NodeTopology) error {
+				*nodeTopology = *caseDetails.nodeTopologies[nodeName]
+				return nil
+			})
 			Expect(err).ToNot(HaveOccurred())
 
 			Eventually(getNodeLabelsFromKube(clientset)).WithTimeout(19 * time.Second).Should(Equal(caseDetails.expectedLabels))
@@ -208,14 +216,15 @@ func getTestCases() map[string]testCase {
 					Metric: []*dto.Metric{
 						{
 							Label: buildMetricLabels(map[string]string{
-								"pod":       podName,
-								"namespace": podNamespace,
-								"device":    "nvidia0",
-								"gpu":       "0",
-								"modelName": "Tesla P100",
-								"Hostname":  "nvidia-dcgm-exporter-ce8600",
-								"UUID":      "fake-gpu-id-1",
-								"container": containerName,
+								"pod":            podName,
+								"namespace":      podNamespace,
+								"device":         "nvidia0",
+								"gpu":            "0",
+								"modelName":      "Tesla P100",
+								"Hostname":       "nvidia-dcgm-exporter-ce8600",
+								"UUID":           "fake-gpu-id-1",
+								"container":      containerName,
+								"compute_domain": "",
 							}),
 							Gauge: &dto.Gauge{
 								Value: createPtr(float64(80)),
@@ -230,14 +239,15 @@ func getTestCases() map[string]testCase {
 					Metric: []*dto.Metric{
 						{
 							Label: buildMetricLabels(map[string]string{
-								"Hostname":  "nvidia-dcgm-exporter-ce8600",
-								"UUID":      "fake-gpu-id-1",
-								"pod":       podName,
-								"namespace": podNamespace,
-								"device":    "nvidia0",
-								"gpu":       "0",
-								"modelName": "Tesla P100",
-								"container": containerName,
+								"Hostname":       "nvidia-dcgm-exporter-ce8600",
+								"UUID":           "fake-gpu-id-1",
+								"pod":            podName,
+								"namespace":      podNamespace,
+								"device":         "nvidia0",
+								"gpu":            "0",
+								"modelName":      "Tesla P100",
+								"container":      containerName,
+								"compute_domain": "",
 							}),
 							Gauge: &dto.Gauge{
 								Value: createPtr(float64(0)),
@@ -252,14 +262,15 @@ func getTestCases() map[string]testCase {
 					Metric: []*dto.Metric{
 						{
 							Label: buildMetricLabels(map[string]string{
-								"Hostname":  "nvidia-dcgm-exporter-ce8600",
-								"UUID":      "fake-gpu-id-1",
-								"pod":       podName,
-								"namespace": podNamespace,
-								"device":    "nvidia0",
-								"gpu":       "0",
-								"modelName": "Tesla P100",
-								"container": containerName,
+								"Hostname":       "nvidia-dcgm-exporter-ce8600",
+								"UUID":           "fake-gpu-id-1",
+								"pod":            podName,
+								"namespace":      podNamespace,
+								"device":         "nvidia0",
+								"gpu":            "0",
+								"modelName":      "Tesla P100",
+								"container":      containerName,
+								"compute_domain": "",
 							}),
 							Gauge: &dto.Gauge{
 								Value: createPtr(float64(20000)),
@@ -333,14 +344,15 @@ func getTestCases() map[string]testCase {
 					Metric: []*dto.Metric{
 						{
 							Label: buildMetricLabels(map[string]string{
-								"Hostname":  "nvidia-dcgm-exporter-ce8600",
-								"UUID":      "fake-gpu-id-1",
-								"pod":       podName,
-								"namespace": podNamespace,
-								"device":    "nvidia0",
-								"gpu":       "0",
-								"modelName": "Tesla P100",
-								"container": containerName,
+								"Hostname":       "nvidia-dcgm-exporter-ce8600",
+								"UUID":           "fake-gpu-id-1",
+								"pod":            podName,
+								"namespace":      podNamespace,
+								"device":         "nvidia0",
+								"gpu":            "0",
+								"modelName":      "Tesla P100",
+								"container":      containerName,
+								"compute_domain": "",
 							}),
 							Gauge: &dto.Gauge{
 								Value: createPtr(float64(100)),
@@ -348,14 +360,15 @@ func getTestCases() map[string]testCase {
 						},
 						{
 							Label: buildMetricLabels(map[string]string{
-								"Hostname":  "nvidia-dcgm-exporter-ce8600",
-								"UUID":      "fake-gpu-id-2",
-								"pod":       podName,
-								"namespace": podNamespace,
-								"device":    "nvidia1",
-								"gpu":       "1",
-								"modelName": "Tesla P100",
-								"container": containerName,
+								"Hostname":       "nvidia-dcgm-exporter-ce8600",
+								"UUID":           "fake-gpu-id-2",
+								"pod":            podName,
+								"namespace":      podNamespace,
+								"device":         "nvidia1",
+								"gpu":            "1",
+								"modelName":      "Tesla P100",
+								"container":      containerName,
+								"compute_domain": "",
 							}),
 							Gauge: &dto.Gauge{
 								Value: createPtr(float64(100)),
@@ -370,14 +383,15 @@ func getTestCases() map[string]testCase {
 					Metric: []*dto.Metric{
 						{
 							Label: buildMetricLabels(map[string]string{
-								"Hostname":  "nvidia-dcgm-exporter-ce8600",
-								"UUID":      "fake-gpu-id-1",
-								"pod":       podName,
-								"namespace": podNamespace,
-								"device":    "nvidia0",
-								"gpu":       "0",
-								"modelName": "Tesla P100",
-								"container": containerName,
+								"Hostname":       "nvidia-dcgm-exporter-ce8600",
+								"UUID":           "fake-gpu-id-1",
+								"pod":            podName,
+								"namespace":      podNamespace,
+								"device":         "nvidia0",
+								"gpu":            "0",
+								"modelName":      "Tesla P100",
+								"container":      containerName,
+								"compute_domain": "",
 							}),
 							Gauge: &dto.Gauge{
 								Value: createPtr(float64(20000)),
@@ -385,14 +399,15 @@ func getTestCases() map[string]testCase {
 						},
 						{
 							Label: buildMetricLabels(map[string]string{
-								"Hostname":  "nvidia-dcgm-exporter-ce8600",
-								"UUID":      "fake-gpu-id-2",
-								"pod":       podName,
-								"namespace": podNamespace,
-								"device":    "nvidia1",
-								"gpu":       "1",
-								"modelName": "Tesla P100",
-								"container": containerName,
+								"Hostname":       "nvidia-dcgm-exporter-ce8600",
+								"UUID":           "fake-gpu-id-2",
+								"pod":            podName,
+								"namespace":      podNamespace,
+								"device":         "nvidia1",
+								"gpu":            "1",
+								"modelName":      "Tesla P100",
+								"container":      containerName,
+								"compute_domain": "",
 							}),
 							Gauge: &dto.Gauge{
 								Value: createPtr(float64(20000)),
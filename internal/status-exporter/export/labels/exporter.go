@@ -44,7 +44,17 @@ func (e *LabelsExporter) Run(stopCh <-chan struct{}) {
 func (e *LabelsExporter) export(nodeTopology *topology.NodeTopology) error {
 	labels := BuildNodeLabels(nodeTopology)
 
-	err := e.kubeclient.SetNodeLabels(labels)
+	nodeLabels, err := e.kubeclient.GetNodeLabels()
+	if err != nil {
+		return fmt.Errorf("failed to get node labels: %w", err)
+	}
+	if stale := StaleGpuLabels(nodeLabels, labels); len(stale) > 0 {
+		if err := e.kubeclient.RemoveNodeLabels(stale); err != nil {
+			return fmt.Errorf("failed to remove stale node labels: %w", err)
+		}
+	}
+
+	err = e.kubeclient.SetNodeLabels(labels)
 	if err != nil {
 		return fmt.Errorf("failed to set node labels: %w", err)
 	}
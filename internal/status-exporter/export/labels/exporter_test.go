@@ -1,6 +1,7 @@
 package labels_test
 
 import (
+	"context"
 	"strconv"
 	"strings"
 	"sync"
@@ -12,6 +13,10 @@ import (
 	"github.com/run-ai/fake-gpu-operator/internal/status-exporter/export/labels"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 type FakeWatcher struct {
@@ -38,6 +43,9 @@ func TestExport(t *testing.T) {
 	wg := &sync.WaitGroup{}
 	wg.Add(1)
 	kubeClientMock := &kubeclient.KubeClientMock{}
+	kubeClientMock.ActualGetNodeLabels = func() (map[string]string, error) {
+		return map[string]string{}, nil
+	}
 	kubeClientMock.ActualSetNodeLabels = func(labels map[string]string) {
 		assert.Equal(t, labels["nvidia.com/gpu.memory"], strconv.Itoa(myNode.GpuMemory))
 		assert.Equal(t, labels["nvidia.com/gpu.product"], "some-gpu") // spaces sanitized to dashes
@@ -60,6 +68,92 @@ func TestExport(t *testing.T) {
 	wg.Wait()
 }
 
+func TestExport_RemovesStaleGpuLabels(t *testing.T) {
+	viper.SetDefault(constants.EnvNodeName, "my_node")
+
+	// The node was labelled while it had two mixed cards.
+	nodeLabels := labels.BuildNodeLabels(&topology.NodeTopology{
+		GpuProduct: "NVIDIA A10",
+		GpuMemory:  23028,
+		Gpus: []topology.GpuDetails{
+			{ID: "gpu-0", Product: "NVIDIA L4", Memory: 23034},
+			{ID: "gpu-1"},
+		},
+	})
+	nodeLabels["run.ai/fake.gpu"] = "true"
+	nodeLabels["team"] = "a"
+
+	var removed []string
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	kubeClientMock := &kubeclient.KubeClientMock{}
+	kubeClientMock.ActualGetNodeLabels = func() (map[string]string, error) {
+		return nodeLabels, nil
+	}
+	kubeClientMock.ActualRemoveNodeLabels = func(keys []string) {
+		removed = keys
+	}
+	kubeClientMock.ActualSetNodeLabels = func(map[string]string) {
+		wg.Done()
+	}
+
+	fakeWatcher := &FakeWatcher{}
+	lablesExporter := labels.NewLabelsExporter(fakeWatcher, kubeClientMock)
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		lablesExporter.Run(stop)
+	}()
+
+	// It moved to a homogeneous pool.
+	fakeWatcher.topologyChan <- &topology.NodeTopology{
+		GpuProduct: "NVIDIA A10",
+		GpuMemory:  23028,
+		Gpus:       []topology.GpuDetails{{ID: "gpu-0"}},
+	}
+	stop <- struct{}{}
+	wg.Wait()
+
+	assert.Equal(t, []string{
+		"run.ai/fake.gpu-0.memory", "run.ai/fake.gpu-0.product",
+		"run.ai/fake.gpu-1.memory", "run.ai/fake.gpu-1.product",
+	}, removed)
+}
+
+func TestMultiNodeLabelsExporter_RemovesStaleGpuLabels(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "kwok-node", Labels: map[string]string{"team": "a"}}})
+	exporter := labels.NewMultiNodeLabelsExporter(kubeClient)
+
+	heterogeneous := &topology.NodeTopology{
+		GpuProduct: "NVIDIA A10",
+		GpuMemory:  23028,
+		Gpus: []topology.GpuDetails{
+			{ID: "gpu-0", Product: "NVIDIA L4", Memory: 23034},
+			{ID: "gpu-1"},
+		},
+	}
+	require.NoError(t, exporter.SetLabelsForNode("kwok-node", heterogeneous))
+	node, err := kubeClient.CoreV1().Nodes().Get(context.TODO(), "kwok-node", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "NVIDIA-L4", node.Labels["run.ai/fake.gpu-0.product"])
+
+	homogeneous := &topology.NodeTopology{
+		GpuProduct: "NVIDIA A10",
+		GpuMemory:  23028,
+		Gpus:       []topology.GpuDetails{{ID: "gpu-0"}, {ID: "gpu-1"}},
+	}
+	require.NoError(t, exporter.SetLabelsForNode("kwok-node", homogeneous))
+	node, err = kubeClient.CoreV1().Nodes().Get(context.TODO(), "kwok-node", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, labels.BuildNodeLabels(homogeneous)["nvidia.com/gpu.product"], node.Labels["nvidia.com/gpu.product"])
+	assert.Equal(t, "a", node.Labels["team"])
+	assert.Equal(t, "true", node.Labels["run.ai/fake.gpu"])
+	for key := range node.Labels {
+		assert.False(t, strings.HasPrefix(key, "run.ai/fake.gpu-"), "stale label %s", key)
+	}
+}
+
 func TestBuildNodeLabels_SanitizesGpuProduct(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -104,3 +198,35 @@ func TestBuildNodeLabels_SanitizesGpuProduct(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildNodeLabels_Clique(t *testing.T) {
+	l := labels.BuildNodeLabels(&topology.NodeTopology{})
+	assert.NotContains(t, l, constants.LabelGpuClique)
+
+	l = labels.BuildNodeLabels(&topology.NodeTopology{CliqueID: "cluster.1"})
+	assert.Equal(t, "cluster.1", l[constants.LabelGpuClique])
+}
+
+func TestBuildNodeLabels_MixedGpus(t *testing.T) {
+	topo := &topology.NodeTopology{
+		GpuProduct: "NVIDIA A10",
+		GpuMemory:  23028,
+		Gpus: []topology.GpuDetails{
+			{ID: "gpu-0", Product: "NVIDIA L4", Memory: 23034},
+			{ID: "gpu-1"},
+		},
+	}
+
+	l := labels.BuildNodeLabels(topo)
+	assert.Equal(t, "NVIDIA-L4", l["nvidia.com/gpu.product"])
+	assert.Equal(t, "23034", l["nvidia.com/gpu.memory"])
+	assert.Equal(t, "NVIDIA-L4", l["run.ai/fake.gpu-0.product"])
+	assert.Equal(t, "23034", l["run.ai/fake.gpu-0.memory"])
+	assert.Equal(t, "NVIDIA-A10", l["run.ai/fake.gpu-1.product"])
+	assert.Equal(t, "23028", l["run.ai/fake.gpu-1.memory"])
+
+	topo.Gpus[0] = topology.GpuDetails{ID: "gpu-0"}
+	l = labels.BuildNodeLabels(topo)
+	assert.Equal(t, "NVIDIA-A10", l["nvidia.com/gpu.product"])
+	assert.NotContains(t, l, "run.ai/fake.gpu-0.product")
+}
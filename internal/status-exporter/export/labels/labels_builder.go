@@ -1,10 +1,13 @@
 package labels
 
 import (
+	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
 	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
 )
 
@@ -12,16 +15,44 @@ import (
 // Valid chars: [a-zA-Z0-9._-]
 var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
 
-// BuildNodeLabels creates the standard node labels from a topology
+// gpuLabelPrefix prefixes the labels describing a single GPU of the node.
+const gpuLabelPrefix = "run.ai/fake.gpu-"
+
+// BuildNodeLabels creates the standard node labels from a topology. The
+// product and memory labels describe the first GPU; nodes with mixed cards
+// also get a product and memory label per GPU.
 func BuildNodeLabels(nodeTopology *topology.NodeTopology) map[string]string {
-	return map[string]string{
-		"nvidia.com/gpu.memory":   strconv.Itoa(nodeTopology.GpuMemory),
-		"nvidia.com/gpu.product":  sanitizeLabelValue(nodeTopology.GpuProduct),
+	labels := map[string]string{
+		"nvidia.com/gpu.memory":   strconv.Itoa(nodeTopology.GpuMemoryOf(0)),
+		"nvidia.com/gpu.product":  sanitizeLabelValue(nodeTopology.GpuProductOf(0)),
 		"nvidia.com/mig.strategy": nodeTopology.MigStrategy,
 		"nvidia.com/gpu.count":    strconv.Itoa(len(nodeTopology.Gpus)),
 		"nvidia.com/gpu.present":  "true",
 		"run.ai/fake.gpu":         "true",
 	}
+	if nodeTopology.CliqueID != "" {
+		labels[constants.LabelGpuClique] = nodeTopology.CliqueID
+	}
+	if nodeTopology.IsHeterogeneous() {
+		for idx := range nodeTopology.Gpus {
+			labels[fmt.Sprintf("%s%d.product", gpuLabelPrefix, idx)] = sanitizeLabelValue(nodeTopology.GpuProductOf(idx))
+			labels[fmt.Sprintf("%s%d.memory", gpuLabelPrefix, idx)] = strconv.Itoa(nodeTopology.GpuMemoryOf(idx))
+		}
+	}
+	return labels
+}
+
+// StaleGpuLabels returns the per-GPU labels of the node that labels no
+// longer sets, e.g. after the node got fewer GPUs or a homogeneous pool.
+func StaleGpuLabels(nodeLabels, labels map[string]string) []string {
+	var stale []string
+	for key := range nodeLabels {
+		if _, ok := labels[key]; !ok && strings.HasPrefix(key, gpuLabelPrefix) {
+			stale = append(stale, key)
+		}
+	}
+	sort.Strings(stale)
+	return stale
 }
 
 // sanitizeLabelValue replaces characters invalid in Kubernetes label values
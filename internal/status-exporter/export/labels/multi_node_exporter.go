@@ -56,6 +56,9 @@ func (e *MultiNodeLabelsExporter) setNodeLabels(nodeName string, labels map[stri
 		}
 
 		// Update labels
+		for _, k := range StaleGpuLabels(node.Labels, labels) {
+			delete(node.Labels, k)
+		}
 		for k, v := range labels {
 			node.Labels[k] = v
 		}
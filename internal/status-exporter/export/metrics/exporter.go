@@ -71,11 +71,12 @@ func (e *MetricsExporter) export(nodeTopology *topology.NodeTopology) error {
 		labels := buildGpuMetricLabels(nodeName, gpuIdx, &gpu, nodeTopology)
 
 		utilization := gpu.Status.PodGpuUsageStatus.Utilization()
-		fbUsed := gpu.Status.PodGpuUsageStatus.FbUsed(nodeTopology.GpuMemory)
+		gpuMemory := nodeTopology.GpuMemoryOf(gpuIdx)
+		fbUsed := gpu.Status.PodGpuUsageStatus.FbUsed(gpuMemory)
 
 		gpuUtilization.With(labels).Set(float64(utilization))
 		gpuFbUsed.With(labels).Set(float64(fbUsed))
-		gpuFbFree.With(labels).Set(float64(nodeTopology.GpuMemory - fbUsed))
+		gpuFbFree.With(labels).Set(float64(gpuMemory - fbUsed))
 	}
 
 	return nil
@@ -9,13 +9,13 @@ var (
 	gpuUtilization = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "DCGM_FI_DEV_GPU_UTIL",
 		Help: "GPU Utilization",
-	}, []string{"gpu", "UUID", "device", "modelName", "Hostname", "container", "namespace", "pod"})
+	}, []string{"gpu", "UUID", "device", "modelName", "Hostname", "container", "namespace", "pod", "compute_domain"})
 	gpuFbUsed = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "DCGM_FI_DEV_FB_USED",
 		Help: "GPU Framebuffer Used",
-	}, []string{"gpu", "UUID", "device", "modelName", "Hostname", "container", "namespace", "pod"})
+	}, []string{"gpu", "UUID", "device", "modelName", "Hostname", "container", "namespace", "pod", "compute_domain"})
 	gpuFbFree = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "DCGM_FI_DEV_FB_FREE",
 		Help: "GPU Framebuffer Free",
-	}, []string{"gpu", "UUID", "device", "modelName", "Hostname", "container", "namespace", "pod"})
+	}, []string{"gpu", "UUID", "device", "modelName", "Hostname", "container", "namespace", "pod", "compute_domain"})
 )
@@ -10,13 +10,14 @@ import (
 // buildGpuMetricLabels creates Prometheus labels for a GPU metric
 func buildGpuMetricLabels(nodeName string, gpuIdx int, gpu *topology.GpuDetails, nodeTopology *topology.NodeTopology) prometheus.Labels {
 	return prometheus.Labels{
-		"gpu":       strconv.Itoa(gpuIdx),
-		"UUID":      gpu.ID,
-		"device":    "nvidia" + strconv.Itoa(gpuIdx),
-		"modelName": nodeTopology.GpuProduct,
-		"Hostname":  generateFakeHostname(nodeName),
-		"namespace": gpu.Status.AllocatedBy.Namespace,
-		"pod":       gpu.Status.AllocatedBy.Pod,
-		"container": gpu.Status.AllocatedBy.Container,
+		"gpu":            strconv.Itoa(gpuIdx),
+		"UUID":           gpu.ID,
+		"device":         "nvidia" + strconv.Itoa(gpuIdx),
+		"modelName":      nodeTopology.GpuProductOf(gpuIdx),
+		"Hostname":       generateFakeHostname(nodeName),
+		"namespace":      gpu.Status.AllocatedBy.Namespace,
+		"pod":            gpu.Status.AllocatedBy.Pod,
+		"container":      gpu.Status.AllocatedBy.Container,
+		"compute_domain": gpu.Status.AllocatedBy.ComputeDomain,
 	}
 }
@@ -103,11 +103,12 @@ func (e *MultiNodeMetricsExporter) exportNode(nodeName string, nodeTopology *top
 		labels["Hostname"] = nodeName
 
 		utilization := gpu.Status.PodGpuUsageStatus.Utilization()
-		fbUsed := gpu.Status.PodGpuUsageStatus.FbUsed(nodeTopology.GpuMemory)
+		gpuMemory := nodeTopology.GpuMemoryOf(gpuIdx)
+		fbUsed := gpu.Status.PodGpuUsageStatus.FbUsed(gpuMemory)
 
 		gpuUtilization.With(labels).Set(float64(utilization))
 		gpuFbUsed.With(labels).Set(float64(fbUsed))
-		gpuFbFree.With(labels).Set(float64(nodeTopology.GpuMemory - fbUsed))
+		gpuFbFree.With(labels).Set(float64(gpuMemory - fbUsed))
 	}
 
 	return nil
@@ -124,4 +125,4 @@ func (e *MultiNodeMetricsExporter) deleteNodeMetrics(nodeName string, nodeTopolo
 		gpuFbUsed.Delete(labels)
 		gpuFbFree.Delete(labels)
 	}
-}
\ No newline at end of file
+}
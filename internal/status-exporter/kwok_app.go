@@ -93,15 +93,12 @@ func (app *KWOKStatusExporterApp) Init(stopCh chan struct{}) {
 	labelsExporter := labels.NewMultiNodeLabelsExporter(kubeClient)
 
 	// Setup multi-node watcher with exporters
-	namespace := viper.GetString(constants.EnvTopologyCmNamespace)
-	topologyCMName := viper.GetString(constants.EnvTopologyCmName)
-
 	var nrtExporter watch.NRTExporter
 	if viper.GetBool(constants.EnvNodeResourceTopologyEnabled) {
 		nrtExporter = nrtexport.NewMultiNodeExporter(kubeClient, nrtexport.NewReconciler(dynamic.NewForConfigOrDie(cfg)))
 	}
 
-	_, err = watch.SetupMultiNodeWatcherWithManager(app.mgr, namespace, topologyCMName, app.metricsExporter, labelsExporter, nrtExporter)
+	_, err = watch.SetupMultiNodeWatcherWithManager(app.mgr, app.metricsExporter, labelsExporter, nrtExporter)
 	if err != nil {
 		log.Fatalf("Failed to setup multi-node watcher: %v", err)
 	}
@@ -29,7 +29,7 @@ func (w *KubeWatcher) Subscribe(subscriber chan<- *topology.NodeTopology) {
 }
 
 func (w *KubeWatcher) Watch(stopCh <-chan struct{}) {
-	cmChan, err := w.kubeclient.WatchConfigMap(viper.GetString(constants.EnvTopologyCmNamespace), topology.GetNodeTopologyCMName(viper.GetString(constants.EnvNodeName)))
+	fakeGpuNodeChan, err := w.kubeclient.WatchFakeGpuNode(viper.GetString(constants.EnvNodeName))
 	if err != nil {
 		panic(err)
 	}
@@ -40,10 +40,10 @@ func (w *KubeWatcher) Watch(stopCh <-chan struct{}) {
 
 	for {
 		select {
-		case cm := <-cmChan:
+		case fakeGpuNode := <-fakeGpuNodeChan:
 			ticker.Reset(maxInterval)
 			log.Printf("Got topology update, publishing...\n")
-			nodeTopology, err := topology.FromNodeTopologyCM(cm)
+			nodeTopology, err := topology.NodeTopologyFromUnstructured(fakeGpuNode)
 			if err != nil {
 				panic(err)
 			}
@@ -51,11 +51,11 @@ func (w *KubeWatcher) Watch(stopCh <-chan struct{}) {
 
 		case <-ticker.C:
 			log.Printf("Topology update not received within interval, publishing...\n")
-			cm, ok := w.kubeclient.GetConfigMap(viper.GetString(constants.EnvTopologyCmNamespace), topology.GetNodeTopologyCMName(viper.GetString(constants.EnvNodeName)))
+			fakeGpuNode, ok := w.kubeclient.GetFakeGpuNode(viper.GetString(constants.EnvNodeName))
 			if !ok {
 				break
 			}
-			nodeTopology, err := topology.FromNodeTopologyCM(cm)
+			nodeTopology, err := topology.NodeTopologyFromUnstructured(fakeGpuNode)
 			if err != nil {
 				panic(err)
 			}
@@ -3,9 +3,8 @@ package watch
 import (
 	"context"
 	"log"
-	"strings"
 
-	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -30,50 +29,40 @@ type NRTExporter interface {
 
 type MultiNodeWatcher struct {
 	client.Client
-	scheme           *runtime.Scheme
-	namespace        string
-	topologyCMPrefix string
-	metricsExporter  MetricsExporter
-	labelsExporter   LabelsExporter
-	nrtExporter      NRTExporter
+	scheme          *runtime.Scheme
+	metricsExporter MetricsExporter
+	labelsExporter  LabelsExporter
+	nrtExporter     NRTExporter
 }
 
-func NewMultiNodeWatcher(mgr ctrl.Manager, namespace, topologyCMPrefix string, metricsExporter MetricsExporter, labelsExporter LabelsExporter, nrtExporter NRTExporter) *MultiNodeWatcher {
+func NewMultiNodeWatcher(mgr ctrl.Manager, metricsExporter MetricsExporter, labelsExporter LabelsExporter, nrtExporter NRTExporter) *MultiNodeWatcher {
 	return &MultiNodeWatcher{
-		Client:           mgr.GetClient(),
-		scheme:           mgr.GetScheme(),
-		namespace:        namespace,
-		topologyCMPrefix: topologyCMPrefix,
-		metricsExporter:  metricsExporter,
-		labelsExporter:   labelsExporter,
-		nrtExporter:      nrtExporter,
+		Client:          mgr.GetClient(),
+		scheme:          mgr.GetScheme(),
+		metricsExporter: metricsExporter,
+		labelsExporter:  labelsExporter,
+		nrtExporter:     nrtExporter,
 	}
 }
 
 func (w *MultiNodeWatcher) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	var cm corev1.ConfigMap
-	if err := w.Get(ctx, req.NamespacedName, &cm); err != nil {
+	// FakeGpuNodes are named after their node.
+	nodeName := req.Name
+
+	obj := newFakeGpuNodeObject()
+	if err := w.Get(ctx, req.NamespacedName, obj); err != nil {
 		if client.IgnoreNotFound(err) != nil {
 			return ctrl.Result{}, err
 		}
 
-		nodeName := extractNodeNameFromCMName(req.Name, w.topologyCMPrefix)
-		if nodeName != "" {
-			log.Printf("KWOK node ConfigMap deleted: %s\n", nodeName)
-			w.handleNodeDeletion(nodeName)
-		}
+		log.Printf("KWOK node FakeGpuNode deleted: %s\n", nodeName)
+		w.handleNodeDeletion(nodeName)
 		return ctrl.Result{}, nil
 	}
 
-	nodeTopology, err := topology.FromNodeTopologyCM(&cm)
+	nodeTopology, err := topology.NodeTopologyFromUnstructured(obj)
 	if err != nil {
-		log.Printf("Failed to parse topology from ConfigMap %s: %v (skipping)\n", req.Name, err)
-		return ctrl.Result{}, nil
-	}
-
-	nodeName := extractNodeNameFromCMName(req.Name, w.topologyCMPrefix)
-	if nodeName == "" {
-		log.Printf("Failed to extract node name from ConfigMap: %s\n", req.Name)
+		log.Printf("Failed to parse topology from FakeGpuNode %s: %v (skipping)\n", req.Name, err)
 		return ctrl.Result{}, nil
 	}
 
@@ -106,48 +95,25 @@ func (w *MultiNodeWatcher) handleNodeDeletion(nodeName string) {
 	}
 }
 
-func extractNodeNameFromCMName(cmName, topologyCMPrefix string) string {
-	prefix := topologyCMPrefix + "-"
-	if strings.HasPrefix(cmName, prefix) {
-		return strings.TrimPrefix(cmName, prefix)
-	}
-	return cmName
-}
-
 func (w *MultiNodeWatcher) SetupWithManager(mgr ctrl.Manager) error {
-	kwokNodeCMPredicate := predicate.NewPredicateFuncs(func(obj client.Object) bool {
-		cm, ok := obj.(*corev1.ConfigMap)
-		if !ok {
-			return false
-		}
-		return isKWOKNodeConfigMap(cm)
-	})
-
-	namespacePredicate := predicate.NewPredicateFuncs(func(obj client.Object) bool {
-		return obj.GetNamespace() == w.namespace
+	kwokNodePredicate := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetAnnotations()[constants.AnnotationKwokNode] == "fake"
 	})
 
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&corev1.ConfigMap{}).
-		WithEventFilter(predicate.And(namespacePredicate, kwokNodeCMPredicate)).
+		For(newFakeGpuNodeObject()).
+		WithEventFilter(kwokNodePredicate).
 		Complete(w)
 }
 
-func isKWOKNodeConfigMap(cm *corev1.ConfigMap) bool {
-	if cm == nil || cm.Labels == nil || cm.Annotations == nil {
-		return false
-	}
-
-	_, foundNodeName := cm.Labels[constants.LabelTopologyCMNodeName]
-	if !foundNodeName {
-		return false
-	}
-
-	return cm.Annotations[constants.AnnotationKwokNode] == "fake"
+func newFakeGpuNodeObject() *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(topology.FakeGpuNodeGVR.GroupVersion().WithKind(topology.FakeGpuNodeKind))
+	return obj
 }
 
-func SetupMultiNodeWatcherWithManager(mgr ctrl.Manager, namespace, topologyCMName string, metricsExporter MetricsExporter, labelsExporter LabelsExporter, nrtExporter NRTExporter) (*MultiNodeWatcher, error) {
-	watcher := NewMultiNodeWatcher(mgr, namespace, topologyCMName, metricsExporter, labelsExporter, nrtExporter)
+func SetupMultiNodeWatcherWithManager(mgr ctrl.Manager, metricsExporter MetricsExporter, labelsExporter LabelsExporter, nrtExporter NRTExporter) (*MultiNodeWatcher, error) {
+	watcher := NewMultiNodeWatcher(mgr, metricsExporter, labelsExporter, nrtExporter)
 
 	if err := watcher.SetupWithManager(mgr); err != nil {
 		return nil, err
@@ -36,6 +36,8 @@ type StatusUpdaterAppConfiguration struct {
 	DisableNodeLabeling             bool   `mapstructure:"DISABLE_NODE_LABELING"`
 	RunaiIntegrationEnabled         bool   `mapstructure:"RUNAI_INTEGRATION_ENABLED"`
 	RunaiIntegrationPollingInterval string `mapstructure:"RUNAI_INTEGRATION_POLLING_INTERVAL"`
+	LeaderElection                  bool   `mapstructure:"LEADER_ELECT"`
+	HealthProbeBindAddress          string `mapstructure:"HEALTH_PROBE_BIND_ADDRESS"`
 }
 
 type StatusUpdaterApp struct {
@@ -43,14 +45,27 @@ type StatusUpdaterApp struct {
 	kubeClient  kubernetes.Interface
 	stopCh      chan struct{}
 	wg          *sync.WaitGroup
+	election    *leaderElection
 }
 
 func (app *StatusUpdaterApp) Run() {
+	if address := viper.GetString(constants.EnvHealthProbeBindAddress); address != "" {
+		go app.serveHealthProbes(address, app.stopCh)
+	}
+
+	if app.election == nil {
+		app.runControllers(app.stopCh)
+		return
+	}
+	app.election.run(app.runControllers)
+}
+
+func (app *StatusUpdaterApp) runControllers(stopCh <-chan struct{}) {
 	app.wg.Add(len(app.Controllers))
 	for _, controller := range app.Controllers {
 		go func(controller controllers.Interface) {
 			defer app.wg.Done()
-			controller.Run(app.stopCh)
+			controller.Run(stopCh)
 		}(controller)
 	}
 
@@ -73,10 +88,14 @@ func (app *StatusUpdaterApp) Init(stopCh chan struct{}) {
 	app.kubeClient = KubeClientFn(clusterConfig)
 	dynamicClient := DynamicClientFn(clusterConfig)
 
+	if viper.GetBool(constants.EnvLeaderElect) {
+		app.election = newLeaderElection(app.kubeClient, stopCh)
+	}
+
 	disableNodeLabeling := viper.GetBool(constants.EnvDisableNodeLabeling)
 
 	app.Controllers = append(app.Controllers, podcontroller.NewPodController(app.kubeClient, dynamicClient, app.wg))
-	app.Controllers = append(app.Controllers, nodecontroller.NewNodeController(app.kubeClient, app.wg, disableNodeLabeling))
+	app.Controllers = append(app.Controllers, nodecontroller.NewNodeController(app.kubeClient, dynamicClient, app.wg, disableNodeLabeling))
 
 	pullPolicy := corev1.PullPolicy(viper.GetString("IMAGE_PULL_POLICY"))
 	if pullPolicy == "" {
@@ -6,6 +6,7 @@ import (
 	"os"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"testing"
 
 	"gopkg.in/yaml.v3"
@@ -20,11 +21,13 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/dynamic"
 	dfake "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/kubernetes"
 	kfake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
 	"k8s.io/utils/ptr"
 
 	"github.com/run-ai/fake-gpu-operator/internal/common/app"
@@ -72,6 +75,11 @@ var _ = Describe("StatusUpdater", func() {
 					GpuProduct: "Tesla-K80",
 					GpuCount:   nodeGpuCount,
 				},
+				"h100": {
+					GpuMemory:  81559,
+					GpuProduct: "NVIDIA-H100-80GB-HBM3",
+					GpuCount:   1,
+				},
 			},
 			NodePoolLabelKey: "run.ai/simulated-gpu-node-pool",
 			MigStrategy:      "mixed",
@@ -92,7 +100,9 @@ var _ = Describe("StatusUpdater", func() {
 		kubeclient = kfake.NewSimpleClientset()
 		scheme := runtime.NewScheme()
 		scheme.AddKnownTypeWithName(schema.GroupVersionKind{Group: "scheduling.run.ai", Version: "v2alpha2", Kind: "PodGroup"}, &unstructured.UnstructuredList{})
-		dynamicClient = dfake.NewSimpleDynamicClient(scheme)
+		dynamicClient = dfake.NewSimpleDynamicClientWithCustomListKinds(scheme, map[schema.GroupVersionResource]string{
+			topology.FakeGpuNodeGVR: topology.FakeGpuNodeListKind,
+		})
 
 		_, err = kubeclient.CoreV1().ConfigMaps(topologyCmNamespace).Create(context.TODO(), topologyConfigMap, metav1.CreateOptions{})
 		Expect(err).ToNot(HaveOccurred())
@@ -121,11 +131,9 @@ var _ = Describe("StatusUpdater", func() {
 			appRunner.Run()
 		}()
 
-		// Wait for the node controller to initialize the node topology ConfigMap
-		// before any spec creates pods: pod handlers read this CM and aren't
-		// retried (no informer resync), so creating pods before it exists drops
-		// the allocation.
-		Eventually(getTopologyNodeFromKube(kubeclient, node)).Should(Equal(createTopology(nodeGpuCount, node)))
+		// Wait for the node controller to initialize the FakeGpuNode
+		// so that specs start from a known topology.
+		Eventually(getTopologyNodeFromKube(dynamicClient, node)).Should(Equal(createTopology(nodeGpuCount, node)))
 	})
 
 	AfterEach(func() {
@@ -137,7 +145,7 @@ var _ = Describe("StatusUpdater", func() {
 
 	When("the status updater is started", func() {
 		It("should initialize the topology nodes", func() {
-			Eventually(getTopologyNodeFromKube(kubeclient, node)).Should(Equal(createTopology(nodeGpuCount, node)))
+			Eventually(getTopologyNodeFromKube(dynamicClient, node)).Should(Equal(createTopology(nodeGpuCount, node)))
 		})
 	})
 
@@ -208,12 +216,12 @@ var _ = Describe("StatusUpdater", func() {
 							}
 						}
 
-						Eventually(getTopologyNodeFromKube(kubeclient, node)).Should(Equal(expectedTopology))
+						Eventually(getTopologyNodeFromKube(dynamicClient, node)).Should(Equal(expectedTopology))
 
 						By("deleting the pod")
 						err = kubeclient.CoreV1().Pods(podNamespace).Delete(context.TODO(), podName, metav1.DeleteOptions{})
 						Expect(err).ToNot(HaveOccurred())
-						Eventually(getTopologyNodeFromKube(kubeclient, node)).Should(Equal(createTopology(nodeGpuCount, node)))
+						Eventually(getTopologyNodeFromKube(dynamicClient, node)).Should(Equal(createTopology(nodeGpuCount, node)))
 					})
 				}
 			})
@@ -247,7 +255,7 @@ var _ = Describe("StatusUpdater", func() {
 						expectedTopology.Gpus[0].Status.AllocatedBy.Container = containerName
 						expectedTopology.Gpus[0].Status.AllocatedBy.Namespace = podNamespace
 
-						Eventually(getTopologyNodeFromKube(kubeclient, node)).Should(Equal(expectedTopology))
+						Eventually(getTopologyNodeFromKube(dynamicClient, node)).Should(Equal(expectedTopology))
 					})
 				}
 			})
@@ -277,7 +285,7 @@ var _ = Describe("StatusUpdater", func() {
 						}
 
 						By("creating the pod with pending phase")
-						Eventually(getTopologyNodeFromKube(kubeclient, node)).Should(Equal(expectedTopology))
+						Eventually(getTopologyNodeFromKube(dynamicClient, node)).Should(Equal(expectedTopology))
 
 						By("updating the pod phase to running")
 						pod, err = kubeclient.CoreV1().Pods(podNamespace).Get(context.TODO(), podName, metav1.GetOptions{})
@@ -292,7 +300,7 @@ var _ = Describe("StatusUpdater", func() {
 							UseKnativeUtilization: false,
 						}
 
-						Eventually(getTopologyNodeFromKube(kubeclient, node)).Should(Equal(expectedTopology))
+						Eventually(getTopologyNodeFromKube(dynamicClient, node)).Should(Equal(expectedTopology))
 					})
 				})
 			})
@@ -330,7 +338,7 @@ var _ = Describe("StatusUpdater", func() {
 							return false, err
 						}
 
-						nodeTopology, err := getTopologyNodeFromKube(kubeclient, node)()
+						nodeTopology, err := getTopologyNodeFromKube(dynamicClient, node)()
 						if err != nil || nodeTopology == nil {
 							return false, err
 						}
@@ -359,7 +367,7 @@ var _ = Describe("StatusUpdater", func() {
 				expectedTopology.Gpus[0].Status.AllocatedBy.Pod = reservationPodName
 				expectedTopology.Gpus[0].Status.AllocatedBy.Container = reservationPodContainerName
 				expectedTopology.Gpus[0].Status.AllocatedBy.Namespace = resourceReservationNs
-				Eventually(getTopologyNodeFromKube(kubeclient, node)).Should(Equal(expectedTopology))
+				Eventually(getTopologyNodeFromKube(dynamicClient, node)).Should(Equal(expectedTopology))
 			}
 			expectTopologyToBeUpdatedWithSharedGpuPod = func() {
 				expectedTopology.Gpus[0].Status.PodGpuUsageStatus = topology.PodGpuUsageStatusMap{
@@ -372,7 +380,7 @@ var _ = Describe("StatusUpdater", func() {
 					},
 				}
 
-				Eventually(getTopologyNodeFromKube(kubeclient, node)).Should(Equal(expectedTopology))
+				Eventually(getTopologyNodeFromKube(dynamicClient, node)).Should(Equal(expectedTopology))
 			}
 		)
 
@@ -400,6 +408,32 @@ var _ = Describe("StatusUpdater", func() {
 			})
 		})
 
+		Context("that completes", func() {
+			It("should drop its usage from the reservation pod GPU", func() {
+				reservationPod := createGpuIdxReservationPod(ptr.To(0))
+				_, err := kubeclient.CoreV1().Pods(resourceReservationNs).Create(context.TODO(), reservationPod, metav1.CreateOptions{})
+				Expect(err).ToNot(HaveOccurred())
+
+				expectTopologyToBeUpdatedWithReservationPod()
+
+				pod := createGpuIdxSharedGpuPod(0, 0.5)
+				_, err = kubeclient.CoreV1().Pods(podNamespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+				Expect(err).ToNot(HaveOccurred())
+
+				podGroup := createPodGroup("train")
+				_, err = dynamicClient.Resource(schema.GroupVersionResource{Group: "scheduling.run.ai", Version: "v2alpha2", Resource: "podgroups"}).Namespace(podNamespace).Create(context.TODO(), podGroup, metav1.CreateOptions{})
+				Expect(err).ToNot(HaveOccurred())
+
+				expectTopologyToBeUpdatedWithSharedGpuPod()
+
+				pod.Status.Phase = v1.PodSucceeded
+				_, err = kubeclient.CoreV1().Pods(podNamespace).UpdateStatus(context.TODO(), pod, metav1.UpdateOptions{})
+				Expect(err).ToNot(HaveOccurred())
+
+				expectTopologyToBeUpdatedWithReservationPod()
+			})
+		})
+
 		Context("with a runai-gpu-group label", func() {
 			It("should update the cluster topology at its reservation pod location", func() {
 				gpuGroup := "group1"
@@ -422,6 +456,213 @@ var _ = Describe("StatusUpdater", func() {
 
 				expectTopologyToBeUpdatedWithSharedGpuPod()
 			})
+
+			It("should retry the shared GPU pod until its reservation pod is in the topology", func() {
+				gpuGroup := "group1"
+
+				podGroup := createPodGroup("train")
+				_, err := dynamicClient.Resource(schema.GroupVersionResource{Group: "scheduling.run.ai", Version: "v2alpha2", Resource: "podgroups"}).Namespace(podNamespace).Create(context.TODO(), podGroup, metav1.CreateOptions{})
+				Expect(err).ToNot(HaveOccurred())
+
+				pod := createGpuGroupSharedGpuPod(gpuGroup, 0.5)
+				_, err = kubeclient.CoreV1().Pods(podNamespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(func() ([]string, error) {
+					events, err := kubeclient.CoreV1().Events(podNamespace).List(context.TODO(), metav1.ListOptions{})
+					if err != nil {
+						return nil, err
+					}
+					var reasons []string
+					for _, event := range events.Items {
+						reasons = append(reasons, event.Reason)
+					}
+					return reasons, nil
+				}).Should(ContainElement("SimulatedGpuAssignmentFailed"))
+
+				reservationPod := createGpuGroupReservationPod(gpuGroup)
+				_, err = kubeclient.CoreV1().Pods(resourceReservationNs).Create(context.TODO(), reservationPod, metav1.CreateOptions{})
+				Expect(err).ToNot(HaveOccurred())
+
+				expectTopologyToBeUpdatedWithSharedGpuPod()
+			})
+		})
+	})
+
+	When("informed of a burst of dedicated GPU pods", func() {
+		It("should not lose allocations when topology writes conflict", func() {
+			// Every other write of the node topology loses the race against
+			// another writer.
+			var updates atomic.Int32
+			dynamicClient.(*dfake.FakeDynamicClient).PrependReactor("update", "fakegpunodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				fakeGpuNode := action.(k8stesting.UpdateAction).GetObject().(*unstructured.Unstructured)
+				if fakeGpuNode.GetName() != node || updates.Add(1)%2 == 0 {
+					return false, nil, nil
+				}
+				return true, nil, errors.NewConflict(topology.FakeGpuNodeGVR.GroupResource(), fakeGpuNode.GetName(), nil)
+			})
+
+			var expectedPods []string
+			var created sync.WaitGroup
+			for i := 0; i < nodeGpuCount; i++ {
+				pod := createDedicatedGpuPod(1, v1.PodRunning, []v1.PodCondition{{Type: v1.PodScheduled, Status: v1.ConditionTrue}})
+				pod.Name = fmt.Sprintf("%s-%d", podName, i)
+				pod.UID = types.UID(fmt.Sprintf("%s-%d", podUID, i))
+				expectedPods = append(expectedPods, pod.Name)
+
+				created.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer created.Done()
+					_, err := kubeclient.CoreV1().Pods(podNamespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+					Expect(err).ToNot(HaveOccurred())
+				}()
+			}
+			created.Wait()
+
+			Eventually(func() ([]string, error) {
+				nodeTopology, err := topology.GetNodeTopology(dynamicClient, node)
+				if err != nil {
+					return nil, err
+				}
+				var pods []string
+				for _, gpu := range nodeTopology.Gpus {
+					pods = append(pods, gpu.Status.AllocatedBy.Pod)
+				}
+				return pods, nil
+			}).Should(ConsistOf(expectedPods))
+		})
+	})
+
+	When("informed of a dedicated GPU pod with several containers", func() {
+		It("should attribute each GPU to the container that requested it", func() {
+			pod := createDedicatedGpuPod(1, v1.PodRunning, []v1.PodCondition{{Type: v1.PodScheduled, Status: v1.ConditionTrue}})
+			gpuLimits := pod.Spec.Containers[0].Resources
+			pod.Spec.Containers = []v1.Container{{Name: "no-gpu"}, {Name: containerName, Resources: gpuLimits}}
+			pod.Spec.InitContainers = []v1.Container{
+				{Name: "init", Resources: v1.ResourceRequirements{Limits: v1.ResourceList{
+					constants.GpuResourceName: *resource.NewQuantity(nodeGpuCount, resource.DecimalSI),
+				}}},
+				{Name: "sidecar", RestartPolicy: ptr.To(v1.ContainerRestartPolicyAlways), Resources: gpuLimits},
+			}
+			_, err := kubeclient.CoreV1().Pods(podNamespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(getGpuContainersFromKube(dynamicClient, node)).Should(Equal([]string{"sidecar", containerName}))
+
+			err = kubeclient.CoreV1().Pods(podNamespace).Delete(context.TODO(), podName, metav1.DeleteOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(getTopologyNodeFromKube(dynamicClient, node)).Should(Equal(createTopology(nodeGpuCount, node)))
+		})
+
+		It("should keep the extra GPUs of a larger init container attributed to it", func() {
+			pod := createDedicatedGpuPod(1, v1.PodRunning, []v1.PodCondition{{Type: v1.PodScheduled, Status: v1.ConditionTrue}})
+			pod.Spec.InitContainers = []v1.Container{
+				{Name: "init", Resources: v1.ResourceRequirements{Limits: v1.ResourceList{
+					constants.GpuResourceName: *resource.NewQuantity(nodeGpuCount, resource.DecimalSI),
+				}}},
+			}
+			_, err := kubeclient.CoreV1().Pods(podNamespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(getGpuContainersFromKube(dynamicClient, node)).Should(Equal([]string{containerName, "init"}))
+		})
+	})
+
+	When("a dedicated GPU pod completes", func() {
+		It("should release its GPUs without waiting for the pod deletion", func() {
+			pod := createDedicatedGpuPod(1, v1.PodRunning, []v1.PodCondition{{Type: v1.PodScheduled, Status: v1.ConditionTrue}})
+			_, err := kubeclient.CoreV1().Pods(podNamespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(getGpuContainersFromKube(dynamicClient, node)).Should(Equal([]string{containerName, ""}))
+
+			pod.Status.Phase = v1.PodFailed
+			_, err = kubeclient.CoreV1().Pods(podNamespace).UpdateStatus(context.TODO(), pod, metav1.UpdateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(getTopologyNodeFromKube(dynamicClient, node)).Should(Equal(createTopology(nodeGpuCount, node)))
+		})
+
+		It("should release the GPUs of a pod that completed while it was not watched", func() {
+			nodeTopology, err := topology.GetNodeTopology(dynamicClient, node)
+			Expect(err).ToNot(HaveOccurred())
+			nodeTopology.Gpus[0].Status.AllocatedBy = topology.ContainerDetails{Namespace: podNamespace, Pod: podName, Container: containerName}
+			nodeTopology.Gpus[0].Status.PodGpuUsageStatus = topology.PodGpuUsageStatusMap{podUID: topology.GpuUsageStatus{FbUsed: 100}}
+			updateNodeTopology(dynamicClient, node, nodeTopology)
+
+			pod := createDedicatedGpuPod(1, v1.PodSucceeded, []v1.PodCondition{{Type: v1.PodScheduled, Status: v1.ConditionTrue}})
+			_, err = kubeclient.CoreV1().Pods(podNamespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(getTopologyNodeFromKube(dynamicClient, node)).Should(Equal(createTopology(nodeGpuCount, node)))
+		})
+	})
+
+	When("informed of GPU assignments", func() {
+		getPodEvents := func() ([]string, error) {
+			events, err := kubeclient.CoreV1().Events(podNamespace).List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				return nil, err
+			}
+			var descriptions []string
+			for _, event := range events.Items {
+				if event.InvolvedObject.Name == podName {
+					descriptions = append(descriptions, fmt.Sprintf("%s %s: %s", event.Type, event.Reason, event.Message))
+				}
+			}
+			return descriptions, nil
+		}
+
+		getAssignedGpus := func() ([]topology.AssignedGpu, error) {
+			pod, err := kubeclient.CoreV1().Pods(podNamespace).Get(context.TODO(), podName, metav1.GetOptions{})
+			if err != nil {
+				return nil, err
+			}
+			value, found := pod.Annotations[constants.AnnotationAssignedGpus]
+			if !found {
+				return nil, fmt.Errorf("pod %s is not annotated with its GPUs", podName)
+			}
+			return topology.ParseAssignedGpus(value)
+		}
+
+		It("should annotate the pod with its GPUs and record an Event per GPU", func() {
+			pod := createDedicatedGpuPod(nodeGpuCount, v1.PodRunning, []v1.PodCondition{{Type: v1.PodScheduled, Status: v1.ConditionTrue}})
+			pod.Annotations[simulatedGpuUtilizationAnnotation] = "80-100"
+			_, err := kubeclient.CoreV1().Pods(podNamespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			gpus := createTopology(nodeGpuCount, node).Gpus
+			Eventually(getAssignedGpus).Should(Equal([]topology.AssignedGpu{
+				{UUID: gpus[0].ID, Index: 0, Container: containerName, Utilization: "80-100"},
+				{UUID: gpus[1].ID, Index: 1, Container: containerName, Utilization: "80-100"},
+			}))
+			Eventually(getPodEvents).Should(ConsistOf(
+				fmt.Sprintf("Normal SimulatedGpuAssigned: Assigned simulated GPU %s (idx 0) with utilization 80-100", gpus[0].ID),
+				fmt.Sprintf("Normal SimulatedGpuAssigned: Assigned simulated GPU %s (idx 1) with utilization 80-100", gpus[1].ID),
+			))
+		})
+
+		It("should record a Warning Event when there is no free GPU", func() {
+			pod := createDedicatedGpuPod(nodeGpuCount+1, v1.PodRunning, []v1.PodCondition{{Type: v1.PodScheduled, Status: v1.ConditionTrue}})
+			_, err := kubeclient.CoreV1().Pods(podNamespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(getPodEvents).Should(ContainElement(And(
+				HavePrefix("Warning SimulatedGpuAssignmentFailed:"),
+				ContainSubstring("no free GPU for container %s: 1 of 3 GPUs missing", containerName),
+			)))
+			Eventually(getAssignedGpus).Should(HaveLen(nodeGpuCount))
+		})
+
+		It("should record a Warning Event when the reservation pod of a shared GPU pod is missing", func() {
+			pod := createGpuGroupSharedGpuPod("missing-group", 0.5)
+			_, err := kubeclient.CoreV1().Pods(podNamespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(getPodEvents).Should(ContainElement(And(
+				HavePrefix("Warning SimulatedGpuAssignmentFailed:"),
+				ContainSubstring("no reservation pod found for gpu group missing-group"),
+			)))
 		})
 	})
 
@@ -439,13 +680,13 @@ var _ = Describe("StatusUpdater", func() {
 			_, err := kubeclient.CoreV1().Nodes().Create(context.TODO(), node, metav1.CreateOptions{})
 			Expect(err).ToNot(HaveOccurred())
 
-			Eventually(getTopologyNodeFromKube(kubeclient, node.Name)).Should(Not(BeNil()))
+			Eventually(getTopologyNodeFromKube(dynamicClient, node.Name)).Should(Not(BeNil()))
 
 			clusterTopology, err := getTopologyFromKube(kubeclient)()
 			Expect(err).ToNot(HaveOccurred())
 			Expect(clusterTopology).ToNot(BeNil())
 
-			nodeTopology, err := getTopologyNodeFromKube(kubeclient, node.Name)()
+			nodeTopology, err := getTopologyNodeFromKube(dynamicClient, node.Name)()
 			Expect(err).ToNot(HaveOccurred())
 			Expect(nodeTopology).ToNot(BeNil())
 
@@ -466,7 +707,398 @@ var _ = Describe("StatusUpdater", func() {
 
 			_, err := kubeclient.CoreV1().Nodes().Create(context.TODO(), node, metav1.CreateOptions{})
 			Expect(err).ToNot(HaveOccurred())
-			Consistently(getTopologyNodeFromKubeErrorOrNil(kubeclient, node.Name)).Should(MatchError(errors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, topology.GetNodeTopologyCMName(node.Name))))
+			Consistently(getTopologyNodeFromKubeErrorOrNil(dynamicClient, node.Name)).Should(MatchError(errors.NewNotFound(topology.FakeGpuNodeGVR.GroupResource(), node.Name)))
+		})
+	})
+
+	When("informed of a node pool change", func() {
+		It("should regenerate the topology for the new pool and keep allocations that fit", func() {
+			nodeTopology, err := topology.GetNodeTopology(dynamicClient, node)
+			Expect(err).ToNot(HaveOccurred())
+			nodeTopology.Gpus[0].Status.AllocatedBy = topology.ContainerDetails{Namespace: podNamespace, Pod: podName, Container: containerName}
+			nodeTopology.Gpus[1].Status.AllocatedBy = topology.ContainerDetails{Namespace: podNamespace, Pod: "other-pod", Container: containerName}
+			updateNodeTopology(dynamicClient, node, nodeTopology)
+
+			gpuNode, err := kubeclient.CoreV1().Nodes().Get(context.TODO(), node, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			gpuNode.Labels["run.ai/simulated-gpu-node-pool"] = "h100"
+			_, err = kubeclient.CoreV1().Nodes().Update(context.TODO(), gpuNode, metav1.UpdateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(func() (string, error) {
+				nodeTopology, err := topology.GetNodeTopology(dynamicClient, node)
+				if err != nil {
+					return "", err
+				}
+				return nodeTopology.NodePool, nil
+			}).Should(Equal("h100"))
+
+			nodeTopology, err = topology.GetNodeTopology(dynamicClient, node)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nodeTopology.GpuProduct).To(Equal("NVIDIA-H100-80GB-HBM3"))
+			Expect(nodeTopology.GpuMemory).To(Equal(81559))
+			Expect(nodeTopology.Gpus).To(HaveLen(1))
+			Expect(nodeTopology.Gpus[0].ID).To(Equal(createTopology(nodeGpuCount, node).Gpus[0].ID))
+			Expect(nodeTopology.Gpus[0].Status.AllocatedBy.Pod).To(Equal(podName))
+		})
+
+		It("should regenerate a topology created before its pool was recorded", func() {
+			nodeTopology, err := topology.GetNodeTopology(dynamicClient, node)
+			Expect(err).ToNot(HaveOccurred())
+			nodeTopology.NodePool = ""
+			nodeTopology.Gpus[0].Status.AllocatedBy = topology.ContainerDetails{Namespace: podNamespace, Pod: podName, Container: containerName}
+			updateNodeTopology(dynamicClient, node, nodeTopology)
+
+			gpuNode, err := kubeclient.CoreV1().Nodes().Get(context.TODO(), node, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			gpuNode.Labels["run.ai/simulated-gpu-node-pool"] = "h100"
+			_, err = kubeclient.CoreV1().Nodes().Update(context.TODO(), gpuNode, metav1.UpdateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(func() (string, error) {
+				nodeTopology, err := topology.GetNodeTopology(dynamicClient, node)
+				if err != nil {
+					return "", err
+				}
+				return nodeTopology.NodePool, nil
+			}).Should(Equal("h100"))
+
+			nodeTopology, err = topology.GetNodeTopology(dynamicClient, node)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nodeTopology.GpuProduct).To(Equal("NVIDIA-H100-80GB-HBM3"))
+			Expect(nodeTopology.Gpus).To(HaveLen(1))
+			Expect(nodeTopology.Gpus[0].Status.AllocatedBy.Pod).To(Equal(podName))
+		})
+
+		It("should remove the topology when the node leaves all pools", func() {
+			gpuNode, err := kubeclient.CoreV1().Nodes().Get(context.TODO(), node, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			delete(gpuNode.Labels, "run.ai/simulated-gpu-node-pool")
+			_, err = kubeclient.CoreV1().Nodes().Update(context.TODO(), gpuNode, metav1.UpdateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(getTopologyNodeFromKubeErrorOrNil(dynamicClient, node)).Should(MatchError(errors.NewNotFound(topology.FakeGpuNodeGVR.GroupResource(), node)))
+		})
+	})
+
+	When("the cluster topology ConfigMap changes", func() {
+		updateClusterTopology := func(mutate func(*topology.ClusterTopology)) {
+			cm, err := kubeclient.CoreV1().ConfigMaps(topologyCmNamespace).Get(context.TODO(), topologyCmName, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			clusterTopology, err := topology.FromClusterTopologyCM(cm)
+			Expect(err).ToNot(HaveOccurred())
+			mutate(clusterTopology)
+			topologyStr, err := yaml.Marshal(clusterTopology)
+			Expect(err).ToNot(HaveOccurred())
+			cm.Data["topology.yml"] = string(topologyStr)
+			_, err = kubeclient.CoreV1().ConfigMaps(topologyCmNamespace).Update(context.TODO(), cm, metav1.UpdateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		It("should resize the nodes of a resized pool and keep their allocations", func() {
+			nodeTopology, err := topology.GetNodeTopology(dynamicClient, node)
+			Expect(err).ToNot(HaveOccurred())
+			nodeTopology.Gpus[1].Status.AllocatedBy = topology.ContainerDetails{Namespace: podNamespace, Pod: podName, Container: containerName}
+			updateNodeTopology(dynamicClient, node, nodeTopology)
+
+			updateClusterTopology(func(clusterTopology *topology.ClusterTopology) {
+				pool := clusterTopology.NodePools["default"]
+				pool.GpuCount = 4
+				clusterTopology.NodePools["default"] = pool
+			})
+
+			Eventually(func() (int, error) {
+				nodeTopology, err := topology.GetNodeTopology(dynamicClient, node)
+				if err != nil {
+					return 0, err
+				}
+				return len(nodeTopology.Gpus), nil
+			}).Should(Equal(4))
+
+			nodeTopology, err = topology.GetNodeTopology(dynamicClient, node)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nodeTopology.Gpus[1].Status.AllocatedBy.Pod).To(Equal(podName))
+			Expect(nodeTopology.Gpus[3].ID).To(Equal(createTopology(4, node).Gpus[3].ID))
+		})
+
+		It("should give each GPU the product and memory of its profile device", func() {
+			cm, err := kubeclient.CoreV1().ConfigMaps(topologyCmNamespace).Get(context.TODO(), topologyCmName, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			cm.Data["topology.yml"] = `
+nodePoolLabelKey: run.ai/simulated-gpu-node-pool
+migStrategy: mixed
+nodePools:
+  default:
+    gpu:
+      backend: fake
+      overrides:
+        device_defaults:
+          name: NVIDIA A10
+          architecture: ampere
+          memory:
+            total_bytes: 24146608128
+        devices:
+          - name: NVIDIA L4
+            architecture: ada
+            uuid: GPU-00000000-0000-0000-0000-0000000000l4
+            memory:
+              total_bytes: 24152899584
+            pci:
+              bus_id: "0000:01:00.0"
+          - serial: 1324821083801
+          - {}
+`
+			_, err = kubeclient.CoreV1().ConfigMaps(topologyCmNamespace).Update(context.TODO(), cm, metav1.UpdateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(func() (int, error) {
+				nodeTopology, err := topology.GetNodeTopology(dynamicClient, node)
+				if err != nil {
+					return 0, err
+				}
+				return len(nodeTopology.Gpus), nil
+			}).Should(Equal(3))
+
+			nodeTopology, err := topology.GetNodeTopology(dynamicClient, node)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nodeTopology.GpuProduct).To(Equal("NVIDIA A10"))
+			Expect(nodeTopology.GpuMemory).To(Equal(23028))
+			Expect(nodeTopology.IsHeterogeneous()).To(BeTrue())
+
+			Expect(nodeTopology.Gpus[0].ID).To(Equal(fmt.Sprintf("GPU-%s",
+				uuid.NewSHA1(uuid.Nil, []byte("GPU-00000000-0000-0000-0000-0000000000l4-"+node)))))
+			Expect(nodeTopology.GpuProductOf(0)).To(Equal("NVIDIA L4"))
+			Expect(nodeTopology.GpuMemoryOf(0)).To(Equal(23034))
+			Expect(nodeTopology.Gpus[0].Architecture).To(Equal("ada"))
+			Expect(nodeTopology.Gpus[0].PciBusID).To(Equal("0000:01:00.0"))
+
+			for idx := 1; idx < 3; idx++ {
+				Expect(nodeTopology.Gpus[idx].ID).To(Equal(createTopology(3, node).Gpus[idx].ID))
+				Expect(nodeTopology.GpuProductOf(idx)).To(Equal("NVIDIA A10"))
+				Expect(nodeTopology.GpuMemoryOf(idx)).To(Equal(23028))
+				Expect(nodeTopology.Gpus[idx].Architecture).To(Equal("ampere"))
+			}
+			Expect(nodeTopology.Gpus[1].Serial).To(Equal("1324821083801"))
+		})
+
+		It("should give the nodes of a profile pool distinct GPU UUIDs", func() {
+			profileCm := &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "gpu-profile-h100", Namespace: topologyCmNamespace},
+				Data: map[string]string{"profile.yaml": `
+device_defaults:
+  name: NVIDIA H100 80GB HBM3
+  memory:
+    total_bytes: 85899345920
+devices:
+  - uuid: GPU-11111111-1111-1111-1111-111111111111
+  - uuid: GPU-22222222-2222-2222-2222-222222222222
+`},
+			}
+			_, err := kubeclient.CoreV1().ConfigMaps(topologyCmNamespace).Create(context.TODO(), profileCm, metav1.CreateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			secondNode := &v1.Node{ObjectMeta: metav1.ObjectMeta{
+				Name:   "second-node",
+				Labels: map[string]string{"run.ai/simulated-gpu-node-pool": "default"},
+			}}
+			_, err = kubeclient.CoreV1().Nodes().Create(context.TODO(), secondNode, metav1.CreateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			cm, err := kubeclient.CoreV1().ConfigMaps(topologyCmNamespace).Get(context.TODO(), topologyCmName, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			cm.Data["topology.yml"] = `
+nodePoolLabelKey: run.ai/simulated-gpu-node-pool
+migStrategy: mixed
+nodePools:
+  default:
+    gpu:
+      backend: fake
+      profile: h100
+`
+			_, err = kubeclient.CoreV1().ConfigMaps(topologyCmNamespace).Update(context.TODO(), cm, metav1.UpdateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			gpuIDs := func(nodeName string) func() ([]string, error) {
+				return func() ([]string, error) {
+					nodeTopology, err := topology.GetNodeTopology(dynamicClient, nodeName)
+					if err != nil || nodeTopology.GpuProduct != "NVIDIA H100 80GB HBM3" {
+						return nil, err
+					}
+					var ids []string
+					for _, gpu := range nodeTopology.Gpus {
+						ids = append(ids, gpu.ID)
+					}
+					return ids, nil
+				}
+			}
+			Eventually(gpuIDs(node)).Should(HaveLen(2))
+			Eventually(gpuIDs(secondNode.Name)).Should(HaveLen(2))
+
+			firstIDs, _ := gpuIDs(node)()
+			secondIDs, _ := gpuIDs(secondNode.Name)()
+			Expect(firstIDs).To(Equal([]string{createTopology(2, node).Gpus[0].ID, createTopology(2, node).Gpus[1].ID}))
+			for _, id := range append(firstIDs, "GPU-11111111-1111-1111-1111-111111111111", "GPU-22222222-2222-2222-2222-222222222222") {
+				Expect(secondIDs).ToNot(ContainElement(id))
+			}
+
+			// UUIDs pinned by the pool's overrides are shared by its nodes too.
+			cm, err = kubeclient.CoreV1().ConfigMaps(topologyCmNamespace).Get(context.TODO(), topologyCmName, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			cm.Data["topology.yml"] += `
+      overrides:
+        device_count: 2
+        devices:
+          - uuid: GPU-33333333-3333-3333-3333-333333333333
+`
+			_, err = kubeclient.CoreV1().ConfigMaps(topologyCmNamespace).Update(context.TODO(), cm, metav1.UpdateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			overriddenID := func(nodeName string) string {
+				return fmt.Sprintf("GPU-%s", uuid.NewSHA1(uuid.Nil, []byte("GPU-33333333-3333-3333-3333-333333333333-"+nodeName)))
+			}
+			Eventually(gpuIDs(node)).Should(Equal([]string{overriddenID(node), firstIDs[1]}))
+			Eventually(gpuIDs(secondNode.Name)).Should(Equal([]string{overriddenID(secondNode.Name), secondIDs[1]}))
+			Expect(overriddenID(node)).ToNot(Equal(overriddenID(secondNode.Name)))
+		})
+
+		It("should fill the NVLink cliques of a reloaded pool without overfilling one", func() {
+			nodeNames := []string{node}
+			for idx := range 3 {
+				extraNode := &v1.Node{ObjectMeta: metav1.ObjectMeta{
+					Name:   fmt.Sprintf("clique-node-%d", idx),
+					Labels: map[string]string{"run.ai/simulated-gpu-node-pool": "default"},
+				}}
+				_, err := kubeclient.CoreV1().Nodes().Create(context.TODO(), extraNode, metav1.CreateOptions{})
+				Expect(err).ToNot(HaveOccurred())
+				nodeNames = append(nodeNames, extraNode.Name)
+			}
+			for _, nodeName := range nodeNames {
+				Eventually(func() error {
+					_, err := topology.GetNodeTopology(dynamicClient, nodeName)
+					return err
+				}).Should(Succeed())
+			}
+
+			cm, err := kubeclient.CoreV1().ConfigMaps(topologyCmNamespace).Get(context.TODO(), topologyCmName, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			cm.Data["topology.yml"] = `
+nodePoolLabelKey: run.ai/simulated-gpu-node-pool
+migStrategy: mixed
+nodePools:
+  default:
+    gpu:
+      backend: fake
+      overrides:
+        device_defaults:
+          name: Tesla-K80
+          memory:
+            total_bytes: 11996954624
+        device_count: 2
+    nvlink:
+      cliqueSize: 2
+`
+			_, err = kubeclient.CoreV1().ConfigMaps(topologyCmNamespace).Update(context.TODO(), cm, metav1.UpdateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(func() (map[string]int, error) {
+				nodesPerClique := map[string]int{}
+				for _, nodeName := range nodeNames {
+					nodeTopology, err := topology.GetNodeTopology(dynamicClient, nodeName)
+					if err != nil {
+						return nil, err
+					}
+					nodesPerClique[nodeTopology.CliqueID]++
+				}
+				return nodesPerClique, nil
+			}).Should(SatisfyAll(HaveLen(2), Not(HaveKey("")), HaveEach(2)))
+		})
+
+		It("should publish each problem of an invalid topology as a Warning Event", func() {
+			cm, err := kubeclient.CoreV1().ConfigMaps(topologyCmNamespace).Get(context.TODO(), topologyCmName, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			cm.Data["topology.yml"] = `
+nodePoolLabelKey: run.ai/simulated-gpu-node-pool
+migStrategy: mixed
+nodePools:
+  default:
+    gpuProduct: Tesla-K80
+    gpuCount: 2
+    gpuMemory: 11441
+  h100:
+    gpuProduct: NVIDIA-H100-80GB-HBM3
+    gpuCont: 1
+    gpuMemory: 81559
+`
+			_, err = kubeclient.CoreV1().ConfigMaps(topologyCmNamespace).Update(context.TODO(), cm, metav1.UpdateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(func() ([]string, error) {
+				events, err := kubeclient.CoreV1().Events(topologyCmNamespace).List(context.TODO(), metav1.ListOptions{})
+				if err != nil {
+					return nil, err
+				}
+				var messages []string
+				for _, event := range events.Items {
+					if event.InvolvedObject.Name == topologyCmName && event.Type == v1.EventTypeWarning && event.Reason == "InvalidTopology" {
+						messages = append(messages, event.Message)
+					}
+				}
+				return messages, nil
+			}).Should(ConsistOf(
+				"nodePools.h100.gpuCont: unknown field",
+				"nodePools.h100.gpuCount: must be positive, got 0",
+			))
+		})
+
+		It("should publish the problems of an invalid topology once at startup", func() {
+			appRunner.Stop()
+			wg.Wait()
+
+			cm, err := kubeclient.CoreV1().ConfigMaps(topologyCmNamespace).Get(context.TODO(), topologyCmName, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			cm.ResourceVersion = "2"
+			cm.Data["topology.yml"] = `
+nodePoolLabelKey: run.ai/simulated-gpu-node-pool
+nodePools:
+  default:
+    gpuProduct: Tesla-K80
+    gpuCount: two
+`
+			_, err = kubeclient.CoreV1().ConfigMaps(topologyCmNamespace).Update(context.TODO(), cm, metav1.UpdateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			appRunner = app.NewAppRunner(&status_updater.StatusUpdaterApp{})
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				appRunner.Run()
+			}()
+
+			// Repeated Events are aggregated into a count.
+			getWarningCounts := func() (map[string]int32, error) {
+				events, err := kubeclient.CoreV1().Events(topologyCmNamespace).List(context.TODO(), metav1.ListOptions{})
+				if err != nil {
+					return nil, err
+				}
+				counts := map[string]int32{}
+				for _, event := range events.Items {
+					if event.InvolvedObject.Name == topologyCmName && event.Reason == "InvalidTopology" {
+						counts[event.Message] += event.Count
+					}
+				}
+				return counts, nil
+			}
+			Eventually(getWarningCounts).Should(HaveLen(1))
+			Consistently(getWarningCounts, "500ms").Should(And(HaveLen(1), HaveEach(BeEquivalentTo(1))))
+		})
+
+		It("should remove the topology of nodes whose pool was removed", func() {
+			updateClusterTopology(func(clusterTopology *topology.ClusterTopology) {
+				clusterTopology.NodePools["h100"] = clusterTopology.NodePools["default"]
+				delete(clusterTopology.NodePools, "default")
+			})
+
+			Eventually(getTopologyNodeFromKubeErrorOrNil(dynamicClient, node)).Should(MatchError(errors.NewNotFound(topology.FakeGpuNodeGVR.GroupResource(), node)))
 		})
 	})
 
@@ -484,12 +1116,12 @@ var _ = Describe("StatusUpdater", func() {
 			_, err := kubeclient.CoreV1().Nodes().Create(context.TODO(), node, metav1.CreateOptions{})
 			Expect(err).ToNot(HaveOccurred())
 
-			Eventually(getTopologyNodeFromKubeErrorOrNil(kubeclient, node.Name)).Should(BeNil())
+			Eventually(getTopologyNodeFromKubeErrorOrNil(dynamicClient, node.Name)).Should(BeNil())
 
 			err = kubeclient.CoreV1().Nodes().Delete(context.TODO(), node.Name, metav1.DeleteOptions{})
 			Expect(err).ToNot(HaveOccurred())
 
-			Eventually(getTopologyNodeFromKubeErrorOrNil(kubeclient, node.Name)).Should(Not(BeNil()))
+			Eventually(getTopologyNodeFromKubeErrorOrNil(dynamicClient, node.Name)).Should(Not(BeNil()))
 		})
 	})
 })
@@ -501,9 +1133,9 @@ func getTopologyFromKube(kubeclient kubernetes.Interface) func() (*topology.Clus
 	}
 }
 
-func getTopologyNodeFromKube(kubeclient kubernetes.Interface, nodeName string) func() (*topology.NodeTopology, error) {
+func getTopologyNodeFromKube(dynamicClient dynamic.Interface, nodeName string) func() (*topology.NodeTopology, error) {
 	return func() (*topology.NodeTopology, error) {
-		topology, err := topology.GetNodeTopologyFromCM(kubeclient, nodeName)
+		topology, err := topology.GetNodeTopology(dynamicClient, nodeName)
 		if err != nil {
 			return nil, err
 		}
@@ -512,9 +1144,33 @@ func getTopologyNodeFromKube(kubeclient kubernetes.Interface, nodeName string) f
 	}
 }
 
-func getTopologyNodeFromKubeErrorOrNil(kubeclient kubernetes.Interface, nodeName string) func() error {
+// getGpuContainersFromKube returns the container each GPU of the node is
+// allocated to.
+func getGpuContainersFromKube(dynamicClient dynamic.Interface, nodeName string) func() ([]string, error) {
+	return func() ([]string, error) {
+		nodeTopology, err := topology.GetNodeTopology(dynamicClient, nodeName)
+		if err != nil {
+			return nil, err
+		}
+		var containers []string
+		for _, gpu := range nodeTopology.Gpus {
+			containers = append(containers, gpu.Status.AllocatedBy.Container)
+		}
+		return containers, nil
+	}
+}
+
+func updateNodeTopology(dynamicClient dynamic.Interface, nodeName string, nodeTopology *topology.NodeTopology) {
+	err := topology.MutateNodeTopology(dynamicClient, nodeName, func(current *topology.NodeTopology) error {
+		*current = *nodeTopology
+		return nil
+	})
+	Expect(err).ToNot(HaveOccurred())
+}
+
+func getTopologyNodeFromKubeErrorOrNil(dynamicClient dynamic.Interface, nodeName string) func() error {
 	return func() error {
-		_, err := topology.GetNodeTopologyFromCM(kubeclient, nodeName)
+		_, err := topology.GetNodeTopology(dynamicClient, nodeName)
 		if err != nil {
 			return err
 		}
@@ -567,6 +1223,7 @@ func createTopology(gpuCount int64, nodeName string) *topology.NodeTopology {
 		GpuMemory:   11441,
 		GpuProduct:  "Tesla-K80",
 		Gpus:        gpus,
+		NodePool:    "default",
 	}
 }
 
@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"reflect"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
@@ -18,35 +20,84 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
 
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 )
 
+// maxRetries bounds how often a failing node sync is retried.
+const maxRetries = 15
+
+// crdRetryInterval is how often the migration of node topology ConfigMaps is
+// retried while the FakeGpuNode CRD is not installed.
+const crdRetryInterval = 10 * time.Second
+
 type NodeController struct {
-	kubeClient kubernetes.Interface
-	informer   cache.SharedIndexInformer
-	handler    nodehandler.Interface
+	kubeClient       kubernetes.Interface
+	dynamicClient    dynamic.Interface
+	informer         cache.SharedIndexInformer
+	topologyInformer cache.SharedIndexInformer
+	handler          nodehandler.Interface
+	queue            workqueue.TypedRateLimitingInterface[string]
 
+	mu            sync.RWMutex
 	clusterConfig *topology.ClusterConfig
+	// validatedVersion is the resourceVersion of the topology ConfigMap
+	// last validated.
+	validatedVersion string
+	// regenerate holds the nodes whose topology the worker must rebuild, as
+	// the configuration of their pool changed.
+	regenerate sets.Set[string]
+
+	broadcaster record.EventBroadcaster
+	recorder    record.EventRecorder
 }
 
 var _ controllers.Interface = &NodeController{}
 
-func NewNodeController(kubeClient kubernetes.Interface, wg *sync.WaitGroup, disableNodeLabeling bool) *NodeController {
+func NewNodeController(kubeClient kubernetes.Interface, dynamicClient dynamic.Interface, wg *sync.WaitGroup, disableNodeLabeling bool) *NodeController {
+	// Without a cluster topology yet, nodes are handled once the topology
+	// ConfigMap shows up.
 	clusterConfig, err := topology.GetClusterConfigFromCM(kubeClient)
 	if err != nil {
-		log.Fatalf("Failed to get cluster topology: %v", err)
+		log.Printf("Failed to get cluster topology, waiting for it: %v", err)
 	}
 
+	topologyInformerFactory := informers.NewSharedInformerFactoryWithOptions(
+		kubeClient, 0,
+		informers.WithNamespace(viper.GetString(constants.EnvTopologyCmNamespace)),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", viper.GetString(constants.EnvTopologyCmName)).String()
+		}),
+	)
+
+	broadcaster := record.NewBroadcaster()
 	c := &NodeController{
-		kubeClient:    kubeClient,
-		informer:      informers.NewSharedInformerFactory(kubeClient, 0).Core().V1().Nodes().Informer(),
-		handler:       nodehandler.NewNodeHandler(kubeClient, clusterConfig, disableNodeLabeling),
+		kubeClient:       kubeClient,
+		dynamicClient:    dynamicClient,
+		informer:         informers.NewSharedInformerFactory(kubeClient, 0).Core().V1().Nodes().Informer(),
+		topologyInformer: topologyInformerFactory.Core().V1().ConfigMaps().Informer(),
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.DefaultTypedControllerRateLimiter[string](),
+			workqueue.TypedRateLimitingQueueConfig[string]{Name: "nodes"},
+		),
+		handler:       nodehandler.NewNodeHandler(kubeClient, dynamicClient, clusterConfig, disableNodeLabeling),
 		clusterConfig: clusterConfig,
+		regenerate:    sets.New[string](),
+
+		broadcaster: broadcaster,
+		recorder:    broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: "fake-gpu-operator-status-updater"}),
 	}
 
 	_, err = c.informer.AddEventHandler(cache.FilteringResourceEventHandler{
@@ -60,16 +111,19 @@ func NewNodeController(kubeClient kubernetes.Interface, wg *sync.WaitGroup, disa
 		},
 		Handler: cache.ResourceEventHandlerFuncs{
 			AddFunc: func(obj interface{}) {
-				go func() {
-					node := obj.(*v1.Node)
-					util.LogErrorIfExist(c.handler.HandleAdd(node), "Failed to handle node addition")
-				}()
+				c.enqueue(obj)
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				oldNode, newNode := oldObj.(*v1.Node), newObj.(*v1.Node)
+				nodePoolLabelKey := c.config().NodePoolLabelKey
+				if oldNode.Labels[nodePoolLabelKey] != newNode.Labels[nodePoolLabelKey] {
+					c.enqueue(newObj)
+				}
 			},
+			// The filter also routes nodes that lose the node pool label
+			// here, so their topology is cleaned up like a deleted node's.
 			DeleteFunc: func(obj interface{}) {
-				go func() {
-					node := obj.(*v1.Node)
-					util.LogErrorIfExist(c.handler.HandleDelete(node), "Failed to handle node deletion")
-				}()
+				c.enqueue(obj)
 			},
 		},
 	})
@@ -77,24 +131,193 @@ func NewNodeController(kubeClient kubernetes.Interface, wg *sync.WaitGroup, disa
 		log.Fatalf("Failed to add node event handler: %v", err)
 	}
 
+	_, err = c.topologyInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			c.handleClusterTopology(obj.(*v1.ConfigMap))
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			c.handleClusterTopology(newObj.(*v1.ConfigMap))
+		},
+	})
+	if err != nil {
+		log.Fatalf("Failed to add cluster topology event handler: %v", err)
+	}
+
 	return c
 }
 
 func (c *NodeController) Run(stopCh <-chan struct{}) {
-	err := c.pruneTopologyConfigMaps()
-	if err != nil {
-		log.Fatalf("Failed to prune topology nodes: %v", err)
+	c.broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: c.kubeClient.CoreV1().Events("")})
+	defer c.broadcaster.Shutdown()
+
+	if !c.migrateNodeTopologyCMs(stopCh) {
+		return
+	}
+
+	topologyCm, err := c.kubeClient.CoreV1().ConfigMaps(viper.GetString(constants.EnvTopologyCmNamespace)).Get(
+		context.TODO(), viper.GetString(constants.EnvTopologyCmName), metav1.GetOptions{})
+	if err == nil {
+		c.validateClusterTopology(topologyCm)
 	}
 
+	if c.config() != nil {
+		err := c.pruneNodeTopologies()
+		if err != nil {
+			log.Fatalf("Failed to prune topology nodes: %v", err)
+		}
+	}
+
+	defer c.queue.ShutDown()
+
 	log.Println("Starting node controller")
-	c.informer.Run(stopCh)
+	go c.topologyInformer.Run(stopCh)
+	go c.informer.Run(stopCh)
+	go wait.Until(c.runWorker, time.Second, stopCh)
+
+	<-stopCh
+}
+
+// migrateNodeTopologyCMs migrates the node topology ConfigMaps of earlier
+// releases, waiting for the FakeGpuNode CRD to be applied after an upgrade.
+// It reports false when stopped before the migration.
+func (c *NodeController) migrateNodeTopologyCMs(stopCh <-chan struct{}) bool {
+	for {
+		err := topology.MigrateNodeTopologyCMs(c.kubeClient, c.dynamicClient)
+		if err == nil {
+			return true
+		}
+		if err != topology.ErrFakeGpuNodeCRDMissing {
+			log.Fatalf("Failed to migrate node topology ConfigMaps: %v", err)
+		}
+
+		log.Printf("Waiting for the FakeGpuNode CRD, retrying in %s", crdRetryInterval)
+		select {
+		case <-stopCh:
+			return false
+		case <-time.After(crdRetryInterval):
+		}
+	}
+}
+
+func (c *NodeController) enqueue(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		log.Printf("Failed to get node key: %v", err)
+		return
+	}
+	c.queue.Add(key)
+}
+
+func (c *NodeController) runWorker() {
+	for c.processNextNode() {
+	}
+}
+
+// processNextNode syncs a node with its latest state: fake GPU nodes get
+// their topology created, or regenerated after a node pool change, while
+// deleted nodes and nodes that left their pool are cleaned up.
+func (c *NodeController) processNextNode() bool {
+	key, shutdown := c.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	err := c.syncNode(key)
+	if err == nil {
+		c.queue.Forget(key)
+		return true
+	}
+
+	if c.queue.NumRequeues(key) >= maxRetries {
+		log.Printf("Dropping node %s out of the queue: %v", key, err)
+		c.queue.Forget(key)
+		return true
+	}
+
+	log.Printf("Failed to sync node %s, retrying: %v", key, err)
+	c.queue.AddRateLimited(key)
+	return true
+}
+
+// syncNode runs on the worker only, so the topology writes of a node, and
+// the clique assignments of all nodes, never race each other. Nodes of a pool
+// missing from the cluster topology are cleaned up.
+func (c *NodeController) syncNode(key string) error {
+	obj, exists, err := c.informer.GetIndexer().GetByKey(key)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	regenerate := c.regenerate.Has(key)
+	c.regenerate.Delete(key)
+	c.mu.Unlock()
+
+	if !exists || !c.isConfiguredGpuNode(obj.(*v1.Node)) {
+		return c.handler.HandleDelete(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: key}})
+	}
+	if !regenerate {
+		return c.handler.HandleAdd(obj.(*v1.Node))
+	}
+
+	err = c.handler.HandleRegenerate(obj.(*v1.Node))
+	if err != nil {
+		c.markRegenerate(key)
+	}
+	return err
 }
 
-// This function prunes the topology ConfigMaps that are not associated with any fake gpu nodes, and initializes the GpuTopologyStatus field in the remaining ConfigMaps.
-func (c *NodeController) pruneTopologyConfigMaps() error {
-	log.Print("Pruning topology ConfigMaps...")
+// markRegenerate has the worker rebuild the topology of the node on its next
+// sync.
+func (c *NodeController) markRegenerate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.regenerate.Insert(key)
+}
 
-	gpuNodesLabelReq, err := labels.NewRequirement(c.clusterConfig.NodePoolLabelKey, selection.Exists, nil)
+// handleClusterTopology switches to an edited cluster topology ConfigMap and
+// queues the nodes it affects for the worker.
+func (c *NodeController) handleClusterTopology(cm *v1.ConfigMap) {
+	if cm.Name != viper.GetString(constants.EnvTopologyCmName) {
+		return
+	}
+
+	clusterConfig, err := topology.FromClusterConfigCM(cm)
+	if err != nil {
+		log.Printf("Ignoring invalid cluster topology ConfigMap %s: %v", cm.Name, err)
+		c.validateClusterTopology(cm)
+		return
+	}
+
+	c.mu.Lock()
+	if reflect.DeepEqual(c.clusterConfig, clusterConfig) {
+		c.mu.Unlock()
+		return
+	}
+	c.clusterConfig = clusterConfig
+	c.mu.Unlock()
+
+	log.Println("Cluster topology changed, reconciling nodes")
+	c.validateClusterTopology(cm)
+	nodes, err := c.kubeClient.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		log.Printf("Failed to list nodes: %v", err)
+		return
+	}
+	for nodeName, regenerate := range c.handler.UpdateClusterConfig(nodes.Items, clusterConfig) {
+		if regenerate {
+			c.markRegenerate(nodeName)
+		}
+		c.queue.Add(nodeName)
+	}
+}
+
+// This function prunes the node topologies that are not associated with any fake gpu nodes, and initializes the GpuTopologyStatus field in the remaining ones.
+func (c *NodeController) pruneNodeTopologies() error {
+	log.Print("Pruning node topologies...")
+
+	gpuNodesLabelReq, err := labels.NewRequirement(c.config().NodePoolLabelKey, selection.Exists, nil)
 	if err != nil {
 		return fmt.Errorf("failed creating label requirement: %v", err)
 	}
@@ -106,69 +329,79 @@ func (c *NodeController) pruneTopologyConfigMaps() error {
 		return fmt.Errorf("failed listing fake gpu nodes: %v", err)
 	}
 
-	nodeTopologyCms, err := c.kubeClient.CoreV1().ConfigMaps(viper.GetString(constants.EnvTopologyCmNamespace)).List(context.TODO(), metav1.ListOptions{
-		LabelSelector: fmt.Sprintf("%s=true", constants.LabelTopologyCMNodeTopology),
-	})
+	nodeTopologies, err := topology.ListNodeTopologies(c.dynamicClient)
 	if err != nil {
-		return fmt.Errorf("failed listing fake gpu nodes: %v", err)
+		return fmt.Errorf("failed listing node topologies: %v", err)
 	}
 
-	validNodeTopologyCMMap := make(map[string]bool)
+	validNodeTopologyMap := make(map[string]bool)
 	for _, node := range gpuNodes.Items {
-		validNodeTopologyCMMap[topology.GetNodeTopologyCMName(node.Name)] = true
+		validNodeTopologyMap[node.Name] = true
 	}
 
 	var multiErr error
-	for _, cm := range nodeTopologyCms.Items {
-		_, ok := validNodeTopologyCMMap[cm.Name]
-		multiErr = multierror.Append(multiErr, c.pruneTopologyConfigMap(&cm, ok))
+	for nodeName := range nodeTopologies {
+		multiErr = multierror.Append(multiErr, c.pruneNodeTopology(nodeName, validNodeTopologyMap[nodeName]))
 	}
 
 	return nil
 }
 
-func (c *NodeController) pruneTopologyConfigMap(cm *v1.ConfigMap, isValidNodeTopologyCM bool) error {
-	if !isValidNodeTopologyCM {
-		util.LogErrorIfExist(c.kubeClient.CoreV1().ConfigMaps(viper.GetString(constants.EnvTopologyCmNamespace)).Delete(context.TODO(), cm.Name, metav1.DeleteOptions{}), fmt.Sprintf("Failed to delete node topology cm %s", cm.Name))
+func (c *NodeController) pruneNodeTopology(nodeName string, isValidNodeTopology bool) error {
+	if !isValidNodeTopology {
+		util.LogErrorIfExist(topology.DeleteNodeTopology(c.dynamicClient, nodeName), fmt.Sprintf("Failed to delete node topology %s", nodeName))
+		return nil
 	}
 
-	nodeTopology, err := topology.FromNodeTopologyCM(cm)
-	if err != nil {
-		return fmt.Errorf("failed to parse node topology cm %s: %v", cm.Name, err)
-	}
+	err := topology.MutateNodeTopology(c.dynamicClient, nodeName, func(nodeTopology *topology.NodeTopology) error {
+		for i := range nodeTopology.Gpus {
+			nodeTopology.Gpus[i].Status.PodGpuUsageStatus = topology.PodGpuUsageStatusMap{}
 
-	for i := range nodeTopology.Gpus {
-		nodeTopology.Gpus[i].Status.PodGpuUsageStatus = topology.PodGpuUsageStatusMap{}
-
-		// Remove non-existing pods from the allocation info
-		allocatingPodExists, err := isPodExist(c.kubeClient, nodeTopology.Gpus[i].Status.AllocatedBy.Pod, nodeTopology.Gpus[i].Status.AllocatedBy.Namespace)
-		if err != nil {
-			return fmt.Errorf("failed to check if pod %s exists: %v", nodeTopology.Gpus[i].Status.AllocatedBy.Pod, err)
-		}
+			// Remove non-existing pods from the allocation info
+			allocatingPodExists, err := isPodExist(c.kubeClient, nodeTopology.Gpus[i].Status.AllocatedBy.Pod, nodeTopology.Gpus[i].Status.AllocatedBy.Namespace)
+			if err != nil {
+				return fmt.Errorf("failed to check if pod %s exists: %v", nodeTopology.Gpus[i].Status.AllocatedBy.Pod, err)
+			}
 
-		if !allocatingPodExists {
-			nodeTopology.Gpus[i].Status.AllocatedBy = topology.ContainerDetails{}
+			if !allocatingPodExists {
+				nodeTopology.Gpus[i].Status.AllocatedBy = topology.ContainerDetails{}
+			}
 		}
-	}
-
-	nodeName, ok := cm.Labels[constants.LabelTopologyCMNodeName]
-	if !ok {
-		return fmt.Errorf("node topology cm %s does not have node name label", cm.Name)
-	}
-
-	err = topology.UpdateNodeTopologyCM(c.kubeClient, nodeTopology, nodeName)
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to update node topology cm %s: %v", cm.Name, err)
+		return fmt.Errorf("failed to update node topology %s: %v", nodeName, err)
 	}
 
 	return nil
 }
 
 func (c *NodeController) isFakeGpuNode(node *v1.Node) bool {
-	_, isNodeAssignedToNodePool := node.Labels[c.clusterConfig.NodePoolLabelKey]
+	clusterConfig := c.config()
+	if clusterConfig == nil {
+		return false
+	}
+	_, isNodeAssignedToNodePool := node.Labels[clusterConfig.NodePoolLabelKey]
 	return isNodeAssignedToNodePool
 }
 
+// isConfiguredGpuNode reports whether the node belongs to a pool of the
+// cluster topology.
+func (c *NodeController) isConfiguredGpuNode(node *v1.Node) bool {
+	clusterConfig := c.config()
+	if clusterConfig == nil {
+		return false
+	}
+	_, isPoolConfigured := clusterConfig.NodePools[node.Labels[clusterConfig.NodePoolLabelKey]]
+	return isPoolConfigured
+}
+
+func (c *NodeController) config() *topology.ClusterConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.clusterConfig
+}
+
 func isPodExist(kubeClient kubernetes.Interface, podName string, namespace string) (bool, error) {
 	_, err := kubeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
 	if err != nil {
@@ -0,0 +1,39 @@
+package node
+
+import (
+	"log"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
+	"github.com/spf13/viper"
+	v1 "k8s.io/api/core/v1"
+)
+
+const reasonInvalidTopology = "InvalidTopology"
+
+// validateClusterTopology checks the cluster topology ConfigMap and the GPU
+// profiles its pools reference like fgo validate does, and publishes each
+// problem as a Warning Event on the ConfigMap, so that a typo shows up in
+// kubectl describe rather than as nodes without GPUs. A revision of the
+// ConfigMap is only validated once, as both Run and the informer's initial
+// Add see it at startup.
+func (c *NodeController) validateClusterTopology(cm *v1.ConfigMap) {
+	c.mu.Lock()
+	validated := cm.ResourceVersion != "" && cm.ResourceVersion == c.validatedVersion
+	c.validatedVersion = cm.ResourceVersion
+	c.mu.Unlock()
+	if validated {
+		return
+	}
+
+	data, ok := cm.Data[topology.CmTopologyKey]
+	if !ok {
+		return
+	}
+
+	report := topology.ValidateTopology(c.kubeClient, viper.GetString(constants.EnvTopologyCmNamespace), []byte(data))
+	for _, err := range report.Errors {
+		log.Printf("Invalid cluster topology ConfigMap %s: %v\n", cm.Name, err)
+		c.recorder.Event(cm, v1.EventTypeWarning, reasonInvalidTopology, err.Error())
+	}
+}
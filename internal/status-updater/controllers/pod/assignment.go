@@ -0,0 +1,240 @@
+package pod
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
+	"github.com/run-ai/fake-gpu-operator/internal/status-updater/util"
+)
+
+const (
+	reasonGpuAssigned         = "SimulatedGpuAssigned"
+	reasonGpuUtilization      = "SimulatedGpuUtilization"
+	reasonGpuAssignmentFailed = "SimulatedGpuAssignmentFailed"
+)
+
+// podFailure is an error of handling a pod event.
+type podFailure struct {
+	event podEvent
+	err   error
+}
+
+// reportAssignments describes the outcome of a node's pod events once its
+// topology is written: pods are annotated with the GPUs they were assigned,
+// new assignments are recorded as Events on the pod, and failures as Warning
+// Events. Reservation pods are annotated with the index of their GPU.
+// Handling is retried on conflicts, so only the outcome of the written
+// topology is reported. Reservation pods that could not be annotated are
+// returned as failures.
+func (p *PodController) reportAssignments(events []podEvent, failures []podFailure, nodeTopology *topology.NodeTopology) []podFailure {
+	failuresByPod := make(map[types.UID]error, len(failures))
+	for _, failure := range failures {
+		failuresByPod[failure.event.pod.UID] = failure.err
+	}
+
+	// Only the latest state of each pod in the batch is reported.
+	latest := make(map[types.UID]podEvent, len(events))
+	var order []types.UID
+	for _, event := range events {
+		if _, found := latest[event.pod.UID]; !found {
+			order = append(order, event.pod.UID)
+		}
+		latest[event.pod.UID] = event
+	}
+
+	var annotationFailures []podFailure
+	for _, uid := range order {
+		event := latest[uid]
+		if event.eventType == podDeleted {
+			p.forgetPod(uid)
+			continue
+		}
+		if util.IsPodTerminated(event.pod) {
+			continue
+		}
+		if util.IsGpuReservationPod(event.pod) {
+			if err := p.annotateReservationGpu(event.pod, nodeTopology); err != nil {
+				annotationFailures = append(annotationFailures, podFailure{event: event, err: err})
+			}
+			continue
+		}
+
+		p.reportFailure(event.pod, failuresByPod[uid])
+		p.reportAssignedGpus(event.pod, assignedGpus(event.pod, nodeTopology))
+	}
+	return annotationFailures
+}
+
+// annotateReservationGpu sets the index annotation the Run:ai sharing of
+// shared GPU pods matches reservation pods by, to the UUID of the GPU the
+// reservation pod holds.
+func (p *PodController) annotateReservationGpu(pod *v1.Pod, nodeTopology *topology.NodeTopology) error {
+	// DEPRECATED: Prior to 2.17, the scheduler had set the GPU index annotation for the reservation pod,
+	// therefore we skip setting the annotation if it already exists to support backward compatibility.
+	if _, ok := pod.Annotations[constants.AnnotationReservationPodGpuIdx]; ok {
+		return nil
+	}
+
+	gpus := assignedGpus(pod, nodeTopology)
+	if len(gpus) == 0 {
+		return fmt.Errorf("reservation pod %s does not have a GPU allocated", pod.Name)
+	}
+
+	err := p.patchAnnotation(pod, constants.AnnotationReservationPodGpuIdx, gpus[0].UUID)
+	if err != nil {
+		return fmt.Errorf("failed to annotate reservation pod %s with its GPU: %w", pod.Name, err)
+	}
+	return nil
+}
+
+func (p *PodController) reportFailure(pod *v1.Pod, err error) {
+	message := ""
+	if err != nil {
+		message = err.Error()
+	}
+
+	p.reportedMu.Lock()
+	changed := p.reportedFailures[pod.UID] != message
+	if message == "" {
+		delete(p.reportedFailures, pod.UID)
+	} else {
+		p.reportedFailures[pod.UID] = message
+	}
+	p.reportedMu.Unlock()
+
+	if changed && message != "" {
+		p.recorder.Eventf(pod, v1.EventTypeWarning, reasonGpuAssignmentFailed, "Failed to assign simulated GPUs: %s", message)
+	}
+}
+
+func (p *PodController) reportAssignedGpus(pod *v1.Pod, gpus []topology.AssignedGpu) {
+	value, err := topology.FormatAssignedGpus(gpus)
+	if err != nil {
+		log.Printf("Failed to describe the GPUs of pod %s: %v\n", pod.Name, err)
+		return
+	}
+
+	p.reportedMu.Lock()
+	previous, found := p.reportedGpus[pod.UID]
+	p.reportedMu.Unlock()
+	if !found {
+		previous, found = pod.Annotations[constants.AnnotationAssignedGpus]
+	}
+	if found && previous == value {
+		return
+	}
+	if !found && len(gpus) == 0 {
+		return
+	}
+
+	var previousGpus []topology.AssignedGpu
+	if found {
+		// A malformed annotation is overwritten as if it were absent.
+		previousGpus, _ = topology.ParseAssignedGpus(previous)
+	}
+	p.recordAssignmentEvents(pod, previousGpus, gpus)
+
+	if err := p.patchAnnotation(pod, constants.AnnotationAssignedGpus, value); err != nil {
+		log.Printf("Failed to annotate pod %s with its simulated GPUs: %v\n", pod.Name, err)
+		return
+	}
+
+	p.reportedMu.Lock()
+	p.reportedGpus[pod.UID] = value
+	p.reportedMu.Unlock()
+}
+
+func (p *PodController) recordAssignmentEvents(pod *v1.Pod, previousGpus, gpus []topology.AssignedGpu) {
+	previousByUUID := make(map[string]topology.AssignedGpu, len(previousGpus))
+	for _, gpu := range previousGpus {
+		previousByUUID[gpu.UUID] = gpu
+	}
+
+	for _, gpu := range gpus {
+		description := describeGpu(gpu)
+		previous, found := previousByUUID[gpu.UUID]
+		switch {
+		case !found && gpu.Utilization != "":
+			p.recorder.Eventf(pod, v1.EventTypeNormal, reasonGpuAssigned, "Assigned %s with utilization %s", description, gpu.Utilization)
+		case !found:
+			p.recorder.Eventf(pod, v1.EventTypeNormal, reasonGpuAssigned, "Assigned %s", description)
+		case previous.Utilization != gpu.Utilization && gpu.Utilization != "":
+			p.recorder.Eventf(pod, v1.EventTypeNormal, reasonGpuUtilization, "Simulating utilization %s on GPU %s (idx %d)", gpu.Utilization, gpu.UUID, gpu.Index)
+		}
+	}
+}
+
+func (p *PodController) patchAnnotation(pod *v1.Pod, key, value string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{key: value},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = p.kubeClient.CoreV1().Pods(pod.Namespace).Patch(context.TODO(), pod.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (p *PodController) forgetPod(uid types.UID) {
+	p.reportedMu.Lock()
+	defer p.reportedMu.Unlock()
+	delete(p.reportedGpus, uid)
+	delete(p.reportedFailures, uid)
+}
+
+// assignedGpus lists the GPUs of the node topology allocated to the pod or
+// shared with it.
+func assignedGpus(pod *v1.Pod, nodeTopology *topology.NodeTopology) []topology.AssignedGpu {
+	var gpus []topology.AssignedGpu
+	for idx, gpu := range nodeTopology.Gpus {
+		allocated := gpu.Status.AllocatedBy.Namespace == pod.Namespace && gpu.Status.AllocatedBy.Pod == pod.Name
+		usage, hasUsage := gpu.Status.PodGpuUsageStatus[pod.UID]
+		if !allocated && !hasUsage {
+			continue
+		}
+
+		assigned := topology.AssignedGpu{UUID: gpu.ID, Index: idx}
+		if allocated {
+			assigned.Container = gpu.Status.AllocatedBy.Container
+		} else {
+			assigned.Shared = true
+		}
+		if hasUsage && util.IsPodRunning(pod) {
+			assigned.Utilization = formatUtilization(usage)
+		}
+		gpus = append(gpus, assigned)
+	}
+	return gpus
+}
+
+func describeGpu(gpu topology.AssignedGpu) string {
+	if gpu.Shared {
+		return fmt.Sprintf("shared simulated GPU %s (idx %d)", gpu.UUID, gpu.Index)
+	}
+	return fmt.Sprintf("simulated GPU %s (idx %d)", gpu.UUID, gpu.Index)
+}
+
+func formatUtilization(usage topology.GpuUsageStatus) string {
+	if usage.UseKnativeUtilization {
+		return "knative"
+	}
+	if usage.Utilization.Min == usage.Utilization.Max {
+		return fmt.Sprintf("%d", usage.Utilization.Min)
+	}
+	return fmt.Sprintf("%d-%d", usage.Utilization.Min, usage.Utilization.Max)
+}
@@ -1,62 +1,123 @@
 package pod
 
 import (
+	"context"
 	"log"
 	"sync"
+	"time"
 
+	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
 	"github.com/run-ai/fake-gpu-operator/internal/status-updater/controllers"
 	controllers_util "github.com/run-ai/fake-gpu-operator/internal/status-updater/controllers/util"
 	podhandler "github.com/run-ai/fake-gpu-operator/internal/status-updater/handlers/pod"
 	"github.com/run-ai/fake-gpu-operator/internal/status-updater/util"
+	"github.com/run-ai/fake-gpu-operator/internal/status-updater/workload"
 
+	authorizationv1 "k8s.io/api/authorization/v1"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 )
 
+const (
+	// workers is the number of nodes whose pod events are applied in parallel.
+	workers = 4
+	// maxRetries bounds how often the events of a node are retried, e.g.
+	// while its FakeGpuNode is not created yet.
+	maxRetries = 15
+)
+
+type podEventType int
+
+const (
+	podAdded podEventType = iota
+	podUpdated
+	podDeleted
+)
+
+type podEvent struct {
+	eventType podEventType
+	pod       *v1.Pod
+}
+
+// PodController queues pod events per node and applies all pending events of
+// a node in a single conflict-checked write of its topology. A node is only
+// processed by one worker at a time, so bursts of pods on a node are
+// serialized instead of racing each other's topology writes.
 type PodController struct {
-	kubeClient kubernetes.Interface
-	wg         *sync.WaitGroup
+	kubeClient    kubernetes.Interface
+	dynamicClient dynamic.Interface
+	wg            *sync.WaitGroup
 
-	informer cache.SharedIndexInformer
-	handler  podhandler.Interface
+	informer   cache.SharedIndexInformer
+	classifier *workload.Classifier
+	handler    podhandler.Interface
+	queue      workqueue.TypedRateLimitingInterface[string]
+
+	mu      sync.Mutex
+	pending map[string][]podEvent
+
+	broadcaster record.EventBroadcaster
+	recorder    record.EventRecorder
+	// reportedGpus and reportedFailures hold what was last reported per pod,
+	// so that stale informer copies of a pod don't repeat its Events.
+	reportedMu       sync.Mutex
+	reportedGpus     map[types.UID]string
+	reportedFailures map[types.UID]string
 }
 
 var _ controllers.Interface = &PodController{}
 
 func NewPodController(kubeClient kubernetes.Interface, dynamicClient dynamic.Interface, wg *sync.WaitGroup) *PodController {
+	classifier := workload.NewClassifier(kubeClient, dynamicClient)
+	broadcaster := record.NewBroadcaster()
 	c := &PodController{
-		kubeClient: kubeClient,
-		wg:         wg,
-		informer:   informers.NewSharedInformerFactory(kubeClient, 0).Core().V1().Pods().Informer(),
-		handler:    podhandler.NewPodHandler(kubeClient, dynamicClient),
+		kubeClient:    kubeClient,
+		dynamicClient: dynamicClient,
+		wg:            wg,
+		informer:      informers.NewSharedInformerFactory(kubeClient, 0).Core().V1().Pods().Informer(),
+		classifier:    classifier,
+		handler:       podhandler.NewPodHandler(kubeClient, classifier),
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.DefaultTypedControllerRateLimiter[string](),
+			workqueue.TypedRateLimitingQueueConfig[string]{Name: "pods"},
+		),
+		pending: make(map[string][]podEvent),
+
+		broadcaster:      broadcaster,
+		recorder:         broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: "fake-gpu-operator-status-updater"}),
+		reportedGpus:     make(map[types.UID]string),
+		reportedFailures: make(map[types.UID]string),
 	}
 
 	_, err := c.informer.AddEventHandler(cache.FilteringResourceEventHandler{
+		// Terminated pods pass the filter so that their GPUs are released on
+		// completion, including pods that completed while the status-updater
+		// was down.
 		FilterFunc: func(obj interface{}) bool {
-			switch pod := obj.(type) {
-			case *v1.Pod:
-				return (pod != nil) &&
-					(util.IsPodScheduled(pod) && !util.IsPodTerminated(pod)) &&
-					(util.IsDedicatedGpuPod(pod) || util.IsSharedGpuPod(pod) || util.IsDraPod(pod))
-			default:
-				return false
-			}
+			pod := podFromObj(obj)
+			return (pod != nil) &&
+				util.IsPodScheduled(pod) &&
+				(util.IsDedicatedGpuPod(pod) || podhandler.IsSharedGpuPod(pod) || util.IsDraPod(pod))
 		},
 		Handler: cache.ResourceEventHandlerFuncs{
 			AddFunc: func(obj interface{}) {
-				pod := obj.(*v1.Pod)
-				controllers_util.LogErrorIfExist(c.handler.HandleAdd(pod), "Failed to handle pod addition")
+				c.enqueue(podAdded, obj.(*v1.Pod))
 			},
 			UpdateFunc: func(oldObj, newObj interface{}) {
-				newPod := newObj.(*v1.Pod)
-				controllers_util.LogErrorIfExist(c.handler.HandleUpdate(newPod), "Failed to handle pod addition")
+				c.enqueue(podUpdated, newObj.(*v1.Pod))
 			},
 			DeleteFunc: func(obj interface{}) {
-				pod := obj.(*v1.Pod)
-				controllers_util.LogErrorIfExist(c.handler.HandleDelete(pod), "Failed to handle pod deletion")
+				c.enqueue(podDeleted, podFromObj(obj))
 			},
 		},
 	})
@@ -68,6 +129,167 @@ func NewPodController(kubeClient kubernetes.Interface, dynamicClient dynamic.Int
 }
 
 func (p *PodController) Run(stopCh <-chan struct{}) {
+	defer p.queue.ShutDown()
+
 	log.Println("Starting pod controller")
-	p.informer.Run(stopCh)
+	p.broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: p.kubeClient.CoreV1().Events("")})
+	defer p.broadcaster.Shutdown()
+	p.checkPodPatchPermission()
+	go p.classifier.Run(stopCh)
+	go p.informer.Run(stopCh)
+
+	// Pods are classified with the configured rules from the start.
+	if !cache.WaitForCacheSync(stopCh, p.classifier.HasSynced) {
+		return
+	}
+
+	for i := 0; i < workers; i++ {
+		go wait.Until(p.runWorker, time.Second, stopCh)
+	}
+
+	<-stopCh
+}
+
+// checkPodPatchPermission logs when the status-updater may not patch pods,
+// as granted by the chart's optional pod-patch role, instead of leaving every
+// failed annotation to explain it.
+func (p *PodController) checkPodPatchPermission() {
+	review, err := p.kubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(context.TODO(), &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{Verb: "patch", Resource: "pods"},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		log.Printf("Failed to check the permission to patch pods: %v\n", err)
+		return
+	}
+	if !review.Status.Allowed {
+		log.Println("Not allowed to patch pods, set statusUpdater.podPatchRbac.enabled: pods won't be annotated with their simulated GPUs")
+	}
+}
+
+func (p *PodController) enqueue(eventType podEventType, pod *v1.Pod) {
+	nodeName := pod.Spec.NodeName
+
+	p.mu.Lock()
+	p.pending[nodeName] = append(p.pending[nodeName], podEvent{eventType: eventType, pod: pod})
+	p.mu.Unlock()
+
+	p.queue.Add(nodeName)
+}
+
+func (p *PodController) runWorker() {
+	for p.processNextNode() {
+	}
+}
+
+func (p *PodController) processNextNode() bool {
+	nodeName, shutdown := p.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer p.queue.Done(nodeName)
+
+	p.mu.Lock()
+	events := p.pending[nodeName]
+	delete(p.pending, nodeName)
+	p.mu.Unlock()
+
+	if len(events) == 0 {
+		p.queue.Forget(nodeName)
+		return true
+	}
+
+	// The handlers only change the topology; anything else they lead to is
+	// done by reportAssignments once, as the closure is re-run on conflicts.
+	var writtenTopology *topology.NodeTopology
+	var failures []podFailure
+	err := topology.MutateNodeTopology(p.dynamicClient, nodeName, func(nodeTopology *topology.NodeTopology) error {
+		failures = nil
+		for _, event := range events {
+			if err := p.handle(event, nodeTopology); err != nil {
+				failures = append(failures, podFailure{event: event, err: err})
+			}
+		}
+		writtenTopology = nodeTopology
+		return nil
+	})
+	if err == nil {
+		failures = append(failures, p.reportAssignments(events, failures, writtenTopology)...)
+		p.retryFailures(nodeName, failures)
+		return true
+	}
+
+	if p.queue.NumRequeues(nodeName) >= maxRetries {
+		log.Printf("Dropping %d pod events of node %s: %v", len(events), nodeName, err)
+		p.queue.Forget(nodeName)
+		return true
+	}
+
+	log.Printf("Failed to update the topology of node %s, retrying: %v", nodeName, err)
+	// Events that arrived meanwhile happened after the failed ones.
+	p.mu.Lock()
+	p.pending[nodeName] = append(events, p.pending[nodeName]...)
+	p.mu.Unlock()
+	p.queue.AddRateLimited(nodeName)
+	return true
+}
+
+// retryFailures keeps the pod events that failed pending and requeues their
+// node, e.g. a shared GPU pod handled before its reservation pod. Events
+// failing with a permanent error, and events that failed maxRetries times,
+// are dropped.
+func (p *PodController) retryFailures(nodeName string, failures []podFailure) {
+	var retries []podEvent
+	for _, failure := range failures {
+		if !podhandler.IsPermanent(failure.err) {
+			retries = append(retries, failure.event)
+		}
+	}
+	if len(retries) == 0 {
+		p.queue.Forget(nodeName)
+		return
+	}
+
+	if p.queue.NumRequeues(nodeName) >= maxRetries {
+		log.Printf("Dropping %d failed pod events of node %s", len(retries), nodeName)
+		p.queue.Forget(nodeName)
+		return
+	}
+
+	// Events that arrived meanwhile happened after the failed ones.
+	p.mu.Lock()
+	p.pending[nodeName] = append(retries, p.pending[nodeName]...)
+	p.mu.Unlock()
+	p.queue.AddRateLimited(nodeName)
+}
+
+func (p *PodController) handle(event podEvent, nodeTopology *topology.NodeTopology) error {
+	var err error
+	switch event.eventType {
+	case podAdded:
+		err = p.handler.HandleAdd(event.pod, nodeTopology)
+		controllers_util.LogErrorIfExist(err, "Failed to handle pod addition")
+	case podUpdated:
+		err = p.handler.HandleUpdate(event.pod, nodeTopology)
+		controllers_util.LogErrorIfExist(err, "Failed to handle pod update")
+	case podDeleted:
+		err = p.handler.HandleDelete(event.pod, nodeTopology)
+		controllers_util.LogErrorIfExist(err, "Failed to handle pod deletion")
+	}
+	return err
+}
+
+// podFromObj returns the pod of an informer event, unwrapping the tombstone of
+// a deletion the informer missed.
+func podFromObj(obj interface{}) *v1.Pod {
+	switch o := obj.(type) {
+	case *v1.Pod:
+		return o
+	case cache.DeletedFinalStateUnknown:
+		pod, _ := o.Obj.(*v1.Pod)
+		return pod
+	default:
+		return nil
+	}
 }
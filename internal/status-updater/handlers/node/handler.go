@@ -3,73 +3,150 @@ package node
 import (
 	"fmt"
 	"log"
+	"reflect"
+	"sync"
 
 	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 )
 
 type Interface interface {
 	HandleAdd(node *v1.Node) error
 	HandleDelete(node *v1.Node) error
+	HandleRegenerate(node *v1.Node) error
+	UpdateClusterConfig(nodes []v1.Node, clusterConfig *topology.ClusterConfig) map[string]bool
 }
 
 type NodeHandler struct {
-	kubeClient kubernetes.Interface
+	kubeClient    kubernetes.Interface
+	dynamicClient dynamic.Interface
 
+	mu              sync.RWMutex
 	clusterConfig   *topology.ClusterConfig
 	disableLabeling bool
 }
 
 var _ Interface = &NodeHandler{}
 
-func NewNodeHandler(kubeClient kubernetes.Interface, clusterConfig *topology.ClusterConfig, disableLabeling bool) *NodeHandler {
+func NewNodeHandler(kubeClient kubernetes.Interface, dynamicClient dynamic.Interface, clusterConfig *topology.ClusterConfig, disableLabeling bool) *NodeHandler {
 	return &NodeHandler{
 		kubeClient:      kubeClient,
+		dynamicClient:   dynamicClient,
 		clusterConfig:   clusterConfig,
 		disableLabeling: disableLabeling,
 	}
 }
 
+// HandleAdd creates the topology of the node, or regenerates it when the node
+// moved to another node pool, and labels the node.
 func (p *NodeHandler) HandleAdd(node *v1.Node) error {
-	log.Printf("Handling node addition: %s\n", node.Name)
+	log.Printf("Handling node: %s\n", node.Name)
 
-	err := p.createNodeTopologyCM(node)
-	if err != nil {
-		return fmt.Errorf("failed to create node topology ConfigMap: %w", err)
+	return p.resyncNode(node, false)
+}
+
+func (p *NodeHandler) HandleDelete(node *v1.Node) error {
+	log.Printf("Handling node deletion: %s\n", node.Name)
+
+	err := topology.DeleteNodeTopology(p.dynamicClient, node.Name)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete node topology: %w", err)
 	}
 
 	if p.disableLabeling {
-		log.Printf("Skipping node labeling for %s (disabled via config)\n", node.Name)
+		log.Printf("Skipping node unlabeling for %s (disabled via config)\n", node.Name)
 		return nil
 	}
 
-	err = p.labelNode(node)
+	err = p.unlabelNode(node)
 	if err != nil {
-		return fmt.Errorf("failed to label node: %w", err)
+		return fmt.Errorf("failed to unlabel node: %w", err)
 	}
 
 	return nil
 }
 
-func (p *NodeHandler) HandleDelete(node *v1.Node) error {
-	log.Printf("Handling node deletion: %s\n", node.Name)
+// HandleRegenerate rebuilds the topology of the node for its pool, e.g.
+// after the pool's configuration changed, and labels the node.
+func (p *NodeHandler) HandleRegenerate(node *v1.Node) error {
+	log.Printf("Regenerating topology of node: %s\n", node.Name)
 
-	err := topology.DeleteNodeTopologyCM(p.kubeClient, node.Name)
-	if err != nil && !errors.IsNotFound(err) {
-		return fmt.Errorf("failed to delete node topology: %w", err)
+	return p.resyncNode(node, true)
+}
+
+// UpdateClusterConfig switches to a new cluster topology and returns the
+// nodes it affects, mapped to whether their topology must be regenerated:
+// nodes whose pool or pool configuration changed. Nodes that no longer belong
+// to a configured pool are returned to be cleaned up, and with no previous
+// topology every node of a configured pool is returned to be created.
+func (p *NodeHandler) UpdateClusterConfig(nodes []v1.Node, clusterConfig *topology.ClusterConfig) map[string]bool {
+	p.mu.Lock()
+	previous := p.clusterConfig
+	p.clusterConfig = clusterConfig
+	p.mu.Unlock()
+
+	affected := make(map[string]bool)
+	for i := range nodes {
+		node := &nodes[i]
+		oldPool := configuredPool(previous, node)
+		newPool := configuredPool(clusterConfig, node)
+
+		switch {
+		case newPool == "" && oldPool == "":
+			continue
+		case newPool == "":
+			affected[node.Name] = false
+		case previous == nil:
+			affected[node.Name] = false
+		case oldPool != newPool || previous.MigStrategy != clusterConfig.MigStrategy ||
+			!reflect.DeepEqual(previous.NodePools[oldPool], clusterConfig.NodePools[newPool]):
+			log.Printf("Nodepool %s of node %s changed, regenerating its topology\n", newPool, node.Name)
+			affected[node.Name] = true
+		}
+	}
+
+	return affected
+}
+
+// resyncNode makes the node's topology match its pool and labels the node.
+// With regenerate set the topology is rebuilt even if it is already for that
+// pool.
+func (p *NodeHandler) resyncNode(node *v1.Node, regenerate bool) error {
+	err := p.syncNodeTopology(node, regenerate)
+	if err != nil {
+		return fmt.Errorf("failed to sync node topology: %w", err)
 	}
 
 	if p.disableLabeling {
-		log.Printf("Skipping node unlabeling for %s (disabled via config)\n", node.Name)
+		log.Printf("Skipping node labeling for %s (disabled via config)\n", node.Name)
 		return nil
 	}
 
-	err = p.unlabelNode(node)
+	err = p.labelNode(node)
 	if err != nil {
-		return fmt.Errorf("failed to unlabel node: %w", err)
+		return fmt.Errorf("failed to label node: %w", err)
 	}
 
 	return nil
 }
+
+func (p *NodeHandler) config() *topology.ClusterConfig {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.clusterConfig
+}
+
+// configuredPool returns the node's pool if clusterConfig defines it.
+func configuredPool(clusterConfig *topology.ClusterConfig, node *v1.Node) string {
+	if clusterConfig == nil {
+		return ""
+	}
+	pool := node.Labels[clusterConfig.NodePoolLabelKey]
+	if _, ok := clusterConfig.NodePools[pool]; !ok {
+		return ""
+	}
+	return pool
+}
@@ -0,0 +1,173 @@
+package node
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
+	"github.com/spf13/viper"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// syncNodeTopology creates the node topology for the node's pool, or
+// regenerates it when the node was moved to another pool or regenerate is set.
+func (p *NodeHandler) syncNodeTopology(node *v1.Node, regenerate bool) error {
+	clusterConfig := p.config()
+	nodePoolName, ok := node.Labels[clusterConfig.NodePoolLabelKey]
+	if !ok {
+		return fmt.Errorf("node %s does not have a nodepool label", node.Name)
+	}
+
+	current, _ := topology.GetNodeTopology(p.dynamicClient, node.Name)
+	// Topologies created before the pool was recorded are regenerated, as the
+	// node may have been moved to another pool since.
+	if current != nil && !regenerate && current.NodePool == nodePoolName {
+		return nil
+	}
+
+	nodeTopology, err := p.generateNodeTopology(clusterConfig, node, nodePoolName, current)
+	if err != nil {
+		return err
+	}
+
+	if current == nil {
+		err = topology.CreateNodeTopology(p.dynamicClient, nodeTopology, node)
+		// A concurrent cluster topology reload may have created it first.
+		if err != nil && !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create node topology: %w", err)
+		}
+		return nil
+	}
+
+	if current.NodePool != "" && current.NodePool != nodePoolName {
+		log.Printf("Node %s moved from nodepool %s to %s, regenerating its topology\n", node.Name, current.NodePool, nodePoolName)
+	}
+	// The allocations are taken from the latest topology, as pods keep being
+	// allocated while the new topology is generated.
+	err = topology.MutateNodeTopology(p.dynamicClient, node.Name, func(latest *topology.NodeTopology) error {
+		next := *nodeTopology
+		next.Gpus = append([]topology.GpuDetails(nil), nodeTopology.Gpus...)
+		keepAllocations(latest, &next)
+		*latest = next
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update node topology: %w", err)
+	}
+
+	return nil
+}
+
+// generateNodeTopology builds the topology of a node of the given pool. The
+// NVLink clique of the current topology is kept while it is in the pool's
+// fabric.
+func (p *NodeHandler) generateNodeTopology(clusterConfig *topology.ClusterConfig, node *v1.Node, nodePoolName string, current *topology.NodeTopology) (*topology.NodeTopology, error) {
+	poolConfig, ok := clusterConfig.NodePools[nodePoolName]
+	if !ok {
+		return nil, fmt.Errorf("nodepool %s not found in cluster topology", nodePoolName)
+	}
+
+	namespace := viper.GetString(constants.EnvTopologyCmNamespace)
+	resolved, err := topology.ResolveNodePool(p.kubeClient, namespace, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve nodepool %s: %w", nodePoolName, err)
+	}
+
+	nodeTopology := &topology.NodeTopology{
+		GpuMemory:     resolved.GpuMemory,
+		GpuProduct:    resolved.GpuProduct,
+		DriverVersion: resolved.DriverVersion,
+		CudaVersion:   resolved.CudaVersion,
+		Gpus:          generateGpuDetails(resolved, node.Name),
+		MigStrategy:   clusterConfig.MigStrategy,
+		OtherDevices:  resolved.OtherDevices,
+		NodePool:      nodePoolName,
+	}
+
+	if poolConfig.NvLink != nil {
+		fabric := poolConfig.NvLink.FabricClusterUUID(nodePoolName) + "."
+		if current != nil && current.NodePool == nodePoolName && strings.HasPrefix(current.CliqueID, fabric) {
+			nodeTopology.CliqueID = current.CliqueID
+			return nodeTopology, nil
+		}
+		nodeTopology.CliqueID, err = p.assignClique(*poolConfig.NvLink, nodePoolName, node.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to assign NVLink clique for node %s: %w", node.Name, err)
+		}
+	}
+
+	return nodeTopology, nil
+}
+
+// keepAllocations carries the allocations of the previous topology over to
+// the GPUs of the new one. GPUs are matched by index, so an allocation still
+// fits when the new pool has a GPU at the same index.
+func keepAllocations(previous, next *topology.NodeTopology) {
+	for idx, gpu := range previous.Gpus {
+		if gpu.Status.AllocatedBy.Pod == "" {
+			continue
+		}
+		if idx >= len(next.Gpus) {
+			log.Printf("Dropping allocation of GPU %s by pod %s/%s: nodepool %s has only %d GPUs\n",
+				gpu.ID, gpu.Status.AllocatedBy.Namespace, gpu.Status.AllocatedBy.Pod, next.NodePool, len(next.Gpus))
+			continue
+		}
+		next.Gpus[idx].Status = gpu.Status
+	}
+}
+
+// assignClique places the node in the first clique of the pool's NVLink
+// fabric that is not yet full.
+func (p *NodeHandler) assignClique(nvLink topology.NvLinkConfig, nodePoolName, nodeName string) (string, error) {
+	nodeTopologies, err := topology.ListNodeTopologies(p.dynamicClient)
+	if err != nil {
+		return "", fmt.Errorf("failed to list node topologies: %w", err)
+	}
+
+	var assigned []string
+	for name, nodeTopology := range nodeTopologies {
+		// The node's own clique is reassigned when it changes pools.
+		if name != nodeName && nodeTopology.CliqueID != "" {
+			assigned = append(assigned, nodeTopology.CliqueID)
+		}
+	}
+
+	return topology.AssignClique(nvLink, nodePoolName, assigned)
+}
+
+// generateGpuDetails lists the GPUs of a node of the resolved pool. A GPU's
+// UUID derives from the node name and index, or, when the pool's overrides
+// set one for its device, from that UUID and the node name, so the nodes of
+// a pool never share a UUID. Product and memory are only recorded on GPUs
+// that differ from the pool's device defaults.
+func generateGpuDetails(resolved *topology.ResolvedPool, nodeName string) []topology.GpuDetails {
+	gpus := make([]topology.GpuDetails, resolved.GpuCount)
+	for idx := range gpus {
+		gpus[idx] = topology.GpuDetails{
+			ID: fmt.Sprintf("GPU-%s", uuid.NewSHA1(uuid.Nil, []byte(fmt.Sprintf("%s-%d", nodeName, idx)))),
+		}
+		if idx >= len(resolved.Devices) {
+			continue
+		}
+
+		device := resolved.Devices[idx]
+		if device.UUID != "" {
+			gpus[idx].ID = fmt.Sprintf("GPU-%s", uuid.NewSHA1(uuid.Nil, []byte(fmt.Sprintf("%s-%s", device.UUID, nodeName))))
+		}
+		if device.Product != resolved.GpuProduct {
+			gpus[idx].Product = device.Product
+		}
+		if device.Memory != resolved.GpuMemory {
+			gpus[idx].Memory = device.Memory
+		}
+		gpus[idx].Architecture = device.Architecture
+		gpus[idx].Serial = device.Serial
+		gpus[idx].PciBusID = device.PciBusID
+	}
+
+	return gpus
+}
@@ -4,14 +4,15 @@ import (
 	"fmt"
 	"log"
 
-	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+	"github.com/hashicorp/go-multierror"
+
 	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
 	"github.com/run-ai/fake-gpu-operator/internal/status-updater/util"
 	v1 "k8s.io/api/core/v1"
 )
 
 func (p *PodHandler) handleDedicatedGpuPodAddition(pod *v1.Pod, nodeTopology *topology.NodeTopology) error {
-	if !util.IsDedicatedGpuPod(pod) {
+	if !isDedicatedGpuPod(pod) {
 		return nil
 	}
 
@@ -22,57 +23,56 @@ func (p *PodHandler) handleDedicatedGpuPodAddition(pod *v1.Pod, nodeTopology *to
 		return nil
 	}
 
-	requestedGpus := pod.Spec.Containers[0].Resources.Limits.Name(constants.GpuResourceName, "")
-	if requestedGpus == nil {
-		return fmt.Errorf("no GPUs requested in pod %s", pod.Name)
-	}
+	var missingGpusErr error
+	for _, request := range util.ContainerGpuRequests(pod) {
+		requestedGpusCount := request.Count
+		log.Printf("Requested GPUs by container %s: %d\n", request.Container, requestedGpusCount)
+		for idx := range nodeTopology.Gpus {
+			gpu := &nodeTopology.Gpus[idx]
 
-	requestedGpusCount := requestedGpus.Value()
-	log.Printf("Requested GPUs: %d\n", requestedGpusCount)
-	for idx := range nodeTopology.Gpus {
-		gpu := &nodeTopology.Gpus[idx]
+			if requestedGpusCount <= 0 {
+				break
+			}
 
-		if requestedGpusCount <= 0 {
-			break
-		}
+			if gpu.Status.AllocatedBy.Pod == "" {
+				log.Printf("GPU %s is free, allocating...\n", gpu.ID)
+				gpu.Status.AllocatedBy.Namespace = pod.Namespace
+				gpu.Status.AllocatedBy.Pod = pod.Name
+				gpu.Status.AllocatedBy.Container = request.Container
 
-		if gpu.Status.AllocatedBy.Pod == "" {
-			log.Printf("GPU %s is free, allocating...\n", gpu.ID)
-			gpu.Status.AllocatedBy.Namespace = pod.Namespace
-			gpu.Status.AllocatedBy.Pod = pod.Name
-			gpu.Status.AllocatedBy.Container = pod.Spec.Containers[0].Name
+				if !util.IsGpuReservationPod(pod) {
+					gpu.Status.PodGpuUsageStatus[pod.UID] = calculateUsage(p.classifier, pod, nodeTopology.GpuMemoryOf(idx))
+				}
 
-			if !util.IsGpuReservationPod(pod) {
-				gpu.Status.PodGpuUsageStatus[pod.UID] = calculateUsage(p.dynamicClient, pod, nodeTopology.GpuMemory)
+				requestedGpusCount--
 			}
-
-			requestedGpusCount--
+		}
+		if requestedGpusCount > 0 {
+			missingGpusErr = multierror.Append(missingGpusErr,
+				fmt.Errorf("no free GPU for container %s: %d of %d GPUs missing", request.Container, requestedGpusCount, request.Count))
 		}
 	}
 
-	err := p.handleGpuReservationPodAddition(pod, nodeTopology)
-	if err != nil {
-		return fmt.Errorf("failed to handle GPU reservation pod addition: %w", err)
+	// The GPUs the pod got are kept, so handling it again would skip it as
+	// already allocated.
+	if missingGpusErr != nil {
+		return permanent(missingGpusErr)
 	}
-
 	return nil
 }
 
 func (p *PodHandler) handleDedicatedGpuPodUpdate(pod *v1.Pod, nodeTopology *topology.NodeTopology) error {
-	if !util.IsDedicatedGpuPod(pod) {
+	if !isDedicatedGpuPod(pod) {
 		return nil
 	}
 
 	for idx := range nodeTopology.Gpus {
 		gpu := &nodeTopology.Gpus[idx]
 
-		isGpuOccupiedByPod := gpu.Status.AllocatedBy.Namespace == pod.Namespace &&
-			gpu.Status.AllocatedBy.Pod == pod.Name &&
-			gpu.Status.AllocatedBy.Container == pod.Spec.Containers[0].Name
-		if isGpuOccupiedByPod {
+		if isGpuOccupiedByPodContainer(gpu, pod) {
 			if !util.IsGpuReservationPod(pod) {
 				gpu.Status.PodGpuUsageStatus[pod.UID] =
-					calculateUsage(p.dynamicClient, pod, nodeTopology.GpuMemory)
+					calculateUsage(p.classifier, pod, nodeTopology.GpuMemoryOf(idx))
 			}
 		}
 	}
@@ -81,29 +81,37 @@ func (p *PodHandler) handleDedicatedGpuPodUpdate(pod *v1.Pod, nodeTopology *topo
 }
 
 func (p *PodHandler) handleDedicatedGpuPodDeletion(pod *v1.Pod, nodeTopology *topology.NodeTopology) {
-	if !util.IsDedicatedGpuPod(pod) {
+	if !isDedicatedGpuPod(pod) {
 		return
 	}
 
-	for idx, gpu := range nodeTopology.Gpus {
-		isGpuOccupiedByPod := gpu.Status.AllocatedBy.Namespace == pod.Namespace &&
-			gpu.Status.AllocatedBy.Pod == pod.Name &&
-			gpu.Status.AllocatedBy.Container == pod.Spec.Containers[0].Name
-		if isGpuOccupiedByPod {
+	for idx := range nodeTopology.Gpus {
+		if isGpuOccupiedByPodContainer(&nodeTopology.Gpus[idx], pod) {
 			nodeTopology.Gpus[idx].Status = topology.GpuStatus{}
 		}
 	}
 }
 
 func isAlreadyAllocated(pod *v1.Pod, nodeTopology *topology.NodeTopology) bool {
-	for _, gpu := range nodeTopology.Gpus {
-		isGpuOccupiedByPod := gpu.Status.AllocatedBy.Namespace == pod.Namespace &&
-			gpu.Status.AllocatedBy.Pod == pod.Name &&
-			gpu.Status.AllocatedBy.Container == pod.Spec.Containers[0].Name
-		if isGpuOccupiedByPod {
+	for idx := range nodeTopology.Gpus {
+		if isGpuOccupiedByPodContainer(&nodeTopology.Gpus[idx], pod) {
 			return true
 		}
 	}
 
 	return false
 }
+
+// isGpuOccupiedByPodContainer reports whether the GPU is allocated to one of
+// the pod's GPU-requesting containers.
+func isGpuOccupiedByPodContainer(gpu *topology.GpuDetails, pod *v1.Pod) bool {
+	if gpu.Status.AllocatedBy.Namespace != pod.Namespace || gpu.Status.AllocatedBy.Pod != pod.Name {
+		return false
+	}
+	for _, request := range util.ContainerGpuRequests(pod) {
+		if gpu.Status.AllocatedBy.Container == request.Container {
+			return true
+		}
+	}
+	return false
+}
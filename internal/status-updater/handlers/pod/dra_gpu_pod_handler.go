@@ -6,8 +6,10 @@ import (
 	"log"
 	"strings"
 
+	"github.com/run-ai/fake-gpu-operator/internal/common/dra"
 	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
 	"github.com/run-ai/fake-gpu-operator/internal/status-updater/util"
+	"github.com/run-ai/fake-gpu-operator/pkg/compute-domain/consts"
 	v1 "k8s.io/api/core/v1"
 	resourceapi "k8s.io/api/resource/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -30,7 +32,7 @@ func (p *PodHandler) handleDraGpuPodAddition(pod *v1.Pod, nodeTopology *topology
 	}
 
 	// Get allocated device names from ResourceClaims
-	deviceNames, err := p.getDeviceNamesFromClaims(pod)
+	deviceNames, computeDomain, err := p.getDeviceNamesFromClaims(pod)
 	if err != nil {
 		return fmt.Errorf("failed to get device names from claims for pod %s: %w", pod.Name, err)
 	}
@@ -57,11 +59,12 @@ func (p *PodHandler) handleDraGpuPodAddition(pod *v1.Pod, nodeTopology *topology
 			gpu.Status.AllocatedBy.Pod = pod.Name
 			// Use first container that has a claim reference
 			gpu.Status.AllocatedBy.Container = getContainerWithClaim(pod)
+			gpu.Status.AllocatedBy.ComputeDomain = computeDomain
 
 			if gpu.Status.PodGpuUsageStatus == nil {
 				gpu.Status.PodGpuUsageStatus = make(topology.PodGpuUsageStatusMap)
 			}
-			gpu.Status.PodGpuUsageStatus[pod.UID] = calculateUsage(p.dynamicClient, pod, nodeTopology.GpuMemory)
+			gpu.Status.PodGpuUsageStatus[pod.UID] = calculateUsage(p.classifier, pod, nodeTopology.GpuMemoryOf(gpuIdx))
 		} else {
 			log.Printf("DRA: GPU %s is already allocated by pod %s/%s\n", gpu.ID, gpu.Status.AllocatedBy.Namespace, gpu.Status.AllocatedBy.Pod)
 		}
@@ -83,7 +86,7 @@ func (p *PodHandler) handleDraGpuPodUpdate(pod *v1.Pod, nodeTopology *topology.N
 	if !isAllocated {
 		// GPU not yet allocated - try to allocate now (late allocation)
 		// This handles the case where Add event happened before ResourceClaim was allocated
-		deviceNames, err := p.getDeviceNamesFromClaims(pod)
+		deviceNames, computeDomain, err := p.getDeviceNamesFromClaims(pod)
 		if err != nil {
 			return fmt.Errorf("failed to get device names from claims for pod %s: %w", pod.Name, err)
 		}
@@ -103,11 +106,12 @@ func (p *PodHandler) handleDraGpuPodUpdate(pod *v1.Pod, nodeTopology *topology.N
 					gpu.Status.AllocatedBy.Namespace = pod.Namespace
 					gpu.Status.AllocatedBy.Pod = pod.Name
 					gpu.Status.AllocatedBy.Container = getContainerWithClaim(pod)
+					gpu.Status.AllocatedBy.ComputeDomain = computeDomain
 
 					if gpu.Status.PodGpuUsageStatus == nil {
 						gpu.Status.PodGpuUsageStatus = make(topology.PodGpuUsageStatusMap)
 					}
-					gpu.Status.PodGpuUsageStatus[pod.UID] = calculateUsage(p.dynamicClient, pod, nodeTopology.GpuMemory)
+					gpu.Status.PodGpuUsageStatus[pod.UID] = calculateUsage(p.classifier, pod, nodeTopology.GpuMemoryOf(gpuIdx))
 				}
 			}
 		}
@@ -122,7 +126,7 @@ func (p *PodHandler) handleDraGpuPodUpdate(pod *v1.Pod, nodeTopology *topology.N
 				if gpu.Status.PodGpuUsageStatus == nil {
 					gpu.Status.PodGpuUsageStatus = make(topology.PodGpuUsageStatusMap)
 				}
-				gpu.Status.PodGpuUsageStatus[pod.UID] = calculateUsage(p.dynamicClient, pod, nodeTopology.GpuMemory)
+				gpu.Status.PodGpuUsageStatus[pod.UID] = calculateUsage(p.classifier, pod, nodeTopology.GpuMemoryOf(idx))
 			}
 		}
 	}
@@ -146,26 +150,31 @@ func (p *PodHandler) handleDraGpuPodDeletion(pod *v1.Pod, nodeTopology *topology
 	}
 }
 
-// getDeviceNamesFromClaims retrieves allocated device names from the pod's ResourceClaims.
-func (p *PodHandler) getDeviceNamesFromClaims(pod *v1.Pod) ([]string, error) {
+// getDeviceNamesFromClaims retrieves allocated device names from the pod's ResourceClaims,
+// along with the name of the ComputeDomain whose channel the pod claims, if any.
+func (p *PodHandler) getDeviceNamesFromClaims(pod *v1.Pod) ([]string, string, error) {
 	claimNames := getResourceClaimNamesFromPod(pod)
 	if len(claimNames) == 0 {
-		return nil, nil
+		return nil, "", nil
 	}
 
 	var deviceNames []string
+	var computeDomain string
 	for _, claimName := range claimNames {
 		claim, err := p.kubeClient.ResourceV1().ResourceClaims(pod.Namespace).Get(
 			context.TODO(), claimName, metav1.GetOptions{})
 		if err != nil {
-			return nil, fmt.Errorf("failed to get ResourceClaim %s: %w", claimName, err)
+			return nil, "", fmt.Errorf("failed to get ResourceClaim %s: %w", claimName, err)
 		}
 
 		devices := getDevicesFromClaim(claim)
 		deviceNames = append(deviceNames, devices...)
+		if domain := claim.Labels[consts.ComputeDomainClaimLabel]; domain != "" && claim.Status.Allocation != nil {
+			computeDomain = domain
+		}
 	}
 
-	return deviceNames, nil
+	return deviceNames, computeDomain, nil
 }
 
 // getResourceClaimNamesFromPod extracts ResourceClaim names from a pod.
@@ -198,7 +207,7 @@ func getResourceClaimNamesFromPod(pod *v1.Pod) []string {
 	return claimNames
 }
 
-// getDevicesFromClaim extracts device names from a ResourceClaim's allocation.
+// getDevicesFromClaim extracts the names of the devices a ResourceClaim consumes.
 func getDevicesFromClaim(claim *resourceapi.ResourceClaim) []string {
 	if claim.Status.Allocation == nil {
 		return nil
@@ -206,8 +215,9 @@ func getDevicesFromClaim(claim *resourceapi.ResourceClaim) []string {
 
 	var devices []string
 	for _, result := range claim.Status.Allocation.Devices.Results {
-		// Only include devices from our GPU driver
-		if result.Driver == draDriverName {
+		// Only include devices from our GPU driver. Admin-access allocations
+		// (e.g. monitoring agents) observe a device without consuming it.
+		if result.Driver == draDriverName && !dra.IsAdminAccess(result) {
 			devices = append(devices, result.Device)
 		}
 	}
@@ -1,16 +1,20 @@
 package pod
 
 import (
+	"context"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
 	"github.com/run-ai/fake-gpu-operator/internal/status-updater/util"
+	"github.com/run-ai/fake-gpu-operator/pkg/compute-domain/consts"
 	corev1 "k8s.io/api/core/v1"
 	resourceapi "k8s.io/api/resource/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
 )
 
 const (
@@ -196,6 +200,28 @@ var _ = Describe("DRA GPU Pod Handler", func() {
 			Expect(devices[0]).To(Equal(testGpuID0))
 		})
 
+		It("should ignore devices allocated with admin access", func() {
+			claim := &resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "monitoring-claim",
+					Namespace: testNamespace,
+				},
+				Status: resourceapi.ResourceClaimStatus{
+					Allocation: &resourceapi.AllocationResult{
+						Devices: resourceapi.DeviceAllocationResult{
+							Results: []resourceapi.DeviceRequestAllocationResult{
+								{Driver: draDriverName, Device: testGpuID0, Pool: testNodeName, Request: "gpu", AdminAccess: ptr.To(true)},
+								{Driver: draDriverName, Device: testGpuID1, Pool: testNodeName, Request: "gpu", AdminAccess: ptr.To(false)},
+							},
+						},
+					},
+				},
+			}
+
+			devices := getDevicesFromClaim(claim)
+			Expect(devices).To(Equal([]string{testGpuID1}))
+		})
+
 		It("should return nil for unallocated claims", func() {
 			claim := &resourceapi.ResourceClaim{
 				ObjectMeta: metav1.ObjectMeta{
@@ -233,8 +259,7 @@ var _ = Describe("DRA GPU Pod Handler", func() {
 
 			fakeClient = fake.NewClientset(claim)
 			handler = &PodHandler{
-				kubeClient:    fakeClient,
-				dynamicClient: nil,
+				kubeClient: fakeClient,
 			}
 		})
 
@@ -278,6 +303,50 @@ var _ = Describe("DRA GPU Pod Handler", func() {
 			Expect(nodeTopology.Gpus[2].Status.AllocatedBy.Pod).To(BeEmpty())
 		})
 
+		It("should tag GPUs with the ComputeDomain whose channel the pod claims", func() {
+			channelClaim := &resourceapi.ResourceClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "channel-claim",
+					Namespace: testNamespace,
+					Labels:    map[string]string{consts.ComputeDomainClaimLabel: "training-domain"},
+				},
+				Status: resourceapi.ResourceClaimStatus{
+					Allocation: &resourceapi.AllocationResult{
+						Devices: resourceapi.DeviceAllocationResult{
+							Results: []resourceapi.DeviceRequestAllocationResult{
+								{Driver: consts.ComputeDomainDriverName, Device: "channel-0", Pool: testNodeName, Request: "channel"},
+							},
+						},
+					},
+				},
+			}
+			_, err := fakeClient.ResourceV1().ResourceClaims(testNamespace).Create(context.TODO(), channelClaim, metav1.CreateOptions{})
+			Expect(err).NotTo(HaveOccurred())
+
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      testPodName,
+					Namespace: testNamespace,
+					UID:       testPodUID,
+				},
+				Spec: corev1.PodSpec{
+					NodeName:   testNodeName,
+					Containers: []corev1.Container{{Name: testContainerName}},
+					ResourceClaims: []corev1.PodResourceClaim{
+						{Name: "gpu", ResourceClaimName: ptr.To(testClaimName)},
+						{Name: "channel", ResourceClaimName: ptr.To("channel-claim")},
+					},
+				},
+			}
+
+			err = handler.handleDraGpuPodAddition(pod, nodeTopology)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(nodeTopology.Gpus[0].Status.AllocatedBy.Pod).To(Equal(testPodName))
+			Expect(nodeTopology.Gpus[0].Status.AllocatedBy.ComputeDomain).To(Equal("training-domain"))
+			Expect(nodeTopology.Gpus[1].Status.AllocatedBy.ComputeDomain).To(BeEmpty())
+		})
+
 		It("should skip non-DRA pods", func() {
 			pod := &corev1.Pod{
 				ObjectMeta: metav1.ObjectMeta{
@@ -338,8 +407,7 @@ var _ = Describe("DRA GPU Pod Handler", func() {
 		BeforeEach(func() {
 			fakeClient = fake.NewClientset()
 			handler = &PodHandler{
-				kubeClient:    fakeClient,
-				dynamicClient: nil,
+				kubeClient: fakeClient,
 			}
 
 			// Pre-allocate GPUs
@@ -380,6 +448,32 @@ var _ = Describe("DRA GPU Pod Handler", func() {
 			Expect(nodeTopology.Gpus[0].Status.AllocatedBy.Container).To(BeEmpty())
 		})
 
+		It("should release GPU and usage when the DRA pod completes", func() {
+			claimName := testClaimName
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      testPodName,
+					Namespace: testNamespace,
+					UID:       testPodUID,
+				},
+				Spec: corev1.PodSpec{
+					NodeName: testNodeName,
+					Containers: []corev1.Container{
+						{Name: testContainerName},
+					},
+					ResourceClaims: []corev1.PodResourceClaim{
+						{Name: "gpu", ResourceClaimName: &claimName},
+					},
+				},
+				Status: corev1.PodStatus{Phase: corev1.PodSucceeded},
+			}
+
+			Expect(handler.HandleUpdate(pod, nodeTopology)).To(Succeed())
+
+			Expect(nodeTopology.Gpus[0].Status.AllocatedBy.Pod).To(BeEmpty())
+			Expect(nodeTopology.Gpus[0].Status.PodGpuUsageStatus).ToNot(HaveKey(pod.UID))
+		})
+
 		It("should skip non-DRA pods", func() {
 			pod := &corev1.Pod{
 				ObjectMeta: metav1.ObjectMeta{
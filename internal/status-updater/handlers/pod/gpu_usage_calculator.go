@@ -1,7 +1,6 @@
 package pod
 
 import (
-	"context"
 	"fmt"
 	"log"
 	"regexp"
@@ -11,26 +10,18 @@ import (
 	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
 	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
 	"github.com/run-ai/fake-gpu-operator/internal/status-updater/util"
+	"github.com/run-ai/fake-gpu-operator/internal/status-updater/workload"
 	v1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/client-go/dynamic"
 )
 
 const (
-	gpuUtilizationAnnotationKey = "run.ai/simulated-gpu-utilization"
+	gpuUtilizationAnnotationKey = constants.AnnotationGpuUtilization
 	gpuFractionAnnotationKey    = constants.AnnotationGpuFraction
 
 	idleGpuPodNamePrefix = "runai-idle-gpu-"
 )
 
-var defaultGpuUtil = topology.Range{
-	Min: 100,
-	Max: 100,
-}
-
-func calculateUsage(dynamicclient dynamic.Interface, pod *v1.Pod, totalGpuMemory int) topology.GpuUsageStatus {
+func calculateUsage(classifier *workload.Classifier, pod *v1.Pod, totalGpuMemory int) topology.GpuUsageStatus {
 	gpuFraction := 1.0
 	if podGpuFractionStr, ok := pod.Annotations[gpuFractionAnnotationKey]; ok {
 		if parsed, err := strconv.ParseFloat(podGpuFractionStr, 32); err == nil {
@@ -40,6 +31,12 @@ func calculateUsage(dynamicclient dynamic.Interface, pod *v1.Pod, totalGpuMemory
 		}
 	}
 
+	return calculateUsageWithFraction(classifier, pod, gpuFraction, totalGpuMemory)
+}
+
+// calculateUsageWithFraction returns the usage of a pod that received
+// gpuFraction of a GPU's memory.
+func calculateUsageWithFraction(classifier *workload.Classifier, pod *v1.Pod, gpuFraction float64, totalGpuMemory int) topology.GpuUsageStatus {
 	if !util.IsPodRunning(pod) {
 		return generateGpuUsageStatus(topology.Range{Min: 0, Max: 0}, gpuFraction, totalGpuMemory, false)
 	}
@@ -59,25 +56,8 @@ func calculateUsage(dynamicclient dynamic.Interface, pod *v1.Pod, totalGpuMemory
 		}
 	}
 
-	return calculateGpuUsageFromPodType(dynamicclient, pod, gpuFraction, totalGpuMemory)
-}
-
-func calculateGpuUsageFromPodType(dynamicclient dynamic.Interface, pod *v1.Pod, gpuFraction float64, totalGpuMemory int) topology.GpuUsageStatus {
-	podType, err := getPodType(dynamicclient, pod)
-	if err != nil {
-		log.Printf("Error getting pod type for pod %s: %s\n", pod.Name, err)
-	}
-
-	switch podType {
-	case "train":
-		return generateGpuUsageStatus(topology.Range{Min: 80, Max: 100}, gpuFraction, totalGpuMemory, false)
-	case "build", "interactive-preemptible", "interactive", "distributed":
-		return generateGpuUsageStatus(topology.Range{Min: 0, Max: 0}, gpuFraction, totalGpuMemory, false)
-	case "inference", "distributed-inference":
-		return generateGpuUsageStatus(topology.Range{Min: 0, Max: 0}, gpuFraction, totalGpuMemory, true)
-	default:
-		return generateGpuUsageStatus(defaultGpuUtil, gpuFraction, totalGpuMemory, false)
-	}
+	model := classifier.Classify(pod)
+	return generateGpuUsageStatus(model.Utilization, gpuFraction*float64(model.MemoryPercent)/100, totalGpuMemory, model.Inference)
 }
 
 func calculateUtilizationFromAnnotation(annotationValue string) (*topology.Range, error) {
@@ -103,43 +83,6 @@ func calculateUtilizationFromAnnotation(annotationValue string) (*topology.Range
 	return &topology.Range{Min: minUtilization, Max: maxUtilization}, nil
 }
 
-func getPodType(dynamicClient dynamic.Interface, pod *v1.Pod) (string, error) {
-	if workloadKind, ok := pod.Labels["workloadKind"]; ok {
-		switch workloadKind {
-		case "TrainingWorkload":
-			return "train", nil
-		case "DistributedWorkload":
-			return "distributed-inference", nil
-		case "InferenceWorkload":
-			return "inference", nil
-		case "InteractiveWorkload":
-			return "interactive", nil
-		}
-	}
-
-	// Fallback to existing PodGroup lookup
-	podGroupName := pod.Annotations[constants.AnnotationPodGroupName]
-	if podGroupName == "" {
-		return "", fmt.Errorf("pod %s has no constants.PodGroupNameAnnotation annotation", pod.Name)
-	}
-
-	gvr := schema.GroupVersionResource{Group: "scheduling.run.ai", Version: "v2alpha2", Resource: "podgroups"}
-	podGroup, err := dynamicClient.Resource(gvr).Namespace(pod.Namespace).Get(context.TODO(), podGroupName, metav1.GetOptions{})
-	if err != nil {
-		return "", fmt.Errorf("error getting podgroup %s: %v", podGroupName, err)
-	}
-
-	podGroupType, found, err := unstructured.NestedString(podGroup.Object, "spec", "priorityClassName")
-	if err != nil {
-		return "", fmt.Errorf("error getting podgroup class name: %v", err)
-	}
-	if !found {
-		return "", fmt.Errorf("podgroup %s has no class name", podGroupName)
-	}
-
-	return podGroupType, nil
-}
-
 func isPodNameMarkedAsIdle(podName string) bool {
 	return strings.HasPrefix(podName, idleGpuPodNamePrefix)
 }
@@ -0,0 +1,28 @@
+package pod
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
+)
+
+// hamiAdapter locates HAMi vGPU pods by the GPU UUIDs and memory that HAMi's
+// scheduler recorded in the pod's hami.io/vgpu-devices-allocated annotation.
+// The memory comes from the pod's nvidia.com/gpumem request.
+type hamiAdapter struct{}
+
+var _ SharingAdapter = &hamiAdapter{}
+
+func (a *hamiAdapter) Name() string {
+	return "HAMi"
+}
+
+func (a *hamiAdapter) Matches(pod *v1.Pod) bool {
+	return pod.Annotations[constants.AnnotationHamiDevicesAllocated] != ""
+}
+
+func (a *hamiAdapter) Locate(_ kubernetes.Interface, pod *v1.Pod, nodeTopology *topology.NodeTopology) ([]GpuShare, error) {
+	return decodeVgpuDevices(pod.Annotations[constants.AnnotationHamiDevicesAllocated], nodeTopology)
+}
@@ -1,103 +1,145 @@
 package pod
 
 import (
-	"fmt"
+	"errors"
 	"log"
 
 	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
+	"github.com/run-ai/fake-gpu-operator/internal/status-updater/util"
+	"github.com/run-ai/fake-gpu-operator/internal/status-updater/workload"
 	v1 "k8s.io/api/core/v1"
-	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 )
 
+// Interface applies pod events to the topology of the pod's node. The caller
+// reads and writes the node topology, so that the events of a node can be
+// applied together and written with optimistic concurrency.
 type Interface interface {
-	HandleAdd(pod *v1.Pod) error
-	HandleUpdate(pod *v1.Pod) error
-	HandleDelete(pod *v1.Pod) error
+	HandleAdd(pod *v1.Pod, nodeTopology *topology.NodeTopology) error
+	HandleUpdate(pod *v1.Pod, nodeTopology *topology.NodeTopology) error
+	HandleDelete(pod *v1.Pod, nodeTopology *topology.NodeTopology) error
+}
+
+// permanentError is an error that handling the same pod event again cannot
+// resolve, e.g. a malformed annotation.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+func permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err marks a pod event that is not worth
+// retrying.
+func IsPermanent(err error) bool {
+	var permanentErr *permanentError
+	return errors.As(err, &permanentErr)
 }
 
 type PodHandler struct {
-	kubeClient    kubernetes.Interface
-	dynamicClient dynamic.Interface
+	kubeClient kubernetes.Interface
+	classifier *workload.Classifier
 }
 
 var _ Interface = &PodHandler{}
 
-func NewPodHandler(kubeClient kubernetes.Interface, dynamicClient dynamic.Interface) *PodHandler {
+func NewPodHandler(kubeClient kubernetes.Interface, classifier *workload.Classifier) *PodHandler {
 	return &PodHandler{
-		kubeClient:    kubeClient,
-		dynamicClient: dynamicClient,
+		kubeClient: kubeClient,
+		classifier: classifier,
 	}
 }
 
-func (p *PodHandler) HandleAdd(pod *v1.Pod) error {
-	log.Printf("Handling pod addition: %s\n", pod.Name)
-
-	nodeTopology, err := topology.GetNodeTopologyFromCM(p.kubeClient, pod.Spec.NodeName)
-	if err != nil {
-		return fmt.Errorf("could not get node %s topology: %w", pod.Spec.NodeName, err)
+func (p *PodHandler) HandleAdd(pod *v1.Pod, nodeTopology *topology.NodeTopology) error {
+	if util.IsPodTerminated(pod) {
+		return p.releasePod(pod, nodeTopology)
 	}
 
-	err = p.handleDedicatedGpuPodAddition(pod, nodeTopology)
-	if err != nil {
-		return err
-	}
+	log.Printf("Handling pod addition: %s\n", pod.Name)
 
-	err = p.handleSharedGpuPodAddition(pod, nodeTopology)
+	err := p.handleDedicatedGpuPodAddition(pod, nodeTopology)
 	if err != nil {
 		return err
 	}
 
-	err = p.handleDraGpuPodAddition(pod, nodeTopology)
+	err = p.handleSharedGpuPodAddition(pod, nodeTopology)
 	if err != nil {
 		return err
 	}
 
-	return topology.UpdateNodeTopologyCM(p.kubeClient, nodeTopology, pod.Spec.NodeName)
+	return p.handleDraGpuPodAddition(pod, nodeTopology)
 }
 
-func (p *PodHandler) HandleUpdate(pod *v1.Pod) error {
-	log.Printf("Handling pod update: %s\n", pod.Name)
-
-	nodeTopology, err := topology.GetNodeTopologyFromCM(p.kubeClient, pod.Spec.NodeName)
-	if err != nil {
-		return fmt.Errorf("could not get node %s topology: %w", pod.Spec.NodeName, err)
+func (p *PodHandler) HandleUpdate(pod *v1.Pod, nodeTopology *topology.NodeTopology) error {
+	if util.IsPodTerminated(pod) {
+		return p.releasePod(pod, nodeTopology)
 	}
 
-	err = p.handleDedicatedGpuPodUpdate(pod, nodeTopology)
-	if err != nil {
-		return err
-	}
+	log.Printf("Handling pod update: %s\n", pod.Name)
 
-	err = p.handleSharedGpuPodUpdate(pod, nodeTopology)
+	err := p.handleDedicatedGpuPodUpdate(pod, nodeTopology)
 	if err != nil {
 		return err
 	}
 
-	err = p.handleDraGpuPodUpdate(pod, nodeTopology)
+	err = p.handleSharedGpuPodUpdate(pod, nodeTopology)
 	if err != nil {
 		return err
 	}
 
-	return topology.UpdateNodeTopologyCM(p.kubeClient, nodeTopology, pod.Spec.NodeName)
+	return p.handleDraGpuPodUpdate(pod, nodeTopology)
 }
 
-func (p *PodHandler) HandleDelete(pod *v1.Pod) error {
+func (p *PodHandler) HandleDelete(pod *v1.Pod, nodeTopology *topology.NodeTopology) error {
 	log.Printf("Handling pod deletion: %s\n", pod.Name)
 
-	nodeTopology, err := topology.GetNodeTopologyFromCM(p.kubeClient, pod.Spec.NodeName)
-	if err != nil {
-		return fmt.Errorf("could not get node %s topology: %w", pod.Spec.NodeName, err)
+	return p.releasePodGpus(pod, nodeTopology)
+}
+
+// releasePod frees the GPUs of a pod that reached a terminal phase. Completed
+// pods can linger long after they stop using their GPUs, so their GPUs are
+// not held until the pod is deleted.
+func (p *PodHandler) releasePod(pod *v1.Pod, nodeTopology *topology.NodeTopology) error {
+	if !isPodInTopology(pod, nodeTopology) {
+		return nil
 	}
 
+	log.Printf("Releasing GPUs of completed pod %s (%s)\n", pod.Name, pod.Status.Phase)
+	return p.releasePodGpus(pod, nodeTopology)
+}
+
+func (p *PodHandler) releasePodGpus(pod *v1.Pod, nodeTopology *topology.NodeTopology) error {
 	p.handleDedicatedGpuPodDeletion(pod, nodeTopology)
+	p.handleDraGpuPodDeletion(pod, nodeTopology)
 
-	err = p.handleSharedGpuPodDeletion(pod, nodeTopology)
-	if err != nil {
-		return err
+	// Drop the usage the pod reports on GPUs it doesn't hold, which includes
+	// every GPU a shared GPU pod shares, even when its reservation pod is
+	// already gone.
+	for idx := range nodeTopology.Gpus {
+		delete(nodeTopology.Gpus[idx].Status.PodGpuUsageStatus, pod.UID)
 	}
 
-	p.handleDraGpuPodDeletion(pod, nodeTopology)
+	return nil
+}
 
-	return topology.UpdateNodeTopologyCM(p.kubeClient, nodeTopology, pod.Spec.NodeName)
+// isPodInTopology reports whether the pod holds a GPU or reports usage on one.
+func isPodInTopology(pod *v1.Pod, nodeTopology *topology.NodeTopology) bool {
+	for _, gpu := range nodeTopology.Gpus {
+		if gpu.Status.AllocatedBy.Namespace == pod.Namespace && gpu.Status.AllocatedBy.Pod == pod.Name {
+			return true
+		}
+		if _, found := gpu.Status.PodGpuUsageStatus[pod.UID]; found {
+			return true
+		}
+	}
+	return false
 }
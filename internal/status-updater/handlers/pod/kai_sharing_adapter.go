@@ -0,0 +1,56 @@
+package pod
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
+)
+
+// kaiAdapter locates KAI scheduler fractional pods. KAI binds a pod's
+// gpu-fraction or gpu-memory request to reservation pods in its own namespace
+// that carry the pod's runai-gpu-group label, one per GPU the pod shares.
+type kaiAdapter struct{}
+
+var _ SharingAdapter = &kaiAdapter{}
+
+func (a *kaiAdapter) Name() string {
+	return "KAI scheduler"
+}
+
+func (a *kaiAdapter) Matches(pod *v1.Pod) bool {
+	_, gpuGroupExists := pod.Labels[constants.LabelGpuGroup]
+	return pod.Spec.SchedulerName == constants.KaiSchedulerName && gpuGroupExists
+}
+
+func (a *kaiAdapter) Locate(kubeClient kubernetes.Interface, pod *v1.Pod, nodeTopology *topology.NodeTopology) ([]GpuShare, error) {
+	gpuGroup := pod.Labels[constants.LabelGpuGroup]
+	reservationNs := viper.GetString(constants.EnvKaiReservationNamespace)
+
+	nodeReservationPods, err := getNodeReservationPods(kubeClient, reservationNs, pod.Spec.NodeName)
+	if err != nil {
+		return nil, err
+	}
+
+	var shares []GpuShare
+	for _, reservationPod := range nodeReservationPods.Items {
+		if reservationPod.Labels[constants.LabelGpuGroup] != gpuGroup {
+			continue
+		}
+
+		gpuIdx, err := findReservationPodGpuIdx(pod, nodeTopology, reservationNs, reservationPod.Name)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, GpuShare{GpuIdx: gpuIdx, MemoryFraction: annotatedMemoryFraction(pod, nodeTopology.GpuMemoryOf(gpuIdx))})
+	}
+
+	if len(shares) == 0 {
+		return nil, fmt.Errorf("no reservation pod found for gpu group %s on node %s", gpuGroup, pod.Spec.NodeName)
+	}
+	return shares, nil
+}
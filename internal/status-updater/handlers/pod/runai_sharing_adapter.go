@@ -0,0 +1,120 @@
+package pod
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/spf13/viper"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
+)
+
+// runaiAdapter locates Run:ai fractional pods on the GPU of their reservation
+// pod, which shares the pod's runai-gpu-group label or, before Run:ai 2.17,
+// the GPU index of its runai-gpu annotation.
+type runaiAdapter struct{}
+
+var _ SharingAdapter = &runaiAdapter{}
+
+func (a *runaiAdapter) Name() string {
+	return "Run:ai"
+}
+
+func (a *runaiAdapter) Matches(pod *v1.Pod) bool {
+	_, runaiGpuExists := pod.Annotations[constants.AnnotationGpuIdx]
+	_, runaiGpuGroupExists := pod.Labels[constants.LabelGpuGroup]
+
+	return runaiGpuExists || runaiGpuGroupExists
+}
+
+func (a *runaiAdapter) Locate(kubeClient kubernetes.Interface, pod *v1.Pod, nodeTopology *topology.NodeTopology) ([]GpuShare, error) {
+	reservationPodName, err := getMatchingReservationPodName(kubeClient, pod)
+	if err != nil {
+		return nil, err
+	}
+
+	resourceReservationNs := viper.GetString(constants.EnvResourceReservationNamespace)
+	gpuIdx, err := findReservationPodGpuIdx(pod, nodeTopology, resourceReservationNs, reservationPodName)
+	if err != nil {
+		return nil, err
+	}
+
+	return []GpuShare{{GpuIdx: gpuIdx, MemoryFraction: annotatedMemoryFraction(pod, nodeTopology.GpuMemoryOf(gpuIdx))}}, nil
+}
+
+func getMatchingReservationPodName(kubeclient kubernetes.Interface, pod *v1.Pod) (string, error) {
+	var multiErr error
+
+	// DEPRECATED_START
+	reservationPodName, err := getMatchingReservationPodNameByRunaiGpuAnnotation(kubeclient, pod)
+	if err == nil {
+		return reservationPodName, nil
+	} else {
+		multiErr = multierror.Append(multiErr, fmt.Errorf("failed to find reservation pod by runai-gpu annotation: %v", err))
+	}
+	// DEPRECATED_END
+
+	reservationPodName, err = getMatchingReservationPodNameByRunaiGpuGroupLabel(kubeclient, pod)
+	if err == nil {
+		return reservationPodName, nil
+	} else {
+		multiErr = multierror.Append(multiErr, fmt.Errorf("failed to find reservation pod by runai-gpu-group label: %v", err))
+	}
+
+	return "", multiErr
+}
+
+func getMatchingReservationPodNameByRunaiGpuAnnotation(kubeclient kubernetes.Interface, pod *v1.Pod) (string, error) {
+	runaiGpu := pod.Annotations[constants.AnnotationGpuIdx]
+	if runaiGpu == "" {
+		return "", fmt.Errorf("pod %s has empty runai-gpu annotation", pod.Name)
+	}
+
+	gpuIdx, err := strconv.Atoi(runaiGpu)
+	if err != nil {
+		return "", err
+	}
+
+	nodeReservationPods, err := getNodeReservationPods(kubeclient, viper.GetString(constants.EnvResourceReservationNamespace), pod.Spec.NodeName)
+	if err != nil {
+		return "", err
+	}
+
+	for _, nodeReservationPod := range nodeReservationPods.Items {
+		if nodeReservationPod.Annotations[constants.AnnotationReservationPodGpuIdx] == runaiGpu {
+			return nodeReservationPod.Name, nil
+		}
+	}
+
+	return "", fmt.Errorf("no reservation pod found for gpu %d on node %s", gpuIdx, pod.Spec.NodeName)
+}
+
+func getMatchingReservationPodNameByRunaiGpuGroupLabel(kubeclient kubernetes.Interface, pod *v1.Pod) (string, error) {
+	runaiGpuGroup := pod.Labels[constants.LabelGpuGroup]
+	if runaiGpuGroup == "" {
+		return "", fmt.Errorf("pod %s has empty runai-gpu-group label", pod.Name)
+	}
+
+	nodeReservationPods, err := getNodeReservationPods(kubeclient, viper.GetString(constants.EnvResourceReservationNamespace), pod.Spec.NodeName)
+	if err != nil {
+		return "", err
+	}
+
+	for _, nodeReservationPod := range nodeReservationPods.Items {
+		if nodeReservationPod.Labels[constants.LabelGpuGroup] == runaiGpuGroup {
+			return nodeReservationPod.Name, nil
+		}
+	}
+
+	return "", fmt.Errorf("no reservation pod found for gpu group %s on node %s", runaiGpuGroup, pod.Spec.NodeName)
+}
+
+func getNodeReservationPods(kubeclient kubernetes.Interface, reservationNs, nodeName string) (*v1.PodList, error) {
+	return kubeclient.CoreV1().Pods(reservationNs).List(context.TODO(), metav1.ListOptions{FieldSelector: "spec.nodeName=" + nodeName})
+}
@@ -1,157 +1,58 @@
 package pod
 
 import (
-	"context"
 	"fmt"
 	"log"
-	"strconv"
 
-	"github.com/hashicorp/go-multierror"
-	"github.com/spf13/viper"
 	v1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
 
-	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
 	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
-	"github.com/run-ai/fake-gpu-operator/internal/status-updater/util"
 )
 
 func (p *PodHandler) handleSharedGpuPodAddition(pod *v1.Pod, nodeTopology *topology.NodeTopology) error {
-	if !util.IsSharedGpuPod(pod) {
+	adapter := sharingAdapterFor(pod)
+	if adapter == nil {
 		return nil
 	}
 
-	return p.calculateAndSetPodGpuUsageStatus(pod, nodeTopology)
+	return p.calculateAndSetPodGpuUsageStatus(adapter, pod, nodeTopology)
 }
 
 func (p *PodHandler) handleSharedGpuPodUpdate(pod *v1.Pod, nodeTopology *topology.NodeTopology) error {
-	if !util.IsSharedGpuPod(pod) {
+	adapter := sharingAdapterFor(pod)
+	if adapter == nil {
 		return nil
 	}
 
 	// Recalculate the pod's GPU usage status.
-	return p.calculateAndSetPodGpuUsageStatus(pod, nodeTopology)
+	return p.calculateAndSetPodGpuUsageStatus(adapter, pod, nodeTopology)
 }
 
-func (p *PodHandler) handleSharedGpuPodDeletion(pod *v1.Pod, nodeTopology *topology.NodeTopology) error {
-	if !util.IsSharedGpuPod(pod) {
-		return nil
-	}
-
-	reservationPodGpuIdx, err := getMatchingReservationPodGpuIdx(p.kubeClient, pod, nodeTopology)
+func (p *PodHandler) calculateAndSetPodGpuUsageStatus(adapter SharingAdapter, pod *v1.Pod, nodeTopology *topology.NodeTopology) error {
+	shares, err := adapter.Locate(p.kubeClient, pod, nodeTopology)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to locate the %s shared GPUs of pod %s: %w", adapter.Name(), pod.Name, err)
 	}
-
-	delete(nodeTopology.Gpus[reservationPodGpuIdx].Status.PodGpuUsageStatus, pod.UID)
-	return nil
-}
-
-func (p *PodHandler) calculateAndSetPodGpuUsageStatus(pod *v1.Pod, nodeTopology *topology.NodeTopology) error {
-	reservationPodGpuIdx, err := getMatchingReservationPodGpuIdx(p.kubeClient, pod, nodeTopology)
-	if err != nil {
-		return err
+	if len(shares) == 0 {
+		log.Printf("No %s shared GPUs found for pod %s\n", adapter.Name(), pod.Name)
+		return nil
 	}
 
-	nodeTopology.Gpus[reservationPodGpuIdx].Status.PodGpuUsageStatus[pod.UID] = calculateUsage(p.dynamicClient, pod, nodeTopology.GpuMemory)
+	for _, share := range shares {
+		nodeTopology.Gpus[share.GpuIdx].Status.PodGpuUsageStatus[pod.UID] =
+			calculateUsageWithFraction(p.classifier, pod, share.MemoryFraction, nodeTopology.GpuMemoryOf(share.GpuIdx))
+	}
 	return nil
 }
 
-func getMatchingReservationPodGpuIdx(kubeclient kubernetes.Interface, pod *v1.Pod, nodeTopology *topology.NodeTopology) (int, error) {
-	reservationPodName, err := getMatchingReservationPodName(kubeclient, pod)
-	if err != nil {
-		return -1, err
-	}
-
-	if reservationPodName == "" {
-		log.Printf("Empty reservation pod name for pod %s\n", pod.Name)
-		return -1, nil
-	}
-
-	reservationPodGpuIdx := -1
+// findReservationPodGpuIdx returns the index of the GPU held by the
+// reservation pod of a shared GPU pod.
+func findReservationPodGpuIdx(pod *v1.Pod, nodeTopology *topology.NodeTopology, namespace, reservationPodName string) (int, error) {
 	for gpuIdx, gpuDetails := range nodeTopology.Gpus {
-		if gpuDetails.Status.AllocatedBy.Pod == reservationPodName {
-			reservationPodGpuIdx = gpuIdx
-			break
+		if gpuDetails.Status.AllocatedBy.Namespace == namespace && gpuDetails.Status.AllocatedBy.Pod == reservationPodName {
+			return gpuIdx, nil
 		}
 	}
 
-	if reservationPodGpuIdx == -1 {
-		return -1, fmt.Errorf("could not find reservation pod %s in node %s topology", reservationPodName, pod.Spec.NodeName)
-	}
-
-	return reservationPodGpuIdx, nil
-}
-
-func getMatchingReservationPodName(kubeclient kubernetes.Interface, pod *v1.Pod) (string, error) {
-	var multiErr error
-
-	// DEPRECATED_START
-	reservationPodName, err := getMatchingReservationPodNameByRunaiGpuAnnotation(kubeclient, pod)
-	if err == nil {
-		return reservationPodName, nil
-	} else {
-		multiErr = multierror.Append(multiErr, fmt.Errorf("failed to find reservation pod by runai-gpu annotation: %v", err))
-	}
-	// DEPRECATED_END
-
-	reservationPodName, err = getMatchingReservationPodNameByRunaiGpuGroupLabel(kubeclient, pod)
-	if err == nil {
-		return reservationPodName, nil
-	} else {
-		multiErr = multierror.Append(multiErr, fmt.Errorf("failed to find reservation pod by runai-gpu-group label: %v", err))
-	}
-
-	return "", multiErr
-}
-
-func getMatchingReservationPodNameByRunaiGpuAnnotation(kubeclient kubernetes.Interface, pod *v1.Pod) (string, error) {
-	runaiGpu := pod.Annotations[constants.AnnotationGpuIdx]
-	if runaiGpu == "" {
-		return "", fmt.Errorf("pod %s has empty runai-gpu annotation", pod.Name)
-	}
-
-	gpuIdx, err := strconv.Atoi(runaiGpu)
-	if err != nil {
-		return "", err
-	}
-
-	nodeReservationPods, err := getNodeReservationPods(kubeclient, pod.Spec.NodeName)
-	if err != nil {
-		return "", err
-	}
-
-	for _, nodeReservationPod := range nodeReservationPods.Items {
-		if nodeReservationPod.Annotations[constants.AnnotationReservationPodGpuIdx] == runaiGpu {
-			return nodeReservationPod.Name, nil
-		}
-	}
-
-	return "", fmt.Errorf("no reservation pod found for gpu %d on node %s", gpuIdx, pod.Spec.NodeName)
-}
-
-func getMatchingReservationPodNameByRunaiGpuGroupLabel(kubeclient kubernetes.Interface, pod *v1.Pod) (string, error) {
-	runaiGpuGroup := pod.Labels[constants.LabelGpuGroup]
-	if runaiGpuGroup == "" {
-		return "", fmt.Errorf("pod %s has empty runai-gpu-group label", pod.Name)
-	}
-
-	nodeReservationPods, err := getNodeReservationPods(kubeclient, pod.Spec.NodeName)
-	if err != nil {
-		return "", err
-	}
-
-	for _, nodeReservationPod := range nodeReservationPods.Items {
-		if nodeReservationPod.Labels[constants.LabelGpuGroup] == runaiGpuGroup {
-			return nodeReservationPod.Name, nil
-		}
-	}
-
-	return "", fmt.Errorf("no reservation pod found for gpu group %s on node %s", runaiGpuGroup, pod.Spec.NodeName)
-}
-
-func getNodeReservationPods(kubeclient kubernetes.Interface, nodeName string) (*v1.PodList, error) {
-	resourceReservationNs := viper.GetString(constants.EnvResourceReservationNamespace)
-	return kubeclient.CoreV1().Pods(resourceReservationNs).List(context.TODO(), metav1.ListOptions{FieldSelector: "spec.nodeName=" + nodeName})
+	return -1, fmt.Errorf("could not find reservation pod %s/%s in node %s topology", namespace, reservationPodName, pod.Spec.NodeName)
 }
@@ -0,0 +1,140 @@
+package pod
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
+	"github.com/run-ai/fake-gpu-operator/internal/status-updater/util"
+)
+
+// SharingAdapter decodes how a GPU-sharing scheduler placed a pod: which GPUs
+// of its node the pod shares and what share of each it received.
+type SharingAdapter interface {
+	// Name identifies the scheduler in logs.
+	Name() string
+	// Matches reports whether the pod is a shared GPU pod of the scheduler.
+	Matches(pod *v1.Pod) bool
+	// Locate returns the GPUs of the node topology that the pod shares.
+	Locate(kubeClient kubernetes.Interface, pod *v1.Pod, nodeTopology *topology.NodeTopology) ([]GpuShare, error)
+}
+
+// GpuShare is the part of one GPU that a shared GPU pod received.
+type GpuShare struct {
+	GpuIdx int
+	// MemoryFraction is the fraction of the GPU's memory allocated to the pod.
+	MemoryFraction float64
+}
+
+// sharingAdapters are tried in order and the first one matching a pod handles
+// it. HAMi and Volcano pods are recognized by their allocation annotations
+// and KAI pods by their scheduler, so they go before Run:ai, whose labels KAI
+// reuses.
+var sharingAdapters = []SharingAdapter{
+	&hamiAdapter{},
+	&volcanoAdapter{},
+	&kaiAdapter{},
+	&runaiAdapter{},
+}
+
+// sharingAdapterFor returns the adapter of the scheduler that shares a GPU
+// with the pod, or nil if the pod doesn't share a GPU.
+func sharingAdapterFor(pod *v1.Pod) SharingAdapter {
+	if util.IsGpuReservationPod(pod) {
+		return nil
+	}
+
+	for _, adapter := range sharingAdapters {
+		if adapter.Matches(pod) {
+			return adapter
+		}
+	}
+	return nil
+}
+
+// IsSharedGpuPod reports whether the pod shares a GPU through one of the
+// supported GPU-sharing schedulers.
+func IsSharedGpuPod(pod *v1.Pod) bool {
+	return sharingAdapterFor(pod) != nil
+}
+
+// isDedicatedGpuPod reports whether the pod is allocated whole GPUs. HAMi
+// counts vGPUs in the nvidia.com/gpu resource, so its pods are excluded.
+func isDedicatedGpuPod(pod *v1.Pod) bool {
+	return util.IsDedicatedGpuPod(pod) && !IsSharedGpuPod(pod)
+}
+
+// annotatedMemoryFraction returns the GPU share requested by the gpu-fraction
+// annotation, or by the gpu-memory annotation in MiB. Without either, the pod
+// is assumed to use the whole GPU.
+func annotatedMemoryFraction(pod *v1.Pod, totalGpuMemory int) float64 {
+	if gpuFraction, ok := pod.Annotations[constants.AnnotationGpuFraction]; ok {
+		if parsed, err := strconv.ParseFloat(gpuFraction, 64); err == nil {
+			return parsed
+		}
+		log.Printf("Error parsing gpu-fraction annotation of pod %s: %s\n", pod.Name, gpuFraction)
+	}
+
+	if gpuMemory, ok := pod.Annotations[constants.AnnotationGpuMemory]; ok {
+		if parsed, err := strconv.Atoi(gpuMemory); err == nil && totalGpuMemory > 0 {
+			return min(float64(parsed)/float64(totalGpuMemory), 1)
+		}
+		log.Printf("Error parsing gpu-memory annotation of pod %s: %s\n", pod.Name, gpuMemory)
+	}
+
+	return 1
+}
+
+// decodeVgpuDevices decodes the vGPU allocation annotation shared by HAMi and
+// Volcano, e.g. "GPU-<uuid>,NVIDIA,3000,30:GPU-<uuid>,NVIDIA,3000,30:;" for a
+// container with two vGPUs. Containers are separated by ";", devices by ":",
+// and each device is "<uuid>,<type>,<memory MiB>,<cores %>". The memory of the
+// pod's vGPUs on the same GPU adds up.
+func decodeVgpuDevices(annotation string, nodeTopology *topology.NodeTopology) ([]GpuShare, error) {
+	gpuIdxByID := make(map[string]int, len(nodeTopology.Gpus))
+	for idx, gpu := range nodeTopology.Gpus {
+		gpuIdxByID[gpu.ID] = idx
+	}
+
+	var shares []GpuShare
+	shareIdxByGpu := map[int]int{}
+	for _, container := range strings.Split(annotation, ";") {
+		for _, device := range strings.Split(container, ":") {
+			if device == "" {
+				continue
+			}
+
+			fields := strings.Split(device, ",")
+			if len(fields) < 3 {
+				return nil, permanent(fmt.Errorf("invalid vGPU device %q", device))
+			}
+			gpuIdx, found := gpuIdxByID[fields[0]]
+			if !found {
+				return nil, fmt.Errorf("GPU %s is not in the node topology", fields[0])
+			}
+			memory, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, permanent(fmt.Errorf("invalid memory of vGPU device %q: %w", device, err))
+			}
+
+			fraction := 1.0
+			if gpuMemory := nodeTopology.GpuMemoryOf(gpuIdx); gpuMemory > 0 {
+				fraction = float64(memory) / float64(gpuMemory)
+			}
+			if shareIdx, found := shareIdxByGpu[gpuIdx]; found {
+				shares[shareIdx].MemoryFraction = min(shares[shareIdx].MemoryFraction+fraction, 1)
+				continue
+			}
+			shareIdxByGpu[gpuIdx] = len(shares)
+			shares = append(shares, GpuShare{GpuIdx: gpuIdx, MemoryFraction: min(fraction, 1)})
+		}
+	}
+
+	return shares, nil
+}
@@ -0,0 +1,203 @@
+package pod
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
+)
+
+var _ = Describe("Sharing adapters", func() {
+	const (
+		runaiReservationNs = "runai-reservation"
+		kaiReservationNs   = "kai-resource-reservation"
+	)
+
+	var (
+		handler      *PodHandler
+		fakeClient   *fake.Clientset
+		nodeTopology *topology.NodeTopology
+	)
+
+	newSharedPod := func(annotations, labels map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        testPodName,
+				Namespace:   testNamespace,
+				UID:         testPodUID,
+				Annotations: annotations,
+				Labels:      labels,
+			},
+			Spec: corev1.PodSpec{
+				NodeName:   testNodeName,
+				Containers: []corev1.Container{{Name: testContainerName}},
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+
+	createReservationPod := func(namespace, name, gpuGroup string, gpuIdx int) {
+		_, err := fakeClient.CoreV1().Pods(namespace).Create(context.TODO(), &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    map[string]string{constants.LabelGpuGroup: gpuGroup},
+			},
+			Spec: corev1.PodSpec{NodeName: testNodeName},
+		}, metav1.CreateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		nodeTopology.Gpus[gpuIdx].Status.AllocatedBy = topology.ContainerDetails{Namespace: namespace, Pod: name, Container: "reservation"}
+	}
+
+	usageOf := func(gpuIdx int) (topology.GpuUsageStatus, bool) {
+		usage, found := nodeTopology.Gpus[gpuIdx].Status.PodGpuUsageStatus[testPodUID]
+		return usage, found
+	}
+
+	BeforeEach(func() {
+		viper.Set(constants.EnvResourceReservationNamespace, runaiReservationNs)
+		viper.Set(constants.EnvKaiReservationNamespace, kaiReservationNs)
+		DeferCleanup(viper.Reset)
+
+		fakeClient = fake.NewSimpleClientset()
+		handler = NewPodHandler(fakeClient, nil)
+		nodeTopology = &topology.NodeTopology{
+			GpuMemory:  testGpuMemory,
+			GpuProduct: testGpuProduct,
+			Gpus: []topology.GpuDetails{
+				{ID: testGpuID0, Status: topology.GpuStatus{PodGpuUsageStatus: make(topology.PodGpuUsageStatusMap)}},
+				{ID: testGpuID1, Status: topology.GpuStatus{PodGpuUsageStatus: make(topology.PodGpuUsageStatusMap)}},
+				{ID: testGpuID2, Status: topology.GpuStatus{PodGpuUsageStatus: make(topology.PodGpuUsageStatusMap)}},
+			},
+		}
+	})
+
+	Context("HAMi", func() {
+		It("should report the allocated vGPU memory on the allocated GPU without allocating it", func() {
+			pod := newSharedPod(map[string]string{
+				constants.AnnotationHamiDevicesAllocated: testGpuID1 + ",NVIDIA,4000,30:;",
+			}, nil)
+			pod.Spec.Containers[0].Resources.Limits = corev1.ResourceList{
+				constants.GpuResourceName: resource.MustParse("1"),
+				"nvidia.com/gpumem":       resource.MustParse("4000"),
+			}
+			Expect(IsSharedGpuPod(pod)).To(BeTrue())
+			Expect(isDedicatedGpuPod(pod)).To(BeFalse())
+
+			Expect(handler.HandleAdd(pod, nodeTopology)).To(Succeed())
+
+			usage, found := usageOf(1)
+			Expect(found).To(BeTrue())
+			Expect(usage.FbUsed).To(Equal(4000))
+			Expect(nodeTopology.Gpus[1].Status.AllocatedBy.Pod).To(BeEmpty())
+
+			Expect(handler.HandleDelete(pod, nodeTopology)).To(Succeed())
+			_, found = usageOf(1)
+			Expect(found).To(BeFalse())
+		})
+
+		It("should add up the vGPUs of the pod's containers on the same GPU", func() {
+			pod := newSharedPod(map[string]string{
+				constants.AnnotationHamiDevicesAllocated: testGpuID0 + ",NVIDIA,4000,30:;" + testGpuID0 + ",NVIDIA,2000,10:" + testGpuID2 + ",NVIDIA,8000,50:;",
+			}, nil)
+
+			Expect(handler.HandleAdd(pod, nodeTopology)).To(Succeed())
+
+			usage, _ := usageOf(0)
+			Expect(usage.FbUsed).To(Equal(6000))
+			usage, _ = usageOf(2)
+			Expect(usage.FbUsed).To(Equal(8000))
+			_, found := usageOf(1)
+			Expect(found).To(BeFalse())
+		})
+
+		It("should fail on GPUs that are not in the node topology", func() {
+			pod := newSharedPod(map[string]string{
+				constants.AnnotationHamiDevicesAllocated: "GPU-unknown,NVIDIA,4000,30:;",
+			}, nil)
+
+			err := handler.HandleAdd(pod, nodeTopology)
+			Expect(err).To(MatchError(ContainSubstring("not in the node topology")))
+			Expect(IsPermanent(err)).To(BeFalse())
+		})
+
+		It("should fail permanently on malformed vGPU devices", func() {
+			pod := newSharedPod(map[string]string{
+				constants.AnnotationHamiDevicesAllocated: testGpuID0 + ",NVIDIA,lots,30:;",
+			}, nil)
+
+			err := handler.HandleAdd(pod, nodeTopology)
+			Expect(err).To(MatchError(ContainSubstring("invalid memory of vGPU device")))
+			Expect(IsPermanent(err)).To(BeTrue())
+		})
+	})
+
+	Context("Volcano", func() {
+		It("should report the allocated vGPU memory on each allocated GPU", func() {
+			pod := newSharedPod(map[string]string{
+				constants.AnnotationVolcanoVgpuIds: testGpuID0 + ",NVIDIA,8000,0:" + testGpuID1 + ",NVIDIA,16000,0:;",
+			}, nil)
+			Expect(sharingAdapterFor(pod).Name()).To(Equal("Volcano"))
+
+			Expect(handler.HandleAdd(pod, nodeTopology)).To(Succeed())
+
+			usage, _ := usageOf(0)
+			Expect(usage.FbUsed).To(Equal(8000))
+			usage, _ = usageOf(1)
+			Expect(usage.FbUsed).To(Equal(16000))
+		})
+	})
+
+	Context("KAI scheduler", func() {
+		It("should report the gpu-memory share on the GPUs of its reservation pods", func() {
+			createReservationPod(kaiReservationNs, "kai-reservation-a", "group-a", 2)
+			createReservationPod(kaiReservationNs, "kai-reservation-b", "group-b", 0)
+			pod := newSharedPod(
+				map[string]string{constants.AnnotationGpuMemory: "4000"},
+				map[string]string{constants.LabelGpuGroup: "group-a"},
+			)
+			pod.Spec.SchedulerName = constants.KaiSchedulerName
+			Expect(sharingAdapterFor(pod).Name()).To(Equal("KAI scheduler"))
+
+			Expect(handler.HandleAdd(pod, nodeTopology)).To(Succeed())
+
+			usage, found := usageOf(2)
+			Expect(found).To(BeTrue())
+			Expect(usage.FbUsed).To(Equal(4000))
+			_, found = usageOf(0)
+			Expect(found).To(BeFalse())
+		})
+
+		It("should not treat its reservation pods as shared GPU pods", func() {
+			reservationPod := newSharedPod(nil, map[string]string{constants.LabelGpuGroup: "group-a"})
+			reservationPod.Namespace = kaiReservationNs
+			reservationPod.Spec.SchedulerName = constants.KaiSchedulerName
+
+			Expect(IsSharedGpuPod(reservationPod)).To(BeFalse())
+		})
+	})
+
+	Context("Run:ai", func() {
+		It("should handle gpu-group pods of other schedulers", func() {
+			createReservationPod(runaiReservationNs, "runai-reservation-a", "group-a", 1)
+			pod := newSharedPod(
+				map[string]string{constants.AnnotationGpuFraction: "0.25"},
+				map[string]string{constants.LabelGpuGroup: "group-a"},
+			)
+			Expect(sharingAdapterFor(pod).Name()).To(Equal("Run:ai"))
+
+			Expect(handler.HandleAdd(pod, nodeTopology)).To(Succeed())
+
+			usage, _ := usageOf(1)
+			Expect(usage.FbUsed).To(Equal(4000))
+		})
+	})
+})
@@ -0,0 +1,28 @@
+package pod
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
+)
+
+// volcanoAdapter locates Volcano vGPU pods, which request the
+// volcano.sh/vgpu-number and volcano.sh/vgpu-memory resources, by the GPUs
+// the Volcano scheduler recorded in their volcano.sh/vgpu-ids-new annotation.
+type volcanoAdapter struct{}
+
+var _ SharingAdapter = &volcanoAdapter{}
+
+func (a *volcanoAdapter) Name() string {
+	return "Volcano"
+}
+
+func (a *volcanoAdapter) Matches(pod *v1.Pod) bool {
+	return pod.Annotations[constants.AnnotationVolcanoVgpuIds] != ""
+}
+
+func (a *volcanoAdapter) Locate(_ kubernetes.Interface, pod *v1.Pod, nodeTopology *topology.NodeTopology) ([]GpuShare, error) {
+	return decodeVgpuDevices(pod.Annotations[constants.AnnotationVolcanoVgpuIds], nodeTopology)
+}
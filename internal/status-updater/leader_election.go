@@ -0,0 +1,189 @@
+package status_updater
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/viper"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+)
+
+const (
+	leaseName = "fake-status-updater"
+
+	leaseDuration = 15 * time.Second
+	renewDeadline = 10 * time.Second
+	retryPeriod   = 2 * time.Second
+	// leaderHealthTolerance is how long past its lease a leader that can't
+	// renew it still passes the liveness probe.
+	leaderHealthTolerance = 20 * time.Second
+)
+
+// leaderElection lets a single status-updater replica run the controllers,
+// holding a Lease in the operator namespace. Standby replicas take over when
+// the leader releases the Lease on shutdown or stops renewing it.
+type leaderElection struct {
+	identity string
+	elector  *leaderelection.LeaderElector
+	health   *leaderelection.HealthzAdaptor
+	stopCh   <-chan struct{}
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	leading chan context.Context
+}
+
+func newLeaderElection(kubeClient kubernetes.Interface, stopCh <-chan struct{}) *leaderElection {
+	le := &leaderElection{
+		identity: leaderElectionIdentity(),
+		health:   leaderelection.NewLeaderHealthzAdaptor(leaderHealthTolerance),
+		stopCh:   stopCh,
+		leading:  make(chan context.Context, 1),
+	}
+	le.ctx, le.cancel = context.WithCancel(context.Background())
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta: metav1.ObjectMeta{
+				Name:      leaseName,
+				Namespace: viper.GetString(constants.EnvFakeGpuOperatorNs),
+			},
+			Client:     kubeClient.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: le.identity},
+		},
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   renewDeadline,
+		RetryPeriod:     retryPeriod,
+		ReleaseOnCancel: true,
+		Name:            leaseName,
+		WatchDog:        le.health,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				le.leading <- ctx
+			},
+			OnStoppedLeading: func() {
+				if le.ctx.Err() != nil {
+					return
+				}
+				// Another replica may already be mutating the topology.
+				log.Fatalf("%s lost the status-updater lease, exiting", le.identity)
+			},
+			OnNewLeader: func(identity string) {
+				if identity != le.identity {
+					log.Printf("Standing by, %s holds the status-updater lease", identity)
+				}
+			},
+		},
+	})
+	if err != nil {
+		log.Fatalf("Failed to create the status-updater leader elector: %v", err)
+	}
+	le.elector = elector
+
+	return le
+}
+
+// run campaigns for the Lease and runs the controllers while holding it. On
+// shutdown, the leader stops its controllers before releasing the Lease, so
+// the next leader starts from a settled topology instead of racing the last
+// writes of the previous one.
+func (le *leaderElection) run(runControllers func(stopCh <-chan struct{})) {
+	electionDone := make(chan struct{})
+	go func() {
+		defer close(electionDone)
+		le.elector.Run(le.ctx)
+	}()
+
+	select {
+	case <-le.stopCh:
+		le.cancel()
+	case <-electionDone:
+	case leaderCtx := <-le.leading:
+		log.Printf("%s acquired the status-updater lease, starting controllers", le.identity)
+
+		stopCh := make(chan struct{})
+		go func() {
+			select {
+			case <-leaderCtx.Done():
+			case <-le.stopCh:
+			}
+			close(stopCh)
+		}()
+		runControllers(stopCh)
+
+		le.cancel()
+	}
+
+	<-electionDone
+}
+
+// healthz fails when the replica leads but has not renewed the Lease in time.
+func (le *leaderElection) healthz(r *http.Request) error {
+	return le.health.Check(r)
+}
+
+// readyz passes once the replica observed a leader, be it itself or the
+// replica it stands by for.
+func (le *leaderElection) readyz() error {
+	if le.elector.GetLeader() == "" {
+		return fmt.Errorf("no status-updater leader observed yet")
+	}
+	return nil
+}
+
+// leaderElectionIdentity identifies the replica in the Lease, preferring the
+// pod name from the downward API.
+func leaderElectionIdentity() string {
+	if podName := viper.GetString(constants.EnvPodName); podName != "" {
+		return podName
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.Fatalf("Failed to get the hostname for leader election: %v", err)
+	}
+	return hostname
+}
+
+// serveHealthProbes serves the liveness (/healthz) and readiness (/readyz)
+// probes until stopCh is closed.
+func (app *StatusUpdaterApp) serveHealthProbes(address string, stopCh <-chan struct{}) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if app.election != nil {
+			if err := app.election.healthz(r); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		_, _ = w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if app.election != nil {
+			if err := app.election.readyz(); err != nil {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	server := &http.Server{Addr: address, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-stopCh
+		_ = server.Shutdown(context.Background())
+	}()
+
+	log.Printf("Serving health probes on %s", address)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Printf("Health probe server failed: %v", err)
+	}
+}
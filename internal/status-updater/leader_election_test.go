@@ -0,0 +1,153 @@
+package status_updater_test
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dfake "k8s.io/client-go/dynamic/fake"
+	kfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/app"
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
+	status_updater "github.com/run-ai/fake-gpu-operator/internal/status-updater"
+)
+
+var _ = Describe("StatusUpdater leader election", func() {
+	const (
+		operatorNamespace = "fake-gpu-operator"
+		leaseName         = "fake-status-updater"
+		identity          = "status-updater-0"
+	)
+
+	var (
+		kubeclient    *kfake.Clientset
+		dynamicClient *dfake.FakeDynamicClient
+		appRunner     *app.AppRunner
+		wg            *sync.WaitGroup
+	)
+
+	getLease := func() (*coordinationv1.Lease, error) {
+		return kubeclient.CoordinationV1().Leases(operatorNamespace).Get(context.TODO(), leaseName, metav1.GetOptions{})
+	}
+
+	leaseHolder := func() string {
+		lease, err := getLease()
+		if err != nil {
+			return ""
+		}
+		return ptr.Deref(lease.Spec.HolderIdentity, "")
+	}
+
+	startStatusUpdater := func() {
+		appRunner = app.NewAppRunner(&status_updater.StatusUpdaterApp{})
+		wg = &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			appRunner.Run()
+		}()
+	}
+
+	stopStatusUpdater := func() {
+		appRunner.Stop()
+		wg.Wait()
+		appRunner = nil
+	}
+
+	BeforeEach(func() {
+		topologyStr, err := yaml.Marshal(&topology.ClusterTopology{
+			NodePools: map[string]topology.NodePoolTopology{
+				"default": {GpuMemory: 11441, GpuProduct: "Tesla-K80", GpuCount: nodeGpuCount},
+			},
+			NodePoolLabelKey: "run.ai/simulated-gpu-node-pool",
+			MigStrategy:      "mixed",
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		kubeclient = kfake.NewSimpleClientset(
+			&v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: topologyCmName, Namespace: topologyCmNamespace},
+				Data:       map[string]string{"topology.yml": string(topologyStr)},
+			},
+			&v1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   node,
+					Labels: map[string]string{"run.ai/simulated-gpu-node-pool": "default"},
+				},
+			},
+		)
+		dynamicClient = dfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+			topology.FakeGpuNodeGVR: topology.FakeGpuNodeListKind,
+		})
+
+		setupFakes(kubeclient, dynamicClient)
+		setupConfig()
+		for key, value := range map[string]string{
+			constants.EnvLeaderElect:       "true",
+			constants.EnvPodName:           identity,
+			constants.EnvFakeGpuOperatorNs: operatorNamespace,
+		} {
+			Expect(os.Setenv(key, value)).To(Succeed())
+			DeferCleanup(os.Unsetenv, key)
+		}
+	})
+
+	AfterEach(func() {
+		if appRunner != nil {
+			stopStatusUpdater()
+		}
+	})
+
+	It("should run the controllers while holding the lease and release it on shutdown", func() {
+		startStatusUpdater()
+
+		Eventually(leaseHolder).Should(Equal(identity))
+		Eventually(getTopologyNodeFromKube(dynamicClient, node)).Should(Equal(createTopology(nodeGpuCount, node)))
+
+		stopStatusUpdater()
+
+		Expect(leaseHolder()).To(BeEmpty())
+	})
+
+	It("should stand by while another replica holds the lease", func() {
+		_, err := kubeclient.CoordinationV1().Leases(operatorNamespace).Create(context.TODO(), &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: leaseName, Namespace: operatorNamespace},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       ptr.To("status-updater-1"),
+				LeaseDurationSeconds: ptr.To(int32(15)),
+				AcquireTime:          &metav1.MicroTime{Time: time.Now()},
+				RenewTime:            &metav1.MicroTime{Time: time.Now()},
+			},
+		}, metav1.CreateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		startStatusUpdater()
+
+		Consistently(getTopologyNodeFromKubeErrorOrNil(dynamicClient, node), 3*time.Second).Should(HaveOccurred())
+		Expect(leaseHolder()).To(Equal("status-updater-1"))
+
+		// The other replica shuts down and releases the lease.
+		lease, err := getLease()
+		Expect(err).ToNot(HaveOccurred())
+		lease.Spec.HolderIdentity = ptr.To("")
+		lease.Spec.LeaseDurationSeconds = ptr.To(int32(1))
+		_, err = kubeclient.CoordinationV1().Leases(operatorNamespace).Update(context.TODO(), lease, metav1.UpdateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(leaseHolder, 10*time.Second).Should(Equal(identity))
+		Eventually(getTopologyNodeFromKube(dynamicClient, node), 10*time.Second).Should(Equal(createTopology(nodeGpuCount, node)))
+	})
+})
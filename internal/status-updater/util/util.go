@@ -3,20 +3,75 @@ package util
 import (
 	"github.com/spf13/viper"
 	v1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/resource"
 
 	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
 )
 
-func IsSharedGpuPod(pod *v1.Pod) bool {
-	_, runaiGpuExists := pod.Annotations[constants.AnnotationGpuIdx]
-	_, runaiGpuGroupExists := pod.Labels[constants.LabelGpuGroup]
+func IsDedicatedGpuPod(pod *v1.Pod) bool {
+	return len(ContainerGpuRequests(pod)) > 0
+}
 
-	return !IsGpuReservationPod(pod) && (runaiGpuExists || runaiGpuGroupExists)
+// ContainerGpuRequest is the number of whole GPUs attributed to a container.
+type ContainerGpuRequest struct {
+	Container string
+	Count     int64
 }
 
-func IsDedicatedGpuPod(pod *v1.Pod) bool {
-	return !pod.Spec.Containers[0].Resources.Limits[constants.GpuResourceName].Equal(resource.MustParse("0"))
+// ContainerGpuRequests returns the GPUs a pod holds, per container, following
+// kubelet's device allocation rules: app containers and restartable init
+// containers (native sidecars) keep their GPUs for the pod's lifetime, while
+// the GPUs of regular init containers are reused by the containers started
+// after them. When an init container needs more GPUs than the long-running
+// containers, kubelet keeps the extra GPUs allocated to it.
+func ContainerGpuRequests(pod *v1.Pod) []ContainerGpuRequest {
+	var requests []ContainerGpuRequest
+	var sidecarGpus, initGpus int64
+	var initContainer string
+
+	for _, container := range pod.Spec.InitContainers {
+		count := containerGpus(&container)
+		if isRestartableInitContainer(&container) {
+			if count > 0 {
+				requests = append(requests, ContainerGpuRequest{Container: container.Name, Count: count})
+			}
+			sidecarGpus += count
+			continue
+		}
+		// Sidecars declared before an init container run alongside it.
+		if count > 0 && count+sidecarGpus > initGpus {
+			initGpus = count + sidecarGpus
+			initContainer = container.Name
+		}
+	}
+
+	longRunningGpus := sidecarGpus
+	for _, container := range pod.Spec.Containers {
+		count := containerGpus(&container)
+		if count > 0 {
+			requests = append(requests, ContainerGpuRequest{Container: container.Name, Count: count})
+		}
+		longRunningGpus += count
+	}
+
+	if initGpus > longRunningGpus {
+		requests = append(requests, ContainerGpuRequest{Container: initContainer, Count: initGpus - longRunningGpus})
+	}
+
+	return requests
+}
+
+// containerGpus returns the GPUs a container requests. Extended resources
+// must have equal requests and limits, and the request defaults to the limit.
+func containerGpus(container *v1.Container) int64 {
+	if quantity, ok := container.Resources.Limits[constants.GpuResourceName]; ok {
+		return quantity.Value()
+	}
+	quantity := container.Resources.Requests[constants.GpuResourceName]
+	return quantity.Value()
+}
+
+func isRestartableInitContainer(container *v1.Container) bool {
+	return container.RestartPolicy != nil && *container.RestartPolicy == v1.ContainerRestartPolicyAlways
 }
 
 func IsPodRunning(pod *v1.Pod) bool {
@@ -32,9 +87,12 @@ func IsPodScheduled(pod *v1.Pod) bool {
 	return pod.Spec.NodeName != ""
 }
 
+// IsGpuReservationPod reports whether the pod holds a GPU on behalf of the
+// fractional pods of Run:ai or the KAI scheduler.
 func IsGpuReservationPod(pod *v1.Pod) bool {
 	resourceReservationNs := viper.GetString(constants.EnvResourceReservationNamespace)
-	return pod.Namespace == resourceReservationNs
+	kaiReservationNs := viper.GetString(constants.EnvKaiReservationNamespace)
+	return pod.Namespace == resourceReservationNs || (kaiReservationNs != "" && pod.Namespace == kaiReservationNs)
 }
 
 // IsDraPod returns true if the pod uses Dynamic Resource Allocation (DRA)
@@ -0,0 +1,269 @@
+package workload
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+)
+
+var (
+	podGroupGVR      = schema.GroupVersionResource{Group: "scheduling.run.ai", Version: "v2alpha2", Resource: "podgroups"}
+	kueueWorkloadGVR = schema.GroupVersionResource{Group: "kueue.x-k8s.io", Version: "v1beta1", Resource: "workloads"}
+)
+
+// workloadOwnerIndex indexes Kueue Workloads by the UIDs of their owners.
+const workloadOwnerIndex = "ownerUID"
+
+// workloadSyncTimeout bounds how long the first Kueue Workload lookup waits
+// for the Workloads to be listed, e.g. when Kueue is not installed.
+const workloadSyncTimeout = 5 * time.Second
+
+// Classifier maps pods to their simulated GPU usage model with the rules of
+// the workload classification ConfigMap. Edits to the ConfigMap apply to pod
+// events that follow them; without the ConfigMap, DefaultRules apply.
+type Classifier struct {
+	dynamicClient dynamic.Interface
+	informer      cache.SharedIndexInformer
+	// workloads watches Kueue Workloads from the first rule that matches on
+	// them, so clusters without Kueue never watch them.
+	workloads     cache.SharedIndexInformer
+	startWorkload sync.Once
+
+	mu     sync.RWMutex
+	rules  *RuleSet
+	stopCh <-chan struct{}
+}
+
+// NewClassifier watches the ConfigMap named by WORKLOAD_RULES_CM_NAME in the
+// topology namespace. Without the setting, the classifier uses DefaultRules.
+func NewClassifier(kubeClient kubernetes.Interface, dynamicClient dynamic.Interface) *Classifier {
+	c := &Classifier{
+		dynamicClient: dynamicClient,
+		rules:         DefaultRules,
+	}
+	if dynamicClient != nil {
+		c.workloads = dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, 0).ForResource(kueueWorkloadGVR).Informer()
+		err := c.workloads.AddIndexers(cache.Indexers{workloadOwnerIndex: ownerUIDs})
+		if err != nil {
+			log.Fatalf("Failed to index Kueue workloads: %v", err)
+		}
+	}
+
+	cmName := viper.GetString(constants.EnvWorkloadRulesCmName)
+	if cmName == "" {
+		return c
+	}
+
+	informerFactory := informers.NewSharedInformerFactoryWithOptions(
+		kubeClient, 0,
+		informers.WithNamespace(viper.GetString(constants.EnvTopologyCmNamespace)),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", cmName).String()
+		}),
+	)
+	c.informer = informerFactory.Core().V1().ConfigMaps().Informer()
+
+	_, err := c.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			c.handleRulesConfigMap(obj.(*v1.ConfigMap))
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			c.handleRulesConfigMap(newObj.(*v1.ConfigMap))
+		},
+		DeleteFunc: func(obj interface{}) {
+			if cm, ok := obj.(*v1.ConfigMap); ok && cm.Name != cmName {
+				return
+			}
+			log.Println("Workload classification ConfigMap deleted, using the default rules")
+			c.SetRules(DefaultRules)
+		},
+	})
+	if err != nil {
+		log.Fatalf("Failed to add workload classification event handler: %v", err)
+	}
+
+	return c
+}
+
+// Run watches the workload classification ConfigMap, and the Kueue Workloads
+// once needed, until stopCh is closed.
+func (c *Classifier) Run(stopCh <-chan struct{}) {
+	c.mu.Lock()
+	c.stopCh = stopCh
+	c.mu.Unlock()
+
+	if c.informer == nil {
+		return
+	}
+	c.informer.Run(stopCh)
+}
+
+// HasSynced reports whether the classifier runs and the rules ConfigMap, if
+// any, has been read.
+func (c *Classifier) HasSynced() bool {
+	c.mu.RLock()
+	running := c.stopCh != nil
+	c.mu.RUnlock()
+	return running && (c.informer == nil || c.informer.HasSynced())
+}
+
+func (c *Classifier) handleRulesConfigMap(cm *v1.ConfigMap) {
+	if cm.Name != viper.GetString(constants.EnvWorkloadRulesCmName) {
+		return
+	}
+
+	rules, err := ParseRules([]byte(cm.Data[RulesCmKey]))
+	if err != nil {
+		log.Printf("Ignoring invalid workload classification ConfigMap %s: %v", cm.Name, err)
+		return
+	}
+
+	log.Printf("Loaded %d workload classification rules from ConfigMap %s", len(rules.rules), cm.Name)
+	c.SetRules(rules)
+}
+
+// SetRules replaces the rules pods are classified with.
+func (c *Classifier) SetRules(rules *RuleSet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rules = rules
+}
+
+// Classify returns the usage model of the first rule matching the pod. A nil
+// classifier uses DefaultRules without PodGroup or Kueue lookups.
+func (c *Classifier) Classify(pod *v1.Pod) Model {
+	rules := DefaultRules
+	if c != nil {
+		c.mu.RLock()
+		rules = c.rules
+		c.mu.RUnlock()
+	}
+
+	return rules.classify(pod, &podLookups{classifier: c, pod: pod})
+}
+
+// podLookups fetches the objects rules match on at most once per pod event.
+type podLookups struct {
+	classifier *Classifier
+	pod        *v1.Pod
+
+	podGroup, kueueWorkload             *unstructured.Unstructured
+	podGroupLooked, kueueWorkloadLooked bool
+}
+
+func (l *podLookups) podGroupField(field string) (string, bool) {
+	if !l.podGroupLooked {
+		l.podGroupLooked = true
+		l.podGroup = l.getPodGroup()
+	}
+	return nestedString(l.podGroup, field)
+}
+
+func (l *podLookups) kueueWorkloadField(field string) (string, bool) {
+	if !l.kueueWorkloadLooked {
+		l.kueueWorkloadLooked = true
+		l.kueueWorkload = l.getKueueWorkload()
+	}
+	return nestedString(l.kueueWorkload, field)
+}
+
+func (l *podLookups) getPodGroup() *unstructured.Unstructured {
+	podGroupName := l.pod.Annotations[constants.AnnotationPodGroupName]
+	if podGroupName == "" || l.classifier == nil || l.classifier.dynamicClient == nil {
+		return nil
+	}
+
+	podGroup, err := l.classifier.dynamicClient.Resource(podGroupGVR).Namespace(l.pod.Namespace).Get(context.TODO(), podGroupName, metav1.GetOptions{})
+	if err != nil {
+		log.Printf("Error getting podgroup %s of pod %s: %v\n", podGroupName, l.pod.Name, err)
+		return nil
+	}
+	return podGroup
+}
+
+// getKueueWorkload finds the Workload Kueue created for the pod's job, or for
+// the pod itself when Kueue manages plain pods.
+func (l *podLookups) getKueueWorkload() *unstructured.Unstructured {
+	if l.classifier == nil || l.classifier.workloads == nil {
+		return nil
+	}
+	l.classifier.runWorkloadInformer()
+
+	indexer := l.classifier.workloads.GetIndexer()
+	for _, uid := range append([]types.UID{l.pod.UID}, ownerReferenceUIDs(l.pod.OwnerReferences)...) {
+		workloads, err := indexer.ByIndex(workloadOwnerIndex, string(uid))
+		if err != nil {
+			log.Printf("Error looking up Kueue workloads for pod %s: %v\n", l.pod.Name, err)
+			return nil
+		}
+		for _, obj := range workloads {
+			if workload := obj.(*unstructured.Unstructured); workload.GetNamespace() == l.pod.Namespace {
+				return workload
+			}
+		}
+	}
+	return nil
+}
+
+// runWorkloadInformer starts watching Kueue Workloads on the first lookup and
+// waits, up to workloadSyncTimeout, for them to be listed.
+func (c *Classifier) runWorkloadInformer() {
+	c.startWorkload.Do(func() {
+		c.mu.RLock()
+		stopCh := c.stopCh
+		c.mu.RUnlock()
+
+		go c.workloads.Run(stopCh)
+		ctx, cancel := context.WithTimeout(context.Background(), workloadSyncTimeout)
+		defer cancel()
+		if !cache.WaitForCacheSync(ctx.Done(), c.workloads.HasSynced) {
+			log.Printf("Kueue workloads not listed within %s, is Kueue installed? Rules matching them don't match until they are\n", workloadSyncTimeout)
+		}
+	})
+}
+
+// ownerUIDs indexes an object by the UIDs of its owners.
+func ownerUIDs(obj interface{}) ([]string, error) {
+	object, ok := obj.(metav1.Object)
+	if !ok {
+		return nil, nil
+	}
+	var uids []string
+	for _, uid := range ownerReferenceUIDs(object.GetOwnerReferences()) {
+		uids = append(uids, string(uid))
+	}
+	return uids, nil
+}
+
+func ownerReferenceUIDs(owners []metav1.OwnerReference) []types.UID {
+	uids := make([]types.UID, 0, len(owners))
+	for _, owner := range owners {
+		uids = append(uids, owner.UID)
+	}
+	return uids
+}
+
+func nestedString(obj *unstructured.Unstructured, field string) (string, bool) {
+	if obj == nil {
+		return "", false
+	}
+	value, found, err := unstructured.NestedString(obj.Object, strings.Split(field, ".")...)
+	return value, found && err == nil
+}
@@ -0,0 +1,284 @@
+package workload_test
+
+import (
+	"context"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dfake "k8s.io/client-go/dynamic/fake"
+	kfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/constants"
+	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
+	"github.com/run-ai/fake-gpu-operator/internal/status-updater/workload"
+)
+
+const (
+	namespace     = "team-a"
+	rulesCmName   = "workload-classification"
+	rulesCmNsName = "gpu-operator"
+)
+
+var (
+	podGroupGVR      = schema.GroupVersionResource{Group: "scheduling.run.ai", Version: "v2alpha2", Resource: "podgroups"}
+	kueueWorkloadGVR = schema.GroupVersionResource{Group: "kueue.x-k8s.io", Version: "v1beta1", Resource: "workloads"}
+)
+
+func newDynamicClient(objects ...runtime.Object) *dfake.FakeDynamicClient {
+	return dfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		podGroupGVR:      "PodGroupList",
+		kueueWorkloadGVR: "WorkloadList",
+	}, objects...)
+}
+
+func newObject(gvr schema.GroupVersionResource, kind, name string, spec map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	obj.SetGroupVersionKind(gvr.GroupVersion().WithKind(kind))
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
+
+func newPod(labels, annotations map[string]string) *v1.Pod {
+	return &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:        "pod",
+		Namespace:   namespace,
+		UID:         "pod-uid",
+		Labels:      labels,
+		Annotations: annotations,
+	}}
+}
+
+func newRulesConfigMap(rules string) *v1.ConfigMap {
+	return &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: rulesCmName, Namespace: rulesCmNsName},
+		Data:       map[string]string{workload.RulesCmKey: rules},
+	}
+}
+
+var _ = Describe("Classifier", func() {
+	Context("with the default rules", func() {
+		var classifier *workload.Classifier
+
+		BeforeEach(func() {
+			podGroup := newObject(podGroupGVR, "PodGroup", "pg", map[string]interface{}{"priorityClassName": "train"})
+			inferencePodGroup := newObject(podGroupGVR, "PodGroup", "pg-inference", map[string]interface{}{"priorityClassName": "inference"})
+			classifier = workload.NewClassifier(kfake.NewSimpleClientset(), newDynamicClient(podGroup, inferencePodGroup))
+		})
+
+		DescribeTable("should classify Run:ai workloads",
+			func(labels, annotations map[string]string, expected topology.Range, inference bool) {
+				model := classifier.Classify(newPod(labels, annotations))
+				Expect(model.Utilization).To(Equal(expected))
+				Expect(model.Inference).To(Equal(inference))
+				Expect(model.MemoryPercent).To(Equal(100))
+			},
+			Entry("training workload", map[string]string{"workloadKind": "TrainingWorkload"}, nil, topology.Range{Min: 80, Max: 100}, false),
+			Entry("inference workload", map[string]string{"workloadKind": "InferenceWorkload"}, nil, topology.Range{}, true),
+			Entry("interactive workload", map[string]string{"workloadKind": "InteractiveWorkload"}, nil, topology.Range{}, false),
+			Entry("train PodGroup", nil, map[string]string{constants.AnnotationPodGroupName: "pg"}, topology.Range{Min: 80, Max: 100}, false),
+			Entry("inference PodGroup", nil, map[string]string{constants.AnnotationPodGroupName: "pg-inference"}, topology.Range{}, true),
+			Entry("unknown workloadKind with a PodGroup", map[string]string{"workloadKind": "Other"}, map[string]string{constants.AnnotationPodGroupName: "pg"}, topology.Range{Min: 80, Max: 100}, false),
+			Entry("missing PodGroup", nil, map[string]string{constants.AnnotationPodGroupName: "missing"}, topology.Range{Min: 100, Max: 100}, false),
+			Entry("unclassified pod", nil, nil, topology.Range{Min: 100, Max: 100}, false),
+		)
+
+		It("should apply the default rules without a classifier", func() {
+			var nilClassifier *workload.Classifier
+			model := nilClassifier.Classify(newPod(map[string]string{"workloadKind": "TrainingWorkload"}, nil))
+			Expect(model.Rule).To(Equal("training-workload"))
+		})
+	})
+
+	Context("with custom rules", func() {
+		ruleSet, err := workload.ParseRules([]byte(`
+rules:
+- name: notebooks
+  match:
+    annotations: app.kubernetes.io/component=notebook
+    namespaces: [team-a]
+  utilization: "5"
+  memoryPercent: 30
+- name: batch
+  match:
+    ownerKinds: [Job]
+    kueueWorkload:
+      field: spec.queueName
+      values: [batch]
+  utilization: 90-100
+- name: serving
+  match:
+    labels: serving.knative.dev/service
+  utilization: "0"
+  inference: true
+`))
+		It("should parse", func() {
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		classify := func(pod *v1.Pod, objects ...runtime.Object) workload.Model {
+			classifier := workload.NewClassifier(kfake.NewSimpleClientset(), newDynamicClient(objects...))
+			classifier.SetRules(ruleSet)
+			return classifier.Classify(pod)
+		}
+
+		It("should match annotation selectors in the listed namespaces", func() {
+			pod := newPod(nil, map[string]string{"app.kubernetes.io/component": "notebook"})
+			Expect(classify(pod)).To(Equal(workload.Model{Rule: "notebooks", Utilization: topology.Range{Min: 5, Max: 5}, MemoryPercent: 30}))
+
+			pod.Namespace = "team-b"
+			Expect(classify(pod).Rule).To(BeEmpty())
+		})
+
+		It("should match the Kueue Workload of the pod's job", func() {
+			pod := newPod(nil, nil)
+			pod.OwnerReferences = []metav1.OwnerReference{{Kind: "Job", Name: "job", UID: "job-uid", Controller: ptr.To(true)}}
+			kueueWorkload := newObject(kueueWorkloadGVR, "Workload", "job-job-1234", map[string]interface{}{"queueName": "batch"})
+			kueueWorkload.SetOwnerReferences([]metav1.OwnerReference{{Kind: "Job", Name: "job", UID: "job-uid"}})
+
+			Expect(classify(pod, kueueWorkload).Rule).To(Equal("batch"))
+			Expect(classify(pod).Rule).To(BeEmpty())
+		})
+
+		It("should list the Kueue Workloads once for all pods", func() {
+			kueueWorkload := newObject(kueueWorkloadGVR, "Workload", "job-job-1234", map[string]interface{}{"queueName": "batch"})
+			kueueWorkload.SetOwnerReferences([]metav1.OwnerReference{{Kind: "Job", Name: "job", UID: "job-uid"}})
+			otherNamespace := newObject(kueueWorkloadGVR, "Workload", "job-other-1234", map[string]interface{}{"queueName": "batch"})
+			otherNamespace.SetNamespace("team-b")
+			otherNamespace.SetOwnerReferences([]metav1.OwnerReference{{Kind: "Job", Name: "other", UID: "other-uid"}})
+			dynamicClient := newDynamicClient(kueueWorkload, otherNamespace)
+			classifier := workload.NewClassifier(kfake.NewSimpleClientset(), dynamicClient)
+			classifier.SetRules(ruleSet)
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+			go classifier.Run(stopCh)
+
+			for _, ownerUID := range []types.UID{"job-uid", "job-uid", "other-uid"} {
+				pod := newPod(nil, nil)
+				pod.OwnerReferences = []metav1.OwnerReference{{Kind: "Job", Name: "job", UID: ownerUID, Controller: ptr.To(true)}}
+				expectedRule := "batch"
+				if ownerUID == "other-uid" {
+					expectedRule = ""
+				}
+				Expect(classifier.Classify(pod).Rule).To(Equal(expectedRule))
+			}
+
+			lists := 0
+			for _, action := range dynamicClient.Actions() {
+				if action.GetVerb() == "list" && action.GetResource() == kueueWorkloadGVR {
+					lists++
+				}
+			}
+			Expect(lists).To(Equal(1))
+		})
+
+		It("should match label existence", func() {
+			model := classify(newPod(map[string]string{"serving.knative.dev/service": "svc"}, nil))
+			Expect(model.Rule).To(Equal("serving"))
+			Expect(model.Inference).To(BeTrue())
+		})
+	})
+
+	Context("with a workload classification ConfigMap", func() {
+		var (
+			kubeClient *kfake.Clientset
+			classifier *workload.Classifier
+			stopCh     chan struct{}
+		)
+
+		BeforeEach(func() {
+			viper.Set(constants.EnvWorkloadRulesCmName, rulesCmName)
+			viper.Set(constants.EnvTopologyCmNamespace, rulesCmNsName)
+			DeferCleanup(viper.Reset)
+
+			kubeClient = kfake.NewSimpleClientset(newRulesConfigMap(`
+rules:
+- name: everything
+  utilization: "42"
+`))
+			classifier = workload.NewClassifier(kubeClient, newDynamicClient())
+			stopCh = make(chan struct{})
+			DeferCleanup(func() { close(stopCh) })
+			go classifier.Run(stopCh)
+			Eventually(classifier.HasSynced).Should(BeTrue())
+		})
+
+		utilization := func() topology.Range {
+			return classifier.Classify(newPod(map[string]string{"workloadKind": "TrainingWorkload"}, nil)).Utilization
+		}
+
+		It("should follow edits of the ConfigMap", func() {
+			Eventually(utilization).Should(Equal(topology.Range{Min: 42, Max: 42}))
+
+			_, err := kubeClient.CoreV1().ConfigMaps(rulesCmNsName).Update(context.TODO(), newRulesConfigMap(`
+rules:
+- name: everything
+  utilization: 10-20
+`), metav1.UpdateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(utilization).Should(Equal(topology.Range{Min: 10, Max: 20}))
+		})
+
+		It("should keep the previous rules when the ConfigMap becomes invalid", func() {
+			Eventually(utilization).Should(Equal(topology.Range{Min: 42, Max: 42}))
+
+			_, err := kubeClient.CoreV1().ConfigMaps(rulesCmNsName).Update(context.TODO(), newRulesConfigMap(`
+rules:
+- name: everything
+  utilization: 50-10
+`), metav1.UpdateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Consistently(utilization).Should(Equal(topology.Range{Min: 42, Max: 42}))
+		})
+
+		It("should fall back to the default rules when the ConfigMap is deleted", func() {
+			Eventually(utilization).Should(Equal(topology.Range{Min: 42, Max: 42}))
+
+			err := kubeClient.CoreV1().ConfigMaps(rulesCmNsName).Delete(context.TODO(), rulesCmName, metav1.DeleteOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(utilization).Should(Equal(topology.Range{Min: 80, Max: 100}))
+		})
+	})
+})
+
+var _ = Describe("ParseRules", func() {
+	DescribeTable("should reject invalid rules",
+		func(rules, message string) {
+			_, err := workload.ParseRules([]byte(rules))
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("bad utilization", "rules: [{name: a, utilization: high}]", "utilization must be"),
+		Entry("descending range", "rules: [{name: a, utilization: 50-10}]", "ascending range"),
+		Entry("memory above 100%", "rules: [{name: a, utilization: '1', memoryPercent: 120}]", "memoryPercent"),
+		Entry("bad selector", "rules: [{name: a, utilization: '1', match: {labels: 'a in b'}}]", "labels selector"),
+		Entry("field match without values", "rules: [{name: a, utilization: '1', match: {podGroup: {field: spec.x}}}]", "podGroup needs"),
+		Entry("not YAML", "rules: {", "failed to parse"),
+	)
+})
+
+var _ = Describe("DefaultRules", func() {
+	It("should match the rules of the chart's values.yaml", func() {
+		data, err := os.ReadFile("../../../deploy/fake-gpu-operator/values.yaml")
+		Expect(err).NotTo(HaveOccurred())
+		var values struct {
+			StatusUpdater struct {
+				WorkloadClassification workload.Config `yaml:"workloadClassification"`
+			} `yaml:"statusUpdater"`
+		}
+		Expect(yaml.Unmarshal(data, &values)).To(Succeed())
+
+		chartRules, err := workload.NewRuleSet(values.StatusUpdater.WorkloadClassification.Rules)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(chartRules).To(Equal(workload.DefaultRules), "statusUpdater.workloadClassification.rules in values.yaml differ from the built-in default rules")
+	})
+})
@@ -0,0 +1,60 @@
+package workload
+
+import "log"
+
+// defaultRulesYAML classifies Run:ai workloads, first by their workloadKind
+// label and then by the priority class of their PodGroup. The chart's
+// statusUpdater.workloadClassification.rules must hold the same rules, which
+// the DefaultRules test checks.
+const defaultRulesYAML = `
+rules:
+- name: training-workload
+  match:
+    labels: workloadKind=TrainingWorkload
+  utilization: 80-100
+- name: distributed-workload
+  match:
+    labels: workloadKind=DistributedWorkload
+  utilization: "0"
+  inference: true
+- name: inference-workload
+  match:
+    labels: workloadKind=InferenceWorkload
+  utilization: "0"
+  inference: true
+- name: interactive-workload
+  match:
+    labels: workloadKind=InteractiveWorkload
+  utilization: "0"
+- name: train
+  match:
+    podGroup:
+      field: spec.priorityClassName
+      values: [train]
+  utilization: 80-100
+- name: interactive
+  match:
+    podGroup:
+      field: spec.priorityClassName
+      values: [build, interactive-preemptible, interactive, distributed]
+  utilization: "0"
+- name: inference
+  match:
+    podGroup:
+      field: spec.priorityClassName
+      values: [inference, distributed-inference]
+  utilization: "0"
+  inference: true
+`
+
+// DefaultRules is the rule set used without a workload classification
+// ConfigMap. Pods no rule matches use 100% of their GPUs.
+var DefaultRules = mustParseRules(defaultRulesYAML)
+
+func mustParseRules(data string) *RuleSet {
+	ruleSet, err := ParseRules([]byte(data))
+	if err != nil {
+		log.Fatalf("Failed to parse default workload rules: %v", err)
+	}
+	return ruleSet
+}
@@ -0,0 +1,224 @@
+package workload
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/run-ai/fake-gpu-operator/internal/common/topology"
+)
+
+// RulesCmKey is the key of the rules in the workload classification ConfigMap.
+const RulesCmKey = "rules.yml"
+
+var utilizationPattern = regexp.MustCompile(`^(\d+)(?:-(\d+))?$`)
+
+// Config is the content of the workload classification ConfigMap.
+type Config struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule maps the pods it matches to a simulated GPU usage model. Rules are
+// evaluated in order and the first matching rule wins.
+type Rule struct {
+	Name  string `yaml:"name"`
+	Match Match  `yaml:"match"`
+	// Utilization is a percentage ("70") or a range ("80-100").
+	Utilization string `yaml:"utilization"`
+	// MemoryPercent is the share of the pod's GPU memory that is reported as
+	// used. Defaults to 100.
+	MemoryPercent *int `yaml:"memoryPercent,omitempty"`
+	// Inference marks the pod as an inference workload, whose utilization
+	// follows its Knative request rate.
+	Inference bool `yaml:"inference,omitempty"`
+}
+
+// Match selects pods. All set conditions must hold; an empty match selects
+// every pod.
+type Match struct {
+	// Labels and Annotations are label selectors, e.g.
+	// "workloadKind=TrainingWorkload" or "team in (a,b)".
+	Labels      string   `yaml:"labels,omitempty"`
+	Annotations string   `yaml:"annotations,omitempty"`
+	OwnerKinds  []string `yaml:"ownerKinds,omitempty"`
+	Namespaces  []string `yaml:"namespaces,omitempty"`
+	// PodGroup matches a field of the Run:ai PodGroup named by the pod's
+	// pod-group-name annotation.
+	PodGroup *FieldMatch `yaml:"podGroup,omitempty"`
+	// KueueWorkload matches a field of the Kueue Workload owned by the pod or
+	// by its controller.
+	KueueWorkload *FieldMatch `yaml:"kueueWorkload,omitempty"`
+}
+
+// FieldMatch holds when the string at Field, a dot-separated path such as
+// "spec.priorityClassName", is one of Values.
+type FieldMatch struct {
+	Field  string   `yaml:"field"`
+	Values []string `yaml:"values"`
+}
+
+// Model is the simulated GPU usage of a classified pod.
+type Model struct {
+	Rule          string
+	Utilization   topology.Range
+	MemoryPercent int
+	Inference     bool
+}
+
+// RuleSet is a parsed and validated list of rules.
+type RuleSet struct {
+	rules []compiledRule
+}
+
+type compiledRule struct {
+	Rule
+	labels      labels.Selector
+	annotations labels.Selector
+	model       Model
+}
+
+// defaultModel applies to pods no rule matches.
+var defaultModel = Model{Utilization: topology.Range{Min: 100, Max: 100}, MemoryPercent: 100}
+
+// ParseRules parses and validates the rules of a workload classification
+// ConfigMap.
+func ParseRules(data []byte) (*RuleSet, error) {
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse workload rules: %w", err)
+	}
+	return NewRuleSet(config.Rules)
+}
+
+// NewRuleSet validates the rules and prepares them for matching.
+func NewRuleSet(rules []Rule) (*RuleSet, error) {
+	ruleSet := &RuleSet{rules: make([]compiledRule, 0, len(rules))}
+	for idx, rule := range rules {
+		compiled, err := compileRule(rule)
+		if err != nil {
+			name := rule.Name
+			if name == "" {
+				name = strconv.Itoa(idx)
+			}
+			return nil, fmt.Errorf("invalid workload rule %s: %w", name, err)
+		}
+		ruleSet.rules = append(ruleSet.rules, compiled)
+	}
+	return ruleSet, nil
+}
+
+func compileRule(rule Rule) (compiledRule, error) {
+	compiled := compiledRule{Rule: rule}
+
+	utilization, err := parseUtilization(rule.Utilization)
+	if err != nil {
+		return compiled, err
+	}
+	memoryPercent := 100
+	if rule.MemoryPercent != nil {
+		memoryPercent = *rule.MemoryPercent
+		if memoryPercent < 0 || memoryPercent > 100 {
+			return compiled, fmt.Errorf("memoryPercent must be between 0 and 100, got %d", memoryPercent)
+		}
+	}
+	compiled.model = Model{
+		Rule:          rule.Name,
+		Utilization:   utilization,
+		MemoryPercent: memoryPercent,
+		Inference:     rule.Inference,
+	}
+
+	if compiled.labels, err = parseSelector(rule.Match.Labels); err != nil {
+		return compiled, fmt.Errorf("invalid labels selector: %w", err)
+	}
+	if compiled.annotations, err = parseSelector(rule.Match.Annotations); err != nil {
+		return compiled, fmt.Errorf("invalid annotations selector: %w", err)
+	}
+	for name, fieldMatch := range map[string]*FieldMatch{"podGroup": rule.Match.PodGroup, "kueueWorkload": rule.Match.KueueWorkload} {
+		if fieldMatch != nil && (fieldMatch.Field == "" || len(fieldMatch.Values) == 0) {
+			return compiled, fmt.Errorf("%s needs a field and values", name)
+		}
+	}
+
+	return compiled, nil
+}
+
+func parseSelector(selector string) (labels.Selector, error) {
+	if selector == "" {
+		return labels.Everything(), nil
+	}
+	return labels.Parse(selector)
+}
+
+// parseUtilization accepts a percentage ("70") or a range ("20-80").
+func parseUtilization(value string) (topology.Range, error) {
+	match := utilizationPattern.FindStringSubmatch(value)
+	if match == nil {
+		return topology.Range{}, fmt.Errorf(`utilization must be a percentage ("70") or a range ("20-80"), got %q`, value)
+	}
+
+	minUtilization, _ := strconv.Atoi(match[1])
+	maxUtilization := minUtilization
+	if match[2] != "" {
+		maxUtilization, _ = strconv.Atoi(match[2])
+	}
+	if maxUtilization > 100 || minUtilization > maxUtilization {
+		return topology.Range{}, fmt.Errorf("utilization %q must be an ascending range between 0 and 100", value)
+	}
+	return topology.Range{Min: minUtilization, Max: maxUtilization}, nil
+}
+
+// classify returns the model of the first rule that matches the pod. Lookups
+// resolves the PodGroup and Kueue Workload of the pod, only when a rule needs
+// them.
+func (r *RuleSet) classify(pod *v1.Pod, lookups *podLookups) Model {
+	for _, rule := range r.rules {
+		if rule.matches(pod, lookups) {
+			return rule.model
+		}
+	}
+	return defaultModel
+}
+
+func (r *compiledRule) matches(pod *v1.Pod, lookups *podLookups) bool {
+	match := r.Match
+	if len(match.Namespaces) > 0 && !slices.Contains(match.Namespaces, pod.Namespace) {
+		return false
+	}
+	if len(match.OwnerKinds) > 0 && !slices.Contains(match.OwnerKinds, ownerKind(pod)) {
+		return false
+	}
+	if !r.labels.Matches(labels.Set(pod.Labels)) || !r.annotations.Matches(labels.Set(pod.Annotations)) {
+		return false
+	}
+	if match.PodGroup != nil && !match.PodGroup.matches(lookups.podGroupField) {
+		return false
+	}
+	if match.KueueWorkload != nil && !match.KueueWorkload.matches(lookups.kueueWorkloadField) {
+		return false
+	}
+	return true
+}
+
+func (m *FieldMatch) matches(lookup func(field string) (string, bool)) bool {
+	value, found := lookup(m.Field)
+	return found && slices.Contains(m.Values, value)
+}
+
+// ownerKind returns the kind of the pod's controller, or of its first owner.
+func ownerKind(pod *v1.Pod) string {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Controller != nil && *owner.Controller {
+			return owner.Kind
+		}
+	}
+	if len(pod.OwnerReferences) > 0 {
+		return pod.OwnerReferences[0].Kind
+	}
+	return ""
+}
@@ -2,6 +2,7 @@ package e2e_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
@@ -18,6 +19,8 @@ import (
 	"github.com/onsi/gomega/types"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -25,7 +28,6 @@ import (
 
 	nvidiaversioned "github.com/NVIDIA/k8s-dra-driver-gpu/pkg/nvidia.com/clientset/versioned"
 	ctrl "sigs.k8s.io/controller-runtime"
-	"gopkg.in/yaml.v3"
 )
 
 const (
@@ -43,6 +45,14 @@ const (
 	expectedHighendGpuCount   = 4
 )
 
+// fakeGpuNodeGVR identifies the FakeGpuNode resources in which the
+// status-updater stores the topology of each fake GPU node.
+var fakeGpuNodeGVR = schema.GroupVersionResource{
+	Group:    "fake-gpu-operator.run.ai",
+	Version:  "v1alpha1",
+	Resource: "fakegpunodes",
+}
+
 var (
 	kubeClient    kubernetes.Interface
 	dynamicClient dynamic.Interface
@@ -350,8 +360,8 @@ var _ = Describe("KWOK DRA Plugin Tests", func() {
 	)
 
 	Describe("ResourceSlice Creation", func() {
-		It("should create ResourceSlice for KWOK node from topology ConfigMap", func() {
-			// The setup.sh script creates a KWOK node with topology ConfigMap
+		It("should create ResourceSlice for KWOK node from its FakeGpuNode", func() {
+			// The setup.sh script creates a KWOK node with a FakeGpuNode
 			// Verify that the kwok-dra-plugin created a ResourceSlice for it
 			resourceSliceName := fmt.Sprintf("kwok-%s-gpu", kwokNodeName)
 
@@ -407,7 +417,7 @@ var _ = Describe("KWOK DRA Plugin Tests", func() {
 	})
 
 	Describe("ResourceSlice Consistency", func() {
-		It("should maintain ResourceSlice when topology ConfigMap is updated", func() {
+		It("should maintain ResourceSlice when the FakeGpuNode is updated", func() {
 			resourceSliceName := fmt.Sprintf("kwok-%s-gpu", kwokNodeName)
 
 			// Get initial ResourceSlice
@@ -417,24 +427,21 @@ var _ = Describe("KWOK DRA Plugin Tests", func() {
 			initialDeviceCount := len(resourceSlice.Spec.Devices)
 			Expect(initialDeviceCount).To(Equal(expectedGpuCount), fmt.Sprintf("Should have %d devices initially", expectedGpuCount))
 
-			// Find the topology ConfigMap for this KWOK node
-			cmList, err := kubeClient.CoreV1().ConfigMaps(gpuOperatorNS).List(
-				context.Background(), metav1.ListOptions{
-					LabelSelector: fmt.Sprintf("node-name=%s", kwokNodeName),
-				})
-			Expect(err).NotTo(HaveOccurred(), "Should list ConfigMaps")
-			Expect(cmList.Items).NotTo(BeEmpty(), "Should find topology ConfigMap")
+			// Find the FakeGpuNode of this KWOK node
+			fakeGpuNode, err := dynamicClient.Resource(fakeGpuNodeGVR).Get(
+				context.Background(), kwokNodeName, metav1.GetOptions{})
+			Expect(err).NotTo(HaveOccurred(), "Should find FakeGpuNode")
 
-			cm := &cmList.Items[0]
-
-			// Update the ConfigMap labels to trigger reconciliation
-			if cm.Labels == nil {
-				cm.Labels = make(map[string]string)
+			// Update the FakeGpuNode labels to trigger reconciliation
+			labels := fakeGpuNode.GetLabels()
+			if labels == nil {
+				labels = make(map[string]string)
 			}
-			cm.Labels["test-label"] = "test-value"
-			_, err = kubeClient.CoreV1().ConfigMaps(gpuOperatorNS).Update(
-				context.Background(), cm, metav1.UpdateOptions{})
-			Expect(err).NotTo(HaveOccurred(), "Should update ConfigMap")
+			labels["test-label"] = "test-value"
+			fakeGpuNode.SetLabels(labels)
+			_, err = dynamicClient.Resource(fakeGpuNodeGVR).Update(
+				context.Background(), fakeGpuNode, metav1.UpdateOptions{})
+			Expect(err).NotTo(HaveOccurred(), "Should update FakeGpuNode")
 
 			// Wait a moment for reconciliation
 			time.Sleep(2 * time.Second)
@@ -444,7 +451,7 @@ var _ = Describe("KWOK DRA Plugin Tests", func() {
 				context.Background(), resourceSliceName, metav1.GetOptions{})
 			Expect(err).NotTo(HaveOccurred(), "Should get ResourceSlice after update")
 			Expect(len(resourceSlice.Spec.Devices)).To(Equal(initialDeviceCount),
-				"ResourceSlice should maintain device count after ConfigMap update")
+				"ResourceSlice should maintain device count after FakeGpuNode update")
 		})
 	})
 })
@@ -516,30 +523,28 @@ var _ = Describe("Multi-Nodepool Topology Tests", func() {
 		{nodeName: "kwok-gpu-node-4", gpuProduct: expectedHighendGpuProduct, gpuCount: expectedHighendGpuCount},
 	}
 
-	Describe("Topology ConfigMap per pool", func() {
+	Describe("FakeGpuNode per pool", func() {
 		for _, pool := range pools {
 			pool := pool // capture range variable
 
 			It(fmt.Sprintf("should create correct topology for node %s", pool.nodeName), func() {
-				cmList, err := kubeClient.CoreV1().ConfigMaps(gpuOperatorNS).List(
-					context.Background(), metav1.ListOptions{
-						LabelSelector: fmt.Sprintf("node-name=%s", pool.nodeName),
-					})
-				Expect(err).NotTo(HaveOccurred(), "Should list ConfigMaps")
-				Expect(cmList.Items).To(HaveLen(1), "Should have exactly one topology CM for %s", pool.nodeName)
-
-				cm := cmList.Items[0]
-				topologyYAML, ok := cm.Data["topology.yml"]
-				Expect(ok).To(BeTrue(), "ConfigMap should have 'topology.yml' key")
+				fakeGpuNode, err := dynamicClient.Resource(fakeGpuNodeGVR).Get(
+					context.Background(), pool.nodeName, metav1.GetOptions{})
+				Expect(err).NotTo(HaveOccurred(), "Should have a FakeGpuNode for %s", pool.nodeName)
 
 				var topo struct {
-					GpuProduct string `yaml:"gpuProduct"`
-					GpuMemory  int    `yaml:"gpuMemory"`
+					GpuProduct string `json:"gpuProduct"`
+					GpuMemory  int    `json:"gpuMemory"`
 					Gpus       []struct {
-						ID string `yaml:"id"`
-					} `yaml:"gpus"`
+						ID string `json:"id"`
+					} `json:"gpus"`
 				}
-				Expect(yaml.Unmarshal([]byte(topologyYAML), &topo)).To(Succeed())
+				spec, found, err := unstructured.NestedMap(fakeGpuNode.Object, "spec")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found).To(BeTrue(), "FakeGpuNode should have a spec")
+				specJSON, err := json.Marshal(spec)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(json.Unmarshal(specJSON, &topo)).To(Succeed())
 
 				Expect(topo.Gpus).To(HaveLen(pool.gpuCount),
 					"Node %s should have %d GPUs", pool.nodeName, pool.gpuCount)
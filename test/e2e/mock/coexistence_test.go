@@ -33,18 +33,10 @@ var _ = Describe("Phase 4: Fake + mock coexistence", func() {
 			"nvml-mock-fake-default ConfigMap must NOT exist")
 	})
 
-	It("status-updater created a node-topology ConfigMap for kwok-fake-1 (existing fake-pool behavior preserved)", func() {
+	It("status-updater created a FakeGpuNode for kwok-fake-1 (existing fake-pool behavior preserved)", func() {
 		Eventually(func() error {
-			cms, err := kubeClient.CoreV1().ConfigMaps(releaseNs).List(context.Background(), metav1.ListOptions{
-				LabelSelector: fmt.Sprintf("node-name=%s", fakeNodeName),
-			})
-			if err != nil {
-				return err
-			}
-			if len(cms.Items) == 0 {
-				return fmt.Errorf("no topology CM yet for %s", fakeNodeName)
-			}
-			return nil
+			_, err := dynamicClient.Resource(fakeGpuNodeGVR).Get(context.Background(), fakeNodeName, metav1.GetOptions{})
+			return err
 		}, 60*time.Second, 2*time.Second).Should(Succeed())
 	})
 
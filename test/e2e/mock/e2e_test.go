@@ -13,6 +13,8 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -41,9 +43,18 @@ const (
 	expectedH100Product = "NVIDIA H100"
 )
 
+// fakeGpuNodeGVR identifies the FakeGpuNode resources in which the
+// status-updater stores the topology of each fake GPU node.
+var fakeGpuNodeGVR = schema.GroupVersionResource{
+	Group:    "fake-gpu-operator.run.ai",
+	Version:  "v1alpha1",
+	Resource: "fakegpunodes",
+}
+
 var (
-	kubeClient kubernetes.Interface
-	restConfig *rest.Config
+	kubeClient    kubernetes.Interface
+	dynamicClient dynamic.Interface
+	restConfig    *rest.Config
 )
 
 func TestE2E(t *testing.T) {
@@ -60,6 +71,9 @@ var _ = BeforeSuite(func() {
 	kubeClient, err = kubernetes.NewForConfig(restConfig)
 	Expect(err).NotTo(HaveOccurred())
 
+	dynamicClient, err = dynamic.NewForConfig(restConfig)
+	Expect(err).NotTo(HaveOccurred())
+
 	// Sanity: cluster reachable, both real workers present, KWOK fake node present.
 	nodes, err := kubeClient.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
 	Expect(err).NotTo(HaveOccurred())
@@ -170,19 +170,19 @@ if [[ "${SKIP_SETUP}" != "true" ]]; then
         create_kwok_node "${NODE_NAME}" "${NODE_POOL}"
     done
 
-    # The status-updater will automatically create a topology ConfigMap for each KWOK node
+    # The status-updater will automatically create a FakeGpuNode for each KWOK node
     # because they have the run.ai/simulated-gpu-node-pool label. The kwok.x-k8s.io/node annotation
-    # is copied from the node to the ConfigMap by the status-updater.
+    # is copied from the node to the FakeGpuNode by the status-updater.
 
-    # Wait for topology ConfigMaps to be created
+    # Wait for FakeGpuNodes to be created
     for NODE_NAME in "${KWOK_NODES[@]}"; do
-        echo "Waiting for status-updater to create topology ConfigMap for ${NODE_NAME}..."
+        echo "Waiting for status-updater to create FakeGpuNode for ${NODE_NAME}..."
     for i in {1..30}; do
-            if kubectl get cm -n gpu-operator -l "node-name=${NODE_NAME}" >/dev/null 2>&1; then
-                echo "Topology ConfigMap created for ${NODE_NAME}!"
+            if kubectl get fakegpunode "${NODE_NAME}" >/dev/null 2>&1; then
+                echo "FakeGpuNode created for ${NODE_NAME}!"
             break
         fi
-        echo "Waiting for topology ConfigMap... ($i/30)"
+        echo "Waiting for FakeGpuNode... ($i/30)"
         sleep 2
     done
 